@@ -3,12 +3,16 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// defaultJWTSecret is the placeholder JWT_SECRET, which production refuses to start with
+const defaultJWTSecret = "your-super-secret-jwt-key-change-this-in-production"
+
 type Config struct {
 	// Database
 	DBHost     string
@@ -33,7 +37,52 @@ type Config struct {
 	CORSOrigins string
 
 	// QR Code
-	QRCodeSize int
+	QRCodeSize          int
+	QRRotationInterval  time.Duration
+	QRAllowStaticTokens bool
+	QRCardSecret        string
+
+	// Offline attendance sync
+	OfflineSyncMaxAge time.Duration
+
+	// Absentee follow-ups
+	FollowUpScanInterval   time.Duration
+	FollowUpMissedServices int
+	FollowUpRecentWindow   time.Duration
+	FollowUpBaselineWindow time.Duration
+	FollowUpRateThreshold  float64
+	FollowUpDueAfter       time.Duration
+
+	// Attendance needed in a membership stage before the next stage is suggested
+	MembershipReturningVisits int
+	MembershipClassVisits     int
+	MembershipMemberVisits    int
+	MembershipWorkerVisits    int
+
+	// Children's check-in: how long after an event ends, or after check-in when there is no event, a child
+	// still waiting to be picked up is flagged
+	ChildPickupGracePeriod time.Duration
+	ChildPickupAlertAfter  time.Duration
+
+	// Geofenced self check-in: the least precise GPS fix, in meters, that is trusted
+	GeofenceMaxAccuracy float64
+
+	// How long a member's role permissions are cached before they are read again
+	PermissionCacheTTL time.Duration
+
+	// Login brute-force protection: failed attempts allowed per account and per IP within the window before a
+	// lockout, and the delay that doubles with every failure after the first
+	LoginMaxAttempts     int
+	LoginIPMaxAttempts   int
+	LoginAttemptWindow   time.Duration
+	LoginLockoutDuration time.Duration
+	LoginDelayBase       time.Duration
+
+	// Two-factor authentication: the issuer name shown in authenticator apps, how long the challenge between
+	// password and code lasts, and whether admin accounts must use it
+	TwoFactorIssuer         string
+	TwoFactorChallengeTTL   time.Duration
+	TwoFactorRequiredAdmins bool
 
 	// Timezone
 	Timezone string
@@ -49,6 +98,10 @@ type Config struct {
 
 	// Password Reset
 	PasswordResetTokenLifespan time.Duration
+
+	// Service Events
+	EventCheckinEarlyWindow time.Duration
+	DefaultLateGracePeriod  time.Duration
 }
 
 func Load() *Config {
@@ -73,6 +126,160 @@ func Load() *Config {
 		log.Fatal("Invalid PASSWORD_RESET_TOKEN_LIFESPAN format:", err)
 	}
 
+	eventCheckinEarlyWindow, err := time.ParseDuration(getEnv("EVENT_CHECKIN_EARLY_WINDOW", "1h"))
+	if err != nil {
+		log.Fatal("Invalid EVENT_CHECKIN_EARLY_WINDOW format:", err)
+	}
+
+	defaultLateGracePeriod, err := time.ParseDuration(getEnv("LATE_GRACE_PERIOD", "15m"))
+	if err != nil {
+		log.Fatal("Invalid LATE_GRACE_PERIOD format:", err)
+	}
+
+	qrRotationInterval, err := time.ParseDuration(getEnv("QR_ROTATION_INTERVAL", "30s"))
+	if err != nil {
+		log.Fatal("Invalid QR_ROTATION_INTERVAL format:", err)
+	}
+	if qrRotationInterval < time.Second {
+		log.Fatal("QR_ROTATION_INTERVAL must be at least 1s")
+	}
+
+	qrAllowStaticTokens, err := strconv.ParseBool(getEnv("QR_ALLOW_STATIC_TOKENS", "false"))
+	if err != nil {
+		log.Fatal("Invalid QR_ALLOW_STATIC_TOKENS format:", err)
+	}
+
+	offlineSyncMaxAge, err := time.ParseDuration(getEnv("OFFLINE_SYNC_MAX_AGE", "72h"))
+	if err != nil {
+		log.Fatal("Invalid OFFLINE_SYNC_MAX_AGE format:", err)
+	}
+
+	followUpScanInterval, err := time.ParseDuration(getEnv("FOLLOW_UP_SCAN_INTERVAL", "24h"))
+	if err != nil {
+		log.Fatal("Invalid FOLLOW_UP_SCAN_INTERVAL format:", err)
+	}
+
+	followUpMissedServices, err := strconv.Atoi(getEnv("FOLLOW_UP_MISSED_SERVICES", "3"))
+	if err != nil {
+		log.Fatal("Invalid FOLLOW_UP_MISSED_SERVICES format:", err)
+	}
+	if followUpMissedServices < 1 {
+		log.Fatal("FOLLOW_UP_MISSED_SERVICES must be at least 1")
+	}
+
+	followUpRecentWindow, err := time.ParseDuration(getEnv("FOLLOW_UP_RECENT_WINDOW", "672h"))
+	if err != nil {
+		log.Fatal("Invalid FOLLOW_UP_RECENT_WINDOW format:", err)
+	}
+
+	followUpBaselineWindow, err := time.ParseDuration(getEnv("FOLLOW_UP_BASELINE_WINDOW", "2016h"))
+	if err != nil {
+		log.Fatal("Invalid FOLLOW_UP_BASELINE_WINDOW format:", err)
+	}
+
+	followUpRateThreshold, err := strconv.ParseFloat(getEnv("FOLLOW_UP_RATE_THRESHOLD", "0.5"), 64)
+	if err != nil {
+		log.Fatal("Invalid FOLLOW_UP_RATE_THRESHOLD format:", err)
+	}
+
+	followUpDueAfter, err := time.ParseDuration(getEnv("FOLLOW_UP_DUE_AFTER", "72h"))
+	if err != nil {
+		log.Fatal("Invalid FOLLOW_UP_DUE_AFTER format:", err)
+	}
+
+	membershipReturningVisits, err := strconv.Atoi(getEnv("MEMBERSHIP_RETURNING_VISITS", "2"))
+	if err != nil {
+		log.Fatal("Invalid MEMBERSHIP_RETURNING_VISITS format:", err)
+	}
+
+	membershipClassVisits, err := strconv.Atoi(getEnv("MEMBERSHIP_CLASS_VISITS", "4"))
+	if err != nil {
+		log.Fatal("Invalid MEMBERSHIP_CLASS_VISITS format:", err)
+	}
+
+	membershipMemberVisits, err := strconv.Atoi(getEnv("MEMBERSHIP_MEMBER_VISITS", "6"))
+	if err != nil {
+		log.Fatal("Invalid MEMBERSHIP_MEMBER_VISITS format:", err)
+	}
+
+	membershipWorkerVisits, err := strconv.Atoi(getEnv("MEMBERSHIP_WORKER_VISITS", "12"))
+	if err != nil {
+		log.Fatal("Invalid MEMBERSHIP_WORKER_VISITS format:", err)
+	}
+
+	childPickupGracePeriod, err := time.ParseDuration(getEnv("CHILD_PICKUP_GRACE_PERIOD", "15m"))
+	if err != nil {
+		log.Fatal("Invalid CHILD_PICKUP_GRACE_PERIOD format:", err)
+	}
+
+	childPickupAlertAfter, err := time.ParseDuration(getEnv("CHILD_PICKUP_ALERT_AFTER", "3h"))
+	if err != nil {
+		log.Fatal("Invalid CHILD_PICKUP_ALERT_AFTER format:", err)
+	}
+
+	permissionCacheTTL, err := time.ParseDuration(getEnv("PERMISSION_CACHE_TTL", "1m"))
+	if err != nil {
+		log.Fatal("Invalid PERMISSION_CACHE_TTL format:", err)
+	}
+
+	geofenceMaxAccuracy, err := strconv.ParseFloat(getEnv("GEOFENCE_MAX_ACCURACY", "100"), 64)
+	if err != nil {
+		log.Fatal("Invalid GEOFENCE_MAX_ACCURACY format:", err)
+	}
+
+	loginMaxAttempts, err := strconv.Atoi(getEnv("LOGIN_MAX_ATTEMPTS", "5"))
+	if err != nil || loginMaxAttempts < 1 {
+		log.Fatal("Invalid LOGIN_MAX_ATTEMPTS format:", err)
+	}
+
+	loginIPMaxAttempts, err := strconv.Atoi(getEnv("LOGIN_IP_MAX_ATTEMPTS", "20"))
+	if err != nil || loginIPMaxAttempts < 1 {
+		log.Fatal("Invalid LOGIN_IP_MAX_ATTEMPTS format:", err)
+	}
+
+	loginAttemptWindow, err := time.ParseDuration(getEnv("LOGIN_ATTEMPT_WINDOW", "15m"))
+	if err != nil {
+		log.Fatal("Invalid LOGIN_ATTEMPT_WINDOW format:", err)
+	}
+
+	loginLockoutDuration, err := time.ParseDuration(getEnv("LOGIN_LOCKOUT_DURATION", "15m"))
+	if err != nil {
+		log.Fatal("Invalid LOGIN_LOCKOUT_DURATION format:", err)
+	}
+
+	loginDelayBase, err := time.ParseDuration(getEnv("LOGIN_DELAY_BASE", "1s"))
+	if err != nil {
+		log.Fatal("Invalid LOGIN_DELAY_BASE format:", err)
+	}
+
+	twoFactorChallengeTTL, err := time.ParseDuration(getEnv("TWO_FACTOR_CHALLENGE_TTL", "5m"))
+	if err != nil {
+		log.Fatal("Invalid TWO_FACTOR_CHALLENGE_TTL format:", err)
+	}
+
+	twoFactorRequiredAdmins, err := strconv.ParseBool(getEnv("TWO_FACTOR_REQUIRED_ADMINS", "true"))
+	if err != nil {
+		log.Fatal("Invalid TWO_FACTOR_REQUIRED_ADMINS format:", err)
+	}
+
+	timezone := getEnv("TIMEZONE", "Africa/Lagos")
+	if _, err := time.LoadLocation(timezone); err != nil {
+		log.Fatal("Invalid TIMEZONE:", err)
+	}
+
+	env := getEnv("ENV", "development")
+	jwtSecret := getEnv("JWT_SECRET", defaultJWTSecret)
+	if jwtSecret == defaultJWTSecret && env == "production" {
+		log.Fatal("JWT_SECRET must be changed from its default in production")
+	}
+	qrCardSecret := getEnv("QR_CARD_SECRET", "")
+	if qrCardSecret == "" {
+		log.Fatal("QR_CARD_SECRET must be set")
+	}
+	if qrCardSecret == jwtSecret {
+		log.Fatal("QR_CARD_SECRET must differ from JWT_SECRET")
+	}
+
 	return &Config{
 		DB_URI:                     getEnv("DB_URI", ""),
 		DBHost:                     getEnv("DB_HOST", "localhost"),
@@ -80,20 +287,48 @@ func Load() *Config {
 		DBName:                     getEnv("DB_NAME", "church_attendance_db"),
 		DBUser:                     getEnv("DB_USER", ""),
 		DBPassword:                 getEnv("DB_PASSWORD", ""),
-		JWTSecret:                  getEnv("JWT_SECRET", "your-super-secret-jwt-key-change-this-in-production"),
+		JWTSecret:                  jwtSecret,
 		JWTAccessExpiry:            accessExpiry,
 		JWTRefreshExpiry:           refreshExpiry,
 		Port:                       getEnv("PORT", "8080"),
-		Env:                        getEnv("ENV", "development"),
+		Env:                        env,
 		CORSOrigins:                getEnv("CORS_ORIGINS", "http://localhost:3000,http://localhost:8080"),
 		QRCodeSize:                 256,
-		Timezone:                   getEnv("TIMEZONE", "Africa/Lagos"),
+		QRRotationInterval:         qrRotationInterval,
+		QRAllowStaticTokens:        qrAllowStaticTokens,
+		QRCardSecret:               qrCardSecret,
+		OfflineSyncMaxAge:          offlineSyncMaxAge,
+		FollowUpScanInterval:       followUpScanInterval,
+		FollowUpMissedServices:     followUpMissedServices,
+		FollowUpRecentWindow:       followUpRecentWindow,
+		FollowUpBaselineWindow:     followUpBaselineWindow,
+		FollowUpRateThreshold:      followUpRateThreshold,
+		FollowUpDueAfter:           followUpDueAfter,
+		MembershipReturningVisits:  membershipReturningVisits,
+		MembershipClassVisits:      membershipClassVisits,
+		MembershipMemberVisits:     membershipMemberVisits,
+		MembershipWorkerVisits:     membershipWorkerVisits,
+		ChildPickupGracePeriod:     childPickupGracePeriod,
+		ChildPickupAlertAfter:      childPickupAlertAfter,
+		GeofenceMaxAccuracy:        geofenceMaxAccuracy,
+		PermissionCacheTTL:         permissionCacheTTL,
+		LoginMaxAttempts:           loginMaxAttempts,
+		LoginIPMaxAttempts:         loginIPMaxAttempts,
+		LoginAttemptWindow:         loginAttemptWindow,
+		LoginLockoutDuration:       loginLockoutDuration,
+		LoginDelayBase:             loginDelayBase,
+		TwoFactorIssuer:            getEnv("TWO_FACTOR_ISSUER", "CCI Member Portal"),
+		TwoFactorChallengeTTL:      twoFactorChallengeTTL,
+		TwoFactorRequiredAdmins:    twoFactorRequiredAdmins,
+		Timezone:                   timezone,
 		ResendAPIKey:               getEnv("RESEND_API_KEY", ""),
 		ResendFrom:                 getEnv("RESEND_FROM", ""),
 		ResendCc:                   getEnvAsSlice("RESEND_CC", []string{}),
 		ResendBcc:                  getEnvAsSlice("RESEND_BCC", []string{}),
 		FrontendURL:                getEnv("FRONTEND_URL", "http://localhost:3000"),
 		PasswordResetTokenLifespan: passwordResetLifespan,
+		EventCheckinEarlyWindow:    eventCheckinEarlyWindow,
+		DefaultLateGracePeriod:     defaultLateGracePeriod,
 	}
 }
 
@@ -79,6 +79,21 @@ func (d *Database) Collection(name string) *mongo.Collection {
 	return d.DB.Collection(name)
 }
 
+// WithTransaction runs fn in a multi-document transaction, committing when it returns nil and aborting otherwise.
+// fn must use the context it is given for its database calls. Transactions need MongoDB to run as a replica set.
+func (d *Database) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	session, err := d.Client.StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+	return err
+}
+
 // CreateIndexes creates necessary indexes for the collections
 func (d *Database) CreateIndexes() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -98,6 +113,9 @@ func (d *Database) CreateIndexes() error {
 		{
 			Keys: map[string]interface{}{"qr_code_token": 1},
 		},
+		{
+			Keys: map[string]interface{}{"membership_stage": 1},
+		},
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create users indexes: %w", err)
@@ -105,6 +123,17 @@ func (d *Database) CreateIndexes() error {
 
 	// Attendance collection indexes
 	attendanceCollection := d.Collection("attendance")
+	// The one-check-in-per-event indexes only cover records with voided set, so give older records the field
+	// and drop the non-unique indexes they replace
+	_, err = attendanceCollection.UpdateMany(ctx, bson.M{"voided": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"voided": false}})
+	if err != nil {
+		return fmt.Errorf("failed to update attendance records: %w", err)
+	}
+	for _, name := range []string{"user_1_event_1", "family_member_1_event_1"} {
+		if err = dropNonUniqueIndex(ctx, attendanceCollection, name); err != nil {
+			return fmt.Errorf("failed to update attendance indexes: %w", err)
+		}
+	}
 	_, err = attendanceCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
 		{
 			Keys: map[string]interface{}{"user": 1},
@@ -118,11 +147,67 @@ func (d *Database) CreateIndexes() error {
 				{Key: "date_time_of_attendance", Value: -1},
 			},
 		},
+		{
+			Keys: bson.D{
+				{Key: "user", Value: 1},
+				{Key: "event", Value: 1},
+			},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(checkedInOnce("user")),
+		},
+		{
+			Keys: map[string]interface{}{"event": 1},
+		},
+		{
+			Keys:    map[string]interface{}{"idempotency_key": 1},
+			Options: options.Index().SetUnique(true).SetSparse(true),
+		},
+		{
+			Keys:    map[string]interface{}{"visitor_profile": 1},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "visitor_profile", Value: 1},
+				{Key: "event", Value: 1},
+			},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(checkedInOnce("visitor_profile")),
+		},
+		{
+			Keys: bson.D{
+				{Key: "family_member", Value: 1},
+				{Key: "event", Value: 1},
+			},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(checkedInOnce("family_member")),
+		},
+		{
+			Keys: bson.D{
+				{Key: "household", Value: 1},
+				{Key: "date_time_of_attendance", Value: -1},
+			},
+			Options: options.Index().SetSparse(true),
+		},
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create attendance indexes: %w", err)
 	}
 
+	// Service events collection indexes
+	serviceEventsCollection := d.Collection("service_events")
+	_, err = serviceEventsCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "start_time", Value: -1},
+				{Key: "end_time", Value: 1},
+			},
+		},
+		{
+			Keys: map[string]interface{}{"event_type": 1},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create service_events indexes: %w", err)
+	}
+
 	// Family members collection indexes
 	familyCollection := d.Collection("family_members")
 	_, err = familyCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
@@ -178,6 +263,9 @@ func (d *Database) CreateIndexes() error {
 		{
 			Keys: map[string]interface{}{"user_id": 1},
 		},
+		{
+			Keys: map[string]interface{}{"family_id": 1},
+		},
 		{
 			Keys:    map[string]interface{}{"expires_at": 1},
 			Options: options.Index().SetExpireAfterSeconds(0),
@@ -187,6 +275,187 @@ func (d *Database) CreateIndexes() error {
 		return fmt.Errorf("failed to create refresh_tokens indexes: %w", err)
 	}
 
+	// Venue codes collection indexes
+	venueCodesCollection := d.Collection("venue_codes")
+	// The event and venue index used to allow duplicates; drop it so it can be rebuilt as unique
+	if err = dropNonUniqueIndex(ctx, venueCodesCollection, "event_1_venue_1"); err != nil {
+		return fmt.Errorf("failed to update venue_codes indexes: %w", err)
+	}
+	_, err = venueCodesCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    map[string]interface{}{"code": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "event", Value: 1},
+				{Key: "venue", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    map[string]interface{}{"expires_at": 1},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create venue_codes indexes: %w", err)
+	}
+
+	// QR token uses collection indexes
+	qrTokenUsesCollection := d.Collection("qr_token_uses")
+	_, err = qrTokenUsesCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    map[string]interface{}{"expires_at": 1},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create qr_token_uses indexes: %w", err)
+	}
+
+	// Login attempts collection indexes
+	loginAttemptsCollection := d.Collection("login_attempts")
+	_, err = loginAttemptsCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    map[string]interface{}{"expires_at": 1},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create login_attempts indexes: %w", err)
+	}
+
+	// Attendance audit collection indexes
+	attendanceAuditCollection := d.Collection("attendance_audit")
+	_, err = attendanceAuditCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "attendance", Value: 1},
+				{Key: "performed_at", Value: 1},
+			},
+		},
+		{
+			Keys: map[string]interface{}{"performed_by": 1},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create attendance_audit indexes: %w", err)
+	}
+
+	// Follow-ups collection indexes
+	followUpsCollection := d.Collection("follow_ups")
+	_, err = followUpsCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "user", Value: 1},
+				{Key: "status", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "assigned_to", Value: 1},
+				{Key: "status", Value: 1},
+			},
+		},
+		{
+			Keys: map[string]interface{}{"due_date": 1},
+		},
+		{
+			// One unresolved follow-up per member. Partial indexes cannot use $ne or $in before MongoDB 6.0,
+			// so this relies on "open" and "contacted" both sorting before "resolved".
+			Keys:    bson.D{{Key: "user", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"status": bson.M{"$lt": "resolved"}}),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create follow_ups indexes: %w", err)
+	}
+
+	// Membership transitions collection indexes
+	membershipTransitionsCollection := d.Collection("membership_transitions")
+	_, err = membershipTransitionsCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "user", Value: 1},
+				{Key: "changed_at", Value: 1},
+			},
+		},
+		{
+			Keys: map[string]interface{}{"from": 1},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create membership_transitions indexes: %w", err)
+	}
+
+	// Child check-ins collection indexes
+	childCheckinsCollection := d.Collection("child_checkins")
+	_, err = childCheckinsCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "family_member", Value: 1},
+				{Key: "checked_out_at", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "pickup_code", Value: 1},
+				{Key: "checked_out_at", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "classroom", Value: 1},
+				{Key: "checked_out_at", Value: 1},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create child_checkins indexes: %w", err)
+	}
+
+	// Visitors collection indexes
+	visitorsCollection := d.Collection("visitors")
+	_, err = visitorsCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: map[string]interface{}{"phone_number": 1},
+		},
+		{
+			Keys:    map[string]interface{}{"email": 1},
+			Options: options.Index().SetSparse(true),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create visitors indexes: %w", err)
+	}
+
 	log.Println("Database indexes created successfully!")
 	return nil
 }
+
+// dropNonUniqueIndex drops the named index if it exists without a unique constraint, so CreateIndexes can
+// recreate it as unique instead of failing on conflicting options
+func dropNonUniqueIndex(ctx context.Context, collection *mongo.Collection, name string) error {
+	specs, err := collection.Indexes().ListSpecifications(ctx)
+	if err != nil {
+		return err
+	}
+	for _, spec := range specs {
+		if spec.Name == name && (spec.Unique == nil || !*spec.Unique) {
+			_, err = collection.Indexes().DropOne(ctx, name)
+			return err
+		}
+	}
+	return nil
+}
+
+// checkedInOnce is the partial filter for the indexes that allow one live attendance record per person and
+// event. Voided records are left out so a voided check-in can be recorded again.
+func checkedInOnce(person string) bson.M {
+	return bson.M{
+		person:   bson.M{"$exists": true},
+		"event":  bson.M{"$exists": true},
+		"voided": false,
+	}
+}
@@ -41,8 +41,9 @@ type CompleteRegisterRequest struct {
 }
 
 type LoginRequest struct {
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required"`
+	Email      string `json:"email" validate:"required,email"`
+	Password   string `json:"password" validate:"required"`
+	DeviceName string `json:"device_name" validate:"omitempty,max=100"`
 }
 
 type RefreshTokenRequest struct {
@@ -89,10 +90,69 @@ type CompleteRegisterResponse struct {
 	UpdatedAt                    time.Time           `json:"date_updated"`
 }
 
+// LoginResponse carries the member's tokens, or a challenge token when they still have to enter a two-factor
+// code (or, for admins who must use two-factor authentication, set it up first)
 type LoginResponse struct {
-	AccessToken  string      `json:"access_token"`
-	RefreshToken string      `json:"refresh_token"`
-	User         UserSummary `json:"user"`
+	AccessToken            string      `json:"access_token,omitempty"`
+	RefreshToken           string      `json:"refresh_token,omitempty"`
+	TwoFactorRequired      bool        `json:"two_factor_required,omitempty"`
+	TwoFactorSetupRequired bool        `json:"two_factor_setup_required,omitempty"`
+	ChallengeToken         string      `json:"challenge_token,omitempty"`
+	ChallengeExpiresAt     *time.Time  `json:"challenge_expires_at,omitempty"`
+	RecoveryCodes          []string    `json:"recovery_codes,omitempty"`
+	User                   UserSummary `json:"user"`
+}
+
+// TwoFactorLoginRequest finishes a login with an authenticator code or, instead, a recovery code
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required_without=RecoveryCode"`
+	RecoveryCode   string `json:"recovery_code"`
+	DeviceName     string `json:"device_name" validate:"omitempty,max=100"`
+}
+
+type TwoFactorChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password     string `json:"password" validate:"required"`
+	Code         string `json:"code" validate:"required_without=RecoveryCode"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// TwoFactorSetupResponse holds a new authenticator secret, as text and as a base64 PNG QR code
+type TwoFactorSetupResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+	QRCode     string `json:"qr_code"`
+}
+
+// TwoFactorRecoveryCodesResponse shows recovery codes once; only their hashes are kept
+type TwoFactorRecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type TwoFactorStatusResponse struct {
+	Enabled           bool `json:"enabled"`
+	Required          bool `json:"required"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// SessionResponse is one device the member is logged in on
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	DeviceName string    `json:"device_name"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
 }
 
 type TokenResponse struct {
@@ -109,41 +169,504 @@ type UserSummary struct {
 
 // Attendance DTOs
 type CreateAttendanceRequest struct {
-	UserID string `json:"user_id" validate:"required"`
+	UserID   string `json:"user_id" validate:"required"`
+	ChurchID string `json:"church_id"`
+	EventID  string `json:"event_id"`
 }
 
 type QRCheckinRequest struct {
 	QRCodeToken string `json:"qr_code_token" validate:"required"`
+	ChurchID    string `json:"church_id"`
+	EventID     string `json:"event_id"`
+}
+
+type BatchAttendanceItem struct {
+	IdempotencyKey string `json:"idempotency_key" validate:"required,max=100"`
+	QRCodeToken    string `json:"qr_code_token"`
+	UserID         string `json:"user_id"`
+	ChurchID       string `json:"church_id"`
+	EventID        string `json:"event_id"`
+	CapturedAt     string `json:"captured_at" validate:"required"`
+}
+
+type BatchAttendanceRequest struct {
+	DeviceID string                `json:"device_id" validate:"max=100"`
+	Items    []BatchAttendanceItem `json:"items" validate:"required,min=1,dive"`
+}
+
+type BatchAttendanceResult struct {
+	IdempotencyKey string              `json:"idempotency_key"`
+	Status         string              `json:"status"`
+	Message        string              `json:"message,omitempty"`
+	Attendance     *AttendanceResponse `json:"attendance,omitempty"`
+}
+
+type BatchAttendanceResponse struct {
+	Recorded   int                     `json:"recorded"`
+	Duplicates int                     `json:"duplicates"`
+	Failed     int                     `json:"failed"`
+	Results    []BatchAttendanceResult `json:"results"`
+}
+
+type SelfCheckinRequest struct {
+	VenueCode string `json:"venue_code" validate:"required"`
+}
+
+// GeofenceCheckinRequest is a self check-in from the mobile app using the phone's GPS position.
+// Accuracy is the reported radius of uncertainty of the fix in meters.
+type GeofenceCheckinRequest struct {
+	Latitude  *float64 `json:"latitude" validate:"required,min=-90,max=90"`
+	Longitude *float64 `json:"longitude" validate:"required,min=-180,max=180"`
+	Accuracy  float64  `json:"accuracy" validate:"min=0"`
+	ChurchID  string   `json:"church_id"`
+	EventID   string   `json:"event_id"`
+}
+
+type CheckOutRequest struct {
+	UserID  string `json:"user_id" validate:"required"`
+	EventID string `json:"event_id"`
+}
+
+type QRCheckoutRequest struct {
+	QRCodeToken string `json:"qr_code_token" validate:"required"`
+	EventID     string `json:"event_id"`
+}
+
+type VoidAttendanceRequest struct {
+	Reason string `json:"reason" validate:"required,min=3,max=500"`
+}
+
+type AmendAttendanceRequest struct {
+	EventID string `json:"event_id"`
+	Late    *bool  `json:"late"`
+	Visitor *bool  `json:"visitor"`
+	Member  *bool  `json:"member"`
+	Reason  string `json:"reason" validate:"required,min=3,max=500"`
+}
+
+type BackdateAttendanceRequest struct {
+	UserID               string `json:"user_id" validate:"required"`
+	EventID              string `json:"event_id" validate:"required"`
+	DateTimeOfAttendance string `json:"date_time_of_attendance"`
+	Reason               string `json:"reason" validate:"required,min=3,max=500"`
+}
+
+type VisitorCheckinRequest struct {
+	FirstName   string `json:"fname" validate:"required,min=2,max=50"`
+	LastName    string `json:"lname" validate:"required,min=2,max=50"`
+	PhoneNumber string `json:"phone_number" validate:"required,min=7,max=20"`
+	Email       string `json:"email" validate:"omitempty,email"`
+	InvitedBy   string `json:"invited_by" validate:"max=100"`
+	ChurchID    string `json:"church_id"`
+	EventID     string `json:"event_id"`
 }
 
 type AttendanceResponse struct {
-	ID                   string    `json:"id"`
+	ID                   string     `json:"id"`
+	UserID               string     `json:"user_id"`
+	EventID              string     `json:"event_id"`
+	DateTimeOfAttendance time.Time  `json:"date_time_of_attendance"`
+	QRCodeBasedCheckin   bool       `json:"qrcode_based_checkin"`
+	Late                 bool       `json:"late"`
+	ManualCheckin        bool       `json:"manual_checkin"`
+	MinutesLate          int        `json:"minutes_late"`
+	CheckinMethod        string     `json:"checkin_method"`
+	Venue                string     `json:"venue,omitempty"`
+	CheckOutTime         *time.Time `json:"check_out_time,omitempty"`
+	CheckedOutBy         string     `json:"checked_out_by,omitempty"`
+	DurationMinutes      *int       `json:"duration_minutes,omitempty"`
+	VisitorProfileID     string     `json:"visitor_profile_id,omitempty"`
+	FamilyMemberID       string     `json:"family_member_id,omitempty"`
+	Household            string     `json:"household,omitempty"`
+	FirstVisit           bool       `json:"first_visit"`
+	Backdated            bool       `json:"backdated,omitempty"`
+	RecordedBy           string     `json:"recorded_by,omitempty"`
+	Voided               bool       `json:"voided,omitempty"`
+	VoidedAt             *time.Time `json:"voided_at,omitempty"`
+	VoidedBy             string     `json:"voided_by,omitempty"`
+	VoidReason           string     `json:"void_reason,omitempty"`
+	Visitor              bool       `json:"visitor"`
+	Member               bool       `json:"member"`
+	DistanceMeters       *float64   `json:"distance_meters,omitempty"`
+}
+
+type AttendanceAuditResponse struct {
+	ID           string              `json:"id"`
+	AttendanceID string              `json:"attendance_id"`
+	Action       string              `json:"action"`
+	Reason       string              `json:"reason"`
+	PerformedBy  string              `json:"performed_by"`
+	PerformedAt  time.Time           `json:"performed_at"`
+	Before       *AttendanceResponse `json:"before,omitempty"`
+	After        *AttendanceResponse `json:"after,omitempty"`
+}
+
+// HouseholdCheckinRequest checks in a family head and the household members they select. Leaving out
+// family_member_ids checks in the whole household.
+type HouseholdCheckinRequest struct {
+	FamilyHeadID    string   `json:"family_head_id"`
+	IncludeHead     *bool    `json:"include_head"`
+	FamilyMemberIDs []string `json:"family_member_ids" validate:"max=20"`
+	ChurchID        string   `json:"church_id"`
+	EventID         string   `json:"event_id"`
+}
+
+type HouseholdCheckinResult struct {
+	UserID         string              `json:"user_id,omitempty"`
+	FamilyMemberID string              `json:"family_member_id,omitempty"`
+	Name           string              `json:"name"`
+	Status         string              `json:"status"`
+	Message        string              `json:"message,omitempty"`
+	Attendance     *AttendanceResponse `json:"attendance,omitempty"`
+}
+
+type HouseholdCheckinResponse struct {
+	FamilyHead string                   `json:"family_head"`
+	EventID    string                   `json:"event_id"`
+	EventName  string                   `json:"event_name"`
+	Recorded   int                      `json:"recorded"`
+	Duplicates int                      `json:"duplicates"`
+	Results    []HouseholdCheckinResult `json:"results"`
+}
+
+type HouseholdAttendanceItem struct {
+	FamilyHead       string    `json:"family_head"`
+	FirstName        string    `json:"fname"`
+	LastName         string    `json:"lname"`
+	ServicesAttended int       `json:"services_attended"`
+	Attendance       int       `json:"attendance"`
+	People           int       `json:"people"`
+	AveragePartySize float64   `json:"average_party_size"`
+	LastAttended     time.Time `json:"last_attended"`
+}
+
+type VisitorProfileResponse struct {
+	ID          string    `json:"id"`
+	FirstName   string    `json:"fname"`
+	LastName    string    `json:"lname"`
+	PhoneNumber string    `json:"phone_number"`
+	Email       string    `json:"email,omitempty"`
+	InvitedBy   string    `json:"invited_by,omitempty"`
+	FirstVisit  time.Time `json:"first_visit"`
+}
+
+type VisitorCheckinResponse struct {
+	Visitor    *VisitorProfileResponse `json:"visitor"`
+	Attendance *AttendanceResponse     `json:"attendance"`
+}
+
+type MergeVisitorRequest struct {
+	UserID string `json:"user_id" validate:"required"`
+}
+
+type MergeVisitorResponse struct {
+	VisitorProfileID string `json:"visitor_profile_id"`
+	UserID           string `json:"user_id"`
+	MergedAttendance int    `json:"merged_attendance"`
+}
+
+type AttendanceHistoryItem struct {
+	Date               string  `json:"date"`
+	Members            int     `json:"members"`
+	Visitors           int     `json:"visitors"`
+	TotalAttendance    int     `json:"total_attendance"`
+	AverageStayMinutes float64 `json:"average_stay_minutes"`
+	NotCheckedOut      int     `json:"not_checked_out"`
+}
+
+type AttendanceEventHistoryItem struct {
+	EventID            string    `json:"event_id"`
+	EventName          string    `json:"event_name"`
+	EventType          string    `json:"event_type"`
+	StartTime          time.Time `json:"start_time"`
+	Members            int       `json:"members"`
+	Visitors           int       `json:"visitors"`
+	TotalAttendance    int       `json:"total_attendance"`
+	AverageStayMinutes float64   `json:"average_stay_minutes"`
+	NotCheckedOut      int       `json:"not_checked_out"`
+}
+
+// AttendanceHistoryFilter narrows and orders the attendance history. Empty fields are not filtered on.
+type AttendanceHistoryFilter struct {
+	ChurchID      string
+	EventID       string
+	Campus        string
+	CheckinMethod string
+	Sort          string
+	Order         string
+}
+
+type AttendanceRecordHistoryItem struct {
+	AttendanceID         string     `json:"attendance_id"`
+	UserID               string     `json:"user_id,omitempty"`
+	VisitorProfileID     string     `json:"visitor_profile_id,omitempty"`
+	FamilyMemberID       string     `json:"family_member_id,omitempty"`
+	Household            string     `json:"household,omitempty"`
+	FirstName            string     `json:"fname"`
+	LastName             string     `json:"lname"`
+	Email                string     `json:"email,omitempty"`
+	PhoneNumber          string     `json:"phone_number,omitempty"`
+	Campus               string     `json:"user_campus,omitempty"`
+	EventID              string     `json:"event_id,omitempty"`
+	EventName            string     `json:"event_name,omitempty"`
+	EventType            string     `json:"event_type,omitempty"`
+	DateTimeOfAttendance time.Time  `json:"date_time_of_attendance"`
+	CheckinMethod        string     `json:"checkin_method"`
+	Late                 bool       `json:"late"`
+	MinutesLate          int        `json:"minutes_late"`
+	Member               bool       `json:"member"`
+	Visitor              bool       `json:"visitor"`
+	CheckOutTime         *time.Time `json:"check_out_time,omitempty"`
+}
+
+type MemberAttendanceRecord struct {
+	AttendanceID         string     `json:"attendance_id"`
+	EventID              string     `json:"event_id"`
+	EventName            string     `json:"event_name"`
+	EventType            string     `json:"event_type"`
+	EventStart           *time.Time `json:"event_start,omitempty"`
+	DateTimeOfAttendance time.Time  `json:"date_time_of_attendance"`
+	Late                 bool       `json:"late"`
+	MinutesLate          int        `json:"minutes_late"`
+	CheckinMethod        string     `json:"checkin_method"`
+	CheckOutTime         *time.Time `json:"check_out_time,omitempty"`
+	DurationMinutes      *int       `json:"duration_minutes,omitempty"`
+}
+
+type AttendanceStreaks struct {
+	CurrentSundayStreak int    `json:"current_sunday_streak"`
+	LongestSundayStreak int    `json:"longest_sunday_streak"`
+	LastSundayAttended  string `json:"last_sunday_attended,omitempty"`
+}
+
+type AttendanceRateWindow struct {
+	Days           int     `json:"days"`
+	EventsHeld     int     `json:"events_held"`
+	EventsAttended int     `json:"events_attended"`
+	Rate           float64 `json:"rate"`
+}
+
+type AttendanceHeatmapDay struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+}
+
+type MemberAttendanceResponse struct {
+	UserID          string                   `json:"user_id"`
+	FirstName       string                   `json:"fname"`
+	LastName        string                   `json:"lname"`
+	TotalAttendance int                      `json:"total_attendance"`
+	Streaks         AttendanceStreaks        `json:"streaks"`
+	Rates           []AttendanceRateWindow   `json:"rates"`
+	Heatmap         []AttendanceHeatmapDay   `json:"heatmap"`
+	Records         []MemberAttendanceRecord `json:"records"`
+	Pagination      Pagination               `json:"pagination"`
+}
+
+type OpenAttendanceItem struct {
+	AttendanceID         string    `json:"attendance_id"`
 	UserID               string    `json:"user_id"`
+	FirstName            string    `json:"fname"`
+	LastName             string    `json:"lname"`
 	DateTimeOfAttendance time.Time `json:"date_time_of_attendance"`
-	QRCodeBasedCheckin   bool      `json:"qrcode_based_checkin"`
+}
+
+type EventAttendanceAnalytics struct {
+	EventID            string               `json:"event_id"`
+	EventName          string               `json:"event_name"`
+	EventType          string               `json:"event_type"`
+	TotalAttendance    int                  `json:"total_attendance"`
+	Members            int                  `json:"members"`
+	Visitors           int                  `json:"visitors"`
+	Late               int                  `json:"late"`
+	Households         int                  `json:"households"`
+	Dependents         int                  `json:"dependents"`
+	AverageMinutesLate float64              `json:"average_minutes_late"`
+	Punctuality        []PunctualityBucket  `json:"punctuality"`
+	CheckedOut         int                  `json:"checked_out"`
+	AverageStayMinutes float64              `json:"average_stay_minutes"`
+	NotCheckedOut      []OpenAttendanceItem `json:"not_checked_out"`
+}
+
+// LiveAttendanceMessage is pushed to live attendance dashboards. Type says which of the payloads is set.
+type LiveAttendanceMessage struct {
+	Type     string                  `json:"type"`
+	Checkin  *LiveCheckin            `json:"checkin,omitempty"`
+	Counters *LiveAttendanceCounters `json:"counters,omitempty"`
+}
+
+type LiveCheckin struct {
+	AttendanceID         string    `json:"attendance_id"`
+	UserID               string    `json:"user_id,omitempty"`
+	VisitorProfileID     string    `json:"visitor_profile_id,omitempty"`
+	FamilyMemberID       string    `json:"family_member_id,omitempty"`
+	FirstName            string    `json:"fname"`
+	LastName             string    `json:"lname"`
+	EventID              string    `json:"event_id"`
+	EventName            string    `json:"event_name"`
+	DateTimeOfAttendance time.Time `json:"date_time_of_attendance"`
+	CheckinMethod        string    `json:"checkin_method"`
 	Late                 bool      `json:"late"`
-	ManualCheckin        bool      `json:"manual_checkin"`
-	Visitor              bool      `json:"visitor"`
+	MinutesLate          int       `json:"minutes_late"`
 	Member               bool      `json:"member"`
+	Visitor              bool      `json:"visitor"`
+	FirstVisit           bool      `json:"first_visit"`
 }
 
-type AttendanceHistoryItem struct {
-	Date            string `json:"date"`
-	Members         int    `json:"members"`
-	Visitors        int    `json:"visitors"`
-	TotalAttendance int    `json:"total_attendance"`
+type LiveAttendanceCounters struct {
+	EventID    string `json:"event_id"`
+	EventName  string `json:"event_name"`
+	Total      int    `json:"total"`
+	Members    int    `json:"members"`
+	Visitors   int    `json:"visitors"`
+	Late       int    `json:"late"`
+	CheckedOut int    `json:"checked_out"`
+}
+
+type PunctualityBucket struct {
+	Label string `json:"label"`
+	Count int    `json:"count"`
 }
 
 type AttendanceAnalytics struct {
 	TotalActiveUsersAllTime int `json:"total_active_users_all_time"`
 	TotalAttendanceForDate  int `json:"total_attendance_for_date"`
+	MembersForDate          int `json:"members_for_date"`
 	MembersForMonth         int `json:"members_for_month"`
 	VisitorsCount           int `json:"visitors_count"`
 }
 
+type AttendanceTrendPoint struct {
+	Period        string  `json:"period"`
+	StartDate     string  `json:"start_date"`
+	Total         int     `json:"total"`
+	Members       int     `json:"members"`
+	Visitors      int     `json:"visitors"`
+	Unique        int     `json:"unique"`
+	FirstTimers   int     `json:"first_timers"`
+	MovingAverage float64 `json:"moving_average"`
+}
+
+type AttendanceTrendResponse struct {
+	Interval  string                 `json:"interval"`
+	Window    int                    `json:"window"`
+	StartDate string                 `json:"start_date"`
+	EndDate   string                 `json:"end_date"`
+	Points    []AttendanceTrendPoint `json:"points"`
+}
+
+type AttendancePeriodSummary struct {
+	StartDate   string `json:"start_date"`
+	EndDate     string `json:"end_date"`
+	Total       int    `json:"total"`
+	Members     int    `json:"members"`
+	Visitors    int    `json:"visitors"`
+	Unique      int    `json:"unique"`
+	FirstTimers int    `json:"first_timers"`
+}
+
+type AttendanceComparison struct {
+	Current       AttendancePeriodSummary `json:"current"`
+	Previous      AttendancePeriodSummary `json:"previous"`
+	Change        int                     `json:"change"`
+	ChangePercent *float64                `json:"change_percent"`
+}
+
+type AttendanceComparisonResponse struct {
+	WeekOverWeek AttendanceComparison `json:"week_over_week"`
+	YearOverYear AttendanceComparison `json:"year_over_year"`
+}
+
+type AttendanceBreakdownItem struct {
+	Value      string  `json:"value"`
+	Attendance int     `json:"attendance"`
+	Unique     int     `json:"unique"`
+	Percent    float64 `json:"percent"`
+}
+
+type AttendanceBreakdownResponse struct {
+	StartDate     string                    `json:"start_date"`
+	EndDate       string                    `json:"end_date"`
+	Total         int                       `json:"total"`
+	Gender        []AttendanceBreakdownItem `json:"gender"`
+	AgeBand       []AttendanceBreakdownItem `json:"age_band"`
+	Campus        []AttendanceBreakdownItem `json:"campus"`
+	Department    []AttendanceBreakdownItem `json:"department"`
+	CheckinMethod []AttendanceBreakdownItem `json:"checkin_method"`
+}
+
+type RetentionWindow struct {
+	Weeks    int      `json:"weeks"`
+	Eligible int      `json:"eligible"`
+	Retained int      `json:"retained"`
+	Rate     *float64 `json:"rate"`
+}
+
+type FirstTimerRetentionResponse struct {
+	StartDate   string            `json:"start_date"`
+	EndDate     string            `json:"end_date"`
+	FirstTimers int               `json:"first_timers"`
+	Retention   []RetentionWindow `json:"retention"`
+}
+
+// Service Event DTOs
+type CreateServiceEventRequest struct {
+	Name        string `json:"name" validate:"required,min=2,max=100"`
+	EventType   string `json:"event_type" validate:"required,oneof=sunday_service midweek_service vigil special_program"`
+	ChurchID    string `json:"church_id"`
+	StartTime   string `json:"start_time" validate:"required"`
+	EndTime     string `json:"end_time" validate:"required"`
+	Description string `json:"description"`
+}
+
+type UpdateServiceEventRequest struct {
+	Name        string `json:"name" validate:"omitempty,min=2,max=100"`
+	EventType   string `json:"event_type" validate:"omitempty,oneof=sunday_service midweek_service vigil special_program"`
+	ChurchID    string `json:"church_id"`
+	StartTime   string `json:"start_time"`
+	EndTime     string `json:"end_time"`
+	Description string `json:"description"`
+}
+
+type ServiceEventResponse struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	EventType   string    `json:"event_type"`
+	ChurchID    string    `json:"church_id"`
+	StartTime   time.Time `json:"start_time"`
+	EndTime     time.Time `json:"end_time"`
+	Description string    `json:"description"`
+	IsOpen      bool      `json:"is_open"`
+	CreatedBy   string    `json:"created_by"`
+	DateAdded   time.Time `json:"date_added"`
+	DateUpdated time.Time `json:"date_updated"`
+}
+
+type CreateVenueCodeRequest struct {
+	Venue string `json:"venue" validate:"required,min=2,max=100"`
+}
+
+type VenueCodeResponse struct {
+	ID          string    `json:"id"`
+	EventID     string    `json:"event_id"`
+	Venue       string    `json:"venue"`
+	Code        string    `json:"code"`
+	QRCodeImage string    `json:"qr_code_image"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	CreatedBy   string    `json:"created_by"`
+	DateAdded   time.Time `json:"date_added"`
+}
+
+type PaginatedServiceEventsResponse struct {
+	Data       []*ServiceEventResponse `json:"data"`
+	Pagination Pagination              `json:"pagination"`
+}
+
 // QR Code DTOs
+// GenerateQRRequest issues a static QR token. UserID defaults to the caller; issuing one for
+// someone else needs users:manage.
 type GenerateQRRequest struct {
-	UserID string `json:"user_id" validate:"required"`
+	UserID string `json:"user_id"`
 }
 
 type QRCodeResponse struct {
@@ -151,6 +674,21 @@ type QRCodeResponse struct {
 	QRCodeImage string `json:"qr_code_image"`
 }
 
+// MemberCardSheetRequest selects the members to print cards for. A sheet holds at most 200 cards.
+type MemberCardSheetRequest struct {
+	UserIDs     []string `json:"user_ids" validate:"omitempty,max=200"`
+	Field       string   `json:"field" validate:"required_with=Value"`
+	Value       string   `json:"value"`
+	JoinedAfter string   `json:"joined_after" validate:"omitempty,datetime=2006-01-02"`
+}
+
+type RotatingQRCodeResponse struct {
+	QRCodeToken     string    `json:"qr_code_token"`
+	QRCodeImage     string    `json:"qr_code_image"`
+	ExpiresAt       time.Time `json:"expires_at"`
+	RefreshInterval int       `json:"refresh_interval_seconds"`
+}
+
 // Role DTOs
 type CreateRoleRequest struct {
 	RoleName        string   `json:"role_name" validate:"required,min=2,max=50"`
@@ -174,9 +712,19 @@ type RoleResponse struct {
 	DateUpdated     time.Time `json:"date_updated"`
 }
 
+type PermissionResponse struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
 type RolesAndPermissionsResponse struct {
-	Roles                []RoleResponse `json:"roles"`
-	AvailablePermissions []string       `json:"available_permissions"`
+	Roles                []RoleResponse       `json:"roles"`
+	AvailablePermissions []PermissionResponse `json:"available_permissions"`
+}
+
+// AssignRoleRequest sets a member's role; an empty role_id removes it
+type AssignRoleRequest struct {
+	RoleID string `json:"role_id"`
 }
 
 type PaginatedRolesResponse struct {
@@ -315,43 +863,130 @@ type PaginatedFamilyMembersResponse struct {
 	Pagination Pagination              `json:"pagination"`
 }
 
+// Children's Check-in DTOs
+type ChildCheckinChild struct {
+	FamilyMemberID string `json:"family_member_id" validate:"required"`
+	Allergies      string `json:"allergies" validate:"max=200"`
+	Notes          string `json:"notes" validate:"max=500"`
+}
+
+type ChildCheckinRequest struct {
+	Children            []ChildCheckinChild `json:"children" validate:"required,min=1,max=10,dive"`
+	Classroom           string              `json:"classroom" validate:"required,max=50"`
+	ChurchID            string              `json:"church_id"`
+	EventID             string              `json:"event_id"`
+	GuardianID          string              `json:"guardian_id"`
+	AuthorizedGuardians []string            `json:"authorized_guardians" validate:"max=5"`
+}
+
+// ChildCheckoutRequest releases a child to whoever presents the pickup code, or to an authorized guardian
+type ChildCheckoutRequest struct {
+	PickupCode string `json:"pickup_code"`
+	GuardianID string `json:"guardian_id"`
+}
+
+type ChildLabelsRequest struct {
+	CheckinIDs []string `json:"checkin_ids" validate:"required,min=1,max=50"`
+}
+
+type ChildCheckinItem struct {
+	ID                  string     `json:"id"`
+	FamilyMemberID      string     `json:"family_member_id"`
+	ChildName           string     `json:"child_name"`
+	Guardian            string     `json:"guardian"`
+	AuthorizedGuardians []string   `json:"authorized_guardians"`
+	EventID             string     `json:"event_id,omitempty"`
+	Classroom           string     `json:"classroom"`
+	Allergies           string     `json:"allergies,omitempty"`
+	Notes               string     `json:"notes,omitempty"`
+	CheckedInBy         string     `json:"checked_in_by"`
+	CheckedInAt         time.Time  `json:"checked_in_at"`
+	CheckedOutAt        *time.Time `json:"checked_out_at,omitempty"`
+	CheckedOutBy        string     `json:"checked_out_by,omitempty"`
+	PickedUpBy          string     `json:"picked_up_by,omitempty"`
+	PickupMethod        string     `json:"pickup_method,omitempty"`
+}
+
+// ChildCheckinResponse is only returned to the guardian at check-in, as it carries the pickup code
+type ChildCheckinResponse struct {
+	PickupCode string              `json:"pickup_code"`
+	EventID    string              `json:"event_id,omitempty"`
+	Children   []*ChildCheckinItem `json:"children"`
+}
+
+type ClassroomRoster struct {
+	Classroom     string              `json:"classroom"`
+	Count         int                 `json:"count"`
+	WithAllergies int                 `json:"with_allergies"`
+	Children      []*ChildCheckinItem `json:"children"`
+}
+
+type ChildRosterResponse struct {
+	Total      int                `json:"total"`
+	Classrooms []*ClassroomRoster `json:"classrooms"`
+}
+
+type ChildPickupAlert struct {
+	Child          *ChildCheckinItem `json:"child"`
+	EventName      string            `json:"event_name,omitempty"`
+	EventEnd       *time.Time        `json:"event_end,omitempty"`
+	WaitingMinutes int               `json:"waiting_minutes"`
+	GuardianName   string            `json:"guardian_name"`
+	GuardianPhone  string            `json:"guardian_phone"`
+	GuardianEmail  string            `json:"guardian_email"`
+}
+
 // Local Church DTOs
 type CreateLocalChurchRequest struct {
-	ChurchName         string `json:"church_name" validate:"required,min=5,max=100"`
-	ChurchPhone        string `json:"church_phone"`
-	ChurchEmail        string `json:"church_email" validate:"required,email"`
-	ChurchAddress      string `json:"church_address" validate:"required,min=4,max=200"`
-	StateCounty        string `json:"state_county" validate:"required,min=2,max=50"`
-	Country            string `json:"country" validate:"required,min=2,max=50"`
-	SundayMeetingTime  int    `json:"sunday_meeting_time" validate:"required,min=0,max=23"`
-	MidweekMeetingDay  string `json:"midweek_meeting_day" validate:"required,oneof=Monday Tuesday Wednesday Thursday Friday"`
-	MidweekMeetingTime int    `json:"midweek_meeting_time" validate:"required,min=0,max=23"`
-	Website            string `json:"website" validate:"omitempty,url"`
-	SocialMedia        string `json:"social_media"`
-	PastorName         string `json:"pastor_name" validate:"required,min=2,max=100"`
-	PastorPhone        string `json:"pastor_phone"`
-	PastorEmail        string `json:"pastor_email" validate:"required,email"`
-	FoundedYear        int    `json:"founded_year" validate:"omitempty,min=1800,max=2500"`
-	Description        string `json:"description"`
+	ChurchName         string    `json:"church_name" validate:"required,min=5,max=100"`
+	ChurchPhone        string    `json:"church_phone"`
+	ChurchEmail        string    `json:"church_email" validate:"required,email"`
+	ChurchAddress      string    `json:"church_address" validate:"required,min=4,max=200"`
+	StateCounty        string    `json:"state_county" validate:"required,min=2,max=50"`
+	Country            string    `json:"country" validate:"required,min=2,max=50"`
+	SundayMeetingTime  int       `json:"sunday_meeting_time" validate:"required,min=0,max=23"`
+	MidweekMeetingDay  string    `json:"midweek_meeting_day" validate:"required,oneof=Monday Tuesday Wednesday Thursday Friday"`
+	MidweekMeetingTime int       `json:"midweek_meeting_time" validate:"required,min=0,max=23"`
+	Timezone           string    `json:"timezone"`
+	LateGracePeriod    *int      `json:"late_grace_period_minutes" validate:"omitempty,min=0,max=180"`
+	Geofence           *Geofence `json:"geofence"`
+	Website            string    `json:"website" validate:"omitempty,url"`
+	SocialMedia        string    `json:"social_media"`
+	PastorName         string    `json:"pastor_name" validate:"required,min=2,max=100"`
+	PastorPhone        string    `json:"pastor_phone"`
+	PastorEmail        string    `json:"pastor_email" validate:"required,email"`
+	FoundedYear        int       `json:"founded_year" validate:"omitempty,min=1800,max=2500"`
+	Description        string    `json:"description"`
 }
 
 type UpdateLocalChurchRequest struct {
-	ChurchName         string `json:"church_name" validate:"omitempty,min=5,max=100"`
-	ChurchPhone        string `json:"church_phone"`
-	ChurchEmail        string `json:"church_email" validate:"omitempty,email"`
-	ChurchAddress      string `json:"church_address" validate:"omitempty,min=10,max=200"`
-	StateCounty        string `json:"state_county" validate:"omitempty,min=2,max=50"`
-	Country            string `json:"country" validate:"omitempty,min=2,max=50"`
-	SundayMeetingTime  int    `json:"sunday_meeting_time" validate:"omitempty,min=0,max=23"`
-	MidweekMeetingDay  string `json:"midweek_meeting_day" validate:"omitempty,oneof=Monday Tuesday Wednesday Thursday Friday"`
-	MidweekMeetingTime int    `json:"midweek_meeting_time" validate:"omitempty,min=0,max=23"`
-	Website            string `json:"website" validate:"omitempty,url"`
-	SocialMedia        string `json:"social_media"`
-	PastorName         string `json:"pastor_name" validate:"required,min=2,max=100"`
-	PastorPhone        string `json:"pastor_phone"`
-	PastorEmail        string `json:"pastor_email" validate:"required,email"`
-	FoundedYear        int    `json:"founded_year" validate:"omitempty,min=1800,max=2500"`
-	Description        string `json:"description"`
+	ChurchName         string    `json:"church_name" validate:"omitempty,min=5,max=100"`
+	ChurchPhone        string    `json:"church_phone"`
+	ChurchEmail        string    `json:"church_email" validate:"omitempty,email"`
+	ChurchAddress      string    `json:"church_address" validate:"omitempty,min=10,max=200"`
+	StateCounty        string    `json:"state_county" validate:"omitempty,min=2,max=50"`
+	Country            string    `json:"country" validate:"omitempty,min=2,max=50"`
+	SundayMeetingTime  int       `json:"sunday_meeting_time" validate:"omitempty,min=0,max=23"`
+	MidweekMeetingDay  string    `json:"midweek_meeting_day" validate:"omitempty,oneof=Monday Tuesday Wednesday Thursday Friday"`
+	MidweekMeetingTime int       `json:"midweek_meeting_time" validate:"omitempty,min=0,max=23"`
+	Timezone           string    `json:"timezone"`
+	LateGracePeriod    *int      `json:"late_grace_period_minutes" validate:"omitempty,min=0,max=180"`
+	Geofence           *Geofence `json:"geofence"`
+	RemoveGeofence     bool      `json:"remove_geofence"`
+	Website            string    `json:"website" validate:"omitempty,url"`
+	SocialMedia        string    `json:"social_media"`
+	PastorName         string    `json:"pastor_name" validate:"required,min=2,max=100"`
+	PastorPhone        string    `json:"pastor_phone"`
+	PastorEmail        string    `json:"pastor_email" validate:"required,email"`
+	FoundedYear        int       `json:"founded_year" validate:"omitempty,min=1800,max=2500"`
+	Description        string    `json:"description"`
+}
+
+// Geofence is the area around a church members must be inside to check themselves in from the mobile app
+type Geofence struct {
+	Latitude     float64 `json:"latitude" validate:"min=-90,max=90"`
+	Longitude    float64 `json:"longitude" validate:"min=-180,max=180"`
+	RadiusMeters int     `json:"radius_meters" validate:"required,min=10,max=5000"`
 }
 
 type LocalChurchResponse struct {
@@ -365,6 +1000,9 @@ type LocalChurchResponse struct {
 	SundayMeetingTime  int       `json:"sunday_meeting_time"`
 	MidweekMeetingDay  string    `json:"midweek_meeting_day"`
 	MidweekMeetingTime int       `json:"midweek_meeting_time"`
+	Timezone           string    `json:"timezone"`
+	LateGracePeriod    *int      `json:"late_grace_period_minutes"`
+	Geofence           *Geofence `json:"geofence"`
 	Website            string    `json:"website"`
 	SocialMedia        string    `json:"social_media"`
 	PastorName         string    `json:"pastor_name"`
@@ -424,6 +1062,88 @@ type ErrorInfo struct {
 	Details []ErrorDetail `json:"details,omitempty"`
 }
 
+type UpdateFollowUpRequest struct {
+	Status     string `json:"status" validate:"omitempty,oneof=open contacted resolved"`
+	Note       string `json:"note" validate:"max=1000"`
+	DueDate    string `json:"due_date"`
+	AssignedTo string `json:"assigned_to"`
+}
+
+type FollowUpResponse struct {
+	ID          string         `json:"id"`
+	UserID      string         `json:"user_id"`
+	Reason      string         `json:"reason"`
+	Details     string         `json:"details"`
+	AssignedTo  string         `json:"assigned_to,omitempty"`
+	Status      string         `json:"status"`
+	DueDate     time.Time      `json:"due_date"`
+	Notes       []FollowUpNote `json:"notes"`
+	ResolvedAt  *time.Time     `json:"resolved_at,omitempty"`
+	DateAdded   time.Time      `json:"date_added"`
+	DateUpdated time.Time      `json:"date_updated"`
+}
+
+type FollowUpNote struct {
+	Text    string    `json:"text"`
+	AddedBy string    `json:"added_by"`
+	AddedAt time.Time `json:"added_at"`
+}
+
+type FollowUpScanResponse struct {
+	Created int `json:"created"`
+}
+
+type AssignShepherdRequest struct {
+	Shepherd string `json:"shepherd"`
+}
+
+type MembershipStageRequest struct {
+	Stage string `json:"stage" validate:"required,oneof=first_time_guest returning_visitor membership_class member worker"`
+	Note  string `json:"note" validate:"max=500"`
+}
+
+type MembershipTransitionResponse struct {
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	FromSince time.Time `json:"from_since"`
+	Note      string    `json:"note,omitempty"`
+	ChangedBy string    `json:"changed_by"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+type MembershipResponse struct {
+	UserID          string                          `json:"user_id"`
+	Stage           string                          `json:"stage"`
+	StageSince      *time.Time                      `json:"stage_since,omitempty"`
+	AttendedInStage int                             `json:"attended_in_stage"`
+	SuggestedStage  string                          `json:"suggested_stage,omitempty"`
+	History         []*MembershipTransitionResponse `json:"history"`
+}
+
+type StageSuggestionResponse struct {
+	UserID         string     `json:"user_id"`
+	FirstName      string     `json:"fname"`
+	LastName       string     `json:"lname"`
+	Stage          string     `json:"stage"`
+	StageSince     *time.Time `json:"stage_since,omitempty"`
+	Attended       int        `json:"attended"`
+	SuggestedStage string     `json:"suggested_stage"`
+}
+
+type MembershipFunnelStage struct {
+	Stage              string   `json:"stage"`
+	Current            int      `json:"current"`
+	Reached            int      `json:"reached"`
+	ConversionRate     *float64 `json:"conversion_rate,omitempty"`
+	AverageDaysInStage *float64 `json:"average_days_in_stage,omitempty"`
+	MovedOn            int      `json:"moved_on"`
+}
+
+type MembershipFunnelResponse struct {
+	Stages            []MembershipFunnelStage `json:"stages"`
+	GuestToMemberRate float64                 `json:"guest_to_member_rate"`
+}
+
 type ErrorResponse struct {
 	Code    string        `json:"code"`
 	Message string        `json:"message"`
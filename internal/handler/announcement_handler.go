@@ -9,7 +9,6 @@ import (
 	"cci-api/internal/service"
 
 	"github.com/labstack/echo/v4"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type AnnouncementHandler struct {
@@ -40,9 +39,8 @@ func (h *AnnouncementHandler) CreateAnnouncement(c echo.Context) error {
 
 	// Get user ID from JWT token
 	userID := c.Get("user_id").(string)
-	objID, _ := primitive.ObjectIDFromHex(userID)
 
-	announcement, err := h.announcementService.CreateAnnouncement(c.Request().Context(), &req, objID)
+	announcement, err := h.announcementService.CreateAnnouncement(c.Request().Context(), &req, userID)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
 			Code:    "ANNOUNCEMENT_CREATION_FAILED",
@@ -81,7 +79,7 @@ func (h *AnnouncementHandler) GetAnnouncementByID(c echo.Context) error {
 
 	announcement, err := h.announcementService.GetAnnouncementByID(c.Request().Context(), id)
 	if err != nil {
-		return c.JSON(http.StatusNotFound, dto.ErrorResponse{
+		return c.JSON(accessErrorStatus(err, http.StatusNotFound), dto.ErrorResponse{
 			Code:    "ANNOUNCEMENT_NOT_FOUND",
 			Message: err.Error(),
 		})
@@ -112,9 +110,12 @@ func (h *AnnouncementHandler) UpdateAnnouncement(c echo.Context) error {
 		})
 	}
 
-	announcement, err := h.announcementService.UpdateAnnouncement(c.Request().Context(), id, &req)
+	userID := c.Get("user_id").(string)
+	isAdmin, _ := c.Get("admin").(bool)
+
+	announcement, err := h.announcementService.UpdateAnnouncement(c.Request().Context(), id, &req, userID, isAdmin)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+		return c.JSON(accessErrorStatus(err, http.StatusInternalServerError), dto.ErrorResponse{
 			Code:    "ANNOUNCEMENT_UPDATE_FAILED",
 			Message: err.Error(),
 		})
@@ -130,9 +131,12 @@ func (h *AnnouncementHandler) UpdateAnnouncement(c echo.Context) error {
 func (h *AnnouncementHandler) DeleteAnnouncement(c echo.Context) error {
 	id := c.Param("id")
 
-	err := h.announcementService.DeleteAnnouncement(c.Request().Context(), id)
+	userID := c.Get("user_id").(string)
+	isAdmin, _ := c.Get("admin").(bool)
+
+	err := h.announcementService.DeleteAnnouncement(c.Request().Context(), id, userID, isAdmin)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+		return c.JSON(accessErrorStatus(err, http.StatusInternalServerError), dto.ErrorResponse{
 			Code:    "ANNOUNCEMENT_DELETE_FAILED",
 			Message: err.Error(),
 		})
@@ -1,7 +1,12 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"cci-api/internal/dto"
@@ -109,48 +114,876 @@ func (h *AttendanceHandler) QRCheckin(c echo.Context) error {
 	})
 }
 
+func (h *AttendanceHandler) SyncBatch(c echo.Context) error {
+	var req dto.BatchAttendanceRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+			},
+		})
+	}
+
+	// Validate request
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "VALIDATION_ERROR",
+				Message: "Validation failed",
+				Details: []dto.ErrorDetail{
+					{Field: "request", Message: err.Error()},
+				},
+			},
+		})
+	}
+
+	resp, err := h.attendanceService.SyncBatch(c.Request().Context(), &req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "BATCH_SYNC_FAILED",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.APIResponse{
+		Success: true,
+		Message: "Batch processed",
+		Data:    resp,
+	})
+}
+
+func (h *AttendanceHandler) SelfCheckin(c echo.Context) error {
+	var req dto.SelfCheckinRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+			},
+		})
+	}
+
+	// Validate request
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "VALIDATION_ERROR",
+				Message: "Validation failed",
+				Details: []dto.ErrorDetail{
+					{Field: "request", Message: err.Error()},
+				},
+			},
+		})
+	}
+
+	userID, _ := c.Get("user_id").(string)
+
+	resp, err := h.attendanceService.SelfCheckin(c.Request().Context(), &req, userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "SELF_CHECKIN_FAILED",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	return c.JSON(http.StatusCreated, dto.APIResponse{
+		Success: true,
+		Message: "Self check-in successful",
+		Data:    resp,
+	})
+}
+
+func (h *AttendanceHandler) GeofenceCheckin(c echo.Context) error {
+	var req dto.GeofenceCheckinRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+			},
+		})
+	}
+
+	// Validate request
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "VALIDATION_ERROR",
+				Message: "Validation failed",
+				Details: []dto.ErrorDetail{
+					{Field: "request", Message: err.Error()},
+				},
+			},
+		})
+	}
+
+	userID, _ := c.Get("user_id").(string)
+
+	resp, err := h.attendanceService.GeofenceCheckin(c.Request().Context(), &req, userID)
+	if err != nil {
+		status, code := http.StatusBadRequest, "GEOFENCE_CHECKIN_FAILED"
+		switch {
+		case errors.Is(err, service.ErrGeofenceNotConfigured):
+			status, code = http.StatusUnprocessableEntity, "GEOFENCE_NOT_CONFIGURED"
+		case errors.Is(err, service.ErrOutsideGeofence):
+			status, code = http.StatusForbidden, "OUTSIDE_GEOFENCE"
+		case errors.Is(err, service.ErrLocationInaccurate):
+			status, code = http.StatusUnprocessableEntity, "LOCATION_INACCURATE"
+		case errors.Is(err, service.ErrCheckinWindowClosed):
+			status, code = http.StatusForbidden, "CHECKIN_WINDOW_CLOSED"
+		case errors.Is(err, service.ErrAlreadyCheckedIn):
+			status, code = http.StatusConflict, "ALREADY_CHECKED_IN"
+		}
+		return c.JSON(status, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    code,
+				Message: err.Error(),
+			},
+		})
+	}
+
+	return c.JSON(http.StatusCreated, dto.APIResponse{
+		Success: true,
+		Message: "Self check-in successful",
+		Data:    resp,
+	})
+}
+
+func (h *AttendanceHandler) RegisterVisitor(c echo.Context) error {
+	var req dto.VisitorCheckinRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+			},
+		})
+	}
+
+	// Validate request
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "VALIDATION_ERROR",
+				Message: "Validation failed",
+				Details: []dto.ErrorDetail{
+					{Field: "request", Message: err.Error()},
+				},
+			},
+		})
+	}
+
+	userID, _ := c.Get("user_id").(string)
+
+	resp, err := h.attendanceService.RegisterVisitor(c.Request().Context(), &req, userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "VISITOR_CHECKIN_FAILED",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	return c.JSON(http.StatusCreated, dto.APIResponse{
+		Success: true,
+		Message: "Visitor checked in successfully",
+		Data:    resp,
+	})
+}
+
+// MergeVisitor moves a visitor profile and its attendance onto a member's account
+func (h *AttendanceHandler) MergeVisitor(c echo.Context) error {
+	var req dto.MergeVisitorRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+			},
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "VALIDATION_ERROR",
+				Message: "Validation failed",
+				Details: []dto.ErrorDetail{
+					{Field: "request", Message: err.Error()},
+				},
+			},
+		})
+	}
+
+	resp, err := h.attendanceService.MergeVisitor(c.Request().Context(), c.Param("id"), req.UserID)
+	if err != nil {
+		status := accessErrorStatus(err, http.StatusBadRequest)
+		if errors.Is(err, service.ErrVisitorAlreadyMerged) {
+			status = http.StatusConflict
+		}
+		return c.JSON(status, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "VISITOR_MERGE_FAILED",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.APIResponse{
+		Success: true,
+		Message: "Visitor profile merged successfully",
+		Data:    resp,
+	})
+}
+
+// CheckInHousehold checks in a family head and the household members they select in one request
+func (h *AttendanceHandler) CheckInHousehold(c echo.Context) error {
+	var req dto.HouseholdCheckinRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+			},
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "VALIDATION_ERROR",
+				Message: "Validation failed",
+				Details: []dto.ErrorDetail{
+					{Field: "request", Message: err.Error()},
+				},
+			},
+		})
+	}
+
+	userID, _ := c.Get("user_id").(string)
+
+	resp, err := h.attendanceService.CheckInHousehold(c.Request().Context(), &req, userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "HOUSEHOLD_CHECKIN_FAILED",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.APIResponse{
+		Success: true,
+		Message: "Household check-in processed",
+		Data:    resp,
+	})
+}
+
+func (h *AttendanceHandler) CheckOut(c echo.Context) error {
+	var req dto.CheckOutRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+			},
+		})
+	}
+
+	// Validate request
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "VALIDATION_ERROR",
+				Message: "Validation failed",
+				Details: []dto.ErrorDetail{
+					{Field: "request", Message: err.Error()},
+				},
+			},
+		})
+	}
+
+	performedBy, _ := c.Get("user_id").(string)
+
+	resp, err := h.attendanceService.CheckOut(c.Request().Context(), &req, performedBy)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "CHECKOUT_FAILED",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.APIResponse{
+		Success: true,
+		Message: "Check-out recorded successfully",
+		Data:    resp,
+	})
+}
+
+func (h *AttendanceHandler) QRCheckout(c echo.Context) error {
+	var req dto.QRCheckoutRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+			},
+		})
+	}
+
+	// Validate request
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "VALIDATION_ERROR",
+				Message: "Validation failed",
+				Details: []dto.ErrorDetail{
+					{Field: "request", Message: err.Error()},
+				},
+			},
+		})
+	}
+
+	performedBy, _ := c.Get("user_id").(string)
+
+	resp, err := h.attendanceService.QRCheckout(c.Request().Context(), &req, performedBy)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "QR_CHECKOUT_FAILED",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.APIResponse{
+		Success: true,
+		Message: "QR check-out successful",
+		Data:    resp,
+	})
+}
+
 func (h *AttendanceHandler) GetAttendanceHistory(c echo.Context) error {
 	var startDate, endDate *time.Time
 
-	// Parse start_date if provided
-	if startDateStr := c.QueryParam("start_date"); startDateStr != "" {
-		if parsed, err := time.Parse("2006-01-02", startDateStr); err == nil {
-			startDate = &parsed
-		} else {
-			return c.JSON(http.StatusBadRequest, dto.APIResponse{
-				Success: false,
-				Error: &dto.ErrorInfo{
-					Code:    "INVALID_DATE_FORMAT",
-					Message: "start_date must be in YYYY-MM-DD format",
+	// Parse start_date if provided
+	if startDateStr := c.QueryParam("start_date"); startDateStr != "" {
+		if parsed, err := time.Parse("2006-01-02", startDateStr); err == nil {
+			startDate = &parsed
+		} else {
+			return c.JSON(http.StatusBadRequest, dto.APIResponse{
+				Success: false,
+				Error: &dto.ErrorInfo{
+					Code:    "INVALID_DATE_FORMAT",
+					Message: "start_date must be in YYYY-MM-DD format",
+				},
+			})
+		}
+	}
+
+	// Parse end_date if provided
+	if endDateStr := c.QueryParam("end_date"); endDateStr != "" {
+		if parsed, err := time.Parse("2006-01-02", endDateStr); err == nil {
+			endDate = &parsed
+		} else {
+			return c.JSON(http.StatusBadRequest, dto.APIResponse{
+				Success: false,
+				Error: &dto.ErrorInfo{
+					Code:    "INVALID_DATE_FORMAT",
+					Message: "end_date must be in YYYY-MM-DD format",
+				},
+			})
+		}
+	}
+
+	groupBy := c.QueryParam("group_by")
+	if groupBy != "" && groupBy != "date" && groupBy != "event" {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "INVALID_GROUP_BY",
+				Message: "group_by must be either 'date' or 'event'",
+			},
+		})
+	}
+
+	page := utils.StringToInt(c.QueryParam("page"), 1)
+	limit := utils.StringToInt(c.QueryParam("limit"), 10)
+
+	resp, err := h.attendanceService.GetAttendanceHistory(c.Request().Context(), startDate, endDate, groupBy, historyFilterParams(c), page, limit)
+	if err != nil {
+		return historyError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, dto.APIResponse{
+		Success: true,
+		Data:    resp,
+	})
+}
+
+// GetAttendanceRecords lists individual check-ins with the attendee's details
+func (h *AttendanceHandler) GetAttendanceRecords(c echo.Context) error {
+	startDate, endDate, err := dateRangeParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "INVALID_DATE_FORMAT",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	page := utils.StringToInt(c.QueryParam("page"), 1)
+	limit := utils.StringToInt(c.QueryParam("limit"), 10)
+
+	resp, err := h.attendanceService.GetAttendanceRecords(c.Request().Context(), startDate, endDate, historyFilterParams(c), page, limit)
+	if err != nil {
+		return historyError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, dto.APIResponse{
+		Success: true,
+		Data:    resp,
+	})
+}
+
+// ExportAttendance downloads the daily summaries, check-in records or member attendance matrix as CSV or XLSX
+func (h *AttendanceHandler) ExportAttendance(c echo.Context) error {
+	startDate, endDate, err := dateRangeParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "INVALID_DATE_FORMAT",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	format := c.QueryParam("format")
+	if format == "" {
+		format = service.ExportFormatCSV
+	}
+
+	export, err := h.attendanceService.ExportAttendance(c.Request().Context(), c.Param("report"), format, startDate, endDate, historyFilterParams(c))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidHistoryFilter) {
+			return c.JSON(http.StatusBadRequest, dto.APIResponse{
+				Success: false,
+				Error: &dto.ErrorInfo{
+					Code:    "INVALID_EXPORT_REQUEST",
+					Message: err.Error(),
+				},
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "EXPORT_FAILED",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, export.ContentType)
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", export.Filename))
+	c.Response().WriteHeader(http.StatusOK)
+
+	// The response has started, so a failure part way through can only be logged
+	if err := export.Write(c.Response()); err != nil {
+		c.Logger().Errorf("attendance export %s failed: %v", export.Filename, err)
+	}
+	return nil
+}
+
+// liveHeartbeatInterval is how often an idle live feed sends a comment to keep proxies from closing it
+const liveHeartbeatInterval = 25 * time.Second
+
+// StreamLiveAttendance pushes check-ins and running counters to a dashboard as Server-Sent Events
+func (h *AttendanceHandler) StreamLiveAttendance(c echo.Context) error {
+	feed, err := h.attendanceService.OpenLiveFeed(c.Request().Context(), c.QueryParam("church_id"), c.QueryParam("event_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "LIVE_FEED_FAILED",
+				Message: err.Error(),
+			},
+		})
+	}
+	defer feed.Close()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	res.Header().Set("Connection", "keep-alive")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+
+	if feed.Counters != nil {
+		if err := writeLiveMessage(res, dto.LiveAttendanceMessage{Type: service.LiveMessageCounters, Counters: feed.Counters}); err != nil {
+			return nil
+		}
+	} else {
+		fmt.Fprint(res, ": connected\n\n")
+		res.Flush()
+	}
+
+	heartbeat := time.NewTicker(liveHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case message := <-feed.Messages:
+			if err := writeLiveMessage(res, message); err != nil {
+				return nil
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(res, ": ping\n\n"); err != nil {
+				return nil
+			}
+			res.Flush()
+		}
+	}
+}
+
+// writeLiveMessage writes a message as a Server-Sent Event named after its type
+func writeLiveMessage(res *echo.Response, message dto.LiveAttendanceMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(res, "event: %s\ndata: %s\n\n", message.Type, data); err != nil {
+		return err
+	}
+	res.Flush()
+	return nil
+}
+
+// historyFilterParams reads the optional filter and sort query parameters shared by the history endpoints
+func historyFilterParams(c echo.Context) dto.AttendanceHistoryFilter {
+	return dto.AttendanceHistoryFilter{
+		ChurchID:      c.QueryParam("church_id"),
+		EventID:       c.QueryParam("event_id"),
+		Campus:        c.QueryParam("campus"),
+		CheckinMethod: c.QueryParam("checkin_method"),
+		Sort:          c.QueryParam("sort"),
+		Order:         c.QueryParam("order"),
+	}
+}
+
+func historyError(c echo.Context, err error) error {
+	if errors.Is(err, service.ErrInvalidHistoryFilter) {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "INVALID_HISTORY_FILTER",
+				Message: err.Error(),
+			},
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, dto.APIResponse{
+		Success: false,
+		Error: &dto.ErrorInfo{
+			Code:    "HISTORY_FETCH_FAILED",
+			Message: err.Error(),
+		},
+	})
+}
+
+func (h *AttendanceHandler) GetAttendanceAnalytics(c echo.Context) error {
+	// Analytics for a single service event
+	if eventID := c.QueryParam("event_id"); eventID != "" {
+		resp, err := h.attendanceService.GetEventAttendanceAnalytics(c.Request().Context(), eventID)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, dto.APIResponse{
+				Success: false,
+				Error: &dto.ErrorInfo{
+					Code:    "ANALYTICS_FETCH_FAILED",
+					Message: err.Error(),
+				},
+			})
+		}
+
+		return c.JSON(http.StatusOK, dto.APIResponse{
+			Success: true,
+			Data:    resp,
+		})
+	}
+
+	dateStr := c.QueryParam("date")
+	if dateStr == "" {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "MISSING_DATE",
+				Message: "date (YYYY-MM-DD format) or event_id query parameter is required",
+			},
+		})
+	}
+
+	date, err := time.Parse("2006-01-02", dateStr)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "INVALID_DATE_FORMAT",
+				Message: "date must be in YYYY-MM-DD format",
+			},
+		})
+	}
+
+	resp, err := h.attendanceService.GetAttendanceAnalytics(c.Request().Context(), c.QueryParam("church_id"), date)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrInvalidHistoryFilter) {
+			status = http.StatusBadRequest
+		}
+		return c.JSON(status, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "ANALYTICS_FETCH_FAILED",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.APIResponse{
+		Success: true,
+		Data:    resp,
+	})
+}
+
+func (h *AttendanceHandler) VoidAttendance(c echo.Context) error {
+	id := c.Param("id")
+
+	var req dto.VoidAttendanceRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+			},
+		})
+	}
+
+	// Validate request
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "VALIDATION_ERROR",
+				Message: "Validation failed",
+				Details: []dto.ErrorDetail{
+					{Field: "request", Message: err.Error()},
+				},
+			},
+		})
+	}
+
+	userID, _ := c.Get("user_id").(string)
+
+	resp, err := h.attendanceService.VoidAttendance(c.Request().Context(), id, &req, userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "VOID_FAILED",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.APIResponse{
+		Success: true,
+		Message: "Attendance voided successfully",
+		Data:    resp,
+	})
+}
+
+func (h *AttendanceHandler) AmendAttendance(c echo.Context) error {
+	id := c.Param("id")
+
+	var req dto.AmendAttendanceRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+			},
+		})
+	}
+
+	// Validate request
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "VALIDATION_ERROR",
+				Message: "Validation failed",
+				Details: []dto.ErrorDetail{
+					{Field: "request", Message: err.Error()},
 				},
-			})
-		}
+			},
+		})
 	}
 
-	// Parse end_date if provided
-	if endDateStr := c.QueryParam("end_date"); endDateStr != "" {
-		if parsed, err := time.Parse("2006-01-02", endDateStr); err == nil {
-			endDate = &parsed
-		} else {
-			return c.JSON(http.StatusBadRequest, dto.APIResponse{
-				Success: false,
-				Error: &dto.ErrorInfo{
-					Code:    "INVALID_DATE_FORMAT",
-					Message: "end_date must be in YYYY-MM-DD format",
+	userID, _ := c.Get("user_id").(string)
+
+	resp, err := h.attendanceService.AmendAttendance(c.Request().Context(), id, &req, userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "AMEND_FAILED",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.APIResponse{
+		Success: true,
+		Message: "Attendance amended successfully",
+		Data:    resp,
+	})
+}
+
+func (h *AttendanceHandler) BackdateAttendance(c echo.Context) error {
+	var req dto.BackdateAttendanceRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+			},
+		})
+	}
+
+	// Validate request
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "VALIDATION_ERROR",
+				Message: "Validation failed",
+				Details: []dto.ErrorDetail{
+					{Field: "request", Message: err.Error()},
 				},
-			})
-		}
+			},
+		})
+	}
+
+	userID, _ := c.Get("user_id").(string)
+
+	resp, err := h.attendanceService.BackdateAttendance(c.Request().Context(), &req, userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "BACKDATE_FAILED",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	return c.JSON(http.StatusCreated, dto.APIResponse{
+		Success: true,
+		Message: "Back-dated attendance recorded successfully",
+		Data:    resp,
+	})
+}
+
+func (h *AttendanceHandler) GetAttendanceAudit(c echo.Context) error {
+	id := c.Param("id")
+
+	resp, err := h.attendanceService.GetAttendanceAudit(c.Request().Context(), id)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "AUDIT_FETCH_FAILED",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.APIResponse{
+		Success: true,
+		Message: "Attendance audit retrieved successfully",
+		Data:    resp,
+	})
+}
+
+// GetMyAttendance returns the logged-in member's own attendance history, streaks and rates
+func (h *AttendanceHandler) GetMyAttendance(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	return h.memberAttendance(c, userID)
+}
+
+// GetUserAttendance returns any member's attendance history, streaks and rates (admin)
+func (h *AttendanceHandler) GetUserAttendance(c echo.Context) error {
+	return h.memberAttendance(c, c.Param("user_id"))
+}
+
+func (h *AttendanceHandler) memberAttendance(c echo.Context, userID string) error {
+	windows, err := parseRateWindows(c.QueryParam("windows"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "INVALID_WINDOWS",
+				Message: err.Error(),
+			},
+		})
 	}
 
 	page := utils.StringToInt(c.QueryParam("page"), 1)
 	limit := utils.StringToInt(c.QueryParam("limit"), 10)
 
-	resp, err := h.attendanceService.GetAttendanceHistory(c.Request().Context(), startDate, endDate, page, limit)
+	resp, err := h.attendanceService.GetMemberAttendance(c.Request().Context(), userID, windows, page, limit)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, dto.APIResponse{
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
 			Success: false,
 			Error: &dto.ErrorInfo{
-				Code:    "HISTORY_FETCH_FAILED",
+				Code:    "MEMBER_ATTENDANCE_FETCH_FAILED",
 				Message: err.Error(),
 			},
 		})
@@ -162,32 +995,163 @@ func (h *AttendanceHandler) GetAttendanceHistory(c echo.Context) error {
 	})
 }
 
-func (h *AttendanceHandler) GetAttendanceAnalytics(c echo.Context) error {
-	dateStr := c.QueryParam("date")
-	if dateStr == "" {
+// parseRateWindows reads a comma-separated list of window lengths in days, e.g. "30,90,365"
+func parseRateWindows(raw string) ([]int, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	var windows []int
+	for _, part := range strings.Split(raw, ",") {
+		days, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, errors.New("windows must be a comma-separated list of days, e.g. 30,90,365")
+		}
+		windows = append(windows, days)
+	}
+	return windows, nil
+}
+
+func (h *AttendanceHandler) GetAttendanceTrend(c echo.Context) error {
+	startDate, endDate, err := dateRangeParams(c)
+	if err != nil {
 		return c.JSON(http.StatusBadRequest, dto.APIResponse{
 			Success: false,
 			Error: &dto.ErrorInfo{
-				Code:    "MISSING_DATE",
-				Message: "date query parameter is required (YYYY-MM-DD format)",
+				Code:    "INVALID_DATE_FORMAT",
+				Message: err.Error(),
 			},
 		})
 	}
 
-	date, err := time.Parse("2006-01-02", dateStr)
+	window := utils.StringToInt(c.QueryParam("window"), 4)
+
+	resp, err := h.attendanceService.GetAttendanceTrend(c.Request().Context(), c.QueryParam("church_id"), startDate, endDate, c.QueryParam("interval"), window)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "ANALYTICS_FETCH_FAILED",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.APIResponse{
+		Success: true,
+		Data:    resp,
+	})
+}
+
+func (h *AttendanceHandler) GetAttendanceComparisons(c echo.Context) error {
+	date, err := dateParam(c, "date")
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, dto.APIResponse{
 			Success: false,
 			Error: &dto.ErrorInfo{
 				Code:    "INVALID_DATE_FORMAT",
-				Message: "date must be in YYYY-MM-DD format",
+				Message: err.Error(),
 			},
 		})
 	}
 
-	resp, err := h.attendanceService.GetAttendanceAnalytics(c.Request().Context(), date)
+	resp, err := h.attendanceService.GetAttendanceComparisons(c.Request().Context(), c.QueryParam("church_id"), date)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, dto.APIResponse{
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrInvalidHistoryFilter) {
+			status = http.StatusBadRequest
+		}
+		return c.JSON(status, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "ANALYTICS_FETCH_FAILED",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.APIResponse{
+		Success: true,
+		Data:    resp,
+	})
+}
+
+func (h *AttendanceHandler) GetAttendanceBreakdown(c echo.Context) error {
+	startDate, endDate, err := dateRangeParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "INVALID_DATE_FORMAT",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	resp, err := h.attendanceService.GetAttendanceBreakdown(c.Request().Context(), c.QueryParam("church_id"), startDate, endDate)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "ANALYTICS_FETCH_FAILED",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.APIResponse{
+		Success: true,
+		Data:    resp,
+	})
+}
+
+func (h *AttendanceHandler) GetFirstTimerRetention(c echo.Context) error {
+	startDate, endDate, err := dateRangeParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "INVALID_DATE_FORMAT",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	resp, err := h.attendanceService.GetFirstTimerRetention(c.Request().Context(), c.QueryParam("church_id"), startDate, endDate)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "ANALYTICS_FETCH_FAILED",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.APIResponse{
+		Success: true,
+		Data:    resp,
+	})
+}
+
+func (h *AttendanceHandler) GetHouseholdAttendance(c echo.Context) error {
+	startDate, endDate, err := dateRangeParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "INVALID_DATE_FORMAT",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	page := utils.StringToInt(c.QueryParam("page"), 1)
+	limit := utils.StringToInt(c.QueryParam("limit"), 10)
+
+	resp, err := h.attendanceService.GetHouseholdAttendance(c.Request().Context(), c.QueryParam("church_id"), startDate, endDate, page, limit)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
 			Success: false,
 			Error: &dto.ErrorInfo{
 				Code:    "ANALYTICS_FETCH_FAILED",
@@ -201,3 +1165,30 @@ func (h *AttendanceHandler) GetAttendanceAnalytics(c echo.Context) error {
 		Data:    resp,
 	})
 }
+
+// dateParam reads an optional YYYY-MM-DD query parameter
+func dateParam(c echo.Context, name string) (*time.Time, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, errors.New(name + " must be in YYYY-MM-DD format")
+	}
+	return &parsed, nil
+}
+
+// dateRangeParams reads the optional start_date and end_date query parameters
+func dateRangeParams(c echo.Context) (*time.Time, *time.Time, error) {
+	startDate, err := dateParam(c, "start_date")
+	if err != nil {
+		return nil, nil, err
+	}
+	endDate, err := dateParam(c, "end_date")
+	if err != nil {
+		return nil, nil, err
+	}
+	return startDate, endDate, nil
+}
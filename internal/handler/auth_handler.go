@@ -1,8 +1,11 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 
 	"cci-api/internal/dto"
 	"cci-api/internal/service"
@@ -158,8 +161,12 @@ func (h *AuthHandler) Login(c echo.Context) error {
 		})
 	}
 
-	resp, err := h.authService.Login(c.Request().Context(), &req)
+	resp, err := h.authService.Login(c.Request().Context(), &req, clientInfo(c))
 	if err != nil {
+		var throttled *service.LoginThrottledError
+		if errors.As(err, &throttled) {
+			return loginThrottledResponse(c, throttled)
+		}
 		return c.JSON(http.StatusUnauthorized, dto.APIResponse{
 			Success: false,
 			Error: &dto.ErrorInfo{
@@ -176,6 +183,152 @@ func (h *AuthHandler) Login(c echo.Context) error {
 	})
 }
 
+// VerifyTwoFactorLogin finishes a login that is waiting on a two-factor code
+func (h *AuthHandler) VerifyTwoFactorLogin(c echo.Context) error {
+	var req dto.TwoFactorLoginRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+			},
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "VALIDATION_ERROR",
+				Message: "Validation failed",
+				Details: []dto.ErrorDetail{
+					{Field: "request", Message: err.Error()},
+				},
+			},
+		})
+	}
+
+	resp, err := h.authService.VerifyTwoFactorLogin(c.Request().Context(), &req, clientInfo(c))
+	if err != nil {
+		var throttled *service.LoginThrottledError
+		status, code := http.StatusBadRequest, "TWO_FACTOR_LOGIN_FAILED"
+		switch {
+		case errors.As(err, &throttled):
+			return loginThrottledResponse(c, throttled)
+		case errors.Is(err, service.ErrInvalidLoginChallenge):
+			status, code = http.StatusUnauthorized, "INVALID_CHALLENGE"
+		case errors.Is(err, service.ErrInvalidTwoFactorCode):
+			status, code = http.StatusUnauthorized, "INVALID_TWO_FACTOR_CODE"
+		}
+		return c.JSON(status, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    code,
+				Message: err.Error(),
+			},
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.APIResponse{
+		Success: true,
+		Message: "Login successful",
+		Data:    resp,
+	})
+}
+
+// SetupTwoFactorLogin starts two-factor setup for an admin whose login requires it
+func (h *AuthHandler) SetupTwoFactorLogin(c echo.Context) error {
+	var req dto.TwoFactorChallengeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+			},
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "VALIDATION_ERROR",
+				Message: "Validation failed",
+				Details: []dto.ErrorDetail{
+					{Field: "request", Message: err.Error()},
+				},
+			},
+		})
+	}
+
+	resp, err := h.authService.SetupTwoFactorLogin(c.Request().Context(), &req)
+	if err != nil {
+		status, code := http.StatusBadRequest, "TWO_FACTOR_SETUP_FAILED"
+		if errors.Is(err, service.ErrInvalidLoginChallenge) {
+			status, code = http.StatusUnauthorized, "INVALID_CHALLENGE"
+		}
+		return c.JSON(status, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    code,
+				Message: err.Error(),
+			},
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.APIResponse{
+		Success: true,
+		Message: "Scan the QR code with your authenticator app, then log in with its code",
+		Data:    resp,
+	})
+}
+
+// clientInfo describes the device a request came from
+func clientInfo(c echo.Context) service.ClientInfo {
+	return service.ClientInfo{
+		IPAddress: c.RealIP(),
+		UserAgent: c.Request().UserAgent(),
+	}
+}
+
+// loginThrottledResponse answers a login that has to wait, telling the client how long in Retry-After
+func loginThrottledResponse(c echo.Context, throttled *service.LoginThrottledError) error {
+	code := "TOO_MANY_ATTEMPTS"
+	if throttled.Locked {
+		code = "ACCOUNT_LOCKED"
+	}
+	c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+	return c.JSON(http.StatusTooManyRequests, dto.APIResponse{
+		Success: false,
+		Error: &dto.ErrorInfo{
+			Code:    code,
+			Message: throttled.Error(),
+		},
+	})
+}
+
+// UnlockAccount lifts a login lockout on a member's account
+func (h *AuthHandler) UnlockAccount(c echo.Context) error {
+	userID := c.Param("user_id")
+
+	if err := h.authService.UnlockAccount(c.Request().Context(), userID); err != nil {
+		return c.JSON(accessErrorStatus(err, http.StatusInternalServerError), dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "UNLOCK_FAILED",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.APIResponse{
+		Success: true,
+		Message: "Account unlocked successfully",
+	})
+}
+
 func (h *AuthHandler) RefreshToken(c echo.Context) error {
 	var req dto.RefreshTokenRequest
 	if err := c.Bind(&req); err != nil {
@@ -202,12 +355,16 @@ func (h *AuthHandler) RefreshToken(c echo.Context) error {
 		})
 	}
 
-	resp, err := h.authService.RefreshToken(c.Request().Context(), &req)
+	resp, err := h.authService.RefreshToken(c.Request().Context(), &req, clientInfo(c))
 	if err != nil {
+		code := "TOKEN_REFRESH_FAILED"
+		if errors.Is(err, service.ErrRefreshTokenReused) {
+			code = "REFRESH_TOKEN_REUSED"
+		}
 		return c.JSON(http.StatusUnauthorized, dto.APIResponse{
 			Success: false,
 			Error: &dto.ErrorInfo{
-				Code:    "TOKEN_REFRESH_FAILED",
+				Code:    code,
 				Message: err.Error(),
 			},
 		})
@@ -275,7 +432,8 @@ func (h *AuthHandler) Logout(c echo.Context) error {
 		})
 	}
 	fmt.Printf("Logout process has begun>>>")
-	err := h.authService.Logout(c.Request().Context(), userID)
+	sessionID, _ := c.Get("session_id").(string)
+	err := h.authService.Logout(c.Request().Context(), userID, sessionID)
 	fmt.Printf("Logout failed due to this error %v", err)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, dto.APIResponse{
@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"cci-api/internal/dto"
+	"cci-api/internal/middleware"
+	"cci-api/internal/models"
+	"cci-api/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+type ChildCheckinHandler struct {
+	childCheckinService *service.ChildCheckinService
+}
+
+func NewChildCheckinHandler(childCheckinService *service.ChildCheckinService) *ChildCheckinHandler {
+	return &ChildCheckinHandler{
+		childCheckinService: childCheckinService,
+	}
+}
+
+func (h *ChildCheckinHandler) CheckIn(c echo.Context) error {
+	var req dto.ChildCheckinRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+			},
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "VALIDATION_ERROR",
+				Message: "Validation failed",
+				Details: []dto.ErrorDetail{
+					{Field: "request", Message: err.Error()},
+				},
+			},
+		})
+	}
+
+	userID, _ := c.Get("user_id").(string)
+	canManage := middleware.HasPermission(c, models.PermissionChildrenManage)
+
+	resp, err := h.childCheckinService.CheckIn(c.Request().Context(), &req, userID, canManage)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "CHILD_CHECKIN_FAILED",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	return c.JSON(http.StatusCreated, dto.APIResponse{
+		Success: true,
+		Message: "Children checked in successfully",
+		Data:    resp,
+	})
+}
+
+func (h *ChildCheckinHandler) CheckOut(c echo.Context) error {
+	var req dto.ChildCheckoutRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+			},
+		})
+	}
+
+	staffID, _ := c.Get("user_id").(string)
+
+	resp, err := h.childCheckinService.CheckOut(c.Request().Context(), c.Param("id"), &req, staffID)
+	if err != nil {
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, service.ErrChildCheckinNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, service.ErrPickupNotAuthorized):
+			status = http.StatusForbidden
+		case errors.Is(err, service.ErrChildAlreadyCheckedOut):
+			status = http.StatusConflict
+		}
+		return c.JSON(status, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "CHILD_CHECKOUT_FAILED",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.APIResponse{
+		Success: true,
+		Message: "Child checked out successfully",
+		Data:    resp,
+	})
+}
+
+func (h *ChildCheckinHandler) GetRoster(c echo.Context) error {
+	resp, err := h.childCheckinService.GetRoster(c.Request().Context(), c.QueryParam("classroom"), c.QueryParam("event_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "ROSTER_FETCH_FAILED",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.APIResponse{
+		Success: true,
+		Data:    resp,
+	})
+}
+
+func (h *ChildCheckinHandler) GetPickupAlerts(c echo.Context) error {
+	resp, err := h.childCheckinService.GetPickupAlerts(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "PICKUP_ALERTS_FETCH_FAILED",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.APIResponse{
+		Success: true,
+		Data:    resp,
+	})
+}
+
+func (h *ChildCheckinHandler) GetChildLabel(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	canManage := middleware.HasPermission(c, models.PermissionChildrenManage)
+
+	label, err := h.childCheckinService.GetChildLabel(c.Request().Context(), c.Param("id"), userID, canManage)
+	if err != nil {
+		return labelError(c, err)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("inline; filename=%q", c.Param("id")+".png"))
+	return c.Blob(http.StatusOK, "image/png", label)
+}
+
+func (h *ChildCheckinHandler) GetLabelSheet(c echo.Context) error {
+	var req dto.ChildLabelsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+			},
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "VALIDATION_ERROR",
+				Message: "Validation failed",
+				Details: []dto.ErrorDetail{
+					{Field: "request", Message: err.Error()},
+				},
+			},
+		})
+	}
+
+	userID, _ := c.Get("user_id").(string)
+	canManage := middleware.HasPermission(c, models.PermissionChildrenManage)
+
+	sheet, err := h.childCheckinService.GetLabelSheet(c.Request().Context(), &req, userID, canManage)
+	if err != nil {
+		return labelError(c, err)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="child-labels.pdf"`)
+	return c.Blob(http.StatusOK, "application/pdf", sheet)
+}
+
+func labelError(c echo.Context, err error) error {
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, service.ErrChildCheckinNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrPickupNotAuthorized):
+		status = http.StatusForbidden
+	}
+	return c.JSON(status, dto.APIResponse{
+		Success: false,
+		Error: &dto.ErrorInfo{
+			Code:    "LABEL_GENERATION_FAILED",
+			Message: err.Error(),
+		},
+	})
+}
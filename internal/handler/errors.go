@@ -0,0 +1,21 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"cci-api/internal/service"
+)
+
+// accessErrorStatus answers the shared service access errors with 404 or 403, and anything else with fallback
+func accessErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrInvalidInput):
+		return http.StatusBadRequest
+	}
+	return fallback
+}
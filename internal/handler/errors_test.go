@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"cci-api/internal/service"
+)
+
+func TestAccessErrorStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"not found", service.ErrSermonNotFound, http.StatusNotFound},
+		{"wrapped not found", fmt.Errorf("loading: %w", service.ErrFamilyMemberNotFound), http.StatusNotFound},
+		{"someone else's", service.ErrNotAnnouncementAuthor, http.StatusForbidden},
+		{"another household", service.ErrNotFamilyHead, http.StatusForbidden},
+		{"invalid input", fmt.Errorf("%w: bad ID", service.ErrInvalidInput), http.StatusBadRequest},
+		{"anything else", errors.New("connection reset"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		if got := accessErrorStatus(tt.err, http.StatusInternalServerError); got != tt.want {
+			t.Errorf("%s: accessErrorStatus = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -34,9 +34,11 @@ func (h *FamilyMemberHandler) CreateFamilyMember(c echo.Context) error {
 		})
 	}
 
-	familyMember, err := h.familyMemberService.CreateFamilyMember(c.Request().Context(), c, &req)
+	userID := c.Get("user_id").(string)
+
+	familyMember, err := h.familyMemberService.CreateFamilyMember(c.Request().Context(), &req, userID)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{
 			Code:    "FAMILY_MEMBER_CREATION_FAILED",
 			Message: err.Error(),
 		})
@@ -53,7 +55,10 @@ func (h *FamilyMemberHandler) GetFamilyMembers(c echo.Context) error {
 	page, _ := strconv.Atoi(c.QueryParam("page"))
 	limit, _ := strconv.Atoi(c.QueryParam("limit"))
 
-	familyMembers, err := h.familyMemberService.GetFamilyMembers(c.Request().Context(), page, limit)
+	userID := c.Get("user_id").(string)
+	isAdmin, _ := c.Get("admin").(bool)
+
+	familyMembers, err := h.familyMemberService.GetFamilyMembers(c.Request().Context(), userID, isAdmin, c.QueryParam("family_head"), page, limit)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
 			Code:    "FAMILY_MEMBERS_FETCH_FAILED",
@@ -71,9 +76,12 @@ func (h *FamilyMemberHandler) GetFamilyMembers(c echo.Context) error {
 func (h *FamilyMemberHandler) GetFamilyMemberByID(c echo.Context) error {
 	id := c.Param("id")
 
-	familyMember, err := h.familyMemberService.GetFamilyMemberByID(c.Request().Context(), id)
+	userID := c.Get("user_id").(string)
+	isAdmin, _ := c.Get("admin").(bool)
+
+	familyMember, err := h.familyMemberService.GetFamilyMemberByID(c.Request().Context(), id, userID, isAdmin)
 	if err != nil {
-		return c.JSON(http.StatusNotFound, dto.ErrorResponse{
+		return c.JSON(accessErrorStatus(err, http.StatusBadRequest), dto.ErrorResponse{
 			Code:    "FAMILY_MEMBER_NOT_FOUND",
 			Message: err.Error(),
 		})
@@ -104,9 +112,12 @@ func (h *FamilyMemberHandler) UpdateFamilyMember(c echo.Context) error {
 		})
 	}
 
-	familyMember, err := h.familyMemberService.UpdateFamilyMember(c.Request().Context(), id, &req)
+	userID := c.Get("user_id").(string)
+	isAdmin, _ := c.Get("admin").(bool)
+
+	familyMember, err := h.familyMemberService.UpdateFamilyMember(c.Request().Context(), id, &req, userID, isAdmin)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+		return c.JSON(accessErrorStatus(err, http.StatusBadRequest), dto.ErrorResponse{
 			Code:    "FAMILY_MEMBER_UPDATE_FAILED",
 			Message: err.Error(),
 		})
@@ -122,9 +133,12 @@ func (h *FamilyMemberHandler) UpdateFamilyMember(c echo.Context) error {
 func (h *FamilyMemberHandler) DeleteFamilyMember(c echo.Context) error {
 	id := c.Param("id")
 
-	err := h.familyMemberService.DeleteFamilyMember(c.Request().Context(), id)
+	userID := c.Get("user_id").(string)
+	isAdmin, _ := c.Get("admin").(bool)
+
+	err := h.familyMemberService.DeleteFamilyMember(c.Request().Context(), id, userID, isAdmin)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+		return c.JSON(accessErrorStatus(err, http.StatusBadRequest), dto.ErrorResponse{
 			Code:    "FAMILY_MEMBER_DELETE_FAILED",
 			Message: err.Error(),
 		})
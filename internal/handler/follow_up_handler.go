@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"net/http"
+
+	"cci-api/internal/dto"
+	"cci-api/internal/middleware"
+	"cci-api/internal/models"
+	"cci-api/internal/service"
+	"cci-api/internal/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+type FollowUpHandler struct {
+	followUpService *service.FollowUpService
+}
+
+func NewFollowUpHandler(followUpService *service.FollowUpService) *FollowUpHandler {
+	return &FollowUpHandler{followUpService: followUpService}
+}
+
+func (h *FollowUpHandler) GetFollowUps(c echo.Context) error {
+	page := utils.StringToInt(c.QueryParam("page"), 1)
+	limit := utils.StringToInt(c.QueryParam("limit"), 10)
+	userID := c.Get("user_id").(string)
+	canManage := middleware.HasPermission(c, models.PermissionFollowUpsManage)
+
+	followUps, err := h.followUpService.GetFollowUps(c.Request().Context(), c.QueryParam("status"), c.QueryParam("assigned_to"), userID, canManage, page, limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Code:    "FOLLOW_UPS_FETCH_FAILED",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{
+		Code:    "FOLLOW_UPS_RETRIEVED",
+		Message: "Follow-ups retrieved successfully",
+		Data:    followUps,
+	})
+}
+
+func (h *FollowUpHandler) GetFollowUpByID(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+	canManage := middleware.HasPermission(c, models.PermissionFollowUpsManage)
+
+	followUp, err := h.followUpService.GetFollowUpByID(c.Request().Context(), c.Param("id"), userID, canManage)
+	if err != nil {
+		return c.JSON(accessErrorStatus(err, http.StatusNotFound), dto.ErrorResponse{
+			Code:    "FOLLOW_UP_NOT_FOUND",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{
+		Code:    "FOLLOW_UP_RETRIEVED",
+		Message: "Follow-up retrieved successfully",
+		Data:    followUp,
+	})
+}
+
+func (h *FollowUpHandler) UpdateFollowUp(c echo.Context) error {
+	var req dto.UpdateFollowUpRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "Invalid request body",
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Code:    "VALIDATION_ERROR",
+			Message: err.Error(),
+		})
+	}
+
+	userID := c.Get("user_id").(string)
+	canManage := middleware.HasPermission(c, models.PermissionFollowUpsManage)
+
+	followUp, err := h.followUpService.UpdateFollowUp(c.Request().Context(), c.Param("id"), &req, userID, canManage)
+	if err != nil {
+		return c.JSON(accessErrorStatus(err, http.StatusBadRequest), dto.ErrorResponse{
+			Code:    "FOLLOW_UP_UPDATE_FAILED",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{
+		Code:    "FOLLOW_UP_UPDATED",
+		Message: "Follow-up updated successfully",
+		Data:    followUp,
+	})
+}
+
+// ScanFollowUps runs the absentee scan straight away instead of waiting for the scheduler
+func (h *FollowUpHandler) ScanFollowUps(c echo.Context) error {
+	created, err := h.followUpService.DetectAbsentees(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Code:    "FOLLOW_UP_SCAN_FAILED",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{
+		Code:    "FOLLOW_UP_SCAN_COMPLETED",
+		Message: "Absentee scan completed successfully",
+		Data:    dto.FollowUpScanResponse{Created: created},
+	})
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"net/http"
+
+	"cci-api/internal/dto"
+	"cci-api/internal/service"
+	"cci-api/internal/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+type MembershipHandler struct {
+	membershipService *service.MembershipService
+}
+
+func NewMembershipHandler(membershipService *service.MembershipService) *MembershipHandler {
+	return &MembershipHandler{membershipService: membershipService}
+}
+
+func (h *MembershipHandler) GetMembership(c echo.Context) error {
+	membership, err := h.membershipService.GetMembership(c.Request().Context(), c.Param("user_id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{
+			Code:    "MEMBERSHIP_NOT_FOUND",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{
+		Code:    "MEMBERSHIP_RETRIEVED",
+		Message: "Membership retrieved successfully",
+		Data:    membership,
+	})
+}
+
+func (h *MembershipHandler) TransitionStage(c echo.Context) error {
+	var req dto.MembershipStageRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "Invalid request body",
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Code:    "VALIDATION_ERROR",
+			Message: err.Error(),
+		})
+	}
+
+	changedBy := c.Get("user_id").(string)
+
+	membership, err := h.membershipService.TransitionStage(c.Request().Context(), c.Param("user_id"), &req, changedBy)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Code:    "STAGE_TRANSITION_FAILED",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{
+		Code:    "STAGE_UPDATED",
+		Message: "Membership stage updated successfully",
+		Data:    membership,
+	})
+}
+
+func (h *MembershipHandler) GetStageSuggestions(c echo.Context) error {
+	page := utils.StringToInt(c.QueryParam("page"), 1)
+	limit := utils.StringToInt(c.QueryParam("limit"), 10)
+
+	suggestions, err := h.membershipService.GetStageSuggestions(c.Request().Context(), page, limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Code:    "SUGGESTIONS_FETCH_FAILED",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{
+		Code:    "SUGGESTIONS_RETRIEVED",
+		Message: "Stage suggestions retrieved successfully",
+		Data:    suggestions,
+	})
+}
+
+func (h *MembershipHandler) GetMembershipFunnel(c echo.Context) error {
+	funnel, err := h.membershipService.GetMembershipFunnel(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Code:    "FUNNEL_FETCH_FAILED",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{
+		Code:    "FUNNEL_RETRIEVED",
+		Message: "Membership funnel retrieved successfully",
+		Data:    funnel,
+	})
+}
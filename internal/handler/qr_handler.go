@@ -1,9 +1,12 @@
 package handler
 
 import (
+	"fmt"
 	"net/http"
 
 	"cci-api/internal/dto"
+	"cci-api/internal/middleware"
+	"cci-api/internal/models"
 	"cci-api/internal/service"
 
 	"github.com/labstack/echo/v4"
@@ -45,6 +48,21 @@ func (h *QRHandler) GenerateQRCode(c echo.Context) error {
 		})
 	}
 
+	// Members can issue their own token; users:manage can issue anyone's
+	currentUserID, _ := c.Get("user_id").(string)
+	if req.UserID == "" {
+		req.UserID = currentUserID
+	}
+	if req.UserID != currentUserID && !middleware.HasPermission(c, models.PermissionUsersManage) {
+		return c.JSON(http.StatusForbidden, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "FORBIDDEN",
+				Message: "You can only generate your own QR code",
+			},
+		})
+	}
+
 	resp, err := h.qrService.GenerateQRCode(c.Request().Context(), &req)
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, dto.APIResponse{
@@ -61,3 +79,127 @@ func (h *QRHandler) GenerateQRCode(c echo.Context) error {
 		Data:    resp,
 	})
 }
+
+func (h *QRHandler) GetRotatingQRCode(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+
+	resp, err := h.qrService.GetRotatingQRCode(c.Request().Context(), userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "QR_GENERATION_FAILED",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.APIResponse{
+		Success: true,
+		Data:    resp,
+	})
+}
+
+func (h *QRHandler) GetMemberCard(c echo.Context) error {
+	userID := c.Param("user_id")
+
+	// Members can print their own card; users:manage can print anyone's
+	canManage := middleware.HasPermission(c, models.PermissionUsersManage)
+	if currentUserID, _ := c.Get("user_id").(string); !canManage && currentUserID != userID {
+		return c.JSON(http.StatusForbidden, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "FORBIDDEN",
+				Message: "You can only view your own member card",
+			},
+		})
+	}
+
+	card, err := h.qrService.GetMemberCard(c.Request().Context(), userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "CARD_GENERATION_FAILED",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("inline; filename=%q", userID+".png"))
+	return c.Blob(http.StatusOK, "image/png", card)
+}
+
+// RevokeMemberCards stops the member's printed cards from scanning
+func (h *QRHandler) RevokeMemberCards(c echo.Context) error {
+	userID := c.Param("user_id")
+
+	// Members can revoke their own cards; users:manage can revoke anyone's
+	canManage := middleware.HasPermission(c, models.PermissionUsersManage)
+	if currentUserID, _ := c.Get("user_id").(string); !canManage && currentUserID != userID {
+		return c.JSON(http.StatusForbidden, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "FORBIDDEN",
+				Message: "You can only revoke your own member cards",
+			},
+		})
+	}
+
+	if err := h.qrService.RevokeMemberCards(c.Request().Context(), userID); err != nil {
+		return c.JSON(accessErrorStatus(err, http.StatusInternalServerError), dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "CARD_REVOKE_FAILED",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.APIResponse{
+		Success: true,
+		Message: "Member cards revoked successfully",
+	})
+}
+
+func (h *QRHandler) GetMemberCardSheet(c echo.Context) error {
+	var req dto.MemberCardSheetRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+			},
+		})
+	}
+
+	// Validate request
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "VALIDATION_ERROR",
+				Message: "Validation failed",
+				Details: []dto.ErrorDetail{
+					{Field: "request", Message: err.Error()},
+				},
+			},
+		})
+	}
+
+	sheet, count, err := h.qrService.GetMemberCardSheet(c.Request().Context(), &req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "CARD_SHEET_GENERATION_FAILED",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="member-cards.pdf"`)
+	c.Response().Header().Set("X-Card-Count", fmt.Sprint(count))
+	return c.Blob(http.StatusOK, "application/pdf", sheet)
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -34,9 +35,16 @@ func (h *RoleHandler) CreateRole(c echo.Context) error {
 		})
 	}
 
-	role, err := h.roleService.CreateRole(c.Request().Context(), &req)
+	callerID, _ := c.Get("user_id").(string)
+	isAdmin, _ := c.Get("admin").(bool)
+
+	role, err := h.roleService.CreateRole(c.Request().Context(), &req, callerID, isAdmin)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+		status := accessErrorStatus(err, http.StatusInternalServerError)
+		if errors.Is(err, service.ErrUnknownPermission) {
+			status = http.StatusBadRequest
+		}
+		return c.JSON(status, dto.ErrorResponse{
 			Code:    "ROLE_CREATION_FAILED",
 			Message: err.Error(),
 		})
@@ -104,9 +112,16 @@ func (h *RoleHandler) UpdateRole(c echo.Context) error {
 		})
 	}
 
-	role, err := h.roleService.UpdateRole(c.Request().Context(), id, &req)
+	callerID, _ := c.Get("user_id").(string)
+	isAdmin, _ := c.Get("admin").(bool)
+
+	role, err := h.roleService.UpdateRole(c.Request().Context(), id, &req, callerID, isAdmin)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+		status := accessErrorStatus(err, http.StatusInternalServerError)
+		if errors.Is(err, service.ErrUnknownPermission) {
+			status = http.StatusBadRequest
+		}
+		return c.JSON(status, dto.ErrorResponse{
 			Code:    "ROLE_UPDATE_FAILED",
 			Message: err.Error(),
 		})
@@ -135,3 +150,44 @@ func (h *RoleHandler) DeleteRole(c echo.Context) error {
 		Message: "Role deleted successfully",
 	})
 }
+
+func (h *RoleHandler) GetRolesAndPermissions(c echo.Context) error {
+	resp, err := h.roleService.GetRolesAndPermissions(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Code:    "PERMISSIONS_FETCH_FAILED",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{
+		Code:    "PERMISSIONS_RETRIEVED",
+		Message: "Roles and permissions retrieved successfully",
+		Data:    resp,
+	})
+}
+
+func (h *RoleHandler) AssignRole(c echo.Context) error {
+	var req dto.AssignRoleRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "Invalid request body",
+		})
+	}
+
+	callerID, _ := c.Get("user_id").(string)
+	isAdmin, _ := c.Get("admin").(bool)
+
+	if err := h.roleService.AssignRole(c.Request().Context(), c.Param("user_id"), req.RoleID, callerID, isAdmin); err != nil {
+		return c.JSON(accessErrorStatus(err, http.StatusBadRequest), dto.ErrorResponse{
+			Code:    "ROLE_ASSIGN_FAILED",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{
+		Code:    "ROLE_ASSIGNED",
+		Message: "Role assigned successfully",
+	})
+}
@@ -9,7 +9,6 @@ import (
 	"cci-api/internal/service"
 
 	"github.com/labstack/echo/v4"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type SermonHandler struct {
@@ -40,9 +39,8 @@ func (h *SermonHandler) CreateSermon(c echo.Context) error {
 
 	// Get user ID from JWT token
 	userID := c.Get("user_id").(string)
-	objID, _ := primitive.ObjectIDFromHex(userID)
 
-	sermon, err := h.sermonService.CreateSermon(c.Request().Context(), &req, objID)
+	sermon, err := h.sermonService.CreateSermon(c.Request().Context(), &req, userID)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
 			Code:    "SERMON_CREATION_FAILED",
@@ -81,7 +79,7 @@ func (h *SermonHandler) GetSermonByID(c echo.Context) error {
 
 	sermon, err := h.sermonService.GetSermonByID(c.Request().Context(), id)
 	if err != nil {
-		return c.JSON(http.StatusNotFound, dto.ErrorResponse{
+		return c.JSON(accessErrorStatus(err, http.StatusNotFound), dto.ErrorResponse{
 			Code:    "SERMON_NOT_FOUND",
 			Message: err.Error(),
 		})
@@ -112,9 +110,12 @@ func (h *SermonHandler) UpdateSermon(c echo.Context) error {
 		})
 	}
 
-	sermon, err := h.sermonService.UpdateSermon(c.Request().Context(), id, &req)
+	userID := c.Get("user_id").(string)
+	isAdmin, _ := c.Get("admin").(bool)
+
+	sermon, err := h.sermonService.UpdateSermon(c.Request().Context(), id, &req, userID, isAdmin)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+		return c.JSON(accessErrorStatus(err, http.StatusInternalServerError), dto.ErrorResponse{
 			Code:    "SERMON_UPDATE_FAILED",
 			Message: err.Error(),
 		})
@@ -130,9 +131,12 @@ func (h *SermonHandler) UpdateSermon(c echo.Context) error {
 func (h *SermonHandler) DeleteSermon(c echo.Context) error {
 	id := c.Param("id")
 
-	err := h.sermonService.DeleteSermon(c.Request().Context(), id)
+	userID := c.Get("user_id").(string)
+	isAdmin, _ := c.Get("admin").(bool)
+
+	err := h.sermonService.DeleteSermon(c.Request().Context(), id, userID, isAdmin)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+		return c.JSON(accessErrorStatus(err, http.StatusInternalServerError), dto.ErrorResponse{
 			Code:    "SERMON_DELETE_FAILED",
 			Message: err.Error(),
 		})
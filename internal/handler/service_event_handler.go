@@ -0,0 +1,236 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cci-api/internal/dto"
+	"cci-api/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+type ServiceEventHandler struct {
+	serviceEventService *service.ServiceEventService
+}
+
+func NewServiceEventHandler(serviceEventService *service.ServiceEventService) *ServiceEventHandler {
+	return &ServiceEventHandler{serviceEventService: serviceEventService}
+}
+
+func (h *ServiceEventHandler) CreateEvent(c echo.Context) error {
+	var req dto.CreateServiceEventRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "Invalid request body",
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Code:    "VALIDATION_ERROR",
+			Message: err.Error(),
+		})
+	}
+
+	userID, _ := c.Get("user_id").(string)
+
+	event, err := h.serviceEventService.CreateEvent(c.Request().Context(), &req, userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Code:    "EVENT_CREATION_FAILED",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusCreated, dto.SuccessResponse{
+		Code:    "EVENT_CREATED",
+		Message: "Service event created successfully",
+		Data:    event,
+	})
+}
+
+func (h *ServiceEventHandler) GetEvents(c echo.Context) error {
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+
+	var startDate, endDate *time.Time
+	if startDateStr := c.QueryParam("start_date"); startDateStr != "" {
+		parsed, err := time.Parse("2006-01-02", startDateStr)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+				Code:    "INVALID_DATE_FORMAT",
+				Message: "start_date must be in YYYY-MM-DD format",
+			})
+		}
+		startDate = &parsed
+	}
+	if endDateStr := c.QueryParam("end_date"); endDateStr != "" {
+		parsed, err := time.Parse("2006-01-02", endDateStr)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+				Code:    "INVALID_DATE_FORMAT",
+				Message: "end_date must be in YYYY-MM-DD format",
+			})
+		}
+		endDate = &parsed
+	}
+
+	events, err := h.serviceEventService.GetEvents(c.Request().Context(), page, limit, c.QueryParam("event_type"), startDate, endDate)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Code:    "EVENTS_FETCH_FAILED",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{
+		Code:    "EVENTS_RETRIEVED",
+		Message: "Service events retrieved successfully",
+		Data:    events,
+	})
+}
+
+func (h *ServiceEventHandler) GetCurrentEvent(c echo.Context) error {
+	event, err := h.serviceEventService.GetCurrentEvent(c.Request().Context(), c.QueryParam("church_id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{
+			Code:    "NO_OPEN_EVENT",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{
+		Code:    "EVENT_RETRIEVED",
+		Message: "Current service event retrieved successfully",
+		Data:    event,
+	})
+}
+
+func (h *ServiceEventHandler) GetEventByID(c echo.Context) error {
+	id := c.Param("id")
+
+	event, err := h.serviceEventService.GetEventByID(c.Request().Context(), id)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{
+			Code:    "EVENT_NOT_FOUND",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{
+		Code:    "EVENT_RETRIEVED",
+		Message: "Service event retrieved successfully",
+		Data:    event,
+	})
+}
+
+func (h *ServiceEventHandler) UpdateEvent(c echo.Context) error {
+	id := c.Param("id")
+	var req dto.UpdateServiceEventRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "Invalid request body",
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Code:    "VALIDATION_ERROR",
+			Message: err.Error(),
+		})
+	}
+
+	event, err := h.serviceEventService.UpdateEvent(c.Request().Context(), id, &req)
+	if err != nil {
+		return c.JSON(accessErrorStatus(err, http.StatusBadRequest), dto.ErrorResponse{
+			Code:    "EVENT_UPDATE_FAILED",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{
+		Code:    "EVENT_UPDATED",
+		Message: "Service event updated successfully",
+		Data:    event,
+	})
+}
+
+func (h *ServiceEventHandler) DeleteEvent(c echo.Context) error {
+	id := c.Param("id")
+
+	err := h.serviceEventService.DeleteEvent(c.Request().Context(), id)
+	if err != nil {
+		status := accessErrorStatus(err, http.StatusBadRequest)
+		if errors.Is(err, service.ErrServiceEventInUse) {
+			status = http.StatusConflict
+		}
+		return c.JSON(status, dto.ErrorResponse{
+			Code:    "EVENT_DELETE_FAILED",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{
+		Code:    "EVENT_DELETED",
+		Message: "Service event deleted successfully",
+	})
+}
+
+func (h *ServiceEventHandler) CreateVenueCode(c echo.Context) error {
+	id := c.Param("id")
+	var req dto.CreateVenueCodeRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "Invalid request body",
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Code:    "VALIDATION_ERROR",
+			Message: err.Error(),
+		})
+	}
+
+	userID, _ := c.Get("user_id").(string)
+
+	venueCode, err := h.serviceEventService.CreateVenueCode(c.Request().Context(), id, &req, userID)
+	if err != nil {
+		return c.JSON(accessErrorStatus(err, http.StatusBadRequest), dto.ErrorResponse{
+			Code:    "VENUE_CODE_CREATION_FAILED",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusCreated, dto.SuccessResponse{
+		Code:    "VENUE_CODE_CREATED",
+		Message: "Venue code created successfully",
+		Data:    venueCode,
+	})
+}
+
+func (h *ServiceEventHandler) GetVenueCodes(c echo.Context) error {
+	id := c.Param("id")
+
+	venueCodes, err := h.serviceEventService.GetVenueCodes(c.Request().Context(), id)
+	if err != nil {
+		return c.JSON(accessErrorStatus(err, http.StatusBadRequest), dto.ErrorResponse{
+			Code:    "VENUE_CODES_FETCH_FAILED",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{
+		Code:    "VENUE_CODES_RETRIEVED",
+		Message: "Venue codes retrieved successfully",
+		Data:    venueCodes,
+	})
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"net/http"
+
+	"cci-api/internal/dto"
+	"cci-api/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+type SessionHandler struct {
+	sessionService *service.SessionService
+}
+
+func NewSessionHandler(sessionService *service.SessionService) *SessionHandler {
+	return &SessionHandler{sessionService: sessionService}
+}
+
+// ListSessions shows the devices the member is logged in on
+func (h *SessionHandler) ListSessions(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+	sessionID, _ := c.Get("session_id").(string)
+
+	sessions, err := h.sessionService.ListSessions(c.Request().Context(), userID, sessionID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "SESSIONS_FETCH_FAILED",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.APIResponse{
+		Success: true,
+		Message: "Sessions retrieved successfully",
+		Data:    sessions,
+	})
+}
+
+// RevokeSession logs the member out on one device
+func (h *SessionHandler) RevokeSession(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+
+	if err := h.sessionService.RevokeSession(c.Request().Context(), userID, c.Param("id")); err != nil {
+		return c.JSON(accessErrorStatus(err, http.StatusBadRequest), dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "SESSION_REVOKE_FAILED",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.APIResponse{
+		Success: true,
+		Message: "Session revoked successfully",
+	})
+}
+
+// RevokeAllSessions logs the member out on every device
+func (h *SessionHandler) RevokeAllSessions(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+
+	if err := h.sessionService.RevokeAllSessions(c.Request().Context(), userID); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "LOGOUT_FAILED",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.APIResponse{
+		Success: true,
+		Message: "Logged out of every device successfully",
+	})
+}
@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"cci-api/internal/dto"
+	"cci-api/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+type TwoFactorHandler struct {
+	twoFactorService *service.TwoFactorService
+}
+
+func NewTwoFactorHandler(twoFactorService *service.TwoFactorService) *TwoFactorHandler {
+	return &TwoFactorHandler{twoFactorService: twoFactorService}
+}
+
+// GetStatus shows whether the member uses two-factor authentication and how many recovery codes are left
+func (h *TwoFactorHandler) GetStatus(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+
+	resp, err := h.twoFactorService.Status(c.Request().Context(), userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "TWO_FACTOR_STATUS_FAILED",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.APIResponse{
+		Success: true,
+		Message: "Two-factor status retrieved successfully",
+		Data:    resp,
+	})
+}
+
+// Enroll returns a new authenticator secret and its QR code
+func (h *TwoFactorHandler) Enroll(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+
+	resp, err := h.twoFactorService.Enroll(c.Request().Context(), userID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrTwoFactorAlreadyEnabled) {
+			status = http.StatusConflict
+		}
+		return c.JSON(status, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "TWO_FACTOR_ENROLL_FAILED",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.APIResponse{
+		Success: true,
+		Message: "Scan the QR code with your authenticator app, then confirm with its code",
+		Data:    resp,
+	})
+}
+
+// Enable turns two-factor authentication on with a code from the enrolled app
+func (h *TwoFactorHandler) Enable(c echo.Context) error {
+	var req dto.TwoFactorCodeRequest
+	if ok, err := bindTwoFactorRequest(c, &req); !ok {
+		return err
+	}
+	userID := c.Get("user_id").(string)
+
+	resp, err := h.twoFactorService.Enable(c.Request().Context(), userID, req.Code)
+	if err != nil {
+		return twoFactorErrorResponse(c, "TWO_FACTOR_ENABLE_FAILED", err)
+	}
+
+	return c.JSON(http.StatusOK, dto.APIResponse{
+		Success: true,
+		Message: "Two-factor authentication enabled. Keep these recovery codes somewhere safe, they are only shown once",
+		Data:    resp,
+	})
+}
+
+// Disable turns two-factor authentication off
+func (h *TwoFactorHandler) Disable(c echo.Context) error {
+	var req dto.DisableTwoFactorRequest
+	if ok, err := bindTwoFactorRequest(c, &req); !ok {
+		return err
+	}
+	userID := c.Get("user_id").(string)
+
+	if err := h.twoFactorService.Disable(c.Request().Context(), userID, &req); err != nil {
+		return twoFactorErrorResponse(c, "TWO_FACTOR_DISABLE_FAILED", err)
+	}
+
+	return c.JSON(http.StatusOK, dto.APIResponse{
+		Success: true,
+		Message: "Two-factor authentication disabled",
+	})
+}
+
+// RegenerateRecoveryCodes replaces the member's recovery codes
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c echo.Context) error {
+	var req dto.TwoFactorCodeRequest
+	if ok, err := bindTwoFactorRequest(c, &req); !ok {
+		return err
+	}
+	userID := c.Get("user_id").(string)
+
+	resp, err := h.twoFactorService.RegenerateRecoveryCodes(c.Request().Context(), userID, req.Code)
+	if err != nil {
+		return twoFactorErrorResponse(c, "RECOVERY_CODES_FAILED", err)
+	}
+
+	return c.JSON(http.StatusOK, dto.APIResponse{
+		Success: true,
+		Message: "Recovery codes replaced. Keep them somewhere safe, they are only shown once",
+		Data:    resp,
+	})
+}
+
+// Reset turns two-factor authentication off for a member who lost access to it
+func (h *TwoFactorHandler) Reset(c echo.Context) error {
+	userID := c.Param("user_id")
+	isAdmin, _ := c.Get("admin").(bool)
+
+	if err := h.twoFactorService.Reset(c.Request().Context(), userID, isAdmin); err != nil {
+		return c.JSON(accessErrorStatus(err, http.StatusInternalServerError), dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "TWO_FACTOR_RESET_FAILED",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.APIResponse{
+		Success: true,
+		Message: "Two-factor authentication reset successfully",
+	})
+}
+
+// bindTwoFactorRequest binds and validates the body. When it reports false the error response is already written.
+func bindTwoFactorRequest(c echo.Context, req interface{}) (bool, error) {
+	if err := c.Bind(req); err != nil {
+		return false, c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+			},
+		})
+	}
+
+	if err := c.Validate(req); err != nil {
+		return false, c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "VALIDATION_ERROR",
+				Message: "Validation failed",
+				Details: []dto.ErrorDetail{
+					{Field: "request", Message: err.Error()},
+				},
+			},
+		})
+	}
+	return true, nil
+}
+
+func twoFactorErrorResponse(c echo.Context, code string, err error) error {
+	status := accessErrorStatus(err, http.StatusBadRequest)
+	switch {
+	case errors.Is(err, service.ErrInvalidTwoFactorCode):
+		code = "INVALID_TWO_FACTOR_CODE"
+	case errors.Is(err, service.ErrTwoFactorAlreadyEnabled):
+		status = http.StatusConflict
+	}
+	return c.JSON(status, dto.APIResponse{
+		Success: false,
+		Error: &dto.ErrorInfo{
+			Code:    code,
+			Message: err.Error(),
+		},
+	})
+}
@@ -106,3 +106,31 @@ func (h *UserHandler) FilterUsers(c echo.Context) error {
 		Data:    resp,
 	})
 }
+
+func (h *UserHandler) AssignShepherd(c echo.Context) error {
+	var req dto.AssignShepherdRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+			},
+		})
+	}
+
+	if err := h.userService.AssignShepherd(c.Request().Context(), c.Param("user_id"), req.Shepherd); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.APIResponse{
+			Success: false,
+			Error: &dto.ErrorInfo{
+				Code:    "SHEPHERD_ASSIGN_FAILED",
+				Message: err.Error(),
+			},
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.APIResponse{
+		Success: true,
+		Message: "Shepherd assigned successfully",
+	})
+}
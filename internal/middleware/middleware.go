@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strings"
@@ -69,6 +70,7 @@ func JWTMiddleware(cfg *config.Config) echo.MiddlewareFunc {
 			c.Set("user_id", claims.UserID)
 			c.Set("email", claims.Email)
 			c.Set("admin", claims.Admin)
+			c.Set("session_id", claims.SessionID)
 
 			// Always check for user_id before proceeding
 			if _, ok := GetUserID(c); !ok {
@@ -86,6 +88,19 @@ func JWTMiddleware(cfg *config.Config) echo.MiddlewareFunc {
 	}
 }
 
+// QueryTokenMiddleware lets a request carry its bearer token in the access_token query parameter, for clients
+// such as the browser's EventSource that cannot set headers. A token in the Authorization header takes precedence.
+func QueryTokenMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if token := c.QueryParam("access_token"); token != "" && c.Request().Header.Get("Authorization") == "" {
+				c.Request().Header.Set("Authorization", "Bearer "+token)
+			}
+			return next(c)
+		}
+	}
+}
+
 // AdminMiddleware checks if user is admin
 func AdminMiddleware() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -105,6 +120,72 @@ func AdminMiddleware() echo.MiddlewareFunc {
 	}
 }
 
+// PermissionLoader looks up the permissions granted by a user's role
+type PermissionLoader interface {
+	UserPermissions(ctx context.Context, userID string) ([]string, error)
+}
+
+// LoadPermissions resolves the permissions granted by the caller's role once per request, for RequirePermission
+// and HasPermission. It must run after JWTMiddleware.
+func LoadPermissions(permissionService PermissionLoader) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if admin, ok := c.Get("admin").(bool); ok && admin {
+				return next(c)
+			}
+
+			userID, _ := GetUserID(c)
+			permissions, err := permissionService.UserPermissions(c.Request().Context(), userID)
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, dto.APIResponse{
+					Success: false,
+					Error: &dto.ErrorInfo{
+						Code:    "PERMISSION_CHECK_FAILED",
+						Message: "Failed to check your permissions",
+					},
+				})
+			}
+			c.Set("permissions", permissions)
+
+			return next(c)
+		}
+	}
+}
+
+// HasPermission reports whether the caller is an admin or their role grants the permission
+func HasPermission(c echo.Context, permission string) bool {
+	if admin, ok := c.Get("admin").(bool); ok && admin {
+		return true
+	}
+
+	permissions, _ := c.Get("permissions").([]string)
+	for _, granted := range permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// RequirePermission lets admins through, and anyone else whose role grants the permission
+func RequirePermission(permission string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if HasPermission(c, permission) {
+				return next(c)
+			}
+
+			return c.JSON(http.StatusForbidden, dto.APIResponse{
+				Success: false,
+				Error: &dto.ErrorInfo{
+					Code:    "INSUFFICIENT_PERMISSIONS",
+					Message: "You do not have the " + permission + " permission required to access this resource.",
+				},
+			})
+		}
+	}
+}
+
 // CORSMiddleware handles CORS
 func CORSMiddleware(origins string) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -25,6 +25,8 @@ type User struct {
 	DateJoinedChurch             time.Time           `bson:"date_joined_church" json:"date_joined_church"`
 	QRCodeToken                  string              `bson:"qr_code_token" json:"qr_code_token"`
 	QRCodeImage                  string              `bson:"qr_code_image" json:"qr_code_image"`
+	QRSecret                     string              `bson:"qr_secret,omitempty" json:"-"`
+	CardVersion                  int                 `bson:"card_version,omitempty" json:"-"`
 	FamilyHead                   bool                `bson:"family_head" json:"family_head"`
 	UserCampus                   string              `bson:"user_campus" json:"user_campus"`
 	CampusState                  string              `bson:"campus_state" json:"campus_state"`
@@ -37,12 +39,25 @@ type User struct {
 	DateJoined                   time.Time           `bson:"date_joined" json:"date_joined"`
 	DateUpdated                  time.Time           `bson:"date_updated" json:"date_updated"`
 	Role                         *primitive.ObjectID `bson:"role,omitempty" json:"role"`
+	Shepherd                     string              `bson:"shepherd,omitempty" json:"shepherd,omitempty"`
+	MembershipStage              string              `bson:"membership_stage,omitempty" json:"membership_stage,omitempty"`
+	MembershipStageSince         *time.Time          `bson:"membership_stage_since,omitempty" json:"membership_stage_since,omitempty"`
 	EmergencyContactName         string              `bson:"emergency_contact_name" json:"emergency_contact_name"`
 	EmergencyContactPhone        string              `bson:"emergency_contact_phone" json:"emergency_contact_phone"`
 	EmergencyContactEmail        string              `bson:"emergency_contact_email" json:"emergency_contact_email" validate:"omitempty,email"`
 	EmergencyContactRelationship string              `bson:"emergency_contact_relationship" json:"emergency_contact_relationship"`
 	PasswordResetToken           string              `bson:"password_reset_token,omitempty" json:"-"`
 	PasswordResetExpires         time.Time           `bson:"password_reset_expires,omitempty" json:"-"`
+
+	// Two-factor authentication. The pending secret is held between enrollment and the first verified code;
+	// recovery codes are bcrypt hashes and the challenge is a SHA-256 hash of the token handed out at login.
+	TwoFactorEnabled          bool      `bson:"two_factor_enabled,omitempty" json:"two_factor_enabled"`
+	TwoFactorSecret           string    `bson:"two_factor_secret,omitempty" json:"-"`
+	TwoFactorPendingSecret    string    `bson:"two_factor_pending_secret,omitempty" json:"-"`
+	TwoFactorRecoveryCodes    []string  `bson:"two_factor_recovery_codes,omitempty" json:"-"`
+	TwoFactorLastStep         int64     `bson:"two_factor_last_step,omitempty" json:"-"`
+	TwoFactorChallenge        string    `bson:"two_factor_challenge,omitempty" json:"-"`
+	TwoFactorChallengeExpires time.Time `bson:"two_factor_challenge_expires,omitempty" json:"-"`
 }
 
 // UserResponse represents user data for API responses (without sensitive data)
@@ -74,6 +89,8 @@ type UserResponse struct {
 	DateJoined                   time.Time           `json:"date_joined"`
 	DateUpdated                  time.Time           `json:"date_updated"`
 	Role                         *primitive.ObjectID `json:"role"`
+	Shepherd                     string              `json:"shepherd,omitempty"`
+	MembershipStage              string              `json:"membership_stage"`
 	EmergencyContactName         string              `json:"emergency_contact_name"`
 	EmergencyContactPhone        string              `json:"emergency_contact_phone"`
 	EmergencyContactEmail        string              `json:"emergency_contact_email"`
@@ -82,14 +99,181 @@ type UserResponse struct {
 
 // Attendance represents the attendance model
 type Attendance struct {
-	ID                   primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	User                 primitive.ObjectID `bson:"user" json:"user" validate:"required"`
-	DateTimeOfAttendance time.Time          `bson:"date_time_of_attendance" json:"date_time_of_attendance"`
-	QRCodeBasedCheckin   bool               `bson:"qrcode_based_checkin" json:"qrcode_based_checkin"`
-	Late                 bool               `bson:"late" json:"late"`
-	ManualCheckin        bool               `bson:"manual_checkin" json:"manual_checkin"`
-	Visitor              bool               `bson:"visitor,omitempty" json:"visitor"`
-	Member               bool               `bson:"member,omitempty" json:"member"`
+	ID                   primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	User                 primitive.ObjectID  `bson:"user,omitempty" json:"user"`
+	VisitorProfile       *primitive.ObjectID `bson:"visitor_profile,omitempty" json:"visitor_profile,omitempty"`
+	FamilyMember         *primitive.ObjectID `bson:"family_member,omitempty" json:"family_member,omitempty"`
+	Household            string              `bson:"household,omitempty" json:"household,omitempty"`
+	Event                *primitive.ObjectID `bson:"event,omitempty" json:"event"`
+	DateTimeOfAttendance time.Time           `bson:"date_time_of_attendance" json:"date_time_of_attendance"`
+	QRCodeBasedCheckin   bool                `bson:"qrcode_based_checkin" json:"qrcode_based_checkin"`
+	Late                 bool                `bson:"late" json:"late"`
+	ManualCheckin        bool                `bson:"manual_checkin" json:"manual_checkin"`
+	MinutesLate          int                 `bson:"minutes_late" json:"minutes_late"`
+	CheckinMethod        string              `bson:"checkin_method,omitempty" json:"checkin_method"`
+	Venue                string              `bson:"venue,omitempty" json:"venue,omitempty"`
+	IdempotencyKey       string              `bson:"idempotency_key,omitempty" json:"idempotency_key,omitempty"`
+	DeviceID             string              `bson:"device_id,omitempty" json:"device_id,omitempty"`
+	SyncedAt             *time.Time          `bson:"synced_at,omitempty" json:"synced_at,omitempty"`
+	CheckOutTime         *time.Time          `bson:"check_out_time,omitempty" json:"check_out_time,omitempty"`
+	CheckedOutBy         string              `bson:"checked_out_by,omitempty" json:"checked_out_by,omitempty"`
+	QRCodeBasedCheckout  bool                `bson:"qrcode_based_checkout,omitempty" json:"qrcode_based_checkout"`
+	FirstVisit           bool                `bson:"first_visit,omitempty" json:"first_visit"`
+	Backdated            bool                `bson:"backdated,omitempty" json:"backdated,omitempty"`
+	RecordedBy           string              `bson:"recorded_by,omitempty" json:"recorded_by,omitempty"`
+	Voided               bool                `bson:"voided" json:"voided,omitempty"`
+	VoidedAt             *time.Time          `bson:"voided_at,omitempty" json:"voided_at,omitempty"`
+	VoidedBy             string              `bson:"voided_by,omitempty" json:"voided_by,omitempty"`
+	VoidReason           string              `bson:"void_reason,omitempty" json:"void_reason,omitempty"`
+	Visitor              bool                `bson:"visitor" json:"visitor"`
+	Member               bool                `bson:"member" json:"member"`
+	DistanceMeters       *float64            `bson:"distance_meters,omitempty" json:"distance_meters,omitempty"`
+	LocationAccuracy     *float64            `bson:"location_accuracy,omitempty" json:"location_accuracy,omitempty"`
+}
+
+// Attendance check-in methods
+const (
+	CheckinMethodManual   = "manual"
+	CheckinMethodQR       = "qr"
+	CheckinMethodVenueQR  = "venue_qr"
+	CheckinMethodGeofence = "geofence"
+)
+
+// Attendance correction actions
+const (
+	AttendanceAuditVoid     = "void"
+	AttendanceAuditAmend    = "amend"
+	AttendanceAuditBackdate = "backdate"
+)
+
+// AttendanceAudit is an immutable record of an admin correction to an attendance record, with the record as it
+// was before and after the change
+type AttendanceAudit struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Attendance  primitive.ObjectID `bson:"attendance" json:"attendance"`
+	Action      string             `bson:"action" json:"action"`
+	Reason      string             `bson:"reason" json:"reason"`
+	PerformedBy string             `bson:"performed_by" json:"performed_by"`
+	PerformedAt time.Time          `bson:"performed_at" json:"performed_at"`
+	Before      *Attendance        `bson:"before,omitempty" json:"before,omitempty"`
+	After       *Attendance        `bson:"after,omitempty" json:"after,omitempty"`
+}
+
+// Service event types
+const (
+	EventTypeSundayService  = "sunday_service"
+	EventTypeMidweekService = "midweek_service"
+	EventTypeVigil          = "vigil"
+	EventTypeSpecialProgram = "special_program"
+)
+
+// ServiceEvent represents a church service or meeting that attendance is recorded against
+type ServiceEvent struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Name        string              `bson:"name" json:"name" validate:"required,min=2,max=100"`
+	EventType   string              `bson:"event_type" json:"event_type" validate:"required,oneof=sunday_service midweek_service vigil special_program"`
+	Church      *primitive.ObjectID `bson:"church,omitempty" json:"church"`
+	StartTime   time.Time           `bson:"start_time" json:"start_time" validate:"required"`
+	EndTime     time.Time           `bson:"end_time" json:"end_time" validate:"required"`
+	Description string              `bson:"description" json:"description"`
+	CreatedBy   string              `bson:"created_by" json:"created_by"`
+	DateAdded   time.Time           `bson:"date_added" json:"date_added"`
+	DateUpdated time.Time           `bson:"date_updated" json:"date_updated"`
+}
+
+// VenueCode is a QR code displayed at a venue entrance that members scan to check themselves in to an event
+type VenueCode struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Event     primitive.ObjectID `bson:"event" json:"event"`
+	Venue     string             `bson:"venue" json:"venue"`
+	Code      string             `bson:"code" json:"code"`
+	ExpiresAt time.Time          `bson:"expires_at" json:"expires_at"`
+	CreatedBy string             `bson:"created_by" json:"created_by"`
+	DateAdded time.Time          `bson:"date_added" json:"date_added"`
+}
+
+// Follow-up task statuses
+const (
+	FollowUpStatusOpen      = "open"
+	FollowUpStatusContacted = "contacted"
+	FollowUpStatusResolved  = "resolved"
+)
+
+// Follow-up reasons
+const (
+	FollowUpReasonMissedServices = "missed_services"
+	FollowUpReasonRateDrop       = "rate_drop"
+)
+
+// FollowUp is a pastoral care task raised when a member's attendance drops, assigned to their shepherd
+type FollowUp struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	User        primitive.ObjectID `bson:"user" json:"user"`
+	UserID      string             `bson:"user_id" json:"user_id"`
+	Reason      string             `bson:"reason" json:"reason"`
+	Details     string             `bson:"details" json:"details"`
+	AssignedTo  string             `bson:"assigned_to,omitempty" json:"assigned_to,omitempty"`
+	Status      string             `bson:"status" json:"status"`
+	DueDate     time.Time          `bson:"due_date" json:"due_date"`
+	Notes       []FollowUpNote     `bson:"notes" json:"notes"`
+	ResolvedAt  *time.Time         `bson:"resolved_at,omitempty" json:"resolved_at,omitempty"`
+	DateAdded   time.Time          `bson:"date_added" json:"date_added"`
+	DateUpdated time.Time          `bson:"date_updated" json:"date_updated"`
+}
+
+// FollowUpNote is a note left on a follow-up task by whoever is handling it
+type FollowUpNote struct {
+	Text    string    `bson:"text" json:"text"`
+	AddedBy string    `bson:"added_by" json:"added_by"`
+	AddedAt time.Time `bson:"added_at" json:"added_at"`
+}
+
+// Membership pipeline stages, in the order people move through them
+const (
+	MembershipStageFirstTimeGuest   = "first_time_guest"
+	MembershipStageReturningVisitor = "returning_visitor"
+	MembershipStageMembershipClass  = "membership_class"
+	MembershipStageMember           = "member"
+	MembershipStageWorker           = "worker"
+)
+
+// MembershipStages lists the membership pipeline stages in order
+var MembershipStages = []string{
+	MembershipStageFirstTimeGuest,
+	MembershipStageReturningVisitor,
+	MembershipStageMembershipClass,
+	MembershipStageMember,
+	MembershipStageWorker,
+}
+
+// MembershipTransition records a user moving from one membership stage to another
+type MembershipTransition struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	User      primitive.ObjectID `bson:"user" json:"user"`
+	UserID    string             `bson:"user_id" json:"user_id"`
+	From      string             `bson:"from" json:"from"`
+	To        string             `bson:"to" json:"to"`
+	FromSince time.Time          `bson:"from_since" json:"from_since"`
+	Note      string             `bson:"note,omitempty" json:"note,omitempty"`
+	ChangedBy string             `bson:"changed_by" json:"changed_by"`
+	ChangedAt time.Time          `bson:"changed_at" json:"changed_at"`
+}
+
+// VisitorProfile is a lightweight record for a guest checked in without an account. Their attendance is
+// moved onto their user once they complete registration.
+type VisitorProfile struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	FirstName    string             `bson:"fname" json:"fname"`
+	LastName     string             `bson:"lname" json:"lname"`
+	PhoneNumber  string             `bson:"phone_number" json:"phone_number"`
+	Email        string             `bson:"email,omitempty" json:"email,omitempty"`
+	InvitedBy    string             `bson:"invited_by,omitempty" json:"invited_by,omitempty"`
+	RegisteredBy string             `bson:"registered_by" json:"registered_by"`
+	FirstVisit   time.Time          `bson:"first_visit" json:"first_visit"`
+	MergedInto   string             `bson:"merged_into,omitempty" json:"merged_into,omitempty"`
+	MergedAt     *time.Time         `bson:"merged_at,omitempty" json:"merged_at,omitempty"`
+	DateAdded    time.Time          `bson:"date_added" json:"date_added"`
+	DateUpdated  time.Time          `bson:"date_updated" json:"date_updated"`
 }
 
 // FamilyMember represents the family member model
@@ -107,6 +291,33 @@ type FamilyMember struct {
 	DateAdded                time.Time          `bson:"date_added" json:"date_added"`
 }
 
+// ChildCheckin is a child checked in to children's church by a guardian. The child is only released to someone
+// presenting the pickup code printed on the guardian's label, or to one of the authorized guardians.
+type ChildCheckin struct {
+	ID                  primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	FamilyMember        primitive.ObjectID  `bson:"family_member" json:"family_member"`
+	ChildName           string              `bson:"child_name" json:"child_name"`
+	Guardian            string              `bson:"guardian" json:"guardian"`
+	AuthorizedGuardians []string            `bson:"authorized_guardians" json:"authorized_guardians"`
+	Event               *primitive.ObjectID `bson:"event,omitempty" json:"event"`
+	Classroom           string              `bson:"classroom" json:"classroom"`
+	PickupCode          string              `bson:"pickup_code" json:"-"`
+	Allergies           string              `bson:"allergies,omitempty" json:"allergies,omitempty"`
+	Notes               string              `bson:"notes,omitempty" json:"notes,omitempty"`
+	CheckedInBy         string              `bson:"checked_in_by" json:"checked_in_by"`
+	CheckedInAt         time.Time           `bson:"checked_in_at" json:"checked_in_at"`
+	CheckedOutAt        *time.Time          `bson:"checked_out_at,omitempty" json:"checked_out_at,omitempty"`
+	CheckedOutBy        string              `bson:"checked_out_by,omitempty" json:"checked_out_by,omitempty"`
+	PickedUpBy          string              `bson:"picked_up_by,omitempty" json:"picked_up_by,omitempty"`
+	PickupMethod        string              `bson:"pickup_method,omitempty" json:"pickup_method,omitempty"`
+}
+
+// How a child was released at pickup
+const (
+	PickupMethodCode     = "pickup_code"
+	PickupMethodGuardian = "guardian"
+)
+
 // Sermon represents the sermon model
 type Sermon struct {
 	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
@@ -181,6 +392,9 @@ type LocalChurch struct {
 	SundayMeetingTime  int                `bson:"sunday_meeting_time" json:"sunday_meeting_time" validate:"required,min=0,max=23"`
 	MidweekMeetingDay  string             `bson:"midweek_meeting_day" json:"midweek_meeting_day" validate:"required,oneof=Monday Tuesday Wednesday Thursday Friday"`
 	MidweekMeetingTime int                `bson:"midweek_meeting_time" json:"midweek_meeting_time" validate:"required,min=0,max=23"`
+	Timezone           string             `bson:"timezone,omitempty" json:"timezone"`
+	LateGracePeriod    *int               `bson:"late_grace_period_minutes,omitempty" json:"late_grace_period_minutes" validate:"omitempty,min=0,max=180"`
+	Geofence           *Geofence          `bson:"geofence,omitempty" json:"geofence,omitempty"`
 	Website            string             `bson:"website" json:"website" validate:"omitempty,url"`
 	SocialMedia        string             `bson:"social_media" json:"social_media"`
 	PastorName         string             `bson:"pastor_name" json:"pastor_name" validate:"required,min=2,max=100"`
@@ -192,6 +406,13 @@ type LocalChurch struct {
 	DateUpdated        time.Time          `bson:"date_updated" json:"date_updated"`
 }
 
+// Geofence is the area around a church members must be inside to check themselves in from the mobile app
+type Geofence struct {
+	Latitude     float64 `bson:"latitude" json:"latitude"`
+	Longitude    float64 `bson:"longitude" json:"longitude"`
+	RadiusMeters int     `bson:"radius_meters" json:"radius_meters"`
+}
+
 // Notification represents the notification model
 type Notification struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
@@ -219,12 +440,33 @@ type UserNotification struct {
 }
 
 // RefreshToken represents a refresh token
+// RefreshToken is one link in a login session's chain of refresh tokens. Every refresh rotates the token;
+// the old one is kept, marked rotated, until it expires so that replaying it can be caught. All tokens of a
+// session share its FamilyID.
 type RefreshToken struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
-	Token     string             `bson:"token" json:"token"`
-	ExpiresAt time.Time          `bson:"expires_at" json:"expires_at"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID           primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Token            string             `bson:"token" json:"token"`
+	FamilyID         primitive.ObjectID `bson:"family_id" json:"family_id"`
+	DeviceName       string             `bson:"device_name,omitempty" json:"device_name"`
+	UserAgent        string             `bson:"user_agent,omitempty" json:"user_agent"`
+	IPAddress        string             `bson:"ip_address,omitempty" json:"ip_address"`
+	SessionCreatedAt time.Time          `bson:"session_created_at" json:"session_created_at"`
+	LastUsedAt       time.Time          `bson:"last_used_at" json:"last_used_at"`
+	RotatedAt        *time.Time         `bson:"rotated_at,omitempty" json:"rotated_at,omitempty"`
+	ExpiresAt        time.Time          `bson:"expires_at" json:"expires_at"`
+	CreatedAt        time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// LoginAttempt counts failed logins for one account or one IP address. Records expire on their own once the
+// counting window and any lockout are over.
+type LoginAttempt struct {
+	Key           string     `bson:"_id" json:"key"`
+	Failures      int        `bson:"failures" json:"failures"`
+	LastFailureAt time.Time  `bson:"last_failure_at" json:"last_failure_at"`
+	NextAttemptAt time.Time  `bson:"next_attempt_at" json:"next_attempt_at"`
+	LockedUntil   *time.Time `bson:"locked_until,omitempty" json:"locked_until,omitempty"`
+	ExpiresAt     time.Time  `bson:"expires_at" json:"expires_at"`
 }
 
 // Pagination represents pagination information
@@ -235,6 +477,58 @@ type Pagination struct {
 	TotalPages int `json:"total_pages"`
 }
 
+// Permissions a role can grant, named resource:action. Admins have every permission.
+const (
+	PermissionUsersRead           = "users:read"
+	PermissionUsersManage         = "users:manage"
+	PermissionRolesManage         = "roles:manage"
+	PermissionAttendanceWrite     = "attendance:write"
+	PermissionAttendanceRead      = "attendance:read"
+	PermissionAttendanceCorrect   = "attendance:correct"
+	PermissionAnalyticsRead       = "analytics:read"
+	PermissionEventsManage        = "events:manage"
+	PermissionSermonsManage       = "sermons:manage"
+	PermissionAnnouncementsManage = "announcements:manage"
+	PermissionChurchesManage      = "churches:manage"
+	PermissionFollowUpsManage     = "follow-ups:manage"
+	PermissionMembershipManage    = "membership:manage"
+	PermissionChildrenManage      = "children:manage"
+)
+
+// PermissionInfo describes a permission in the catalogue
+type PermissionInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// PermissionCatalogue is every permission a role can grant
+var PermissionCatalogue = []PermissionInfo{
+	{PermissionUsersRead, "List, search and filter members"},
+	{PermissionUsersManage, "Assign shepherds and print other members' cards"},
+	{PermissionRolesManage, "Create, change and assign roles"},
+	{PermissionAttendanceWrite, "Check members, visitors and other households in and out"},
+	{PermissionAttendanceRead, "View attendance history and other members' attendance"},
+	{PermissionAttendanceCorrect, "Void, amend and back-date attendance and view its audit trail"},
+	{PermissionAnalyticsRead, "View, export and stream attendance reports"},
+	{PermissionEventsManage, "Create, change and delete service events and venue codes"},
+	{PermissionSermonsManage, "Create, change and delete sermons"},
+	{PermissionAnnouncementsManage, "Create, change and delete announcements"},
+	{PermissionChurchesManage, "Create, change and delete local churches"},
+	{PermissionFollowUpsManage, "See and work every follow-up and run the absentee scan"},
+	{PermissionMembershipManage, "Move members through the membership pipeline"},
+	{PermissionChildrenManage, "Check children out and watch classroom rosters and pickup alerts"},
+}
+
+// IsValidPermission reports whether name is in the permission catalogue
+func IsValidPermission(name string) bool {
+	for _, permission := range PermissionCatalogue {
+		if permission.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 type Permissions struct {
 	CanViewDashboard string `json:"can_view_dashboard"`
 	CanCreateUser    string `json:"can_create_user"`
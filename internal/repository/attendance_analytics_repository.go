@@ -0,0 +1,523 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cci-api/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// AttendanceTotals summarises attendance over a period, or over one bucket of a trend
+type AttendanceTotals struct {
+	Period      string `bson:"_id"`
+	Total       int    `bson:"total"`
+	Members     int    `bson:"members"`
+	Visitors    int    `bson:"visitors"`
+	Unique      int    `bson:"unique"`
+	FirstTimers int    `bson:"-"`
+}
+
+// BreakdownBucket counts attendance, and the distinct people behind it, for one value of a breakdown
+type BreakdownBucket struct {
+	Key        string `bson:"_id"`
+	Attendance int    `bson:"attendance"`
+	Unique     int    `bson:"unique"`
+}
+
+// AttendanceBreakdown splits attendance by attendee demographics and by how they checked in
+type AttendanceBreakdown struct {
+	Gender        []BreakdownBucket `bson:"gender"`
+	AgeBand       []BreakdownBucket `bson:"age_band"`
+	Campus        []BreakdownBucket `bson:"campus"`
+	Department    []BreakdownBucket `bson:"department"`
+	CheckinMethod []BreakdownBucket `bson:"checkin_method"`
+}
+
+// RetentionWindow reports how many first-timers came back at least once N weeks or more after their first visit.
+// Only first-timers whose first visit was at least N weeks ago are eligible.
+type RetentionWindow struct {
+	Weeks    int
+	Eligible int
+	Retained int
+}
+
+// personExpr identifies who an attendance record belongs to: the user, the visitor profile for guests checked
+// in without an account, or the family member for dependents checked in with their household
+func personExpr() bson.D {
+	return coalesce("$user", "$visitor_profile", "$family_member")
+}
+
+// coalesce is the first of the expressions that is not null or missing. $ifNull only takes more than two
+// expressions from MongoDB 5.0, so they are nested instead.
+func coalesce(first, second interface{}, rest ...interface{}) bson.D {
+	if len(rest) > 0 {
+		second = coalesce(second, rest[0], rest[1:]...)
+	}
+	return bson.D{{Key: "$ifNull", Value: bson.A{first, second}}}
+}
+
+// checkinMethodExpr returns how a record was checked in. Records from before check-in methods were saved only
+// have the QR flag.
+func checkinMethodExpr() bson.D {
+	return bson.D{{Key: "$ifNull", Value: bson.A{
+		"$checkin_method",
+		bson.D{{Key: "$cond", Value: bson.A{"$qrcode_based_checkin", models.CheckinMethodQR, models.CheckinMethodManual}}},
+	}}}
+}
+
+// periodExpr labels the period a date falls in: a day (2006-01-02), an ISO week (2006-W01) or a month (2006-01)
+func periodExpr(field, format string, loc *time.Location) bson.D {
+	return bson.D{{Key: "$dateToString", Value: bson.D{
+		{Key: "format", Value: format},
+		{Key: "date", Value: field},
+		{Key: "timezone", Value: loc.String()},
+	}}}
+}
+
+// GetAttendanceTrend buckets attendance at events between start and end into periods using a $dateToString
+// format in the given timezone, returning one entry per period that had attendance. A nil events list counts
+// every event.
+func (r *AttendanceRepository) GetAttendanceTrend(ctx context.Context, events []primitive.ObjectID, start, end time.Time, format string, loc *time.Location) ([]AttendanceTotals, error) {
+	return r.attendanceTotals(ctx, events, start, end, func(field string) interface{} {
+		return periodExpr(field, format, loc)
+	})
+}
+
+// GetAttendanceTotals summarises attendance at events between start and end as a single period
+func (r *AttendanceRepository) GetAttendanceTotals(ctx context.Context, events []primitive.ObjectID, start, end time.Time) (*AttendanceTotals, error) {
+	totals, err := r.attendanceTotals(ctx, events, start, end, func(string) interface{} { return nil })
+	if err != nil {
+		return nil, err
+	}
+	if len(totals) == 0 {
+		return &AttendanceTotals{}, nil
+	}
+	return &totals[0], nil
+}
+
+// attendanceTotals counts attendance, members, visitors, distinct people and first-timers grouped by key. A
+// first-timer is someone whose first attendance ever falls in the period, so the whole history before end is
+// read to find first visits.
+func (r *AttendanceRepository) attendanceTotals(ctx context.Context, events []primitive.ObjectID, start, end time.Time, key func(field string) interface{}) ([]AttendanceTotals, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: atEvents(bson.D{
+			notVoided,
+			{Key: "date_time_of_attendance", Value: bson.D{{Key: "$lt", Value: end}}},
+		}, events)}},
+		{{Key: "$facet", Value: bson.D{
+			{Key: "totals", Value: bson.A{
+				bson.D{{Key: "$match", Value: bson.D{
+					{Key: "date_time_of_attendance", Value: bson.D{{Key: "$gte", Value: start}}},
+				}}},
+				bson.D{{Key: "$lookup", Value: bson.D{
+					{Key: "from", Value: "users"},
+					{Key: "localField", Value: "user"},
+					{Key: "foreignField", Value: "_id"},
+					{Key: "as", Value: "user_info"},
+				}}},
+				bson.D{{Key: "$unwind", Value: bson.D{
+					{Key: "path", Value: "$user_info"},
+					{Key: "preserveNullAndEmptyArrays", Value: true},
+				}}},
+				bson.D{{Key: "$group", Value: bson.D{
+					{Key: "_id", Value: key("$date_time_of_attendance")},
+					{Key: "total", Value: bson.D{{Key: "$sum", Value: 1}}},
+					{Key: "members", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{
+						bson.D{{Key: "$eq", Value: bson.A{memberExpr(), true}}}, 1, 0,
+					}}}}}},
+					{Key: "visitors", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{
+						bson.D{{Key: "$eq", Value: bson.A{visitorExpr(), true}}}, 1, 0,
+					}}}}}},
+					{Key: "people", Value: bson.D{{Key: "$addToSet", Value: personExpr()}}},
+				}}},
+				bson.D{{Key: "$project", Value: bson.D{
+					{Key: "total", Value: 1},
+					{Key: "members", Value: 1},
+					{Key: "visitors", Value: 1},
+					{Key: "unique", Value: bson.D{{Key: "$size", Value: "$people"}}},
+				}}},
+			}},
+			{Key: "first_timers", Value: bson.A{
+				bson.D{{Key: "$group", Value: bson.D{
+					{Key: "_id", Value: personExpr()},
+					{Key: "first", Value: bson.D{{Key: "$min", Value: "$date_time_of_attendance"}}},
+				}}},
+				bson.D{{Key: "$match", Value: bson.D{
+					{Key: "_id", Value: bson.D{{Key: "$ne", Value: nil}}},
+					{Key: "first", Value: bson.D{{Key: "$gte", Value: start}}},
+				}}},
+				bson.D{{Key: "$group", Value: bson.D{
+					{Key: "_id", Value: key("$first")},
+					{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+				}}},
+			}},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Totals      []AttendanceTotals `bson:"totals"`
+		FirstTimers []struct {
+			Period string `bson:"_id"`
+			Count  int    `bson:"count"`
+		} `bson:"first_timers"`
+	}
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+
+	totals := results[0].Totals
+	for _, firstTimers := range results[0].FirstTimers {
+		for i := range totals {
+			if totals[i].Period == firstTimers.Period {
+				totals[i].FirstTimers = firstTimers.Count
+				break
+			}
+		}
+	}
+
+	return totals, nil
+}
+
+// GetAttendanceBreakdown splits attendance between start and end by gender, age band, campus, work department
+// and check-in method. Ages are worked out as at asOf.
+func (r *AttendanceRepository) GetAttendanceBreakdown(ctx context.Context, events []primitive.ObjectID, start, end, asOf time.Time) (*AttendanceBreakdown, error) {
+	const msPerYear = 365.25 * 24 * 60 * 60 * 1000
+	// Dates of birth before 1900 are unset zero dates
+	earliestBirth := time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	ageBand := bson.D{{Key: "$switch", Value: bson.D{
+		{Key: "branches", Value: bson.A{
+			bson.D{{Key: "case", Value: bson.D{{Key: "$eq", Value: bson.A{"$age", nil}}}}, {Key: "then", Value: ""}},
+			bson.D{{Key: "case", Value: bson.D{{Key: "$lt", Value: bson.A{"$age", 18}}}}, {Key: "then", Value: "under_18"}},
+			bson.D{{Key: "case", Value: bson.D{{Key: "$lt", Value: bson.A{"$age", 25}}}}, {Key: "then", Value: "18-24"}},
+			bson.D{{Key: "case", Value: bson.D{{Key: "$lt", Value: bson.A{"$age", 35}}}}, {Key: "then", Value: "25-34"}},
+			bson.D{{Key: "case", Value: bson.D{{Key: "$lt", Value: bson.A{"$age", 45}}}}, {Key: "then", Value: "35-44"}},
+			bson.D{{Key: "case", Value: bson.D{{Key: "$lt", Value: bson.A{"$age", 55}}}}, {Key: "then", Value: "45-54"}},
+			bson.D{{Key: "case", Value: bson.D{{Key: "$lt", Value: bson.A{"$age", 65}}}}, {Key: "then", Value: "55-64"}},
+		}},
+		{Key: "default", Value: "65+"},
+	}}}
+
+	breakdownBy := func(key interface{}) bson.A {
+		return bson.A{
+			bson.D{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: key},
+				{Key: "attendance", Value: bson.D{{Key: "$sum", Value: 1}}},
+				{Key: "people", Value: bson.D{{Key: "$addToSet", Value: "$person"}}},
+			}}},
+			bson.D{{Key: "$project", Value: bson.D{
+				{Key: "attendance", Value: 1},
+				{Key: "unique", Value: bson.D{{Key: "$size", Value: "$people"}}},
+			}}},
+			bson.D{{Key: "$sort", Value: bson.D{{Key: "attendance", Value: -1}, {Key: "_id", Value: 1}}}},
+		}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: atEvents(bson.D{
+			notVoided,
+			{Key: "date_time_of_attendance", Value: bson.D{
+				{Key: "$gte", Value: start},
+				{Key: "$lt", Value: end},
+			}},
+		}, events)}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "users"},
+			{Key: "localField", Value: "user"},
+			{Key: "foreignField", Value: "_id"},
+			{Key: "as", Value: "user_info"},
+		}}},
+		{{Key: "$unwind", Value: bson.D{
+			{Key: "path", Value: "$user_info"},
+			{Key: "preserveNullAndEmptyArrays", Value: true},
+		}}},
+		{{Key: "$addFields", Value: bson.D{
+			{Key: "person", Value: personExpr()},
+			{Key: "age", Value: bson.D{{Key: "$cond", Value: bson.A{
+				bson.D{{Key: "$gt", Value: bson.A{"$user_info.date_of_birth", earliestBirth}}},
+				bson.D{{Key: "$floor", Value: bson.D{{Key: "$divide", Value: bson.A{
+					bson.D{{Key: "$subtract", Value: bson.A{asOf, "$user_info.date_of_birth"}}},
+					msPerYear,
+				}}}}},
+				nil,
+			}}}},
+		}}},
+		{{Key: "$facet", Value: bson.D{
+			{Key: "gender", Value: breakdownBy("$user_info.gender")},
+			{Key: "age_band", Value: breakdownBy(ageBand)},
+			{Key: "campus", Value: breakdownBy("$user_info.user_campus")},
+			{Key: "department", Value: breakdownBy("$user_info.user_work_department")},
+			{Key: "checkin_method", Value: breakdownBy(checkinMethodExpr())},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []AttendanceBreakdown
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return &AttendanceBreakdown{}, nil
+	}
+
+	return &results[0], nil
+}
+
+// GetFirstTimerRetention finds everyone whose first attendance ever was between start and end, and for each
+// number of weeks reports how many of them came back at least once that many weeks or more after their first
+// visit. It returns the number of first-timers and one window per entry in weeks.
+func (r *AttendanceRepository) GetFirstTimerRetention(ctx context.Context, events []primitive.ObjectID, start, end, now time.Time, weeks []int) (int, []RetentionWindow, error) {
+	const msPerWeek = 7 * 24 * 60 * 60 * 1000
+
+	summary := bson.D{
+		{Key: "_id", Value: nil},
+		{Key: "first_timers", Value: bson.D{{Key: "$sum", Value: 1}}},
+	}
+	for _, n := range weeks {
+		returnBy := bson.D{{Key: "$add", Value: bson.A{"$first", n * msPerWeek}}}
+		eligible := bson.D{{Key: "$lte", Value: bson.A{returnBy, now}}}
+		summary = append(summary,
+			bson.E{Key: fmt.Sprintf("eligible_%d", n), Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{eligible, 1, 0}}}}}},
+			bson.E{Key: fmt.Sprintf("retained_%d", n), Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{
+				bson.D{{Key: "$and", Value: bson.A{
+					eligible,
+					bson.D{{Key: "$gte", Value: bson.A{"$last", returnBy}}},
+				}}},
+				1, 0,
+			}}}}}},
+		)
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: atEvents(bson.D{notVoided}, events)}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: personExpr()},
+			{Key: "first", Value: bson.D{{Key: "$min", Value: "$date_time_of_attendance"}}},
+			{Key: "last", Value: bson.D{{Key: "$max", Value: "$date_time_of_attendance"}}},
+		}}},
+		{{Key: "$match", Value: bson.D{
+			{Key: "_id", Value: bson.D{{Key: "$ne", Value: nil}}},
+			{Key: "first", Value: bson.D{
+				{Key: "$gte", Value: start},
+				{Key: "$lt", Value: end},
+			}},
+		}}},
+		{{Key: "$group", Value: summary}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []bson.M
+	if err = cursor.All(ctx, &results); err != nil {
+		return 0, nil, err
+	}
+
+	windows := make([]RetentionWindow, 0, len(weeks))
+	if len(results) == 0 {
+		for _, n := range weeks {
+			windows = append(windows, RetentionWindow{Weeks: n})
+		}
+		return 0, windows, nil
+	}
+
+	firstTimers, _ := toInt(results[0]["first_timers"])
+	for _, n := range weeks {
+		eligible, _ := toInt(results[0][fmt.Sprintf("eligible_%d", n)])
+		retained, _ := toInt(results[0][fmt.Sprintf("retained_%d", n)])
+		windows = append(windows, RetentionWindow{Weeks: n, Eligible: eligible, Retained: retained})
+	}
+
+	return firstTimers, windows, nil
+}
+
+// CountMembersForMonth counts the distinct users checked in as members at least once in the calendar month
+// containing date, in the given timezone. Like utils.DayBounds, the month is read from date as given.
+func (r *AttendanceRepository) CountMembersForMonth(ctx context.Context, events []primitive.ObjectID, date time.Time, loc *time.Location) (int, error) {
+	startOfMonth := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, loc)
+	endOfMonth := startOfMonth.AddDate(0, 1, 0)
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: atEvents(bson.D{
+			notVoided,
+			{Key: "user", Value: bson.D{{Key: "$exists", Value: true}}},
+			{Key: "date_time_of_attendance", Value: bson.D{
+				{Key: "$gte", Value: startOfMonth},
+				{Key: "$lt", Value: endOfMonth},
+			}},
+		}, events)}},
+	}
+	pipeline = append(pipeline, flagSetStages(memberExpr())...)
+	pipeline = append(pipeline,
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$user"},
+		}}},
+		bson.D{{Key: "$count", Value: "total"}},
+	)
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var result []struct {
+		Total int `bson:"total"`
+	}
+	if err = cursor.All(ctx, &result); err != nil {
+		return 0, err
+	}
+	if len(result) == 0 {
+		return 0, nil
+	}
+
+	return result[0].Total, nil
+}
+
+// CountHouseholdsForEvent returns how many households were checked in together to an event, and how many of
+// those attending were dependents without an account
+func (r *AttendanceRepository) CountHouseholdsForEvent(ctx context.Context, eventID primitive.ObjectID) (households, dependents int, err error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{
+			notVoided,
+			{Key: "event", Value: eventID},
+			{Key: "household", Value: bson.D{{Key: "$exists", Value: true}}},
+		}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: nil},
+			{Key: "households", Value: bson.D{{Key: "$addToSet", Value: "$household"}}},
+			{Key: "dependents", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{
+				bson.D{{Key: "$ifNull", Value: bson.A{"$family_member", false}}}, 1, 0,
+			}}}}}},
+		}}},
+		{{Key: "$project", Value: bson.D{
+			{Key: "households", Value: bson.D{{Key: "$size", Value: "$households"}}},
+			{Key: "dependents", Value: 1},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var result []struct {
+		Households int `bson:"households"`
+		Dependents int `bson:"dependents"`
+	}
+	if err = cursor.All(ctx, &result); err != nil {
+		return 0, 0, err
+	}
+	if len(result) == 0 {
+		return 0, 0, nil
+	}
+	return result[0].Households, result[0].Dependents, nil
+}
+
+// HouseholdAttendance summarises the services a household attended together between two dates
+type HouseholdAttendance struct {
+	FamilyHead       string    `bson:"_id"`
+	FirstName        string    `bson:"fname"`
+	LastName         string    `bson:"lname"`
+	ServicesAttended int       `bson:"services_attended"`
+	Attendance       int       `bson:"attendance"`
+	People           int       `bson:"people"`
+	AveragePartySize float64   `bson:"average_party_size"`
+	LastAttended     time.Time `bson:"last_attended"`
+}
+
+// GetHouseholdAttendance returns one page of households checked in together between start and end, most
+// services attended first
+func (r *AttendanceRepository) GetHouseholdAttendance(ctx context.Context, events []primitive.ObjectID, start, end time.Time, page, limit int) ([]HouseholdAttendance, int, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: atEvents(bson.D{
+			notVoided,
+			{Key: "household", Value: bson.D{{Key: "$exists", Value: true}}},
+			{Key: "date_time_of_attendance", Value: bson.D{
+				{Key: "$gte", Value: start},
+				{Key: "$lt", Value: end},
+			}},
+		}, events)}},
+		// One party per household per service
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{{Key: "household", Value: "$household"}, {Key: "event", Value: "$event"}}},
+			{Key: "party", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "people", Value: bson.D{{Key: "$addToSet", Value: personExpr()}}},
+			{Key: "attended_at", Value: bson.D{{Key: "$max", Value: "$date_time_of_attendance"}}},
+		}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$_id.household"},
+			{Key: "services_attended", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "attendance", Value: bson.D{{Key: "$sum", Value: "$party"}}},
+			{Key: "average_party_size", Value: bson.D{{Key: "$avg", Value: "$party"}}},
+			{Key: "people", Value: bson.D{{Key: "$push", Value: "$people"}}},
+			{Key: "last_attended", Value: bson.D{{Key: "$max", Value: "$attended_at"}}},
+		}}},
+		{{Key: "$addFields", Value: bson.D{
+			{Key: "people", Value: bson.D{{Key: "$size", Value: bson.D{{Key: "$reduce", Value: bson.D{
+				{Key: "input", Value: "$people"},
+				{Key: "initialValue", Value: bson.A{}},
+				{Key: "in", Value: bson.D{{Key: "$setUnion", Value: bson.A{"$$value", "$$this"}}}},
+			}}}}}},
+		}}},
+	}
+	pipeline = append(pipeline, pageStages("services_attended", false, page, limit,
+		bson.D{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "users"},
+			{Key: "localField", Value: "_id"},
+			{Key: "foreignField", Value: "user_id"},
+			{Key: "as", Value: "user_info"},
+		}}},
+		bson.D{{Key: "$unwind", Value: bson.D{
+			{Key: "path", Value: "$user_info"},
+			{Key: "preserveNullAndEmptyArrays", Value: true},
+		}}},
+		bson.D{{Key: "$addFields", Value: bson.D{
+			{Key: "fname", Value: "$user_info.fname"},
+			{Key: "lname", Value: "$user_info.lname"},
+		}}},
+		bson.D{{Key: "$project", Value: bson.D{{Key: "user_info", Value: 0}}}},
+	)...)
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Data  []HouseholdAttendance `bson:"data"`
+		Total pageTotal             `bson:"total"`
+	}
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, 0, err
+	}
+	if len(results) == 0 {
+		return nil, 0, nil
+	}
+
+	return results[0].Data, results[0].Total.count(), nil
+}
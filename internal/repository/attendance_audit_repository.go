@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"cci-api/internal/database"
+	"cci-api/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AttendanceAuditRepository stores the audit trail of attendance corrections. Entries are only ever inserted,
+// never updated or deleted.
+type AttendanceAuditRepository struct {
+	db         *database.Database
+	collection *mongo.Collection
+}
+
+func NewAttendanceAuditRepository(db *database.Database) *AttendanceAuditRepository {
+	return &AttendanceAuditRepository{
+		db:         db,
+		collection: db.Collection("attendance_audit"),
+	}
+}
+
+func (r *AttendanceAuditRepository) Create(ctx context.Context, audit *models.AttendanceAudit) error {
+	audit.PerformedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, audit)
+	if err != nil {
+		return err
+	}
+
+	audit.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// GetByAttendance returns the corrections made to an attendance record, oldest first
+func (r *AttendanceAuditRepository) GetByAttendance(ctx context.Context, attendanceID primitive.ObjectID) ([]*models.AttendanceAudit, error) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "performed_at", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"attendance": attendanceID}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var audits []*models.AttendanceAudit
+	if err = cursor.All(ctx, &audits); err != nil {
+		return nil, err
+	}
+
+	return audits, nil
+}
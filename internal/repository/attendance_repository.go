@@ -3,10 +3,12 @@ package repository
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
 
 	"cci-api/internal/database"
 	"cci-api/internal/models"
+	"cci-api/internal/utils"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -14,6 +16,36 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// notVoided matches attendance records that have not been voided by an admin correction
+var notVoided = bson.E{Key: "voided", Value: bson.D{{Key: "$ne", Value: true}}}
+
+// atEvents narrows a $match to attendance at the given events. A nil list leaves it unchanged, so reports that
+// are not limited to one church read every record.
+func atEvents(match bson.D, events []primitive.ObjectID) bson.D {
+	if events == nil {
+		return match
+	}
+	return append(match, bson.E{Key: "event", Value: bson.D{{Key: "$in", Value: events}}})
+}
+
+// ErrDuplicateIdempotencyKey is returned by Create when a record with the same idempotency key already exists
+var ErrDuplicateIdempotencyKey = errors.New("duplicate idempotency key")
+
+// ErrDuplicateAttendance is returned by Create and Amend when the person already has attendance for the event
+var ErrDuplicateAttendance = errors.New("duplicate attendance for event")
+
+// duplicateAttendanceError tells a repeated idempotency key apart from a second record for the same person and
+// event, both of which are rejected by unique indexes
+func duplicateAttendanceError(err error) error {
+	if !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	if strings.Contains(err.Error(), "idempotency_key") {
+		return ErrDuplicateIdempotencyKey
+	}
+	return ErrDuplicateAttendance
+}
+
 type AttendanceRepository struct {
 	db         *database.Database
 	collection *mongo.Collection
@@ -26,12 +58,16 @@ func NewAttendanceRepository(db *database.Database) *AttendanceRepository {
 	}
 }
 
+// Create inserts an attendance record. The attendance time defaults to now but is kept when the caller
+// supplies one, e.g. for check-ins captured offline.
 func (r *AttendanceRepository) Create(ctx context.Context, attendance *models.Attendance) error {
-	attendance.DateTimeOfAttendance = time.Now()
+	if attendance.DateTimeOfAttendance.IsZero() {
+		attendance.DateTimeOfAttendance = time.Now()
+	}
 
 	result, err := r.collection.InsertOne(ctx, attendance)
 	if err != nil {
-		return err
+		return duplicateAttendanceError(err)
 	}
 
 	attendance.ID = result.InsertedID.(primitive.ObjectID)
@@ -50,18 +86,24 @@ func (r *AttendanceRepository) GetByID(ctx context.Context, id primitive.ObjectI
 	return &attendance, nil
 }
 
-func (r *AttendanceRepository) GetByUserAndDate(ctx context.Context, userID primitive.ObjectID, date time.Time) (*models.Attendance, error) {
-	// Create date range for the day
-	startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
-	endOfDay := startOfDay.Add(24 * time.Hour)
+func (r *AttendanceRepository) GetByIdempotencyKey(ctx context.Context, key string) (*models.Attendance, error) {
+	var attendance models.Attendance
+	err := r.collection.FindOne(ctx, bson.M{"idempotency_key": key}).Decode(&attendance)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &attendance, nil
+}
 
+func (r *AttendanceRepository) GetByUserAndEvent(ctx context.Context, userID, eventID primitive.ObjectID) (*models.Attendance, error) {
 	var attendance models.Attendance
 	filter := bson.M{
-		"user": userID,
-		"date_time_of_attendance": bson.M{
-			"$gte": startOfDay,
-			"$lt":  endOfDay,
-		},
+		"user":   userID,
+		"event":  eventID,
+		"voided": bson.M{"$ne": true},
 	}
 
 	err := r.collection.FindOne(ctx, filter).Decode(&attendance)
@@ -74,68 +116,167 @@ func (r *AttendanceRepository) GetByUserAndDate(ctx context.Context, userID prim
 	return &attendance, nil
 }
 
-func (r *AttendanceRepository) GetHistory(ctx context.Context, startDate, endDate *time.Time, page, limit int) ([]*models.Attendance, int, error) {
-	offset := (page - 1) * limit
+// GetByVisitorAndEvent returns a visitor profile's attendance for an event
+func (r *AttendanceRepository) GetByVisitorAndEvent(ctx context.Context, visitorID, eventID primitive.ObjectID) (*models.Attendance, error) {
+	var attendance models.Attendance
+	filter := bson.M{
+		"visitor_profile": visitorID,
+		"event":           eventID,
+		"voided":          bson.M{"$ne": true},
+	}
 
-	filter := bson.M{}
-	if startDate != nil && endDate != nil {
-		filter["date_time_of_attendance"] = bson.M{
-			"$gte": *startDate,
-			"$lte": *endDate,
+	err := r.collection.FindOne(ctx, filter).Decode(&attendance)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
 		}
+		return nil, err
 	}
+	return &attendance, nil
+}
 
-	// Count total documents
-	total, err := r.collection.CountDocuments(ctx, filter)
+// GetByFamilyMemberAndEvent returns a dependent's attendance for an event, if they have been checked in to it
+func (r *AttendanceRepository) GetByFamilyMemberAndEvent(ctx context.Context, familyMemberID, eventID primitive.ObjectID) (*models.Attendance, error) {
+	var attendance models.Attendance
+	filter := bson.M{
+		"family_member": familyMemberID,
+		"event":         eventID,
+		"voided":        bson.M{"$ne": true},
+	}
+
+	err := r.collection.FindOne(ctx, filter).Decode(&attendance)
 	if err != nil {
-		return nil, 0, err
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
 	}
+	return &attendance, nil
+}
 
-	// Find documents
-	findOptions := options.Find().
-		SetSkip(int64(offset)).
-		SetLimit(int64(limit)).
-		SetSort(bson.D{{Key: "date_time_of_attendance", Value: -1}})
+// AssignVisitorToUser attaches a visitor profile's attendance records to the account the visitor registered,
+// returning how many records were moved. Records for events the account already attended stay with the profile,
+// as a person can only be checked in to an event once.
+func (r *AttendanceRepository) AssignVisitorToUser(ctx context.Context, visitorID, userID primitive.ObjectID) (int, error) {
+	attended, err := r.collection.Distinct(ctx, "event", bson.M{
+		"user":   userID,
+		"voided": bson.M{"$ne": true},
+	})
+	if err != nil {
+		return 0, err
+	}
 
-	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	filter := bson.M{
+		"visitor_profile": visitorID,
+		"user":            bson.M{"$exists": false},
+		"event":           bson.M{"$nin": attended},
+	}
+	update := bson.M{"$set": bson.M{"user": userID}}
+
+	result, err := r.collection.UpdateMany(ctx, filter, update)
 	if err != nil {
-		return nil, 0, err
+		return 0, err
 	}
-	defer cursor.Close(ctx)
+	return int(result.ModifiedCount), nil
+}
 
-	var attendances []*models.Attendance
-	if err = cursor.All(ctx, &attendances); err != nil {
-		return nil, 0, err
+// GetLatestOpenForUser returns the user's most recent attendance record that has not been checked out
+func (r *AttendanceRepository) GetLatestOpenForUser(ctx context.Context, userID primitive.ObjectID) (*models.Attendance, error) {
+	var attendance models.Attendance
+	filter := bson.M{
+		"user":           userID,
+		"check_out_time": bson.M{"$exists": false},
+		"voided":         bson.M{"$ne": true},
 	}
+	findOptions := options.FindOne().SetSort(bson.D{{Key: "date_time_of_attendance", Value: -1}})
 
-	return attendances, int(total), nil
+	err := r.collection.FindOne(ctx, filter, findOptions).Decode(&attendance)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &attendance, nil
+}
+
+// CheckOut closes an open attendance record. It reports false if the record was already checked out.
+func (r *AttendanceRepository) CheckOut(ctx context.Context, attendance *models.Attendance) (bool, error) {
+	filter := bson.M{
+		"_id":            attendance.ID,
+		"check_out_time": bson.M{"$exists": false},
+		"voided":         bson.M{"$ne": true},
+	}
+	update := bson.M{"$set": bson.M{
+		"check_out_time":        attendance.CheckOutTime,
+		"checked_out_by":        attendance.CheckedOutBy,
+		"qrcode_based_checkout": attendance.QRCodeBasedCheckout,
+	}}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
 }
 
-func (r *AttendanceRepository) CountTotalForDate(ctx context.Context, date time.Time) (int, error) {
-	startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
-	endOfDay := startOfDay.Add(24 * time.Hour)
+// Void marks a record as voided so it drops out of every count. It reports false if the record was already voided.
+func (r *AttendanceRepository) Void(ctx context.Context, attendance *models.Attendance) (bool, error) {
+	filter := bson.M{
+		"_id":    attendance.ID,
+		"voided": bson.M{"$ne": true},
+	}
+	update := bson.M{"$set": bson.M{
+		"voided":      true,
+		"voided_at":   attendance.VoidedAt,
+		"voided_by":   attendance.VoidedBy,
+		"void_reason": attendance.VoidReason,
+	}}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
 
+// Amend saves corrected event, lateness and visitor/member flags. It reports false if the record has been voided.
+func (r *AttendanceRepository) Amend(ctx context.Context, attendance *models.Attendance) (bool, error) {
 	filter := bson.M{
-		"date_time_of_attendance": bson.M{
-			"$gte": startOfDay,
-			"$lt":  endOfDay,
-		},
+		"_id":    attendance.ID,
+		"voided": bson.M{"$ne": true},
 	}
+	update := bson.M{"$set": bson.M{
+		"event":        attendance.Event,
+		"late":         attendance.Late,
+		"minutes_late": attendance.MinutesLate,
+		"visitor":      attendance.Visitor,
+		"member":       attendance.Member,
+	}}
 
-	total, err := r.collection.CountDocuments(ctx, filter)
-	return int(total), err
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, duplicateAttendanceError(err)
+	}
+	return result.MatchedCount > 0, nil
 }
 
-func (r *AttendanceRepository) CountMembersForDate(ctx context.Context, date time.Time) (int, error) {
-	startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
-	endOfDay := startOfDay.Add(24 * time.Hour)
+// OpenAttendance is an attendance record that was never checked out, with the attendee's name
+type OpenAttendance struct {
+	ID                   primitive.ObjectID `bson:"_id"`
+	UserID               string             `bson:"user_id"`
+	FirstName            string             `bson:"fname"`
+	LastName             string             `bson:"lname"`
+	DateTimeOfAttendance time.Time          `bson:"date_time_of_attendance"`
+}
 
+// GetOpenForEvent lists the event's attendance records that have not been checked out, earliest first
+func (r *AttendanceRepository) GetOpenForEvent(ctx context.Context, eventID primitive.ObjectID) ([]OpenAttendance, error) {
 	pipeline := mongo.Pipeline{
 		{{Key: "$match", Value: bson.D{
-			{Key: "date_time_of_attendance", Value: bson.D{
-				{Key: "$gte", Value: startOfDay},
-				{Key: "$lt", Value: endOfDay},
-			}},
+			notVoided,
+			{Key: "event", Value: eventID},
+			{Key: "check_out_time", Value: bson.D{{Key: "$exists", Value: false}}},
 		}}},
 		{{Key: "$lookup", Value: bson.D{
 			{Key: "from", Value: "users"},
@@ -143,40 +284,133 @@ func (r *AttendanceRepository) CountMembersForDate(ctx context.Context, date tim
 			{Key: "foreignField", Value: "_id"},
 			{Key: "as", Value: "user_info"},
 		}}},
+		{{Key: "$unwind", Value: bson.D{
+			{Key: "path", Value: "$user_info"},
+			{Key: "preserveNullAndEmptyArrays", Value: true},
+		}}},
+		{{Key: "$project", Value: bson.D{
+			{Key: "user_id", Value: "$user_info.user_id"},
+			{Key: "fname", Value: "$user_info.fname"},
+			{Key: "lname", Value: "$user_info.lname"},
+			{Key: "date_time_of_attendance", Value: 1},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "date_time_of_attendance", Value: 1}}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []OpenAttendance
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// GetStayForEvent returns how many of the event's attendees checked out and their average stay in minutes
+func (r *AttendanceRepository) GetStayForEvent(ctx context.Context, eventID primitive.ObjectID) (int, float64, error) {
+	pipeline := mongo.Pipeline{
 		{{Key: "$match", Value: bson.D{
-			{Key: "user_info.member", Value: true},
+			notVoided,
+			{Key: "event", Value: eventID},
+			{Key: "check_out_time", Value: bson.D{{Key: "$exists", Value: true}}},
+		}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: nil},
+			{Key: "checked_out", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "average_stay_minutes", Value: bson.D{{Key: "$avg", Value: stayMinutesExpr()}}},
 		}}},
-		{{Key: "$count", Value: "total"}},
 	}
 
 	cursor, err := r.collection.Aggregate(ctx, pipeline)
 	if err != nil {
-		return 0, err
+		return 0, 0, err
 	}
 	defer cursor.Close(ctx)
 
-	var result []bson.M
+	var result []struct {
+		CheckedOut  int     `bson:"checked_out"`
+		AverageStay float64 `bson:"average_stay_minutes"`
+	}
 	if err = cursor.All(ctx, &result); err != nil {
-		return 0, err
+		return 0, 0, err
 	}
 
 	if len(result) == 0 {
-		return 0, nil
+		return 0, 0, nil
+	}
+
+	return result[0].CheckedOut, result[0].AverageStay, nil
+}
+
+// stayMinutesExpr is the minutes between check-in and check-out, or null while a record is still open
+func stayMinutesExpr() bson.D {
+	return bson.D{{Key: "$cond", Value: bson.A{
+		bson.D{{Key: "$ifNull", Value: bson.A{"$check_out_time", false}}},
+		bson.D{{Key: "$divide", Value: bson.A{
+			bson.D{{Key: "$subtract", Value: bson.A{"$check_out_time", "$date_time_of_attendance"}}},
+			60000,
+		}}},
+		nil,
+	}}}
+}
+
+// memberExpr is the member flag stored on the record when it was checked in or amended. Older records that
+// only stored the flag when it was set fall back to the attendee's current flag.
+func memberExpr() bson.D {
+	return bson.D{{Key: "$ifNull", Value: bson.A{"$member", "$user_info.member"}}}
+}
+
+// visitorExpr is the visitor flag stored on the record, falling back like memberExpr for older records
+func visitorExpr() bson.D {
+	return bson.D{{Key: "$ifNull", Value: bson.A{"$visitor", "$user_info.visitor"}}}
+}
+
+// flagSetStages keeps the records whose member or visitor flag, read with memberExpr or visitorExpr, is set
+func flagSetStages(flag bson.D) mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "users"},
+			{Key: "localField", Value: "user"},
+			{Key: "foreignField", Value: "_id"},
+			{Key: "as", Value: "user_info"},
+		}}},
+		{{Key: "$unwind", Value: bson.D{
+			{Key: "path", Value: "$user_info"},
+			{Key: "preserveNullAndEmptyArrays", Value: true},
+		}}},
+		{{Key: "$match", Value: bson.D{
+			{Key: "$expr", Value: bson.D{{Key: "$eq", Value: bson.A{flag, true}}}},
+		}}},
 	}
+}
 
-	return int(result[0]["total"].(int32)), nil
+// notCheckedOutExpr is 1 for records that were never checked out and 0 otherwise
+func notCheckedOutExpr() bson.D {
+	return bson.D{{Key: "$cond", Value: bson.A{
+		bson.D{{Key: "$ifNull", Value: bson.A{"$check_out_time", false}}}, 0, 1,
+	}}}
 }
 
-func (r *AttendanceRepository) CountVisitorsForDate(ctx context.Context, date time.Time) (int, error) {
-	startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
-	endOfDay := startOfDay.Add(24 * time.Hour)
+// HasForEvent reports whether any attendance record, voided or not, references the event
+func (r *AttendanceRepository) HasForEvent(ctx context.Context, eventID primitive.ObjectID) (bool, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"event": eventID}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
 
+// CountForEvent returns the total, member, visitor and late attendance counts for an event
+func (r *AttendanceRepository) CountForEvent(ctx context.Context, eventID primitive.ObjectID) (total, members, visitors, late int, err error) {
 	pipeline := mongo.Pipeline{
 		{{Key: "$match", Value: bson.D{
-			{Key: "date_time_of_attendance", Value: bson.D{
-				{Key: "$gte", Value: startOfDay},
-				{Key: "$lt", Value: endOfDay},
-			}},
+			notVoided,
+			{Key: "event", Value: eventID},
 		}}},
 		{{Key: "$lookup", Value: bson.D{
 			{Key: "from", Value: "users"},
@@ -184,79 +418,853 @@ func (r *AttendanceRepository) CountVisitorsForDate(ctx context.Context, date ti
 			{Key: "foreignField", Value: "_id"},
 			{Key: "as", Value: "user_info"},
 		}}},
-		{{Key: "$match", Value: bson.D{
-			{Key: "user_info.visitor", Value: true},
+		{{Key: "$unwind", Value: bson.D{
+			{Key: "path", Value: "$user_info"},
+			{Key: "preserveNullAndEmptyArrays", Value: true},
+		}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: nil},
+			{Key: "total", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "members", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{
+				bson.D{{Key: "$eq", Value: bson.A{memberExpr(), true}}}, 1, 0,
+			}}}}}},
+			{Key: "visitors", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{
+				bson.D{{Key: "$eq", Value: bson.A{visitorExpr(), true}}}, 1, 0,
+			}}}}}},
+			{Key: "late", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{
+				bson.D{{Key: "$eq", Value: bson.A{"$late", true}}}, 1, 0,
+			}}}}}},
 		}}},
-		{{Key: "$count", Value: "total"}},
 	}
 
 	cursor, err := r.collection.Aggregate(ctx, pipeline)
 	if err != nil {
-		return 0, err
+		return 0, 0, 0, 0, err
 	}
 	defer cursor.Close(ctx)
 
-	var result []bson.M
+	var result []struct {
+		Total    int `bson:"total"`
+		Members  int `bson:"members"`
+		Visitors int `bson:"visitors"`
+		Late     int `bson:"late"`
+	}
 	if err = cursor.All(ctx, &result); err != nil {
-		return 0, err
+		return 0, 0, 0, 0, err
 	}
 
 	if len(result) == 0 {
-		return 0, nil
+		return 0, 0, 0, 0, nil
 	}
 
-	return int(result[0]["total"].(int32)), nil
+	return result[0].Total, result[0].Members, result[0].Visitors, result[0].Late, nil
 }
 
-func (r *AttendanceRepository) GetAttendanceByDateRange(ctx context.Context, startDate, endDate time.Time) ([]map[string]interface{}, error) {
+// PunctualityBucket counts check-ins that fall within a range of minutes late
+type PunctualityBucket struct {
+	Label string
+	Count int
+}
+
+// punctualityBoundaries are the lower bounds (in minutes late) of each punctuality bucket
+var punctualityBoundaries = []struct {
+	lower int
+	label string
+}{
+	{0, "on_time"},
+	{1, "1-15"},
+	{16, "16-30"},
+	{31, "31-60"},
+}
+
+// GetPunctualityForEvent buckets an event's check-ins by minutes late and returns the average minutes late
+func (r *AttendanceRepository) GetPunctualityForEvent(ctx context.Context, eventID primitive.ObjectID) ([]PunctualityBucket, float64, error) {
+	boundaries := bson.A{}
+	for _, boundary := range punctualityBoundaries {
+		boundaries = append(boundaries, boundary.lower)
+	}
+	boundaries = append(boundaries, 61)
+
 	pipeline := mongo.Pipeline{
 		{{Key: "$match", Value: bson.D{
-			{Key: "date_time_of_attendance", Value: bson.D{
-				{Key: "$gte", Value: startDate},
-				{Key: "$lte", Value: endDate},
+			notVoided,
+			{Key: "event", Value: eventID},
+			{Key: "minutes_late", Value: bson.D{{Key: "$exists", Value: true}}},
+		}}},
+		{{Key: "$facet", Value: bson.D{
+			{Key: "buckets", Value: bson.A{
+				bson.D{{Key: "$bucket", Value: bson.D{
+					{Key: "groupBy", Value: "$minutes_late"},
+					{Key: "boundaries", Value: boundaries},
+					{Key: "default", Value: "60+"},
+					{Key: "output", Value: bson.D{{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}}}},
+				}}},
+			}},
+			{Key: "average", Value: bson.A{
+				bson.D{{Key: "$group", Value: bson.D{
+					{Key: "_id", Value: nil},
+					{Key: "value", Value: bson.D{{Key: "$avg", Value: "$minutes_late"}}},
+				}}},
 			}},
 		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var result []struct {
+		Buckets []struct {
+			ID    interface{} `bson:"_id"`
+			Count int         `bson:"count"`
+		} `bson:"buckets"`
+		Average []struct {
+			Value float64 `bson:"value"`
+		} `bson:"average"`
+	}
+	if err = cursor.All(ctx, &result); err != nil {
+		return nil, 0, err
+	}
+
+	counts := make(map[string]int)
+	var average float64
+	if len(result) > 0 {
+		for _, bucket := range result[0].Buckets {
+			label := "60+"
+			for _, boundary := range punctualityBoundaries {
+				if lower, ok := toInt(bucket.ID); ok && lower == boundary.lower {
+					label = boundary.label
+				}
+			}
+			counts[label] += bucket.Count
+		}
+		if len(result[0].Average) > 0 {
+			average = result[0].Average[0].Value
+		}
+	}
+
+	buckets := make([]PunctualityBucket, 0, len(punctualityBoundaries)+1)
+	for _, boundary := range punctualityBoundaries {
+		buckets = append(buckets, PunctualityBucket{Label: boundary.label, Count: counts[boundary.label]})
+	}
+	buckets = append(buckets, PunctualityBucket{Label: "60+", Count: counts["60+"]})
+
+	return buckets, average, nil
+}
+
+// AttendanceHistoryFilter selects the attendance history reports cover: a [StartDate, EndDate) range and,
+// optionally, one event, the attendee's campus and how they checked in
+type AttendanceHistoryFilter struct {
+	StartDate     time.Time
+	EndDate       time.Time
+	EventID       *primitive.ObjectID
+	Campus        string
+	CheckinMethod string
+	// Events limits the history to one church's events; nil includes every church
+	Events []primitive.ObjectID
+}
+
+// historyMatchStages matches the records a history filter selects, with each record's user joined as user_info
+func historyMatchStages(filter AttendanceHistoryFilter) mongo.Pipeline {
+	match := bson.D{
+		notVoided,
+		{Key: "date_time_of_attendance", Value: bson.D{
+			{Key: "$gte", Value: filter.StartDate},
+			{Key: "$lt", Value: filter.EndDate},
+		}},
+	}
+	if filter.EventID != nil {
+		match = append(match, bson.E{Key: "event", Value: *filter.EventID})
+	}
+	if filter.CheckinMethod != "" {
+		match = append(match, bson.E{Key: "$expr", Value: bson.D{{Key: "$eq", Value: bson.A{checkinMethodExpr(), filter.CheckinMethod}}}})
+	}
+
+	stages := mongo.Pipeline{
+		{{Key: "$match", Value: atEvents(match, filter.Events)}},
 		{{Key: "$lookup", Value: bson.D{
 			{Key: "from", Value: "users"},
 			{Key: "localField", Value: "user"},
 			{Key: "foreignField", Value: "_id"},
 			{Key: "as", Value: "user_info"},
 		}}},
-		{{Key: "$group", Value: bson.D{
-			{Key: "_id", Value: bson.D{
-				{Key: "year", Value: bson.D{{Key: "$year", Value: "$date_time_of_attendance"}}},
-				{Key: "month", Value: bson.D{{Key: "$month", Value: "$date_time_of_attendance"}}},
-				{Key: "day", Value: bson.D{{Key: "$dayOfMonth", Value: "$date_time_of_attendance"}}},
+		{{Key: "$unwind", Value: bson.D{
+			{Key: "path", Value: "$user_info"},
+			{Key: "preserveNullAndEmptyArrays", Value: true},
+		}}},
+	}
+	if filter.Campus != "" {
+		stages = append(stages, bson.D{{Key: "$match", Value: bson.D{
+			{Key: "user_info.user_campus", Value: filter.Campus},
+		}}})
+	}
+	return stages
+}
+
+// pageStages sorts on sortField, breaking ties by _id, and splits the results into one page of data and a
+// total count. Any stages in pageData run on the page only.
+func pageStages(sortField string, ascending bool, page, limit int, pageData ...bson.D) mongo.Pipeline {
+	data := bson.A{
+		bson.D{{Key: "$skip", Value: (page - 1) * limit}},
+		bson.D{{Key: "$limit", Value: limit}},
+	}
+	for _, stage := range pageData {
+		data = append(data, stage)
+	}
+
+	return mongo.Pipeline{
+		sortStage(sortField, ascending),
+		{{Key: "$facet", Value: bson.D{
+			{Key: "data", Value: data},
+			{Key: "total", Value: bson.A{
+				bson.D{{Key: "$count", Value: "n"}},
 			}},
+		}}},
+	}
+}
+
+// sortStage sorts on sortField, breaking ties by _id so the order is stable
+func sortStage(sortField string, ascending bool) bson.D {
+	direction := -1
+	if ascending {
+		direction = 1
+	}
+	return bson.D{{Key: "$sort", Value: bson.D{{Key: sortField, Value: direction}, {Key: "_id", Value: direction}}}}
+}
+
+// eachResult runs an aggregation and calls fn with each result as it is read from the cursor. Large results
+// may spill to disk.
+func eachResult[T any](ctx context.Context, collection *mongo.Collection, pipeline mongo.Pipeline, fn func(*T) error) error {
+	cursor, err := collection.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var result T
+		if err := cursor.Decode(&result); err != nil {
+			return err
+		}
+		if err := fn(&result); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// pageTotal is the total count produced by pageStages
+type pageTotal []struct {
+	N int `bson:"n"`
+}
+
+func (t pageTotal) count() int {
+	if len(t) == 0 {
+		return 0
+	}
+	return t[0].N
+}
+
+// EventAttendanceSummary is a per-event attendance aggregate
+type EventAttendanceSummary struct {
+	EventID            primitive.ObjectID `bson:"_id"`
+	EventName          string             `bson:"event_name"`
+	EventType          string             `bson:"event_type"`
+	StartTime          time.Time          `bson:"start_time"`
+	TotalAttendance    int                `bson:"total_attendance"`
+	Members            int                `bson:"members"`
+	Visitors           int                `bson:"visitors"`
+	AverageStayMinutes float64            `bson:"average_stay_minutes"`
+	NotCheckedOut      int                `bson:"not_checked_out"`
+}
+
+// GetAttendanceByEvent groups the filtered attendance by the event it was recorded against and returns one page,
+// sorted on sortField (start_time, total_attendance, members or visitors)
+func (r *AttendanceRepository) GetAttendanceByEvent(ctx context.Context, filter AttendanceHistoryFilter, sortField string, ascending bool, page, limit int) ([]EventAttendanceSummary, int, error) {
+	pipeline := historyMatchStages(filter)
+	pipeline = append(pipeline,
+		bson.D{{Key: "$match", Value: bson.D{
+			{Key: "event", Value: bson.D{{Key: "$exists", Value: true}}},
+		}}},
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$event"},
 			{Key: "total_attendance", Value: bson.D{{Key: "$sum", Value: 1}}},
 			{Key: "members", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{
-				bson.D{{Key: "$eq", Value: bson.A{"$user_info.member", true}}},
-				1,
-				0,
+				bson.D{{Key: "$eq", Value: bson.A{memberExpr(), true}}}, 1, 0,
 			}}}}}},
 			{Key: "visitors", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{
-				bson.D{{Key: "$eq", Value: bson.A{"$user_info.visitor", true}}},
-				1,
-				0,
+				bson.D{{Key: "$eq", Value: bson.A{visitorExpr(), true}}}, 1, 0,
 			}}}}}},
+			{Key: "average_stay_minutes", Value: bson.D{{Key: "$avg", Value: stayMinutesExpr()}}},
+			{Key: "not_checked_out", Value: bson.D{{Key: "$sum", Value: notCheckedOutExpr()}}},
+		}}},
+		bson.D{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "service_events"},
+			{Key: "localField", Value: "_id"},
+			{Key: "foreignField", Value: "_id"},
+			{Key: "as", Value: "event_info"},
+		}}},
+		bson.D{{Key: "$unwind", Value: bson.D{
+			{Key: "path", Value: "$event_info"},
+			{Key: "preserveNullAndEmptyArrays", Value: true},
+		}}},
+		bson.D{{Key: "$addFields", Value: bson.D{
+			{Key: "event_name", Value: "$event_info.name"},
+			{Key: "event_type", Value: "$event_info.event_type"},
+			{Key: "start_time", Value: "$event_info.start_time"},
+		}}},
+		bson.D{{Key: "$project", Value: bson.D{{Key: "event_info", Value: 0}}}},
+	)
+	pipeline = append(pipeline, pageStages(sortField, ascending, page, limit)...)
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Data  []EventAttendanceSummary `bson:"data"`
+		Total pageTotal                `bson:"total"`
+	}
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, 0, err
+	}
+	if len(results) == 0 {
+		return nil, 0, nil
+	}
+
+	return results[0].Data, results[0].Total.count(), nil
+}
+
+// AttendanceHistoryRecord is a single check-in with the attendee's details and the event it was recorded against
+type AttendanceHistoryRecord struct {
+	ID                   primitive.ObjectID  `bson:"_id"`
+	UserID               string              `bson:"user_id"`
+	VisitorProfile       *primitive.ObjectID `bson:"visitor_profile"`
+	FamilyMember         *primitive.ObjectID `bson:"family_member"`
+	Household            string              `bson:"household"`
+	FirstName            string              `bson:"fname"`
+	LastName             string              `bson:"lname"`
+	Email                string              `bson:"email"`
+	PhoneNumber          string              `bson:"phone_number"`
+	Campus               string              `bson:"user_campus"`
+	Event                *primitive.ObjectID `bson:"event"`
+	EventName            string              `bson:"event_name"`
+	EventType            string              `bson:"event_type"`
+	DateTimeOfAttendance time.Time           `bson:"date_time_of_attendance"`
+	CheckinMethod        string              `bson:"checkin_method"`
+	Late                 bool                `bson:"late"`
+	MinutesLate          int                 `bson:"minutes_late"`
+	Member               bool                `bson:"member"`
+	Visitor              bool                `bson:"visitor"`
+	CheckOutTime         *time.Time          `bson:"check_out_time"`
+}
+
+// GetHistory returns one page of individual check-ins matching the filter, sorted on sortField
+// (date_time_of_attendance or minutes_late). Guests checked in without an account are named from their visitor
+// profile.
+func (r *AttendanceRepository) GetHistory(ctx context.Context, filter AttendanceHistoryFilter, sortField string, ascending bool, page, limit int) ([]AttendanceHistoryRecord, int, error) {
+	pipeline := historyMatchStages(filter)
+	pipeline = append(pipeline, pageStages(sortField, ascending, page, limit, historyRecordStages()...)...)
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Data  []AttendanceHistoryRecord `bson:"data"`
+		Total pageTotal                 `bson:"total"`
+	}
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, 0, err
+	}
+	if len(results) == 0 {
+		return nil, 0, nil
+	}
+
+	return results[0].Data, results[0].Total.count(), nil
+}
+
+// historyRecordStages joins the visitor profile and event to each record and shapes it as an AttendanceHistoryRecord
+func historyRecordStages() []bson.D {
+	return []bson.D{
+		bson.D{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "visitors"},
+			{Key: "localField", Value: "visitor_profile"},
+			{Key: "foreignField", Value: "_id"},
+			{Key: "as", Value: "visitor_info"},
+		}}},
+		bson.D{{Key: "$unwind", Value: bson.D{
+			{Key: "path", Value: "$visitor_info"},
+			{Key: "preserveNullAndEmptyArrays", Value: true},
+		}}},
+		bson.D{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "family_members"},
+			{Key: "localField", Value: "family_member"},
+			{Key: "foreignField", Value: "_id"},
+			{Key: "as", Value: "family_member_info"},
+		}}},
+		bson.D{{Key: "$unwind", Value: bson.D{
+			{Key: "path", Value: "$family_member_info"},
+			{Key: "preserveNullAndEmptyArrays", Value: true},
+		}}},
+		bson.D{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "service_events"},
+			{Key: "localField", Value: "event"},
+			{Key: "foreignField", Value: "_id"},
+			{Key: "as", Value: "event_info"},
+		}}},
+		bson.D{{Key: "$unwind", Value: bson.D{
+			{Key: "path", Value: "$event_info"},
+			{Key: "preserveNullAndEmptyArrays", Value: true},
+		}}},
+		bson.D{{Key: "$project", Value: bson.D{
+			{Key: "user_id", Value: "$user_info.user_id"},
+			{Key: "visitor_profile", Value: 1},
+			{Key: "family_member", Value: 1},
+			{Key: "household", Value: 1},
+			// Family members only have a full name, which is reported as the first name
+			{Key: "fname", Value: coalesce("$user_info.fname", "$visitor_info.fname", "$family_member_info.family_members")},
+			{Key: "lname", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$user_info.lname", "$visitor_info.lname"}}}},
+			{Key: "email", Value: coalesce("$user_info.email", "$visitor_info.email", "$family_member_info.email")},
+			{Key: "phone_number", Value: coalesce("$user_info.phone_number", "$visitor_info.phone_number", "$family_member_info.phone")},
+			{Key: "user_campus", Value: "$user_info.user_campus"},
+			{Key: "event", Value: 1},
+			{Key: "event_name", Value: "$event_info.name"},
+			{Key: "event_type", Value: "$event_info.event_type"},
+			{Key: "date_time_of_attendance", Value: 1},
+			{Key: "checkin_method", Value: checkinMethodExpr()},
+			{Key: "late", Value: 1},
+			{Key: "minutes_late", Value: 1},
+			{Key: "member", Value: memberExpr()},
+			{Key: "visitor", Value: visitorExpr()},
+			{Key: "check_out_time", Value: 1},
+		}}},
+	}
+}
+
+// EachHistoryRecord calls fn with every check-in matching the filter, sorted on sortField, without loading them all
+// into memory
+func (r *AttendanceRepository) EachHistoryRecord(ctx context.Context, filter AttendanceHistoryFilter, sortField string, ascending bool, fn func(*AttendanceHistoryRecord) error) error {
+	pipeline := historyMatchStages(filter)
+	pipeline = append(pipeline, sortStage(sortField, ascending))
+	pipeline = append(pipeline, historyRecordStages()...)
+
+	return eachResult(ctx, r.collection, pipeline, fn)
+}
+
+// UserAttendanceRecord is one of a user's attendance records with the event it was recorded against
+type UserAttendanceRecord struct {
+	ID                   primitive.ObjectID  `bson:"_id"`
+	Event                *primitive.ObjectID `bson:"event"`
+	EventName            string              `bson:"event_name"`
+	EventType            string              `bson:"event_type"`
+	EventStart           *time.Time          `bson:"event_start"`
+	DateTimeOfAttendance time.Time           `bson:"date_time_of_attendance"`
+	Late                 bool                `bson:"late"`
+	MinutesLate          int                 `bson:"minutes_late"`
+	CheckinMethod        string              `bson:"checkin_method"`
+	CheckOutTime         *time.Time          `bson:"check_out_time"`
+}
+
+// GetForUser returns a page of the user's attendance records, newest first, with their events
+func (r *AttendanceRepository) GetForUser(ctx context.Context, userID primitive.ObjectID, page, limit int) ([]UserAttendanceRecord, int, error) {
+	offset := (page - 1) * limit
+	filter := bson.M{
+		"user":   userID,
+		"voided": bson.M{"$ne": true},
+	}
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$sort", Value: bson.D{{Key: "date_time_of_attendance", Value: -1}}}},
+		{{Key: "$skip", Value: offset}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "service_events"},
+			{Key: "localField", Value: "event"},
+			{Key: "foreignField", Value: "_id"},
+			{Key: "as", Value: "event_info"},
+		}}},
+		{{Key: "$unwind", Value: bson.D{
+			{Key: "path", Value: "$event_info"},
+			{Key: "preserveNullAndEmptyArrays", Value: true},
 		}}},
-		{{Key: "$sort", Value: bson.D{
-			{Key: "_id.year", Value: -1},
-			{Key: "_id.month", Value: -1},
-			{Key: "_id.day", Value: -1},
+		{{Key: "$project", Value: bson.D{
+			{Key: "event", Value: 1},
+			{Key: "event_name", Value: "$event_info.name"},
+			{Key: "event_type", Value: "$event_info.event_type"},
+			{Key: "event_start", Value: "$event_info.start_time"},
+			{Key: "date_time_of_attendance", Value: 1},
+			{Key: "late", Value: 1},
+			{Key: "minutes_late", Value: 1},
+			{Key: "checkin_method", Value: 1},
+			{Key: "check_out_time", Value: 1},
 		}}},
 	}
 
 	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []UserAttendanceRecord
+	if err = cursor.All(ctx, &records); err != nil {
+		return nil, 0, err
+	}
+
+	return records, int(total), nil
+}
+
+// GetTimesForUser returns when the user attended the given events, or any event when events is nil, oldest first
+func (r *AttendanceRepository) GetTimesForUser(ctx context.Context, userID primitive.ObjectID, events []primitive.ObjectID) ([]time.Time, error) {
+	filter := bson.M{
+		"user":   userID,
+		"voided": bson.M{"$ne": true},
+	}
+	if events != nil {
+		filter["event"] = bson.M{"$in": events}
+	}
+	findOptions := options.Find().
+		SetProjection(bson.M{"date_time_of_attendance": 1}).
+		SetSort(bson.D{{Key: "date_time_of_attendance", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
 	if err != nil {
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
-	var results []map[string]interface{}
+	var results []struct {
+		DateTimeOfAttendance time.Time `bson:"date_time_of_attendance"`
+	}
 	if err = cursor.All(ctx, &results); err != nil {
 		return nil, err
 	}
 
-	return results, nil
+	times := make([]time.Time, 0, len(results))
+	for _, result := range results {
+		times = append(times, result.DateTimeOfAttendance)
+	}
+	return times, nil
+}
+
+// GetLatestEventForUser returns the event of the user's most recent attendance at an event of the given types,
+// or nil if they have not attended one
+func (r *AttendanceRepository) GetLatestEventForUser(ctx context.Context, userID primitive.ObjectID, eventTypes []string) (*primitive.ObjectID, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{
+			notVoided,
+			{Key: "user", Value: userID},
+			{Key: "event", Value: bson.D{{Key: "$exists", Value: true}}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "date_time_of_attendance", Value: -1}}}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "service_events"},
+			{Key: "localField", Value: "event"},
+			{Key: "foreignField", Value: "_id"},
+			{Key: "as", Value: "event_info"},
+		}}},
+		{{Key: "$match", Value: bson.D{
+			{Key: "event_info.event_type", Value: bson.D{{Key: "$in", Value: eventTypes}}},
+		}}},
+		{{Key: "$limit", Value: 1}},
+		{{Key: "$project", Value: bson.D{{Key: "event", Value: 1}}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Event primitive.ObjectID `bson:"event"`
+	}
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+	return &results[0].Event, nil
+}
+
+// MemberAttendanceSummary is how often a member attended before and during the recent window
+type MemberAttendanceSummary struct {
+	User             primitive.ObjectID `bson:"_id"`
+	UserID           string             `bson:"user_id"`
+	Shepherd         string             `bson:"shepherd"`
+	LastAttended     time.Time          `bson:"last_attended"`
+	BaselineCount    int                `bson:"baseline_count"`
+	RecentCount      int                `bson:"recent_count"`
+	AttendedServices int                `bson:"attended_services"`
+}
+
+// GetMemberSummaries summarises the attendance of every member who attended rateEvents or serviceIDs since
+// baselineStart: visits to rateEvents before recentStart, visits to rateEvents from recentStart on, how many of
+// serviceIDs they attended and when they last attended any of them
+func (r *AttendanceRepository) GetMemberSummaries(ctx context.Context, baselineStart, recentStart time.Time, rateEvents, serviceIDs []primitive.ObjectID) ([]MemberAttendanceSummary, error) {
+	if rateEvents == nil {
+		rateEvents = []primitive.ObjectID{}
+	}
+	if serviceIDs == nil {
+		serviceIDs = []primitive.ObjectID{}
+	}
+	atRateEvent := bson.D{{Key: "$in", Value: bson.A{"$event", rateEvents}}}
+	events := append(append([]primitive.ObjectID{}, rateEvents...), serviceIDs...)
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{
+			notVoided,
+			{Key: "user", Value: bson.D{{Key: "$exists", Value: true}}},
+			{Key: "event", Value: bson.D{{Key: "$in", Value: events}}},
+			{Key: "date_time_of_attendance", Value: bson.D{{Key: "$gte", Value: baselineStart}}},
+		}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$user"},
+			{Key: "last_attended", Value: bson.D{{Key: "$max", Value: "$date_time_of_attendance"}}},
+			{Key: "baseline_count", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{
+				bson.D{{Key: "$and", Value: bson.A{
+					bson.D{{Key: "$lt", Value: bson.A{"$date_time_of_attendance", recentStart}}},
+					atRateEvent,
+				}}}, 1, 0,
+			}}}}}},
+			{Key: "recent_count", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{
+				bson.D{{Key: "$and", Value: bson.A{
+					bson.D{{Key: "$gte", Value: bson.A{"$date_time_of_attendance", recentStart}}},
+					atRateEvent,
+				}}}, 1, 0,
+			}}}}}},
+			{Key: "attended_services", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{
+				bson.D{{Key: "$in", Value: bson.A{"$event", serviceIDs}}}, 1, 0,
+			}}}}}},
+		}}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "users"},
+			{Key: "localField", Value: "_id"},
+			{Key: "foreignField", Value: "_id"},
+			{Key: "as", Value: "user_info"},
+		}}},
+		{{Key: "$unwind", Value: "$user_info"}},
+		{{Key: "$match", Value: bson.D{
+			{Key: "user_info.member", Value: true},
+		}}},
+		{{Key: "$project", Value: bson.D{
+			{Key: "user_id", Value: "$user_info.user_id"},
+			{Key: "shepherd", Value: "$user_info.shepherd"},
+			{Key: "last_attended", Value: 1},
+			{Key: "baseline_count", Value: 1},
+			{Key: "recent_count", Value: 1},
+			{Key: "attended_services", Value: 1},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var summaries []MemberAttendanceSummary
+	if err = cursor.All(ctx, &summaries); err != nil {
+		return nil, err
+	}
+
+	return summaries, nil
+}
+
+// CountForUserSince counts a user's attendance from since onwards
+func (r *AttendanceRepository) CountForUserSince(ctx context.Context, userID primitive.ObjectID, since time.Time) (int, error) {
+	filter := bson.M{
+		"user":                    userID,
+		"voided":                  bson.M{"$ne": true},
+		"date_time_of_attendance": bson.M{"$gte": since},
+	}
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	return int(total), err
+}
+
+func (r *AttendanceRepository) CountTotalForDate(ctx context.Context, events []primitive.ObjectID, date time.Time, loc *time.Location) (int, error) {
+	startOfDay, endOfDay := utils.DayBounds(date, loc)
+
+	filter := atEvents(bson.D{
+		notVoided,
+		{Key: "date_time_of_attendance", Value: bson.D{
+			{Key: "$gte", Value: startOfDay},
+			{Key: "$lt", Value: endOfDay},
+		}},
+	}, events)
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	return int(total), err
+}
+
+func (r *AttendanceRepository) CountMembersForDate(ctx context.Context, events []primitive.ObjectID, date time.Time, loc *time.Location) (int, error) {
+	startOfDay, endOfDay := utils.DayBounds(date, loc)
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: atEvents(bson.D{
+			notVoided,
+			{Key: "date_time_of_attendance", Value: bson.D{
+				{Key: "$gte", Value: startOfDay},
+				{Key: "$lt", Value: endOfDay},
+			}},
+		}, events)}},
+	}
+	pipeline = append(pipeline, flagSetStages(memberExpr())...)
+	pipeline = append(pipeline, bson.D{{Key: "$count", Value: "total"}})
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var result []struct {
+		Total int `bson:"total"`
+	}
+	if err = cursor.All(ctx, &result); err != nil {
+		return 0, err
+	}
+
+	if len(result) == 0 {
+		return 0, nil
+	}
+
+	return result[0].Total, nil
+}
+
+func (r *AttendanceRepository) CountVisitorsForDate(ctx context.Context, events []primitive.ObjectID, date time.Time, loc *time.Location) (int, error) {
+	startOfDay, endOfDay := utils.DayBounds(date, loc)
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: atEvents(bson.D{
+			notVoided,
+			{Key: "date_time_of_attendance", Value: bson.D{
+				{Key: "$gte", Value: startOfDay},
+				{Key: "$lt", Value: endOfDay},
+			}},
+		}, events)}},
+	}
+	pipeline = append(pipeline, flagSetStages(visitorExpr())...)
+	pipeline = append(pipeline, bson.D{{Key: "$count", Value: "total"}})
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var result []struct {
+		Total int `bson:"total"`
+	}
+	if err = cursor.All(ctx, &result); err != nil {
+		return 0, err
+	}
+
+	if len(result) == 0 {
+		return 0, nil
+	}
+
+	return result[0].Total, nil
+}
+
+// DailyAttendanceSummary is the attendance for one calendar day
+type DailyAttendanceSummary struct {
+	Date               string  `bson:"_id"`
+	TotalAttendance    int     `bson:"total_attendance"`
+	Members            int     `bson:"members"`
+	Visitors           int     `bson:"visitors"`
+	AverageStayMinutes float64 `bson:"average_stay_minutes"`
+	NotCheckedOut      int     `bson:"not_checked_out"`
+}
+
+// GetAttendanceByDateRange groups the filtered attendance into calendar days in the given timezone and returns
+// one page, sorted on sortField (_id for the date, total_attendance, members or visitors)
+func (r *AttendanceRepository) GetAttendanceByDateRange(ctx context.Context, filter AttendanceHistoryFilter, loc *time.Location, sortField string, ascending bool, page, limit int) ([]DailyAttendanceSummary, int, error) {
+	pipeline := dailySummaryStages(filter, loc)
+	pipeline = append(pipeline, pageStages(sortField, ascending, page, limit)...)
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Data  []DailyAttendanceSummary `bson:"data"`
+		Total pageTotal                `bson:"total"`
+	}
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, 0, err
+	}
+	if len(results) == 0 {
+		return nil, 0, nil
+	}
+
+	return results[0].Data, results[0].Total.count(), nil
+}
+
+// EachDailySummary calls fn with the filtered attendance for each calendar day, sorted on sortField
+func (r *AttendanceRepository) EachDailySummary(ctx context.Context, filter AttendanceHistoryFilter, loc *time.Location, sortField string, ascending bool, fn func(*DailyAttendanceSummary) error) error {
+	pipeline := dailySummaryStages(filter, loc)
+	pipeline = append(pipeline, sortStage(sortField, ascending))
+
+	return eachResult(ctx, r.collection, pipeline, fn)
+}
+
+// dailySummaryStages groups the filtered attendance into calendar days in the given timezone
+func dailySummaryStages(filter AttendanceHistoryFilter, loc *time.Location) mongo.Pipeline {
+	pipeline := historyMatchStages(filter)
+	return append(pipeline,
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{{Key: "$dateToString", Value: bson.D{
+				{Key: "format", Value: "%Y-%m-%d"},
+				{Key: "date", Value: "$date_time_of_attendance"},
+				{Key: "timezone", Value: loc.String()},
+			}}}},
+			{Key: "total_attendance", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "members", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{
+				bson.D{{Key: "$eq", Value: bson.A{memberExpr(), true}}},
+				1,
+				0,
+			}}}}}},
+			{Key: "visitors", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{
+				bson.D{{Key: "$eq", Value: bson.A{visitorExpr(), true}}},
+				1,
+				0,
+			}}}}}},
+			{Key: "average_stay_minutes", Value: bson.D{{Key: "$avg", Value: stayMinutesExpr()}}},
+			{Key: "not_checked_out", Value: bson.D{{Key: "$sum", Value: notCheckedOutExpr()}}},
+		}}},
+	)
+}
+
+// toInt converts the numeric types Mongo may return for an aggregated value to an int
+func toInt(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case int32:
+		return int(v), true
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), true
+	case int:
+		return v, true
+	default:
+		return 0, false
+	}
 }
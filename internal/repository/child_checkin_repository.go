@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cci-api/internal/database"
+	"cci-api/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrChildAlreadyCheckedOut is returned by CheckOut when the child has already been picked up
+var ErrChildAlreadyCheckedOut = errors.New("child has already been checked out")
+
+// stillCheckedIn matches children who have not been picked up yet
+var stillCheckedIn = bson.E{Key: "checked_out_at", Value: bson.D{{Key: "$exists", Value: false}}}
+
+type ChildCheckinRepository struct {
+	db         *database.Database
+	collection *mongo.Collection
+}
+
+func NewChildCheckinRepository(db *database.Database) *ChildCheckinRepository {
+	return &ChildCheckinRepository{
+		db:         db,
+		collection: db.Collection("child_checkins"),
+	}
+}
+
+// HasForEvent reports whether any child check-in references the event
+func (r *ChildCheckinRepository) HasForEvent(ctx context.Context, eventID primitive.ObjectID) (bool, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"event": eventID}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *ChildCheckinRepository) Create(ctx context.Context, checkin *models.ChildCheckin) error {
+	result, err := r.collection.InsertOne(ctx, checkin)
+	if err != nil {
+		return err
+	}
+
+	checkin.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *ChildCheckinRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*models.ChildCheckin, error) {
+	var checkin models.ChildCheckin
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&checkin)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &checkin, nil
+}
+
+func (r *ChildCheckinRepository) GetByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*models.ChildCheckin, error) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "pickup_code", Value: 1}, {Key: "child_name", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var checkins []*models.ChildCheckin
+	if err = cursor.All(ctx, &checkins); err != nil {
+		return nil, err
+	}
+	return checkins, nil
+}
+
+// GetOpenByFamilyMember returns the child's current check-in, if they have not been picked up yet
+func (r *ChildCheckinRepository) GetOpenByFamilyMember(ctx context.Context, familyMemberID primitive.ObjectID) (*models.ChildCheckin, error) {
+	var checkin models.ChildCheckin
+	err := r.collection.FindOne(ctx, bson.D{{Key: "family_member", Value: familyMemberID}, stillCheckedIn}).Decode(&checkin)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &checkin, nil
+}
+
+// PickupCodeInUse reports whether a child still waiting to be picked up has the code
+func (r *ChildCheckinRepository) PickupCodeInUse(ctx context.Context, code string) (bool, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.D{{Key: "pickup_code", Value: code}, stillCheckedIn}, options.Count().SetLimit(1))
+	return count > 0, err
+}
+
+// GetOpen returns the children still checked in, optionally only those in one classroom or at one event,
+// sorted by classroom and name
+func (r *ChildCheckinRepository) GetOpen(ctx context.Context, classroom string, eventID *primitive.ObjectID) ([]*models.ChildCheckin, error) {
+	filter := bson.D{stillCheckedIn}
+	if classroom != "" {
+		filter = append(filter, bson.E{Key: "classroom", Value: classroom})
+	}
+	if eventID != nil {
+		filter = append(filter, bson.E{Key: "event", Value: *eventID})
+	}
+	findOptions := options.Find().SetSort(bson.D{{Key: "classroom", Value: 1}, {Key: "child_name", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var checkins []*models.ChildCheckin
+	if err = cursor.All(ctx, &checkins); err != nil {
+		return nil, err
+	}
+	return checkins, nil
+}
+
+// CheckOut records a child's pickup. It fails with ErrChildAlreadyCheckedOut if someone else got there first.
+func (r *ChildCheckinRepository) CheckOut(ctx context.Context, checkin *models.ChildCheckin) error {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.D{{Key: "_id", Value: checkin.ID}, stillCheckedIn},
+		bson.M{"$set": bson.M{
+			"checked_out_at": checkin.CheckedOutAt,
+			"checked_out_by": checkin.CheckedOutBy,
+			"picked_up_by":   checkin.PickedUpBy,
+			"pickup_method":  checkin.PickupMethod,
+		}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrChildAlreadyCheckedOut
+	}
+	return nil
+}
+
+// ChildAwaitingPickup is a child still checked in after they should have been collected
+type ChildAwaitingPickup struct {
+	models.ChildCheckin `bson:",inline"`
+	EventName           string     `bson:"event_name"`
+	EventEnd            *time.Time `bson:"event_end"`
+}
+
+// GetAwaitingPickup returns the children still checked in more than gracePeriod after their event ended, or,
+// for children not checked in to an event, more than alertAfter after they were checked in. The longest
+// waiting come first.
+func (r *ChildCheckinRepository) GetAwaitingPickup(ctx context.Context, now time.Time, gracePeriod, alertAfter time.Duration) ([]ChildAwaitingPickup, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{stillCheckedIn}}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "service_events"},
+			{Key: "localField", Value: "event"},
+			{Key: "foreignField", Value: "_id"},
+			{Key: "as", Value: "event_info"},
+		}}},
+		{{Key: "$unwind", Value: bson.D{
+			{Key: "path", Value: "$event_info"},
+			{Key: "preserveNullAndEmptyArrays", Value: true},
+		}}},
+		{{Key: "$match", Value: bson.D{{Key: "$or", Value: bson.A{
+			bson.D{{Key: "event_info.end_time", Value: bson.D{{Key: "$lte", Value: now.Add(-gracePeriod)}}}},
+			bson.D{
+				{Key: "event_info", Value: bson.D{{Key: "$exists", Value: false}}},
+				{Key: "checked_in_at", Value: bson.D{{Key: "$lte", Value: now.Add(-alertAfter)}}},
+			},
+		}}}}},
+		{{Key: "$addFields", Value: bson.D{
+			{Key: "event_name", Value: "$event_info.name"},
+			{Key: "event_end", Value: "$event_info.end_time"},
+		}}},
+		{{Key: "$project", Value: bson.D{{Key: "event_info", Value: 0}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "checked_in_at", Value: 1}}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var children []ChildAwaitingPickup
+	if err = cursor.All(ctx, &children); err != nil {
+		return nil, err
+	}
+	return children, nil
+}
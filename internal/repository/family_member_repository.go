@@ -50,7 +50,8 @@ func (r *FamilyMemberRepository) GetByID(ctx context.Context, id primitive.Objec
 	return &familyMember, nil
 }
 
-func (r *FamilyMemberRepository) GetByFamilyHead(ctx context.Context, familyHeadID primitive.ObjectID, page, limit int) ([]*models.FamilyMember, int, error) {
+// GetByFamilyHead returns one page of a family head's household. Family members store the head's user ID.
+func (r *FamilyMemberRepository) GetByFamilyHead(ctx context.Context, familyHeadID string, page, limit int) ([]*models.FamilyMember, int, error) {
 	offset := (page - 1) * limit
 
 	filter := bson.M{"family_head": familyHeadID}
@@ -81,6 +82,23 @@ func (r *FamilyMemberRepository) GetByFamilyHead(ctx context.Context, familyHead
 	return familyMembers, int(total), nil
 }
 
+// GetAllByFamilyHead returns a family head's whole household, sorted by name
+func (r *FamilyMemberRepository) GetAllByFamilyHead(ctx context.Context, familyHeadID string) ([]*models.FamilyMember, error) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "family_members", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"family_head": familyHeadID}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var familyMembers []*models.FamilyMember
+	if err = cursor.All(ctx, &familyMembers); err != nil {
+		return nil, err
+	}
+	return familyMembers, nil
+}
+
 func (r *FamilyMemberRepository) GetAll(ctx context.Context, page, limit int) ([]*models.FamilyMember, int, error) {
 	offset := (page - 1) * limit
 
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cci-api/internal/database"
+	"cci-api/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrOpenFollowUpExists is returned when a member would end up with more than one unresolved follow-up
+var ErrOpenFollowUpExists = errors.New("member already has an open follow-up")
+
+type FollowUpRepository struct {
+	db         *database.Database
+	collection *mongo.Collection
+}
+
+func NewFollowUpRepository(db *database.Database) *FollowUpRepository {
+	return &FollowUpRepository{
+		db:         db,
+		collection: db.Collection("follow_ups"),
+	}
+}
+
+func (r *FollowUpRepository) Create(ctx context.Context, followUp *models.FollowUp) error {
+	followUp.DateAdded = time.Now()
+	followUp.DateUpdated = time.Now()
+	if followUp.Notes == nil {
+		followUp.Notes = []models.FollowUpNote{}
+	}
+
+	result, err := r.collection.InsertOne(ctx, followUp)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrOpenFollowUpExists
+		}
+		return err
+	}
+
+	followUp.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *FollowUpRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*models.FollowUp, error) {
+	var followUp models.FollowUp
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&followUp)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &followUp, nil
+}
+
+// HasOpenForUser reports whether the member already has a follow-up that is not resolved
+func (r *FollowUpRepository) HasOpenForUser(ctx context.Context, userID primitive.ObjectID) (bool, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{
+		"user":   userID,
+		"status": bson.M{"$ne": models.FollowUpStatusResolved},
+	}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetAll lists follow-ups, optionally filtered by status and assignee, with the soonest due first
+func (r *FollowUpRepository) GetAll(ctx context.Context, status, assignedTo string, page, limit int) ([]*models.FollowUp, int, error) {
+	offset := (page - 1) * limit
+
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+	if assignedTo != "" {
+		filter["assigned_to"] = assignedTo
+	}
+
+	// Count total documents
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Find documents
+	findOptions := options.Find().
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit)).
+		SetSort(bson.D{{Key: "due_date", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var followUps []*models.FollowUp
+	if err = cursor.All(ctx, &followUps); err != nil {
+		return nil, 0, err
+	}
+
+	return followUps, int(total), nil
+}
+
+// Update saves a follow-up's status, assignee and due date, appending the note if one is given
+func (r *FollowUpRepository) Update(ctx context.Context, followUp *models.FollowUp, note *models.FollowUpNote) error {
+	followUp.DateUpdated = time.Now()
+
+	update := bson.M{
+		"$set": bson.M{
+			"status":       followUp.Status,
+			"assigned_to":  followUp.AssignedTo,
+			"due_date":     followUp.DueDate,
+			"resolved_at":  followUp.ResolvedAt,
+			"date_updated": followUp.DateUpdated,
+		},
+	}
+	if note != nil {
+		update["$push"] = bson.M{"notes": note}
+	}
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": followUp.ID}, update)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrOpenFollowUpExists
+	}
+	return err
+}
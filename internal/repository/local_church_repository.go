@@ -71,6 +71,21 @@ func (r *LocalChurchRepository) GetFirst(ctx context.Context) (*models.LocalChur
 // 	return &church, nil
 // }
 
+// ListAll returns every church, unpaginated, for jobs that work through each church in turn
+func (r *LocalChurchRepository) ListAll(ctx context.Context) ([]*models.LocalChurch, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var churches []*models.LocalChurch
+	if err := cursor.All(ctx, &churches); err != nil {
+		return nil, err
+	}
+	return churches, nil
+}
+
 func (r *LocalChurchRepository) GetAll(ctx context.Context, page, limit int) ([]*models.LocalChurch, int, error) {
 	skip := (page - 1) * limit
 
@@ -98,6 +113,9 @@ func (r *LocalChurchRepository) GetAll(ctx context.Context, page, limit int) ([]
 func (r *LocalChurchRepository) Update(ctx context.Context, church *models.LocalChurch) error {
 	filter := bson.M{"_id": church.ID}
 	update := bson.M{"$set": church}
+	if church.Geofence == nil {
+		update["$unset"] = bson.M{"geofence": ""}
+	}
 
 	_, err := r.collection.UpdateOne(ctx, filter, update)
 	return err
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cci-api/internal/database"
+	"cci-api/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type LoginAttemptRepository struct {
+	db         *database.Database
+	collection *mongo.Collection
+}
+
+func NewLoginAttemptRepository(db *database.Database) *LoginAttemptRepository {
+	return &LoginAttemptRepository{
+		db:         db,
+		collection: db.Collection("login_attempts"),
+	}
+}
+
+func (r *LoginAttemptRepository) Get(ctx context.Context, key string) (*models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	err := r.collection.FindOne(ctx, bson.M{"_id": key}).Decode(&attempt)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+// RecordFailure counts one more failed login for key and returns the updated record
+func (r *LoginAttemptRepository) RecordFailure(ctx context.Context, key string, at, expiresAt time.Time) (*models.LoginAttempt, error) {
+	update := bson.M{
+		"$inc": bson.M{"failures": 1},
+		"$set": bson.M{
+			"last_failure_at": at,
+			"expires_at":      expiresAt,
+		},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var attempt models.LoginAttempt
+	if err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&attempt); err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+// Throttle sets when key may try again and, when lockedUntil is given, locks it until then
+func (r *LoginAttemptRepository) Throttle(ctx context.Context, key string, nextAttemptAt time.Time, lockedUntil *time.Time, expiresAt time.Time) error {
+	set := bson.M{
+		"next_attempt_at": nextAttemptAt,
+		"expires_at":      expiresAt,
+	}
+	if lockedUntil != nil {
+		set["locked_until"] = *lockedUntil
+	}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": key}, bson.M{"$set": set})
+	return err
+}
+
+// Delete forgets the failed logins recorded for key
+func (r *LoginAttemptRepository) Delete(ctx context.Context, key string) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": key})
+	return err
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"cci-api/internal/database"
+	"cci-api/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MembershipTransitionRepository struct {
+	db         *database.Database
+	collection *mongo.Collection
+}
+
+func NewMembershipTransitionRepository(db *database.Database) *MembershipTransitionRepository {
+	return &MembershipTransitionRepository{
+		db:         db,
+		collection: db.Collection("membership_transitions"),
+	}
+}
+
+func (r *MembershipTransitionRepository) Create(ctx context.Context, transition *models.MembershipTransition) error {
+	if transition.ChangedAt.IsZero() {
+		transition.ChangedAt = time.Now()
+	}
+
+	result, err := r.collection.InsertOne(ctx, transition)
+	if err != nil {
+		return err
+	}
+
+	transition.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// GetByUser returns a user's stage transitions, oldest first
+func (r *MembershipTransitionRepository) GetByUser(ctx context.Context, userID primitive.ObjectID) ([]*models.MembershipTransition, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "changed_at", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"user": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var transitions []*models.MembershipTransition
+	if err = cursor.All(ctx, &transitions); err != nil {
+		return nil, err
+	}
+
+	return transitions, nil
+}
+
+// StageDuration is how long people spent in a membership stage before moving on from it
+type StageDuration struct {
+	Stage         string  `bson:"_id"`
+	AverageMillis float64 `bson:"average_ms"`
+	Count         int     `bson:"count"`
+}
+
+// GetAverageStageDurations averages the time spent in each stage over transitions that moved people forward
+// through the pipeline. Moves back to an earlier stage are left out so corrections don't skew the figures.
+func (r *MembershipTransitionRepository) GetAverageStageDurations(ctx context.Context) ([]StageDuration, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{
+			{Key: "$expr", Value: bson.D{{Key: "$gt", Value: bson.A{
+				bson.D{{Key: "$indexOfArray", Value: bson.A{models.MembershipStages, "$to"}}},
+				bson.D{{Key: "$indexOfArray", Value: bson.A{models.MembershipStages, "$from"}}},
+			}}}},
+		}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$from"},
+			{Key: "average_ms", Value: bson.D{{Key: "$avg", Value: bson.D{{Key: "$subtract", Value: bson.A{"$changed_at", "$from_since"}}}}}},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var durations []StageDuration
+	if err = cursor.All(ctx, &durations); err != nil {
+		return nil, err
+	}
+
+	return durations, nil
+}
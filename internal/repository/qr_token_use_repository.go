@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"cci-api/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type QRTokenUseRepository struct {
+	db         *database.Database
+	collection *mongo.Collection
+}
+
+func NewQRTokenUseRepository(db *database.Database) *QRTokenUseRepository {
+	return &QRTokenUseRepository{
+		db:         db,
+		collection: db.Collection("qr_token_uses"),
+	}
+}
+
+// IsUsed reports whether a rotating QR token has already been scanned
+func (r *QRTokenUseRepository) IsUsed(ctx context.Context, token string) (bool, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"_id": token})
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// MarkUsed records that a rotating QR token has been scanned. It reports false if the token was already used.
+// Records expire on their own once the token could no longer be accepted anyway.
+func (r *QRTokenUseRepository) MarkUsed(ctx context.Context, token string, userID primitive.ObjectID, expiresAt time.Time) (bool, error) {
+	_, err := r.collection.InsertOne(ctx, bson.M{
+		"_id":        token,
+		"user":       userID,
+		"used_at":    time.Now(),
+		"expires_at": expiresAt,
+	})
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
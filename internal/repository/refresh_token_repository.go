@@ -11,6 +11,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type RefreshTokenRepository struct {
@@ -49,6 +50,56 @@ func (r *RefreshTokenRepository) GetByToken(ctx context.Context, token string) (
 	return &refreshToken, nil
 }
 
+// MarkRotated records that a refresh token was exchanged for a new one. It reports false when the token was
+// already rotated, which means it is being replayed.
+func (r *RefreshTokenRepository) MarkRotated(ctx context.Context, id primitive.ObjectID, at time.Time) (bool, error) {
+	filter := bson.M{"_id": id, "rotated_at": bson.M{"$exists": false}}
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"rotated_at": at}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// ListActiveByUserID returns the current refresh token of each of the user's sessions, most recently used first
+func (r *RefreshTokenRepository) ListActiveByUserID(ctx context.Context, userID primitive.ObjectID) ([]*models.RefreshToken, error) {
+	filter := bson.M{
+		"user_id":    userID,
+		"rotated_at": bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "last_used_at", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var tokens []*models.RefreshToken
+	if err := cursor.All(ctx, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// DeleteByFamily revokes a session by removing every refresh token in its family, reporting how many were
+// removed. A token issued before sessions were tracked has no family and is its own session, named by its ID.
+func (r *RefreshTokenRepository) DeleteByFamily(ctx context.Context, userID, familyID primitive.ObjectID) (int64, error) {
+	filter := bson.M{
+		"user_id": userID,
+		"$or": bson.A{
+			bson.M{"family_id": familyID},
+			bson.M{"_id": familyID, "family_id": bson.M{"$exists": false}},
+		},
+	}
+	result, err := r.collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
 func (r *RefreshTokenRepository) DeleteByToken(ctx context.Context, token string) error {
 	_, err := r.collection.DeleteOne(ctx, bson.M{"token": token})
 	return err
@@ -80,6 +80,21 @@ func (r *RoleRepository) GetAll(ctx context.Context, page, limit int) ([]*models
 	return roles, int(total), nil
 }
 
+// List returns every role sorted by name
+func (r *RoleRepository) List(ctx context.Context) ([]*models.Role, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "role_name", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var roles []*models.Role
+	if err = cursor.All(ctx, &roles); err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
 func (r *RoleRepository) GetByName(ctx context.Context, name string) (*models.Role, error) {
 	var role models.Role
 	err := r.collection.FindOne(ctx, bson.M{"role_name": name}).Decode(&role)
@@ -0,0 +1,295 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cci-api/internal/database"
+	"cci-api/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ServiceEventRepository struct {
+	db         *database.Database
+	collection *mongo.Collection
+}
+
+func NewServiceEventRepository(db *database.Database) *ServiceEventRepository {
+	return &ServiceEventRepository{
+		db:         db,
+		collection: db.Collection("service_events"),
+	}
+}
+
+func (r *ServiceEventRepository) Create(ctx context.Context, event *models.ServiceEvent) error {
+	event.DateAdded = time.Now()
+	event.DateUpdated = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, event)
+	if err != nil {
+		return err
+	}
+
+	event.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *ServiceEventRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*models.ServiceEvent, error) {
+	var event models.ServiceEvent
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&event)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &event, nil
+}
+
+// churchEvents matches the events a church holds. Events created before churches were assigned to them belong
+// to the default church, so includeUnassigned adds those.
+func churchEvents(churchID primitive.ObjectID, includeUnassigned bool) bson.M {
+	if !includeUnassigned {
+		return bson.M{"church": churchID}
+	}
+	return bson.M{"$or": bson.A{
+		bson.M{"church": churchID},
+		bson.M{"church": bson.M{"$exists": false}},
+	}}
+}
+
+// EventScope narrows event queries to one church's events of some types. A nil Church covers every church and
+// nil Types every type; IncludeUnassigned is as for churchEvents.
+type EventScope struct {
+	Church            *primitive.ObjectID
+	IncludeUnassigned bool
+	Types             []string
+}
+
+func (s EventScope) filter() bson.M {
+	filter := bson.M{}
+	if s.Church != nil {
+		filter = churchEvents(*s.Church, s.IncludeUnassigned)
+	}
+	if s.Types != nil {
+		filter["event_type"] = bson.M{"$in": s.Types}
+	}
+	return filter
+}
+
+// GetOpen returns the church's most recently started event whose check-in window contains the given time.
+// Check-in opens earlyWindow before the scheduled start and closes at the event's end time.
+func (r *ServiceEventRepository) GetOpen(ctx context.Context, churchID primitive.ObjectID, includeUnassigned bool, at time.Time, earlyWindow time.Duration) (*models.ServiceEvent, error) {
+	filter := churchEvents(churchID, includeUnassigned)
+	filter["start_time"] = bson.M{"$lte": at.Add(earlyWindow)}
+	filter["end_time"] = bson.M{"$gte": at}
+	findOptions := options.FindOne().SetSort(bson.D{{Key: "start_time", Value: -1}})
+
+	var event models.ServiceEvent
+	err := r.collection.FindOne(ctx, filter, findOptions).Decode(&event)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &event, nil
+}
+
+// EventDateRange bounds the start times of the events in scope. A nil Start or End leaves that side open.
+type EventDateRange struct {
+	Scope EventScope
+	Start *time.Time
+	End   *time.Time
+}
+
+func (d EventDateRange) filter() bson.M {
+	filter := d.Scope.filter()
+	startTime := bson.M{}
+	if d.Start != nil {
+		startTime["$gte"] = *d.Start
+	}
+	if d.End != nil {
+		startTime["$lt"] = *d.End
+	}
+	if len(startTime) > 0 {
+		filter["start_time"] = startTime
+	}
+	return filter
+}
+
+// GetAll returns a page of events, newest first. When ranges are given, only events within one of them are
+// returned.
+func (r *ServiceEventRepository) GetAll(ctx context.Context, page, limit int, eventType string, ranges []EventDateRange) ([]*models.ServiceEvent, int, error) {
+	offset := (page - 1) * limit
+
+	filter := bson.M{}
+	if eventType != "" {
+		filter["event_type"] = eventType
+	}
+	if len(ranges) > 0 {
+		matches := bson.A{}
+		for _, dateRange := range ranges {
+			matches = append(matches, dateRange.filter())
+		}
+		filter["$or"] = matches
+	}
+
+	// Count total documents
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Find documents
+	findOptions := options.Find().
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit)).
+		SetSort(bson.D{{Key: "start_time", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var events []*models.ServiceEvent
+	if err = cursor.All(ctx, &events); err != nil {
+		return nil, 0, err
+	}
+
+	return events, int(total), nil
+}
+
+// GetLastEnded returns the n most recent events in scope that had ended by at, newest first
+func (r *ServiceEventRepository) GetLastEnded(ctx context.Context, at time.Time, scope EventScope, n int) ([]*models.ServiceEvent, error) {
+	filter := scope.filter()
+	filter["end_time"] = bson.M{"$lte": at}
+	findOptions := options.Find().
+		SetLimit(int64(n)).
+		SetSort(bson.D{{Key: "start_time", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var events []*models.ServiceEvent
+	if err = cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// GetStartedBetween returns the events that started in [start, end), oldest first
+func (r *ServiceEventRepository) GetStartedBetween(ctx context.Context, start, end time.Time) ([]*models.ServiceEvent, error) {
+	filter := bson.M{
+		"start_time": bson.M{
+			"$gte": start,
+			"$lt":  end,
+		},
+	}
+	findOptions := options.Find().SetSort(bson.D{{Key: "start_time", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var events []*models.ServiceEvent
+	if err = cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// startedBetween matches the events in scope that started in [start, end)
+func startedBetween(start, end time.Time, scope EventScope) bson.M {
+	filter := scope.filter()
+	filter["start_time"] = bson.M{
+		"$gte": start,
+		"$lt":  end,
+	}
+	return filter
+}
+
+// GetIDsStartedBetween returns the IDs of the events in scope that started in [start, end)
+func (r *ServiceEventRepository) GetIDsStartedBetween(ctx context.Context, start, end time.Time, scope EventScope) ([]primitive.ObjectID, error) {
+	findOptions := options.Find().SetProjection(bson.M{"_id": 1})
+
+	cursor, err := r.collection.Find(ctx, startedBetween(start, end, scope), findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	ids := []primitive.ObjectID{}
+	for cursor.Next(ctx) {
+		var event struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.Decode(&event); err != nil {
+			return nil, err
+		}
+		ids = append(ids, event.ID)
+	}
+	return ids, cursor.Err()
+}
+
+// GetIDsForChurch returns the IDs of every event the church has held
+func (r *ServiceEventRepository) GetIDsForChurch(ctx context.Context, churchID primitive.ObjectID, includeUnassigned bool) ([]primitive.ObjectID, error) {
+	filter := churchEvents(churchID, includeUnassigned)
+	findOptions := options.Find().SetProjection(bson.M{"_id": 1})
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	ids := []primitive.ObjectID{}
+	for cursor.Next(ctx) {
+		var event struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.Decode(&event); err != nil {
+			return nil, err
+		}
+		ids = append(ids, event.ID)
+	}
+	return ids, cursor.Err()
+}
+
+// CountStartedBetween counts the events in scope that started in [start, end)
+func (r *ServiceEventRepository) CountStartedBetween(ctx context.Context, start, end time.Time, scope EventScope) (int, error) {
+	total, err := r.collection.CountDocuments(ctx, startedBetween(start, end, scope))
+	return int(total), err
+}
+
+func (r *ServiceEventRepository) Update(ctx context.Context, event *models.ServiceEvent) error {
+	event.DateUpdated = time.Now()
+
+	filter := bson.M{"_id": event.ID}
+	update := bson.M{"$set": event}
+
+	_, err := r.collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// Delete removes the event, reporting false when it does not exist
+func (r *ServiceEventRepository) Delete(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount == 1, nil
+}
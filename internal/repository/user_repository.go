@@ -217,22 +217,9 @@ func (r *UserRepository) Filter(ctx context.Context, field, value string, page,
 	offset := (page - 1) * limit
 
 	// Create filter
-	filter := bson.M{}
-
-	// Handle different field types
-	switch field {
-	case "member", "visitor", "usher", "admin", "family_head":
-		if value == "true" {
-			filter[field] = true
-		} else if value == "false" {
-			filter[field] = false
-		} else {
-			return nil, 0, fmt.Errorf("invalid boolean value for field %s", field)
-		}
-	case "gender", "campus_state", "campus_country", "profession":
-		filter[field] = bson.M{"$regex": value, "$options": "i"}
-	default:
-		return nil, 0, fmt.Errorf("filtering not supported for field: %s", field)
+	filter, err := fieldFilter(field, value)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	// Count total documents
@@ -261,6 +248,65 @@ func (r *UserRepository) Filter(ctx context.Context, field, value string, page,
 	return users, int(total), nil
 }
 
+// FindForCards returns up to limit users for badge printing, matching any of the given user IDs, an optional
+// field filter (as in Filter) and an optional earliest join date. With no criteria, the newest users are returned.
+func (r *UserRepository) FindForCards(ctx context.Context, userIDs []string, field, value string, joinedAfter *time.Time, limit int) ([]*models.User, error) {
+	filter := bson.M{}
+	if field != "" {
+		fieldMatch, err := fieldFilter(field, value)
+		if err != nil {
+			return nil, err
+		}
+		filter = fieldMatch
+	}
+	if len(userIDs) > 0 {
+		filter["user_id"] = bson.M{"$in": userIDs}
+	}
+	if joinedAfter != nil {
+		filter["date_joined"] = bson.M{"$gte": *joinedAfter}
+	}
+
+	findOptions := options.Find().
+		SetLimit(int64(limit)).
+		SetSort(bson.D{{Key: "lname", Value: 1}, {Key: "fname", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []*models.User
+	if err = cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// fieldFilter builds the query for filtering users on a single field
+func fieldFilter(field, value string) (bson.M, error) {
+	filter := bson.M{}
+
+	// Handle different field types
+	switch field {
+	case "member", "visitor", "usher", "admin", "family_head":
+		if value == "true" {
+			filter[field] = true
+		} else if value == "false" {
+			filter[field] = false
+		} else {
+			return nil, fmt.Errorf("invalid boolean value for field %s", field)
+		}
+	case "gender", "campus_state", "campus_country", "profession":
+		filter[field] = bson.M{"$regex": value, "$options": "i"}
+	default:
+		return nil, fmt.Errorf("filtering not supported for field: %s", field)
+	}
+
+	return filter, nil
+}
+
 func (r *UserRepository) UpdateQRToken(ctx context.Context, userID, token string) error {
 	filter := bson.M{"user_id": userID}
 	update := bson.M{
@@ -274,6 +320,382 @@ func (r *UserRepository) UpdateQRToken(ctx context.Context, userID, token string
 	return err
 }
 
+func (r *UserRepository) UpdateQRSecret(ctx context.Context, userID, secret string) error {
+	filter := bson.M{"user_id": userID}
+	update := bson.M{
+		"$set": bson.M{
+			"qr_secret":    secret,
+			"date_updated": time.Now(),
+		},
+	}
+
+	_, err := r.collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// IncrementCardVersion revokes the user's printed member cards. It reports false when the user does not exist.
+func (r *UserRepository) IncrementCardVersion(ctx context.Context, userID string) (bool, error) {
+	filter := bson.M{"user_id": userID}
+	update := bson.M{
+		"$inc": bson.M{"card_version": 1},
+		"$set": bson.M{"date_updated": time.Now()},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
+func (r *UserRepository) UpdateShepherd(ctx context.Context, userID, shepherd string) error {
+	filter := bson.M{"user_id": userID}
+	update := bson.M{
+		"$set": bson.M{
+			"shepherd":     shepherd,
+			"date_updated": time.Now(),
+		},
+	}
+
+	_, err := r.collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// UpdateRole sets the user's role, or removes it when roleID is nil
+func (r *UserRepository) UpdateRole(ctx context.Context, userID string, roleID *primitive.ObjectID) error {
+	filter := bson.M{"user_id": userID}
+	update := bson.M{"$set": bson.M{"date_updated": time.Now()}}
+	if roleID != nil {
+		update["$set"].(bson.M)["role"] = roleID
+	} else {
+		update["$unset"] = bson.M{"role": ""}
+	}
+
+	_, err := r.collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// GetByTwoFactorChallenge finds the user a login challenge was issued to, by the challenge's hash
+func (r *UserRepository) GetByTwoFactorChallenge(ctx context.Context, challengeHash string) (*models.User, error) {
+	var user models.User
+	err := r.collection.FindOne(ctx, bson.M{"two_factor_challenge": challengeHash}).Decode(&user)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// The two-factor updates below each touch only the fields their operation changes, so they never write a stale
+// copy of the last used time step or the remaining recovery codes over a concurrent update.
+
+// SetTwoFactorPendingSecret stores the secret of an enrollment waiting for its first code
+func (r *UserRepository) SetTwoFactorPendingSecret(ctx context.Context, id primitive.ObjectID, secret string) error {
+	update := bson.M{
+		"$set": bson.M{
+			"two_factor_pending_secret": secret,
+			"date_updated":              time.Now(),
+		},
+	}
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// EnableTwoFactor turns two-factor authentication on with the pending secret. It reports false when it was
+// already on or the pending secret has since been replaced.
+func (r *UserRepository) EnableTwoFactor(ctx context.Context, id primitive.ObjectID, secret string, recoveryCodes []string, step int64) (bool, error) {
+	filter := bson.M{
+		"_id":                       id,
+		"two_factor_enabled":        bson.M{"$ne": true},
+		"two_factor_pending_secret": secret,
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"two_factor_enabled":        true,
+			"two_factor_secret":         secret,
+			"two_factor_recovery_codes": recoveryCodes,
+			"two_factor_last_step":      step,
+			"date_updated":              time.Now(),
+		},
+		"$unset": bson.M{"two_factor_pending_secret": ""},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// ReplaceRecoveryCodes swaps the user's recovery code hashes for a new set
+func (r *UserRepository) ReplaceRecoveryCodes(ctx context.Context, id primitive.ObjectID, recoveryCodes []string) error {
+	update := bson.M{
+		"$set": bson.M{
+			"two_factor_recovery_codes": recoveryCodes,
+			"date_updated":              time.Now(),
+		},
+	}
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// ClearTwoFactor turns two-factor authentication off and forgets its secrets and recovery codes
+func (r *UserRepository) ClearTwoFactor(ctx context.Context, id primitive.ObjectID) error {
+	update := bson.M{
+		"$set": bson.M{"date_updated": time.Now()},
+		"$unset": bson.M{
+			"two_factor_enabled":        "",
+			"two_factor_secret":         "",
+			"two_factor_pending_secret": "",
+			"two_factor_recovery_codes": "",
+			"two_factor_last_step":      "",
+		},
+	}
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// SetTwoFactorChallenge stores the hash of a login challenge and when it expires
+func (r *UserRepository) SetTwoFactorChallenge(ctx context.Context, id primitive.ObjectID, challengeHash string, expires time.Time) error {
+	update := bson.M{
+		"$set": bson.M{
+			"two_factor_challenge":         challengeHash,
+			"two_factor_challenge_expires": expires,
+		},
+	}
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// UseTwoFactorStep records the time step of an accepted authenticator code. It reports false when that step,
+// or a later one, was already used, so the same code cannot be replayed.
+func (r *UserRepository) UseTwoFactorStep(ctx context.Context, id primitive.ObjectID, step int64) (bool, error) {
+	filter := bson.M{
+		"_id": id,
+		"$or": bson.A{
+			bson.M{"two_factor_last_step": bson.M{"$lt": step}},
+			bson.M{"two_factor_last_step": bson.M{"$exists": false}},
+		},
+	}
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"two_factor_last_step": step}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// UseRecoveryCode removes a recovery code hash. It reports false when the code was already used.
+func (r *UserRepository) UseRecoveryCode(ctx context.Context, id primitive.ObjectID, codeHash string) (bool, error) {
+	filter := bson.M{"_id": id, "two_factor_recovery_codes": codeHash}
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$pull": bson.M{"two_factor_recovery_codes": codeHash}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// ClearTwoFactorChallenge ends a login challenge. It reports false when the challenge was already used.
+func (r *UserRepository) ClearTwoFactorChallenge(ctx context.Context, id primitive.ObjectID, challengeHash string) (bool, error) {
+	filter := bson.M{"_id": id, "two_factor_challenge": challengeHash}
+	update := bson.M{"$unset": bson.M{"two_factor_challenge": "", "two_factor_challenge_expires": ""}}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// UpdateMembershipStage saves the user's membership stage along with the member and visitor flags that follow from it
+func (r *UserRepository) UpdateMembershipStage(ctx context.Context, user *models.User) error {
+	user.DateUpdated = time.Now()
+
+	filter := bson.M{"_id": user.ID}
+	update := bson.M{
+		"$set": bson.M{
+			"membership_stage":       user.MembershipStage,
+			"membership_stage_since": user.MembershipStageSince,
+			"member":                 user.Member,
+			"visitor":                user.Visitor,
+			"date_joined_church":     user.DateJoinedChurch,
+			"date_updated":           user.DateUpdated,
+		},
+	}
+
+	_, err := r.collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// StageSuggestion is a user who has attended enough since entering their membership stage to be moved on
+type StageSuggestion struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	UserID    string             `bson:"user_id"`
+	FirstName string             `bson:"fname"`
+	LastName  string             `bson:"lname"`
+	Stage     string             `bson:"stage"`
+	Since     *time.Time         `bson:"membership_stage_since"`
+	Attended  int                `bson:"attended"`
+}
+
+// membershipStageExpr works out a user's membership stage. Users from before the pipeline existed have no
+// stage saved, so it is inferred from their member flag and whether they serve in a department.
+// Keep in step with membershipStageOf in the service package.
+func membershipStageExpr() bson.D {
+	return bson.D{{Key: "$switch", Value: bson.D{
+		{Key: "branches", Value: bson.A{
+			bson.D{
+				{Key: "case", Value: bson.D{{Key: "$gt", Value: bson.A{bson.D{{Key: "$ifNull", Value: bson.A{"$membership_stage", ""}}}, ""}}}},
+				{Key: "then", Value: "$membership_stage"},
+			},
+			bson.D{
+				{Key: "case", Value: bson.D{{Key: "$and", Value: bson.A{
+					"$member",
+					bson.D{{Key: "$or", Value: bson.A{
+						"$usher",
+						bson.D{{Key: "$gt", Value: bson.A{bson.D{{Key: "$ifNull", Value: bson.A{"$user_work_department", ""}}}, ""}}},
+					}}},
+				}}}},
+				{Key: "then", Value: models.MembershipStageWorker},
+			},
+			bson.D{
+				{Key: "case", Value: "$member"},
+				{Key: "then", Value: models.MembershipStageMember},
+			},
+		}},
+		{Key: "default", Value: models.MembershipStageFirstTimeGuest},
+	}}}
+}
+
+// CountByMembershipStage counts users in each membership stage
+func (r *UserRepository) CountByMembershipStage(ctx context.Context) (map[string]int, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: membershipStageExpr()},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Stage string `bson:"_id"`
+		Count int    `bson:"count"`
+	}
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(results))
+	for _, result := range results {
+		counts[result.Stage] = result.Count
+	}
+	return counts, nil
+}
+
+// GetStageSuggestions finds users whose attendance since entering their current stage has reached the
+// threshold for that stage. Attendance is counted from the start for users who have never changed stage,
+// so visits made before registering count towards moving on from first-time guest.
+func (r *UserRepository) GetStageSuggestions(ctx context.Context, thresholds map[string]int, page, limit int) ([]StageSuggestion, int, error) {
+	stages := bson.A{}
+	branches := bson.A{}
+	for stage, threshold := range thresholds {
+		stages = append(stages, stage)
+		branches = append(branches, bson.D{
+			{Key: "case", Value: bson.D{{Key: "$eq", Value: bson.A{"$stage", stage}}}},
+			{Key: "then", Value: threshold},
+		})
+	}
+	if len(stages) == 0 {
+		return nil, 0, nil
+	}
+
+	offset := (page - 1) * limit
+	pipeline := mongo.Pipeline{
+		{{Key: "$addFields", Value: bson.D{
+			{Key: "stage", Value: membershipStageExpr()},
+		}}},
+		{{Key: "$match", Value: bson.D{
+			{Key: "stage", Value: bson.D{{Key: "$in", Value: stages}}},
+		}}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "attendance"},
+			{Key: "let", Value: bson.D{
+				{Key: "uid", Value: "$_id"},
+				{Key: "since", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$membership_stage_since", time.Unix(0, 0)}}}},
+			}},
+			{Key: "pipeline", Value: bson.A{
+				bson.D{{Key: "$match", Value: bson.D{
+					notVoided,
+					{Key: "$expr", Value: bson.D{{Key: "$and", Value: bson.A{
+						bson.D{{Key: "$eq", Value: bson.A{"$user", "$$uid"}}},
+						bson.D{{Key: "$gte", Value: bson.A{"$date_time_of_attendance", "$$since"}}},
+					}}}},
+				}}},
+				bson.D{{Key: "$count", Value: "n"}},
+			}},
+			{Key: "as", Value: "visits"},
+		}}},
+		{{Key: "$addFields", Value: bson.D{
+			{Key: "attended", Value: bson.D{{Key: "$ifNull", Value: bson.A{bson.D{{Key: "$arrayElemAt", Value: bson.A{"$visits.n", 0}}}, 0}}}},
+			{Key: "threshold", Value: bson.D{{Key: "$switch", Value: bson.D{
+				{Key: "branches", Value: branches},
+				{Key: "default", Value: 0},
+			}}}},
+		}}},
+		{{Key: "$match", Value: bson.D{
+			{Key: "$expr", Value: bson.D{{Key: "$gte", Value: bson.A{"$attended", "$threshold"}}}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "attended", Value: -1}, {Key: "user_id", Value: 1}}}},
+		{{Key: "$facet", Value: bson.D{
+			{Key: "data", Value: bson.A{
+				bson.D{{Key: "$skip", Value: offset}},
+				bson.D{{Key: "$limit", Value: limit}},
+				bson.D{{Key: "$project", Value: bson.D{
+					{Key: "user_id", Value: 1},
+					{Key: "fname", Value: 1},
+					{Key: "lname", Value: 1},
+					{Key: "stage", Value: 1},
+					{Key: "membership_stage_since", Value: 1},
+					{Key: "attended", Value: 1},
+				}}},
+			}},
+			{Key: "total", Value: bson.A{
+				bson.D{{Key: "$count", Value: "n"}},
+			}},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Data  []StageSuggestion `bson:"data"`
+		Total []struct {
+			N int `bson:"n"`
+		} `bson:"total"`
+	}
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, 0, err
+	}
+	if len(results) == 0 || len(results[0].Total) == 0 {
+		return nil, 0, nil
+	}
+
+	return results[0].Data, results[0].Total[0].N, nil
+}
+
 func (r *UserRepository) UpdateQRCodeImage(ctx context.Context, userID, qrImage string) error {
 	filter := bson.M{"user_id": userID}
 	update := bson.M{
@@ -318,7 +740,63 @@ func (r *UserRepository) CountMembers(ctx context.Context) (int, error) {
 	return int(total), err
 }
 
+func (r *UserRepository) CountByRole(ctx context.Context, roleID primitive.ObjectID) (int, error) {
+	total, err := r.collection.CountDocuments(ctx, bson.M{"role": roleID})
+	return int(total), err
+}
+
 func (r *UserRepository) CountVisitors(ctx context.Context) (int, error) {
 	total, err := r.collection.CountDocuments(ctx, bson.M{"visitor": true})
 	return int(total), err
 }
+
+// MemberEventAttendance is a member and the events, out of those asked about, they attended
+type MemberEventAttendance struct {
+	UserID    string               `bson:"user_id"`
+	FirstName string               `bson:"fname"`
+	LastName  string               `bson:"lname"`
+	Campus    string               `bson:"user_campus"`
+	Attended  []primitive.ObjectID `bson:"attended"`
+}
+
+// EachMemberAttendance calls fn with every member, optionally only those from one campus, sorted by name, along
+// with which of eventIDs they attended. checkinMethod, when set, only counts check-ins made that way.
+func (r *UserRepository) EachMemberAttendance(ctx context.Context, eventIDs []primitive.ObjectID, campus, checkinMethod string, fn func(*MemberEventAttendance) error) error {
+	match := bson.D{{Key: "member", Value: true}}
+	if campus != "" {
+		match = append(match, bson.E{Key: "user_campus", Value: campus})
+	}
+
+	conditions := bson.A{bson.D{{Key: "$eq", Value: bson.A{"$user", "$$uid"}}}}
+	if checkinMethod != "" {
+		conditions = append(conditions, bson.D{{Key: "$eq", Value: bson.A{checkinMethodExpr(), checkinMethod}}})
+	}
+	attendanceMatch := bson.D{
+		notVoided,
+		{Key: "event", Value: bson.D{{Key: "$in", Value: eventIDs}}},
+		{Key: "$expr", Value: bson.D{{Key: "$and", Value: conditions}}},
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: bson.D{{Key: "lname", Value: 1}, {Key: "fname", Value: 1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "attendance"},
+			{Key: "let", Value: bson.D{{Key: "uid", Value: "$_id"}}},
+			{Key: "pipeline", Value: bson.A{
+				bson.D{{Key: "$match", Value: attendanceMatch}},
+				bson.D{{Key: "$project", Value: bson.D{{Key: "event", Value: 1}}}},
+			}},
+			{Key: "as", Value: "visits"},
+		}}},
+		{{Key: "$project", Value: bson.D{
+			{Key: "user_id", Value: 1},
+			{Key: "fname", Value: 1},
+			{Key: "lname", Value: 1},
+			{Key: "user_campus", Value: 1},
+			{Key: "attended", Value: "$visits.event"},
+		}}},
+	}
+
+	return eachResult(ctx, r.collection, pipeline, fn)
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cci-api/internal/database"
+	"cci-api/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type VenueCodeRepository struct {
+	db         *database.Database
+	collection *mongo.Collection
+}
+
+func NewVenueCodeRepository(db *database.Database) *VenueCodeRepository {
+	return &VenueCodeRepository{
+		db:         db,
+		collection: db.Collection("venue_codes"),
+	}
+}
+
+// Replace saves the venue code over any earlier code for the same event and venue in a single upsert, so the
+// old code stops working the moment the new one exists
+func (r *VenueCodeRepository) Replace(ctx context.Context, venueCode *models.VenueCode) error {
+	venueCode.DateAdded = time.Now()
+
+	filter := bson.M{"event": venueCode.Event, "venue": venueCode.Venue}
+	update := bson.M{
+		"$set": bson.M{
+			"code":       venueCode.Code,
+			"expires_at": venueCode.ExpiresAt,
+			"created_by": venueCode.CreatedBy,
+			"date_added": venueCode.DateAdded,
+		},
+	}
+	updateOptions := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var saved models.VenueCode
+	if err := r.collection.FindOneAndUpdate(ctx, filter, update, updateOptions).Decode(&saved); err != nil {
+		return err
+	}
+
+	venueCode.ID = saved.ID
+	return nil
+}
+
+func (r *VenueCodeRepository) GetByCode(ctx context.Context, code string) (*models.VenueCode, error) {
+	var venueCode models.VenueCode
+	err := r.collection.FindOne(ctx, bson.M{"code": code}).Decode(&venueCode)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &venueCode, nil
+}
+
+func (r *VenueCodeRepository) GetByEvent(ctx context.Context, eventID primitive.ObjectID) ([]*models.VenueCode, error) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "venue", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"event": eventID}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var venueCodes []*models.VenueCode
+	if err = cursor.All(ctx, &venueCodes); err != nil {
+		return nil, err
+	}
+
+	return venueCodes, nil
+}
+
+// UpdateExpiryForEvent moves the expiry of an event's venue codes, used when the event's end time changes
+func (r *VenueCodeRepository) UpdateExpiryForEvent(ctx context.Context, eventID primitive.ObjectID, expiresAt time.Time) error {
+	_, err := r.collection.UpdateMany(ctx, bson.M{"event": eventID}, bson.M{"$set": bson.M{"expires_at": expiresAt}})
+	return err
+}
+
+func (r *VenueCodeRepository) DeleteByEvent(ctx context.Context, eventID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"event": eventID})
+	return err
+}
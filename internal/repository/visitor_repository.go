@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cci-api/internal/database"
+	"cci-api/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type VisitorRepository struct {
+	db         *database.Database
+	collection *mongo.Collection
+}
+
+func NewVisitorRepository(db *database.Database) *VisitorRepository {
+	return &VisitorRepository{
+		db:         db,
+		collection: db.Collection("visitors"),
+	}
+}
+
+func (r *VisitorRepository) Create(ctx context.Context, visitor *models.VisitorProfile) error {
+	visitor.DateAdded = time.Now()
+	visitor.DateUpdated = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, visitor)
+	if err != nil {
+		return err
+	}
+
+	visitor.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *VisitorRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*models.VisitorProfile, error) {
+	var visitor models.VisitorProfile
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&visitor)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &visitor, nil
+}
+
+// GetUnmergedByPhone returns the most recent visitor profile with the phone number that has not been merged into an account
+func (r *VisitorRepository) GetUnmergedByPhone(ctx context.Context, phone string) (*models.VisitorProfile, error) {
+	return r.getUnmerged(ctx, bson.M{"phone_number": phone})
+}
+
+// GetUnmergedByEmail returns the most recent visitor profile with the email that has not been merged into an account
+func (r *VisitorRepository) GetUnmergedByEmail(ctx context.Context, email string) (*models.VisitorProfile, error) {
+	return r.getUnmerged(ctx, bson.M{"email": email})
+}
+
+func (r *VisitorRepository) getUnmerged(ctx context.Context, filter bson.M) (*models.VisitorProfile, error) {
+	filter["merged_into"] = bson.M{"$exists": false}
+	findOptions := options.FindOne().SetSort(bson.D{{Key: "date_added", Value: -1}})
+
+	var visitor models.VisitorProfile
+	err := r.collection.FindOne(ctx, filter, findOptions).Decode(&visitor)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &visitor, nil
+}
+
+// MarkMerged records that the visitor registered as the given user. It reports false if the profile was already merged.
+func (r *VisitorRepository) MarkMerged(ctx context.Context, id primitive.ObjectID, userID string) (bool, error) {
+	now := time.Now()
+	filter := bson.M{
+		"_id":         id,
+		"merged_into": bson.M{"$exists": false},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"merged_into":  userID,
+			"merged_at":    now,
+			"date_updated": now,
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
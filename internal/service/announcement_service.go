@@ -15,19 +15,28 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+var (
+	// ErrAnnouncementNotFound is returned when an announcement does not exist
+	ErrAnnouncementNotFound = newNotFoundError("announcement not found")
+	// ErrNotAnnouncementAuthor is returned when someone other than an admin or the announcement's author changes it
+	ErrNotAnnouncementAuthor = newForbiddenError("only the author can change this announcement")
+)
+
 type AnnouncementService struct {
 	config           *config.Config
 	announcementRepo repository.AnnouncementRepository
+	userRepo         *repository.UserRepository
 }
 
-func NewAnnouncementService(cfg *config.Config, announcementRepo *repository.AnnouncementRepository) *AnnouncementService {
+func NewAnnouncementService(cfg *config.Config, announcementRepo *repository.AnnouncementRepository, userRepo *repository.UserRepository) *AnnouncementService {
 	return &AnnouncementService{
 		config:           cfg,
 		announcementRepo: *announcementRepo,
+		userRepo:         userRepo,
 	}
 }
 
-func (s *AnnouncementService) CreateAnnouncement(ctx context.Context, req *dto.CreateAnnouncementRequest, userID primitive.ObjectID) (*dto.AnnouncementResponse, error) {
+func (s *AnnouncementService) CreateAnnouncement(ctx context.Context, req *dto.CreateAnnouncementRequest, userID string) (*dto.AnnouncementResponse, error) {
 	// Validate request
 	if req.Title == "" {
 		return nil, errors.New("announcement title is required")
@@ -37,6 +46,11 @@ func (s *AnnouncementService) CreateAnnouncement(ctx context.Context, req *dto.C
 		return nil, errors.New("announcement content is required")
 	}
 
+	author, err := resolveAuthor(ctx, s.userRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+
 	announcement_due_date, _ := time.Parse("2006-01-02", req.AnnouncementDueDate)
 	start_date, _ := time.Parse("2006-01-02", req.StartDate)
 	end_date, _ := time.Parse("2006-01-02", req.EndDate)
@@ -52,13 +66,13 @@ func (s *AnnouncementService) CreateAnnouncement(ctx context.Context, req *dto.C
 		Priority:                req.Priority,
 		TargetUsers:             req.TargetUsers,
 		ImageUrl:                req.ImageUrl,
-		AnnouncementEntryMadeBy: userID,
+		AnnouncementEntryMadeBy: author,
 		Status:                  "Pending",
 		DateAdded:               time.Now(),
 		DateUpdated:             time.Now(),
 	}
 
-	err := s.announcementRepo.Create(ctx, announcement)
+	err = s.announcementRepo.Create(ctx, announcement)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create announcement: %w", err)
 	}
@@ -135,6 +149,9 @@ func (s *AnnouncementService) GetAnnouncementByID(ctx context.Context, id string
 	if err != nil {
 		return nil, fmt.Errorf("failed to get announcement: %w", err)
 	}
+	if announcement == nil {
+		return nil, ErrAnnouncementNotFound
+	}
 
 	return &dto.AnnouncementResponse{
 		ID:                      announcement.ID.Hex(),
@@ -154,15 +171,11 @@ func (s *AnnouncementService) GetAnnouncementByID(ctx context.Context, id string
 	}, nil
 }
 
-func (s *AnnouncementService) UpdateAnnouncement(ctx context.Context, id string, req *dto.UpdateAnnouncementRequest) (*dto.AnnouncementResponse, error) {
-	objID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return nil, errors.New("invalid announcement ID")
-	}
-
-	announcement, err := s.announcementRepo.GetByID(ctx, objID)
+// UpdateAnnouncement changes an announcement; only admins and the announcement's author may
+func (s *AnnouncementService) UpdateAnnouncement(ctx context.Context, id string, req *dto.UpdateAnnouncementRequest, userID string, isAdmin bool) (*dto.AnnouncementResponse, error) {
+	announcement, err := s.getAuthoredAnnouncement(ctx, id, userID, isAdmin)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get announcement: %w", err)
+		return nil, err
 	}
 	announcement_due_date, _ := time.Parse("2006-01-02", req.AnnouncementDueDate)
 	start_date, _ := time.Parse("2006-01-02", req.StartDate)
@@ -176,22 +189,22 @@ func (s *AnnouncementService) UpdateAnnouncement(ctx context.Context, id string,
 		announcement.AnnouncementContent = req.AnnouncementContent
 	}
 	if req.Title == "" {
-		return nil, errors.New("announcement title is required")
+		return nil, newValidationError("announcement title is required")
 	}
 	if req.AnnouncementContent == "" {
-		return nil, errors.New("announcement content is required")
+		return nil, newValidationError("announcement content is required")
 	}
 	if req.StartDate == "" {
 		announcement.StartDate = start_date
-		return nil, errors.New("no new announcement start date was submitted")
+		return nil, newValidationError("no new announcement start date was submitted")
 	}
 	if req.EndDate == "" {
 		announcement.EndDate = end_date
-		return nil, errors.New("no new announcement end date was submitted")
+		return nil, newValidationError("no new announcement end date was submitted")
 	}
 	if req.AnnouncementDueDate == "" {
 		announcement.AnnouncementDueDate = announcement_due_date
-		return nil, errors.New("no new announcement due date was submitted")
+		return nil, newValidationError("no new announcement due date was submitted")
 	}
 	// if !req.StartDate.IsZero() {
 	// 	announcement.StartDate = req.StartDate
@@ -223,18 +236,47 @@ func (s *AnnouncementService) UpdateAnnouncement(ctx context.Context, id string,
 	}, nil
 }
 
-func (s *AnnouncementService) DeleteAnnouncement(ctx context.Context, id string) error {
-	objID, err := primitive.ObjectIDFromHex(id)
+// DeleteAnnouncement removes an announcement; only admins and the announcement's author may
+func (s *AnnouncementService) DeleteAnnouncement(ctx context.Context, id, userID string, isAdmin bool) error {
+	announcement, err := s.getAuthoredAnnouncement(ctx, id, userID, isAdmin)
 	if err != nil {
-		return errors.New("invalid announcement ID")
+		return err
 	}
 
-	if err := s.announcementRepo.Delete(ctx, objID); err != nil {
+	if err := s.announcementRepo.Delete(ctx, announcement.ID); err != nil {
 		return fmt.Errorf("failed to delete announcement: %w", err)
 	}
 	return nil
 }
 
+// getAuthoredAnnouncement loads an announcement, allowing only admins and its author
+func (s *AnnouncementService) getAuthoredAnnouncement(ctx context.Context, id, userID string, isAdmin bool) (*models.Announcement, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, newValidationError("invalid announcement ID")
+	}
+
+	announcement, err := s.announcementRepo.GetByID(ctx, objID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get announcement: %w", err)
+	}
+	if announcement == nil {
+		return nil, ErrAnnouncementNotFound
+	}
+	if isAdmin {
+		return announcement, nil
+	}
+
+	author, err := resolveAuthor(ctx, s.userRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+	if announcement.AnnouncementEntryMadeBy != author {
+		return nil, ErrNotAnnouncementAuthor
+	}
+	return announcement, nil
+}
+
 func (s *AnnouncementService) GetActiveAnnouncements(ctx context.Context, page, limit int) (*dto.PaginatedAnnouncementsResponse, error) {
 	if page < 1 {
 		page = 1
@@ -0,0 +1,403 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"cci-api/internal/dto"
+	"cci-api/internal/repository"
+	"cci-api/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Attendance trend intervals
+const (
+	TrendIntervalDay   = "day"
+	TrendIntervalWeek  = "week"
+	TrendIntervalMonth = "month"
+)
+
+const (
+	// maxTrendPoints caps how many periods a trend can cover
+	maxTrendPoints = 370
+	// maxMovingAverageWindow caps how many periods a moving average can span
+	maxMovingAverageWindow = 52
+)
+
+// retentionWeeks are the checkpoints first-timer retention is reported at
+var retentionWeeks = []int{4, 8, 12}
+
+// trendPeriod describes how a trend interval splits time: where a period starts, where the next one starts, and
+// how it is labelled, both in Go and as a Mongo $dateToString format. Like utils.DayBounds, start reads the
+// calendar date from t as given.
+type trendPeriod struct {
+	start       func(t time.Time, loc *time.Location) time.Time
+	next        func(t time.Time) time.Time
+	label       func(t time.Time) string
+	mongoFormat string
+}
+
+var trendPeriods = map[string]trendPeriod{
+	TrendIntervalDay: {
+		start:       func(t time.Time, loc *time.Location) time.Time { day, _ := utils.DayBounds(t, loc); return day },
+		next:        func(t time.Time) time.Time { return t.AddDate(0, 0, 1) },
+		label:       func(t time.Time) string { return t.Format("2006-01-02") },
+		mongoFormat: "%Y-%m-%d",
+	},
+	TrendIntervalWeek: {
+		start:       startOfWeek,
+		next:        func(t time.Time) time.Time { return t.AddDate(0, 0, 7) },
+		label:       func(t time.Time) string { year, week := t.ISOWeek(); return fmt.Sprintf("%d-W%02d", year, week) },
+		mongoFormat: "%G-W%V",
+	},
+	TrendIntervalMonth: {
+		start: func(t time.Time, loc *time.Location) time.Time {
+			return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+		},
+		next:        func(t time.Time) time.Time { return t.AddDate(0, 1, 0) },
+		label:       func(t time.Time) string { return t.Format("2006-01") },
+		mongoFormat: "%Y-%m",
+	},
+}
+
+// startOfWeek returns midnight on the Monday of the ISO week containing t
+func startOfWeek(t time.Time, loc *time.Location) time.Time {
+	day, _ := utils.DayBounds(t, loc)
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// GetAttendanceTrend splits attendance between startDate and endDate into days, weeks or months, with a
+// trailing moving average of the total over window periods. Weeks run Monday to Sunday. It defaults to the
+// last 12 periods.
+func (s *AttendanceService) GetAttendanceTrend(ctx context.Context, churchID string, startDate, endDate *time.Time, interval string, window int) (*dto.AttendanceTrendResponse, error) {
+	if interval == "" {
+		interval = TrendIntervalWeek
+	}
+	period, ok := trendPeriods[interval]
+	if !ok {
+		return nil, errors.New("interval must be one of day, week or month")
+	}
+	if window < 1 || window > maxMovingAverageWindow {
+		return nil, fmt.Errorf("window must be between 1 and %d", maxMovingAverageWindow)
+	}
+
+	scope, err := s.reportScopeFor(ctx, churchID, "")
+	if err != nil {
+		return nil, err
+	}
+	loc := scope.loc
+
+	now := time.Now().In(loc)
+	if endDate == nil {
+		endDate = &now
+	}
+	_, rangeEnd := utils.DayBounds(*endDate, loc)
+
+	var start time.Time
+	if startDate != nil {
+		start = period.start(*startDate, loc)
+	} else {
+		// The last 12 periods up to and including the one endDate falls in
+		start = period.start(*endDate, loc)
+		for i := 0; i < 11; i++ {
+			start = period.start(start.Add(-time.Hour), loc)
+		}
+	}
+	if !start.Before(rangeEnd) {
+		return nil, errors.New("start_date must be before end_date")
+	}
+
+	var starts []time.Time
+	for cursor := start; cursor.Before(rangeEnd); cursor = period.next(cursor) {
+		if len(starts) == maxTrendPoints {
+			return nil, fmt.Errorf("date range covers more than %d periods, use a longer interval", maxTrendPoints)
+		}
+		starts = append(starts, cursor)
+	}
+	// Whole periods are reported, so the last one can run past end_date
+	queryEnd := period.next(starts[len(starts)-1])
+
+	buckets, err := s.attendanceRepo.GetAttendanceTrend(ctx, scope.events, start, queryEnd, period.mongoFormat, loc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attendance trend: %w", err)
+	}
+	byPeriod := make(map[string]repository.AttendanceTotals, len(buckets))
+	for _, bucket := range buckets {
+		byPeriod[bucket.Period] = bucket
+	}
+
+	points := make([]dto.AttendanceTrendPoint, 0, len(starts))
+	sum := 0
+	for i, periodStart := range starts {
+		label := period.label(periodStart)
+		bucket := byPeriod[label]
+
+		sum += bucket.Total
+		if i >= window {
+			sum -= points[i-window].Total
+		}
+		span := window
+		if i+1 < window {
+			span = i + 1
+		}
+
+		points = append(points, dto.AttendanceTrendPoint{
+			Period:        label,
+			StartDate:     periodStart.Format("2006-01-02"),
+			Total:         bucket.Total,
+			Members:       bucket.Members,
+			Visitors:      bucket.Visitors,
+			Unique:        bucket.Unique,
+			FirstTimers:   bucket.FirstTimers,
+			MovingAverage: math.Round(float64(sum)/float64(span)*10) / 10,
+		})
+	}
+
+	return &dto.AttendanceTrendResponse{
+		Interval:  interval,
+		Window:    window,
+		StartDate: start.Format("2006-01-02"),
+		EndDate:   queryEnd.AddDate(0, 0, -1).Format("2006-01-02"),
+		Points:    points,
+	}, nil
+}
+
+// GetAttendanceComparisons compares the week (Monday to Sunday) containing date with the week before it and
+// with the same week a year earlier, 52 weeks back so weekdays line up
+func (s *AttendanceService) GetAttendanceComparisons(ctx context.Context, churchID string, date *time.Time) (*dto.AttendanceComparisonResponse, error) {
+	scope, err := s.reportScopeFor(ctx, churchID, "")
+	if err != nil {
+		return nil, err
+	}
+	loc := scope.loc
+
+	if date == nil {
+		now := time.Now().In(loc)
+		date = &now
+	}
+	week := startOfWeek(*date, loc)
+
+	current, err := s.periodSummary(ctx, scope.events, week, week.AddDate(0, 0, 7))
+	if err != nil {
+		return nil, err
+	}
+	previousWeek, err := s.periodSummary(ctx, scope.events, week.AddDate(0, 0, -7), week)
+	if err != nil {
+		return nil, err
+	}
+	lastYear := week.AddDate(0, 0, -7*52)
+	previousYear, err := s.periodSummary(ctx, scope.events, lastYear, lastYear.AddDate(0, 0, 7))
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.AttendanceComparisonResponse{
+		WeekOverWeek: compareAttendance(current, previousWeek),
+		YearOverYear: compareAttendance(current, previousYear),
+	}, nil
+}
+
+func (s *AttendanceService) periodSummary(ctx context.Context, events []primitive.ObjectID, start, end time.Time) (dto.AttendancePeriodSummary, error) {
+	totals, err := s.attendanceRepo.GetAttendanceTotals(ctx, events, start, end)
+	if err != nil {
+		return dto.AttendancePeriodSummary{}, fmt.Errorf("failed to get attendance totals: %w", err)
+	}
+
+	return dto.AttendancePeriodSummary{
+		StartDate:   start.Format("2006-01-02"),
+		EndDate:     end.AddDate(0, 0, -1).Format("2006-01-02"),
+		Total:       totals.Total,
+		Members:     totals.Members,
+		Visitors:    totals.Visitors,
+		Unique:      totals.Unique,
+		FirstTimers: totals.FirstTimers,
+	}, nil
+}
+
+func compareAttendance(current, previous dto.AttendancePeriodSummary) dto.AttendanceComparison {
+	comparison := dto.AttendanceComparison{
+		Current:  current,
+		Previous: previous,
+		Change:   current.Total - previous.Total,
+	}
+	if previous.Total > 0 {
+		percent := math.Round(float64(comparison.Change)/float64(previous.Total)*1000) / 10
+		comparison.ChangePercent = &percent
+	}
+	return comparison
+}
+
+// GetAttendanceBreakdown splits attendance between startDate and endDate by gender, age band, campus, work
+// department and check-in method. It defaults to the last 30 days.
+func (s *AttendanceService) GetAttendanceBreakdown(ctx context.Context, churchID string, startDate, endDate *time.Time) (*dto.AttendanceBreakdownResponse, error) {
+	scope, err := s.reportScopeFor(ctx, churchID, "")
+	if err != nil {
+		return nil, err
+	}
+	loc := scope.loc
+
+	rangeStart, rangeEnd := analyticsRange(startDate, endDate, loc, 0, 0, -30)
+	if !rangeStart.Before(rangeEnd) {
+		return nil, errors.New("start_date must be before end_date")
+	}
+
+	breakdown, err := s.attendanceRepo.GetAttendanceBreakdown(ctx, scope.events, rangeStart, rangeEnd, rangeEnd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attendance breakdown: %w", err)
+	}
+
+	// Every record has exactly one check-in method, so those buckets add up to the total
+	total := 0
+	for _, bucket := range breakdown.CheckinMethod {
+		total += bucket.Attendance
+	}
+
+	return &dto.AttendanceBreakdownResponse{
+		StartDate:     rangeStart.Format("2006-01-02"),
+		EndDate:       rangeEnd.AddDate(0, 0, -1).Format("2006-01-02"),
+		Total:         total,
+		Gender:        toBreakdownItems(breakdown.Gender, total),
+		AgeBand:       toBreakdownItems(breakdown.AgeBand, total),
+		Campus:        toBreakdownItems(breakdown.Campus, total),
+		Department:    toBreakdownItems(breakdown.Department, total),
+		CheckinMethod: toBreakdownItems(breakdown.CheckinMethod, total),
+	}, nil
+}
+
+func toBreakdownItems(buckets []repository.BreakdownBucket, total int) []dto.AttendanceBreakdownItem {
+	items := make([]dto.AttendanceBreakdownItem, 0, len(buckets))
+	unknown := -1
+	for _, bucket := range buckets {
+		// Missing and empty values are both reported as unknown
+		if bucket.Key == "" {
+			if unknown >= 0 {
+				items[unknown].Attendance += bucket.Attendance
+				items[unknown].Unique += bucket.Unique
+				continue
+			}
+			unknown = len(items)
+			bucket.Key = "unknown"
+		}
+		items = append(items, dto.AttendanceBreakdownItem{
+			Value:      bucket.Key,
+			Attendance: bucket.Attendance,
+			Unique:     bucket.Unique,
+		})
+	}
+
+	for i := range items {
+		if total > 0 {
+			items[i].Percent = math.Round(float64(items[i].Attendance)/float64(total)*1000) / 10
+		}
+	}
+	return items
+}
+
+// GetFirstTimerRetention looks at everyone whose first visit was between startDate and endDate and reports how
+// many came back 4, 8 and 12 weeks or more after it. It defaults to first visits in the last 26 weeks.
+func (s *AttendanceService) GetFirstTimerRetention(ctx context.Context, churchID string, startDate, endDate *time.Time) (*dto.FirstTimerRetentionResponse, error) {
+	scope, err := s.reportScopeFor(ctx, churchID, "")
+	if err != nil {
+		return nil, err
+	}
+	loc := scope.loc
+
+	rangeStart, rangeEnd := analyticsRange(startDate, endDate, loc, 0, 0, -7*26)
+	if !rangeStart.Before(rangeEnd) {
+		return nil, errors.New("start_date must be before end_date")
+	}
+
+	firstTimers, windows, err := s.attendanceRepo.GetFirstTimerRetention(ctx, scope.events, rangeStart, rangeEnd, time.Now(), retentionWeeks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get first-timer retention: %w", err)
+	}
+
+	retention := make([]dto.RetentionWindow, 0, len(windows))
+	for _, window := range windows {
+		item := dto.RetentionWindow{
+			Weeks:    window.Weeks,
+			Eligible: window.Eligible,
+			Retained: window.Retained,
+		}
+		if window.Eligible > 0 {
+			rate := math.Round(float64(window.Retained)/float64(window.Eligible)*1000) / 10
+			item.Rate = &rate
+		}
+		retention = append(retention, item)
+	}
+
+	return &dto.FirstTimerRetentionResponse{
+		StartDate:   rangeStart.Format("2006-01-02"),
+		EndDate:     rangeEnd.AddDate(0, 0, -1).Format("2006-01-02"),
+		FirstTimers: firstTimers,
+		Retention:   retention,
+	}, nil
+}
+
+// analyticsRange turns optional start and end dates into whole days in loc. A missing end date is today and a
+// missing start date is the end date moved back by the given years, months and days.
+func analyticsRange(startDate, endDate *time.Time, loc *time.Location, years, months, days int) (time.Time, time.Time) {
+	if endDate == nil {
+		now := time.Now().In(loc)
+		endDate = &now
+	}
+	if startDate == nil {
+		start := endDate.AddDate(years, months, days)
+		startDate = &start
+	}
+
+	rangeStart, _ := utils.DayBounds(*startDate, loc)
+	_, rangeEnd := utils.DayBounds(*endDate, loc)
+	return rangeStart, rangeEnd
+}
+
+// GetHouseholdAttendance lists the households checked in together between startDate and endDate, with how many
+// services each attended and how many of the household usually come. It defaults to the last 3 months.
+func (s *AttendanceService) GetHouseholdAttendance(ctx context.Context, churchID string, startDate, endDate *time.Time, page, limit int) (*dto.PaginatedResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	scope, err := s.reportScopeFor(ctx, churchID, "")
+	if err != nil {
+		return nil, err
+	}
+	loc := scope.loc
+
+	rangeStart, rangeEnd := analyticsRange(startDate, endDate, loc, 0, -3, 0)
+	if !rangeStart.Before(rangeEnd) {
+		return nil, errors.New("start_date must be before end_date")
+	}
+
+	households, total, err := s.attendanceRepo.GetHouseholdAttendance(ctx, scope.events, rangeStart, rangeEnd, page, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get household attendance: %w", err)
+	}
+
+	items := make([]dto.HouseholdAttendanceItem, 0, len(households))
+	for _, household := range households {
+		items = append(items, dto.HouseholdAttendanceItem{
+			FamilyHead:       household.FamilyHead,
+			FirstName:        household.FirstName,
+			LastName:         household.LastName,
+			ServicesAttended: household.ServicesAttended,
+			Attendance:       household.Attendance,
+			People:           household.People,
+			AveragePartySize: math.Round(household.AveragePartySize*10) / 10,
+			LastAttended:     household.LastAttended,
+		})
+	}
+
+	return &dto.PaginatedResponse{
+		Data:       items,
+		Pagination: utils.NewPagination(page, limit, total),
+	}, nil
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cci-api/internal/dto"
+	"cci-api/internal/models"
+	"cci-api/internal/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestStartOfWeek(t *testing.T) {
+	tests := []struct {
+		day  time.Time
+		want string
+	}{
+		{time.Date(2025, 8, 18, 10, 0, 0, 0, lagos), "2025-08-18"},
+		{time.Date(2025, 8, 20, 10, 0, 0, 0, lagos), "2025-08-18"},
+		{time.Date(2025, 8, 24, 23, 0, 0, 0, lagos), "2025-08-18"},
+		{time.Date(2025, 1, 1, 9, 0, 0, 0, lagos), "2024-12-30"},
+	}
+
+	for _, tt := range tests {
+		got := startOfWeek(tt.day, lagos)
+		if got.Format("2006-01-02") != tt.want || got.Hour() != 0 {
+			t.Errorf("startOfWeek(%s) = %v, want midnight on %s", tt.day.Format("Mon 2006-01-02"), got, tt.want)
+		}
+	}
+
+	// Weeks are labelled by ISO year, so the last days of December can belong to the next year's first week
+	if label := trendPeriods[TrendIntervalWeek].label(time.Date(2024, 12, 30, 0, 0, 0, 0, lagos)); label != "2025-W01" {
+		t.Errorf("week label = %q, want 2025-W01", label)
+	}
+}
+
+func TestCompareAttendance(t *testing.T) {
+	grew := compareAttendance(dto.AttendancePeriodSummary{Total: 130}, dto.AttendancePeriodSummary{Total: 120})
+	if grew.Change != 10 || grew.ChangePercent == nil || *grew.ChangePercent != 8.3 {
+		t.Errorf("compareAttendance(130, 120) = %+v, want +10 and 8.3%%", grew)
+	}
+
+	fromNothing := compareAttendance(dto.AttendancePeriodSummary{Total: 40}, dto.AttendancePeriodSummary{})
+	if fromNothing.Change != 40 || fromNothing.ChangePercent != nil {
+		t.Errorf("compareAttendance(40, 0) = %+v, want +40 with no percentage", fromNothing)
+	}
+}
+
+func TestToBreakdownItems(t *testing.T) {
+	items := toBreakdownItems([]repository.BreakdownBucket{
+		{Key: "Female", Attendance: 60, Unique: 30},
+		{Key: "", Attendance: 5, Unique: 4},
+		{Key: "Male", Attendance: 30, Unique: 15},
+		{Key: "", Attendance: 5, Unique: 1},
+	}, 100)
+
+	want := []dto.AttendanceBreakdownItem{
+		{Value: "Female", Attendance: 60, Unique: 30, Percent: 60},
+		{Value: "unknown", Attendance: 10, Unique: 5, Percent: 10},
+		{Value: "Male", Attendance: 30, Unique: 15, Percent: 30},
+	}
+	if len(items) != len(want) {
+		t.Fatalf("toBreakdownItems = %+v, want %+v", items, want)
+	}
+	for i := range want {
+		if items[i] != want[i] {
+			t.Errorf("item %d = %+v, want %+v", i, items[i], want[i])
+		}
+	}
+}
+
+func TestGetAttendanceTrend(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	church := &models.LocalChurch{ID: primitive.NewObjectID(), ChurchName: "Ikeja", Timezone: "Africa/Lagos"}
+	start := time.Date(2025, 8, 4, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 8, 24, 0, 0, 0, 0, time.UTC)
+
+	mt.Run("weeks with a moving average", func(mt *mtest.T) {
+		week := func(label string, total, members, visitors int) bson.D {
+			return bson.D{
+				{Key: "_id", Value: label},
+				{Key: "total", Value: total},
+				{Key: "members", Value: members},
+				{Key: "visitors", Value: visitors},
+			}
+		}
+		mt.AddMockResponses(
+			mockFound(mt, "local_churches", church),
+			mockFound(mt, "attendance", bson.D{
+				// The week of 11 August had no attendance at all
+				{Key: "totals", Value: bson.A{week("2025-W32", 120, 100, 20), week("2025-W34", 90, 80, 10)}},
+				{Key: "first_timers", Value: bson.A{bson.D{{Key: "_id", Value: "2025-W34"}, {Key: "count", Value: 4}}}},
+			}),
+		)
+
+		resp, err := newMockAttendanceService(mt).GetAttendanceTrend(context.Background(), "", &start, &end, TrendIntervalWeek, 2)
+		if err != nil {
+			t.Fatalf("GetAttendanceTrend: %v", err)
+		}
+
+		want := []dto.AttendanceTrendPoint{
+			{Period: "2025-W32", StartDate: "2025-08-04", Total: 120, Members: 100, Visitors: 20, MovingAverage: 120},
+			{Period: "2025-W33", StartDate: "2025-08-11", MovingAverage: 60},
+			{Period: "2025-W34", StartDate: "2025-08-18", Total: 90, Members: 80, Visitors: 10, FirstTimers: 4, MovingAverage: 45},
+		}
+		if len(resp.Points) != len(want) {
+			t.Fatalf("points = %+v, want %+v", resp.Points, want)
+		}
+		for i := range want {
+			if resp.Points[i] != want[i] {
+				t.Errorf("point %d = %+v, want %+v", i, resp.Points[i], want[i])
+			}
+		}
+		if resp.EndDate != "2025-08-24" {
+			t.Errorf("end date = %s, want the Sunday closing the last week", resp.EndDate)
+		}
+	})
+
+	mt.Run("bad options are refused before querying", func(mt *mtest.T) {
+		s := newMockAttendanceService(mt)
+		if _, err := s.GetAttendanceTrend(context.Background(), "", nil, nil, "fortnight", 4); err == nil {
+			t.Error("GetAttendanceTrend accepted an unknown interval")
+		}
+		if _, err := s.GetAttendanceTrend(context.Background(), "", nil, nil, TrendIntervalWeek, maxMovingAverageWindow+1); err == nil {
+			t.Error("GetAttendanceTrend accepted an oversized window")
+		}
+		if commands := sentCommands(mt); len(commands) > 0 {
+			t.Errorf("sent %v, want nothing", commands)
+		}
+	})
+}
@@ -0,0 +1,376 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"cci-api/internal/dto"
+	"cci-api/internal/models"
+	"cci-api/internal/repository"
+	"cci-api/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Attendance export reports
+const (
+	ExportReportDaily   = "daily"
+	ExportReportRecords = "records"
+	ExportReportMatrix  = "matrix"
+)
+
+// Attendance export formats
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatXLSX = "xlsx"
+)
+
+// exportFlushRows is how many CSV rows are written between flushes to the client
+const exportFlushRows = 500
+
+var exportContentTypes = map[string]string{
+	ExportFormatCSV:  "text/csv; charset=utf-8",
+	ExportFormatXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// AttendanceExport is an attendance export ready to be written. CSV exports are streamed from the database as
+// they are written. XLSX exports are built in full beforehand, so a failed query is still reported as an error
+// rather than a truncated file.
+type AttendanceExport struct {
+	Filename    string
+	ContentType string
+	write       func(w io.Writer) error
+}
+
+// Write writes the export to w
+func (e *AttendanceExport) Write(w io.Writer) error {
+	return e.write(w)
+}
+
+// exportRows produces the rows of an export, each with the month (YYYY-MM) it belongs to
+type exportRows func(fn func(month string, row []interface{}) error) error
+
+// ExportAttendance prepares an export of the daily summaries, the individual check-ins or the member attendance
+// matrix (members down the side, services across the top) for a date range. The history filters and sort
+// options apply; the matrix is always sorted by name. XLSX exports have one sheet per month.
+func (s *AttendanceService) ExportAttendance(ctx context.Context, report, format string, startDate, endDate *time.Time, filter dto.AttendanceHistoryFilter) (*AttendanceExport, error) {
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		return nil, fmt.Errorf("%w: format must be either '%s' or '%s'", ErrInvalidHistoryFilter, ExportFormatCSV, ExportFormatXLSX)
+	}
+
+	scope, err := s.reportScopeFor(ctx, filter.ChurchID, filter.EventID)
+	if err != nil {
+		return nil, err
+	}
+	loc := scope.loc
+
+	historyFilter, err := historyFilterFor(startDate, endDate, filter, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &AttendanceExport{
+		Filename: fmt.Sprintf("attendance-%s-%s-to-%s.%s", report,
+			historyFilter.StartDate.In(loc).Format("2006-01-02"),
+			historyFilter.EndDate.In(loc).AddDate(0, 0, -1).Format("2006-01-02"), format),
+		ContentType: contentType,
+	}
+
+	var header []string
+	var rows exportRows
+	switch report {
+	case ExportReportDaily:
+		header, rows, err = s.dailyExportRows(ctx, historyFilter, filter, loc)
+	case ExportReportRecords:
+		header, rows, err = s.recordExportRows(ctx, historyFilter, filter, loc)
+	case ExportReportMatrix:
+		return s.matrixExport(ctx, export, format, historyFilter, loc)
+	default:
+		return nil, fmt.Errorf("%w: report must be one of %s, %s or %s", ErrInvalidHistoryFilter,
+			ExportReportDaily, ExportReportRecords, ExportReportMatrix)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if format == ExportFormatCSV {
+		export.write = func(w io.Writer) error {
+			return writeCSVExport(w, header, rows)
+		}
+		return export, nil
+	}
+
+	sheets, err := monthlySheets(header, rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build export: %w", err)
+	}
+	export.write = func(w io.Writer) error {
+		return utils.WriteXLSX(w, sheets)
+	}
+	return export, nil
+}
+
+func (s *AttendanceService) dailyExportRows(ctx context.Context, historyFilter repository.AttendanceHistoryFilter, filter dto.AttendanceHistoryFilter, loc *time.Location) ([]string, exportRows, error) {
+	sortField, ascending, err := historySortFor(dailyHistorySorts, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := []string{"Date", "Total attendance", "Members", "Visitors", "Average stay (minutes)", "Not checked out"}
+	rows := func(fn func(month string, row []interface{}) error) error {
+		return s.attendanceRepo.EachDailySummary(ctx, historyFilter, loc, sortField, ascending, func(summary *repository.DailyAttendanceSummary) error {
+			return fn(summary.Date[:7], []interface{}{
+				summary.Date,
+				summary.TotalAttendance,
+				summary.Members,
+				summary.Visitors,
+				math.Round(summary.AverageStayMinutes*10) / 10,
+				summary.NotCheckedOut,
+			})
+		})
+	}
+	return header, rows, nil
+}
+
+func (s *AttendanceService) recordExportRows(ctx context.Context, historyFilter repository.AttendanceHistoryFilter, filter dto.AttendanceHistoryFilter, loc *time.Location) ([]string, exportRows, error) {
+	sortField, ascending, err := historySortFor(recordHistorySorts, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := []string{"Attendance ID", "User ID", "Visitor profile ID", "First name", "Last name", "Email", "Phone number",
+		"Campus", "Event", "Event type", "Checked in at", "Check-in method", "Late", "Minutes late", "Member", "Visitor", "Checked out at"}
+	rows := func(fn func(month string, row []interface{}) error) error {
+		return s.attendanceRepo.EachHistoryRecord(ctx, historyFilter, sortField, ascending, func(record *repository.AttendanceHistoryRecord) error {
+			checkedIn := record.DateTimeOfAttendance.In(loc)
+			var visitorProfileID, checkedOut string
+			if record.VisitorProfile != nil {
+				visitorProfileID = record.VisitorProfile.Hex()
+			}
+			if record.CheckOutTime != nil {
+				checkedOut = record.CheckOutTime.In(loc).Format("2006-01-02 15:04")
+			}
+
+			return fn(checkedIn.Format("2006-01"), []interface{}{
+				record.ID.Hex(),
+				record.UserID,
+				visitorProfileID,
+				record.FirstName,
+				record.LastName,
+				record.Email,
+				record.PhoneNumber,
+				record.Campus,
+				record.EventName,
+				record.EventType,
+				checkedIn.Format("2006-01-02 15:04"),
+				record.CheckinMethod,
+				record.Late,
+				record.MinutesLate,
+				record.Member,
+				record.Visitor,
+				checkedOut,
+			})
+		})
+	}
+	return header, rows, nil
+}
+
+// matrixExport prepares the member attendance matrix. Each service is a column holding 1 for members who
+// attended it and 0 for those who did not, followed by each member's total. In XLSX exports each month's sheet
+// only has that month's services.
+func (s *AttendanceService) matrixExport(ctx context.Context, export *AttendanceExport, format string, historyFilter repository.AttendanceHistoryFilter, loc *time.Location) (*AttendanceExport, error) {
+	events, err := s.serviceEventRepo.GetStartedBetween(ctx, historyFilter.StartDate, historyFilter.EndDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service events: %w", err)
+	}
+	if historyFilter.EventID != nil || historyFilter.Events != nil {
+		var selected []*models.ServiceEvent
+		for _, event := range events {
+			if historyFilter.EventID != nil && event.ID != *historyFilter.EventID {
+				continue
+			}
+			if historyFilter.Events != nil && !containsObjectID(historyFilter.Events, event.ID) {
+				continue
+			}
+			selected = append(selected, event)
+		}
+		events = selected
+	}
+
+	eventIDs := make([]primitive.ObjectID, 0, len(events))
+	for _, event := range events {
+		eventIDs = append(eventIDs, event.ID)
+	}
+
+	eachMember := func(fn func(*repository.MemberEventAttendance) error) error {
+		return s.userRepo.EachMemberAttendance(ctx, eventIDs, historyFilter.Campus, historyFilter.CheckinMethod, fn)
+	}
+
+	if format == ExportFormatCSV {
+		header, rows := matrixRows(events, eachMember, loc)
+		export.write = func(w io.Writer) error {
+			return writeCSVExport(w, header, rows)
+		}
+		return export, nil
+	}
+
+	var members []*repository.MemberEventAttendance
+	if err := eachMember(func(member *repository.MemberEventAttendance) error {
+		members = append(members, member)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to get member attendance: %w", err)
+	}
+	loaded := func(fn func(*repository.MemberEventAttendance) error) error {
+		for _, member := range members {
+			if err := fn(member); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var months []string
+	eventsByMonth := map[string][]*models.ServiceEvent{}
+	for _, event := range events {
+		month := event.StartTime.In(loc).Format("2006-01")
+		if _, ok := eventsByMonth[month]; !ok {
+			months = append(months, month)
+		}
+		eventsByMonth[month] = append(eventsByMonth[month], event)
+	}
+
+	var sheets []utils.XLSXSheet
+	for _, month := range months {
+		header, rows := matrixRows(eventsByMonth[month], loaded, loc)
+		sheet := utils.XLSXSheet{Name: month, Rows: [][]interface{}{toCells(header)}}
+		if err := rows(func(_ string, row []interface{}) error {
+			sheet.Rows = append(sheet.Rows, row)
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("failed to build export: %w", err)
+		}
+		sheets = append(sheets, sheet)
+	}
+
+	export.write = func(w io.Writer) error {
+		return utils.WriteXLSX(w, sheets)
+	}
+	return export, nil
+}
+
+// matrixRows lays out one matrix row per member with a column for each of events
+func matrixRows(events []*models.ServiceEvent, eachMember func(func(*repository.MemberEventAttendance) error) error, loc *time.Location) ([]string, exportRows) {
+	header := []string{"User ID", "First name", "Last name", "Campus"}
+	for _, event := range events {
+		header = append(header, event.StartTime.In(loc).Format("2006-01-02 15:04")+" "+event.Name)
+	}
+	header = append(header, "Attended")
+
+	rows := func(fn func(month string, row []interface{}) error) error {
+		return eachMember(func(member *repository.MemberEventAttendance) error {
+			attended := make(map[primitive.ObjectID]bool, len(member.Attended))
+			for _, eventID := range member.Attended {
+				attended[eventID] = true
+			}
+
+			row := []interface{}{member.UserID, member.FirstName, member.LastName, member.Campus}
+			total := 0
+			for _, event := range events {
+				if attended[event.ID] {
+					row = append(row, 1)
+					total++
+				} else {
+					row = append(row, 0)
+				}
+			}
+			return fn("", append(row, total))
+		})
+	}
+	return header, rows
+}
+
+// writeCSVExport streams the rows to w as CSV, flushing regularly so large exports reach the client as they
+// are read
+func writeCSVExport(w io.Writer, header []string, rows exportRows) error {
+	cw := csv.NewWriter(w)
+	titles := make([]string, len(header))
+	for i, title := range header {
+		titles[i] = utils.EscapeSpreadsheetCell(title)
+	}
+	if err := cw.Write(titles); err != nil {
+		return err
+	}
+
+	written := 0
+	record := make([]string, len(header))
+	err := rows(func(_ string, row []interface{}) error {
+		for i, value := range row {
+			if text, ok := value.(string); ok {
+				record[i] = utils.EscapeSpreadsheetCell(text)
+			} else {
+				record[i] = fmt.Sprint(value)
+			}
+		}
+		if err := cw.Write(record[:len(row)]); err != nil {
+			return err
+		}
+
+		written++
+		if written%exportFlushRows == 0 {
+			cw.Flush()
+			if flusher, ok := w.(interface{ Flush() }); ok {
+				flusher.Flush()
+			}
+		}
+		return cw.Error()
+	})
+	if err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// monthlySheets reads every row and groups them into one sheet per month, oldest month first. Rows keep their
+// order within each sheet.
+func monthlySheets(header []string, rows exportRows) ([]utils.XLSXSheet, error) {
+	byMonth := map[string][][]interface{}{}
+	err := rows(func(month string, row []interface{}) error {
+		byMonth[month] = append(byMonth[month], row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	months := make([]string, 0, len(byMonth))
+	for month := range byMonth {
+		months = append(months, month)
+	}
+	sort.Strings(months)
+
+	sheets := make([]utils.XLSXSheet, 0, len(months))
+	for _, month := range months {
+		sheets = append(sheets, utils.XLSXSheet{
+			Name: month,
+			Rows: append([][]interface{}{toCells(header)}, byMonth[month]...),
+		})
+	}
+	return sheets, nil
+}
+
+func toCells(header []string) []interface{} {
+	cells := make([]interface{}, len(header))
+	for i, title := range header {
+		cells[i] = title
+	}
+	return cells
+}
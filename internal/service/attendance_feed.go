@@ -0,0 +1,311 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"cci-api/internal/dto"
+	"cci-api/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Live attendance message types
+const (
+	LiveMessageCheckin  = "checkin"
+	LiveMessageCounters = "counters"
+)
+
+// liveFeedBuffer is how many messages a slow subscriber can fall behind by before messages to it are dropped
+const liveFeedBuffer = 64
+
+// liveChannelTTL is how long the church an event belongs to is remembered, so a busy check-in desk does not look
+// it up on every scan. An event moved to another church is picked up once the entry expires.
+const liveChannelTTL = 5 * time.Minute
+
+// attendanceFeed fans live attendance messages out to the dashboards watching each church. It lives in memory,
+// so a dashboard only sees check-ins handled by the instance it is connected to.
+type attendanceFeed struct {
+	mu          sync.RWMutex
+	subscribers map[string]map[*feedSubscriber]struct{}
+
+	channelsMu sync.Mutex
+	channels   map[primitive.ObjectID]cachedLiveChannel // by event
+}
+
+type cachedLiveChannel struct {
+	channel   string
+	expiresAt time.Time
+}
+
+type feedSubscriber struct {
+	eventID  string // empty receives every event
+	messages chan dto.LiveAttendanceMessage
+}
+
+func newAttendanceFeed() *attendanceFeed {
+	return &attendanceFeed{
+		subscribers: map[string]map[*feedSubscriber]struct{}{},
+		channels:    map[primitive.ObjectID]cachedLiveChannel{},
+	}
+}
+
+func (f *attendanceFeed) subscribe(churchID, eventID string) *feedSubscriber {
+	subscriber := &feedSubscriber{
+		eventID:  eventID,
+		messages: make(chan dto.LiveAttendanceMessage, liveFeedBuffer),
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.subscribers[churchID] == nil {
+		f.subscribers[churchID] = map[*feedSubscriber]struct{}{}
+	}
+	f.subscribers[churchID][subscriber] = struct{}{}
+	return subscriber
+}
+
+func (f *attendanceFeed) unsubscribe(churchID string, subscriber *feedSubscriber) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.subscribers[churchID], subscriber)
+	if len(f.subscribers[churchID]) == 0 {
+		delete(f.subscribers, churchID)
+	}
+}
+
+func (f *attendanceFeed) hasSubscribers(churchID string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return len(f.subscribers[churchID]) > 0
+}
+
+// idle reports whether no dashboard is watching any church
+func (f *attendanceFeed) idle() bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return len(f.subscribers) == 0
+}
+
+func (f *attendanceFeed) cachedChannel(eventID primitive.ObjectID, now time.Time) (string, bool) {
+	f.channelsMu.Lock()
+	defer f.channelsMu.Unlock()
+	cached, ok := f.channels[eventID]
+	if !ok || now.After(cached.expiresAt) {
+		delete(f.channels, eventID)
+		return "", false
+	}
+	return cached.channel, true
+}
+
+func (f *attendanceFeed) cacheChannel(eventID primitive.ObjectID, channel string, now time.Time) {
+	f.channelsMu.Lock()
+	defer f.channelsMu.Unlock()
+	// Drop expired entries as events end rather than letting the cache grow forever
+	for id, cached := range f.channels {
+		if now.After(cached.expiresAt) {
+			delete(f.channels, id)
+		}
+	}
+	f.channels[eventID] = cachedLiveChannel{channel: channel, expiresAt: now.Add(liveChannelTTL)}
+}
+
+// publish sends a message about eventID to the church's subscribers without blocking. A subscriber too far behind
+// misses the message; the next counters message brings it back up to date.
+func (f *attendanceFeed) publish(churchID, eventID string, message dto.LiveAttendanceMessage) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for subscriber := range f.subscribers[churchID] {
+		if subscriber.eventID != "" && subscriber.eventID != eventID {
+			continue
+		}
+		select {
+		case subscriber.messages <- message:
+		default:
+		}
+	}
+}
+
+// LiveAttendanceFeed is an open subscription to a church's live attendance
+type LiveAttendanceFeed struct {
+	// Counters are the running counters for the event being watched when the feed opened, if there is one
+	Counters *dto.LiveAttendanceCounters
+	Messages <-chan dto.LiveAttendanceMessage
+	close    func()
+}
+
+// Close ends the subscription
+func (f *LiveAttendanceFeed) Close() {
+	f.close()
+}
+
+// OpenLiveFeed subscribes to check-ins at a church, the first configured church when churchID is empty. With an
+// eventID only that event's check-ins are sent; otherwise the feed follows every event and starts with the
+// counters of the event currently open for check-in.
+func (s *AttendanceService) OpenLiveFeed(ctx context.Context, churchID, eventID string) (*LiveAttendanceFeed, error) {
+	church, err := checkinChurch(ctx, s.localChurchRepo, churchID)
+	if err != nil {
+		return nil, err
+	}
+
+	var event *models.ServiceEvent
+	if eventID != "" {
+		objID, err := primitive.ObjectIDFromHex(eventID)
+		if err != nil {
+			return nil, errors.New("invalid event ID")
+		}
+		event, err = s.serviceEventRepo.GetByID(ctx, objID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get service event: %w", err)
+		}
+		if event == nil {
+			return nil, errors.New("service event not found")
+		}
+	} else {
+		event, err = openEventAt(ctx, s.localChurchRepo, s.serviceEventRepo, church, time.Now(), s.cfg.EventCheckinEarlyWindow)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	key := liveChannel(church)
+	feed := &LiveAttendanceFeed{}
+	if event != nil {
+		feed.Counters, err = s.liveCounters(ctx, event)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	subscriber := s.feed.subscribe(key, eventID)
+	feed.Messages = subscriber.messages
+	feed.close = func() {
+		s.feed.unsubscribe(key, subscriber)
+	}
+	return feed, nil
+}
+
+// publishCheckin sends a new check-in and the event's updated counters to the dashboards watching its church.
+// Failures are logged rather than returned, as the check-in itself has already been saved.
+func (s *AttendanceService) publishCheckin(ctx context.Context, attendance *models.Attendance, event *models.ServiceEvent, userID, firstName, lastName string) {
+	key, ok := s.watchedChannel(ctx, event)
+	if !ok {
+		return
+	}
+
+	checkin := &dto.LiveCheckin{
+		AttendanceID:         attendance.ID.Hex(),
+		UserID:               userID,
+		FirstName:            firstName,
+		LastName:             lastName,
+		EventID:              event.ID.Hex(),
+		EventName:            event.Name,
+		DateTimeOfAttendance: attendance.DateTimeOfAttendance,
+		CheckinMethod:        attendance.CheckinMethod,
+		Late:                 attendance.Late,
+		MinutesLate:          attendance.MinutesLate,
+		Member:               attendance.Member,
+		Visitor:              attendance.Visitor,
+		FirstVisit:           attendance.FirstVisit,
+	}
+	if attendance.VisitorProfile != nil {
+		checkin.VisitorProfileID = attendance.VisitorProfile.Hex()
+	}
+	if attendance.FamilyMember != nil {
+		checkin.FamilyMemberID = attendance.FamilyMember.Hex()
+	}
+	s.feed.publish(key, checkin.EventID, dto.LiveAttendanceMessage{Type: LiveMessageCheckin, Checkin: checkin})
+
+	counters, err := s.liveCounters(ctx, event)
+	if err != nil {
+		log.Printf("Live attendance feed: %v", err)
+		return
+	}
+	s.feed.publish(key, checkin.EventID, dto.LiveAttendanceMessage{Type: LiveMessageCounters, Counters: counters})
+}
+
+// publishCounters sends the updated counters of the events a correction or check-out touched to the dashboards
+// watching their church, so their totals drop voided records and count back-dated ones. Failures are logged.
+func (s *AttendanceService) publishCounters(ctx context.Context, eventIDs ...*primitive.ObjectID) {
+	if s.feed.idle() {
+		return
+	}
+
+	for _, eventID := range eventIDs {
+		if eventID == nil {
+			continue
+		}
+		event, err := s.serviceEventRepo.GetByID(ctx, *eventID)
+		if err != nil {
+			log.Printf("Live attendance feed: failed to get service event: %v", err)
+			continue
+		}
+		if event == nil {
+			continue
+		}
+
+		key, ok := s.watchedChannel(ctx, event)
+		if !ok {
+			continue
+		}
+		counters, err := s.liveCounters(ctx, event)
+		if err != nil {
+			log.Printf("Live attendance feed: %v", err)
+			continue
+		}
+		s.feed.publish(key, counters.EventID, dto.LiveAttendanceMessage{Type: LiveMessageCounters, Counters: counters})
+	}
+}
+
+// watchedChannel returns the feed channel of the event's church, and false when no dashboard is watching it.
+// The church is only looked up while someone is watching, and then cached by event.
+func (s *AttendanceService) watchedChannel(ctx context.Context, event *models.ServiceEvent) (string, bool) {
+	if s.feed.idle() {
+		return "", false
+	}
+
+	now := time.Now()
+	key, ok := s.feed.cachedChannel(event.ID, now)
+	if !ok {
+		church, err := s.churchForEvent(ctx, event)
+		if err != nil {
+			log.Printf("Live attendance feed: %v", err)
+			return "", false
+		}
+		key = liveChannel(church)
+		s.feed.cacheChannel(event.ID, key, now)
+	}
+	return key, s.feed.hasSubscribers(key)
+}
+
+func (s *AttendanceService) liveCounters(ctx context.Context, event *models.ServiceEvent) (*dto.LiveAttendanceCounters, error) {
+	total, members, visitors, late, err := s.attendanceRepo.CountForEvent(ctx, event.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count attendance for event: %w", err)
+	}
+	checkedOut, _, err := s.attendanceRepo.GetStayForEvent(ctx, event.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stay for event: %w", err)
+	}
+	return &dto.LiveAttendanceCounters{
+		EventID:    event.ID.Hex(),
+		EventName:  event.Name,
+		Total:      total,
+		Members:    members,
+		Visitors:   visitors,
+		Late:       late,
+		CheckedOut: checkedOut,
+	}, nil
+}
+
+// liveChannel is the feed channel for a church. Without any church configured everything shares one channel.
+func liveChannel(church *models.LocalChurch) string {
+	if church == nil {
+		return ""
+	}
+	return church.ID.Hex()
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"cci-api/internal/dto"
+	"cci-api/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// received drains whatever is waiting for a subscriber without blocking
+func received(subscriber *feedSubscriber) []dto.LiveAttendanceMessage {
+	var messages []dto.LiveAttendanceMessage
+	for {
+		select {
+		case message := <-subscriber.messages:
+			messages = append(messages, message)
+		default:
+			return messages
+		}
+	}
+}
+
+func TestAttendanceFeedPublish(t *testing.T) {
+	feed := newAttendanceFeed()
+	if !feed.idle() {
+		t.Fatal("new feed should be idle")
+	}
+
+	everything := feed.subscribe("ikeja", "")
+	sunday := feed.subscribe("ikeja", "sunday")
+	vigil := feed.subscribe("ikeja", "vigil")
+	otherChurch := feed.subscribe("lekki", "")
+
+	feed.publish("ikeja", "sunday", dto.LiveAttendanceMessage{Type: LiveMessageCounters})
+
+	for name, tt := range map[string]struct {
+		subscriber *feedSubscriber
+		want       int
+	}{
+		"church-wide subscriber": {everything, 1},
+		"same event":             {sunday, 1},
+		"other event":            {vigil, 0},
+		"other church":           {otherChurch, 0},
+	} {
+		if got := len(received(tt.subscriber)); got != tt.want {
+			t.Errorf("%s received %d messages, want %d", name, got, tt.want)
+		}
+	}
+
+	feed.unsubscribe("ikeja", everything)
+	feed.unsubscribe("ikeja", sunday)
+	feed.unsubscribe("ikeja", vigil)
+	if feed.hasSubscribers("ikeja") {
+		t.Error("ikeja still has subscribers after all left")
+	}
+	if feed.idle() {
+		t.Error("feed is idle while lekki is still watched")
+	}
+	feed.unsubscribe("lekki", otherChurch)
+	if !feed.idle() {
+		t.Error("feed should be idle once every subscriber has left")
+	}
+}
+
+func TestAttendanceFeedSlowSubscriber(t *testing.T) {
+	feed := newAttendanceFeed()
+	slow := feed.subscribe("ikeja", "")
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < liveFeedBuffer+10; i++ {
+			feed.publish("ikeja", "sunday", dto.LiveAttendanceMessage{Type: LiveMessageCheckin})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("publish blocked on a subscriber that is not reading")
+	}
+	if got := len(received(slow)); got != liveFeedBuffer {
+		t.Errorf("slow subscriber received %d messages, want the %d buffered", got, liveFeedBuffer)
+	}
+}
+
+func TestAttendanceFeedChannelCache(t *testing.T) {
+	feed := newAttendanceFeed()
+	now := time.Now()
+	event := primitive.NewObjectID()
+	feed.cacheChannel(event, "ikeja", now)
+
+	if channel, ok := feed.cachedChannel(event, now.Add(liveChannelTTL-time.Second)); !ok || channel != "ikeja" {
+		t.Errorf("cachedChannel before expiry = (%q, %v), want (ikeja, true)", channel, ok)
+	}
+	if _, ok := feed.cachedChannel(event, now.Add(liveChannelTTL+time.Second)); ok {
+		t.Error("cachedChannel returned an expired entry")
+	}
+
+	// Caching another event drops entries that have expired by then
+	stale := primitive.NewObjectID()
+	feed.cacheChannel(stale, "lekki", now)
+	feed.cacheChannel(primitive.NewObjectID(), "ikeja", now.Add(2*liveChannelTTL))
+	if _, ok := feed.channels[stale]; ok {
+		t.Error("expired entry was kept")
+	}
+}
+
+func TestPublishCheckin(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	churchID := primitive.NewObjectID()
+	church := models.LocalChurch{ID: churchID, ChurchName: "Ikeja", Timezone: "Africa/Lagos"}
+	attendance := &models.Attendance{ID: primitive.NewObjectID(), CheckinMethod: models.CheckinMethodQR, Member: true}
+	counts := bson.M{"total": 41, "members": 30, "visitors": 11, "late": 4}
+
+	mt.Run("nobody watching sends no queries", func(mt *mtest.T) {
+		s := newMockAttendanceService(mt)
+		event := openService()
+		event.Church = &churchID
+
+		s.publishCheckin(context.Background(), attendance, event, "u1", "Ada", "Obi")
+		if cmds := sentCommands(mt); len(cmds) != 0 {
+			t.Errorf("commands = %v, want none", cmds)
+		}
+	})
+
+	mt.Run("watched church gets the check-in then counters", func(mt *mtest.T) {
+		s := newMockAttendanceService(mt)
+		event := openService()
+		event.Church = &churchID
+		dashboard := s.feed.subscribe(churchID.Hex(), "")
+
+		mt.AddMockResponses(
+			mockFound(mt, "local_churches", church),
+			mockFound(mt, "attendance", counts),
+			mockFound(mt, "attendance"),
+			mockFound(mt, "attendance", counts),
+			mockFound(mt, "attendance"),
+		)
+		s.publishCheckin(context.Background(), attendance, event, "u1", "Ada", "Obi")
+		s.publishCheckin(context.Background(), attendance, event, "u2", "Bola", "Ade")
+
+		// The church is looked up once and then cached for the event
+		want := "find aggregate aggregate aggregate aggregate"
+		if cmds := strings.Join(sentCommands(mt), " "); cmds != want {
+			t.Errorf("commands = %s, want %s", cmds, want)
+		}
+
+		messages := received(dashboard)
+		if len(messages) != 4 {
+			t.Fatalf("received %d messages, want 4", len(messages))
+		}
+		if messages[0].Type != LiveMessageCheckin || messages[0].Checkin.UserID != "u1" || messages[0].Checkin.EventID != event.ID.Hex() {
+			t.Errorf("first message = %+v, want u1's check-in", messages[0])
+		}
+		if messages[1].Type != LiveMessageCounters || messages[1].Counters.Total != 41 || messages[1].Counters.Late != 4 {
+			t.Errorf("second message = %+v, want the event counters", messages[1])
+		}
+	})
+}
@@ -4,209 +4,1679 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
+	"strings"
 	"time"
 
 	"cci-api/internal/config"
+	"cci-api/internal/database"
 	"cci-api/internal/dto"
 	"cci-api/internal/models"
 	"cci-api/internal/repository"
+	"cci-api/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// maxBatchItems caps how many offline check-ins can be synced in one request
+	maxBatchItems = 500
+	// maxDeviceClockSkew is how far ahead of the server an usher device's clock may be
+	maxDeviceClockSkew = 5 * time.Minute
+	// maxRateWindows and maxRateWindowDays bound the attendance rate windows a member report can ask for
+	maxRateWindows    = 5
+	maxRateWindowDays = 3650
+	// heatmapDays is how many days of attendance the member heatmap covers
+	heatmapDays = 365
+)
+
+// defaultRateWindows are the periods, in days, attendance rates are reported over when none are requested
+var defaultRateWindows = []int{30, 90, 365}
+
+// regularServiceTypes are the event types that count as a church's regular services, which attendance rates
+// and missed attendance are measured against
+var regularServiceTypes = []string{models.EventTypeSundayService, models.EventTypeMidweekService}
+
+// Batch item outcomes
+const (
+	batchStatusRecorded     = "recorded"
+	batchStatusDuplicate    = "duplicate"
+	batchStatusInvalidToken = "invalid_token"
+	batchStatusRejected     = "rejected"
+)
+
+var (
+	errInvalidQRToken = errors.New("invalid QR code token")
+	errQRTokenUsed    = errors.New("QR code has already been used")
+)
+
+// ErrAlreadyCheckedIn is returned when the person already has attendance for the target event
+var ErrAlreadyCheckedIn = errors.New("attendance already recorded")
+
+// Geofenced self check-in rejections, each mapped to its own error code so the app can fall back to QR
+var (
+	ErrGeofenceNotConfigured = errors.New("geofenced check-in is not set up for this church")
+	ErrOutsideGeofence       = errors.New("you are too far from the church to check in")
+	ErrLocationInaccurate    = errors.New("your location is not accurate enough to check in")
+	ErrCheckinWindowClosed   = errors.New("check-in is not open")
 )
 
-type AttendanceService struct {
-	cfg            *config.Config
-	attendanceRepo *repository.AttendanceRepository
-	userRepo       *repository.UserRepository
-}
+// Visitor merge errors
+var (
+	ErrVisitorNotFound      = newNotFoundError("visitor profile not found")
+	ErrVisitorAlreadyMerged = errors.New("visitor profile has already been merged into an account")
+)
+
+// ErrInvalidHistoryFilter is returned when an attendance history filter or sort option is not recognised
+var ErrInvalidHistoryFilter = errors.New("invalid history filter")
+
+// Sort options for each attendance history view, mapped to the field they sort on. The first option is the default.
+var (
+	dailyHistorySorts  = []historySort{{"date", "_id"}, {"total_attendance", "total_attendance"}, {"members", "members"}, {"visitors", "visitors"}}
+	eventHistorySorts  = []historySort{{"start_time", "start_time"}, {"total_attendance", "total_attendance"}, {"members", "members"}, {"visitors", "visitors"}}
+	recordHistorySorts = []historySort{{"date", "date_time_of_attendance"}, {"minutes_late", "minutes_late"}}
+)
+
+type historySort struct {
+	name  string
+	field string
+}
+
+// checkin describes how and when an attendance was captured
+type checkin struct {
+	churchID       string // picks the open event when eventID is empty; the first church when both are
+	eventID        string
+	method         string
+	venue          string
+	capturedAt     time.Time // zero means now
+	idempotencyKey string
+	deviceID       string
+	backdatedBy    string // set when an admin adds a missed attendance after the fact
+	distanceMeters *float64
+	accuracy       *float64
+	qrUse          *qrTokenUse // spent together with the attendance, so a failed check-in leaves the code usable
+}
+
+// qrTokenUse is a scanned rotating QR code waiting to be recorded as used
+type qrTokenUse struct {
+	token     string
+	user      primitive.ObjectID
+	expiresAt time.Time
+}
+
+type AttendanceService struct {
+	cfg              *config.Config
+	db               *database.Database
+	attendanceRepo   *repository.AttendanceRepository
+	userRepo         *repository.UserRepository
+	serviceEventRepo *repository.ServiceEventRepository
+	localChurchRepo  *repository.LocalChurchRepository
+	qrTokenUseRepo   *repository.QRTokenUseRepository
+	venueCodeRepo    *repository.VenueCodeRepository
+	visitorRepo      *repository.VisitorRepository
+	auditRepo        *repository.AttendanceAuditRepository
+	familyMemberRepo *repository.FamilyMemberRepository
+	feed             *attendanceFeed
+}
+
+func NewAttendanceService(cfg *config.Config, db *database.Database, attendanceRepo *repository.AttendanceRepository, userRepo *repository.UserRepository, serviceEventRepo *repository.ServiceEventRepository, localChurchRepo *repository.LocalChurchRepository, qrTokenUseRepo *repository.QRTokenUseRepository, venueCodeRepo *repository.VenueCodeRepository, visitorRepo *repository.VisitorRepository, auditRepo *repository.AttendanceAuditRepository, familyMemberRepo *repository.FamilyMemberRepository) *AttendanceService {
+	return &AttendanceService{
+		cfg:              cfg,
+		db:               db,
+		attendanceRepo:   attendanceRepo,
+		userRepo:         userRepo,
+		serviceEventRepo: serviceEventRepo,
+		localChurchRepo:  localChurchRepo,
+		qrTokenUseRepo:   qrTokenUseRepo,
+		venueCodeRepo:    venueCodeRepo,
+		visitorRepo:      visitorRepo,
+		auditRepo:        auditRepo,
+		familyMemberRepo: familyMemberRepo,
+		feed:             newAttendanceFeed(),
+	}
+}
+
+func (s *AttendanceService) CreateAttendance(ctx context.Context, req *dto.CreateAttendanceRequest) (*dto.AttendanceResponse, error) {
+	// Get user
+	user, err := s.userRepo.GetByUserID(ctx, req.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	attendance, err := s.recordAttendance(ctx, user, checkin{churchID: req.ChurchID, eventID: req.EventID, method: models.CheckinMethodManual})
+	if err != nil {
+		return nil, err
+	}
+
+	return toAttendanceResponse(attendance, user.UserID), nil
+}
+
+func (s *AttendanceService) QRCheckin(ctx context.Context, req *dto.QRCheckinRequest) (*dto.AttendanceResponse, error) {
+	// Get user by QR token
+	user, qrUse, err := s.userFromQRToken(ctx, req.QRCodeToken, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	attendance, err := s.recordAttendance(ctx, user, checkin{churchID: req.ChurchID, eventID: req.EventID, method: models.CheckinMethodQR, qrUse: qrUse})
+	if err != nil {
+		return nil, err
+	}
+
+	return toAttendanceResponse(attendance, user.UserID), nil
+}
+
+func (s *AttendanceService) CheckOut(ctx context.Context, req *dto.CheckOutRequest, performedBy string) (*dto.AttendanceResponse, error) {
+	// Get user
+	user, err := s.userRepo.GetByUserID(ctx, req.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	attendance, err := s.closeAttendance(ctx, user, req.EventID, performedBy, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	s.publishCounters(ctx, attendance.Event)
+
+	return toAttendanceResponse(attendance, user.UserID), nil
+}
+
+func (s *AttendanceService) QRCheckout(ctx context.Context, req *dto.QRCheckoutRequest, performedBy string) (*dto.AttendanceResponse, error) {
+	// Get user by QR token
+	user, qrUse, err := s.userFromQRToken(ctx, req.QRCodeToken, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	attendance, err := s.closeAttendance(ctx, user, req.EventID, performedBy, qrUse, true)
+	if err != nil {
+		return nil, err
+	}
+	s.publishCounters(ctx, attendance.Event)
+
+	return toAttendanceResponse(attendance, user.UserID), nil
+}
+
+// SyncBatch applies check-ins captured offline by an usher device. Each item is processed independently with the
+// same rules as a live check-in, and items whose idempotency key was already recorded are reported as duplicates,
+// so a batch can safely be re-sent.
+func (s *AttendanceService) SyncBatch(ctx context.Context, req *dto.BatchAttendanceRequest) (*dto.BatchAttendanceResponse, error) {
+	if len(req.Items) > maxBatchItems {
+		return nil, fmt.Errorf("a batch can contain at most %d check-ins", maxBatchItems)
+	}
+
+	resp := &dto.BatchAttendanceResponse{
+		Results: make([]dto.BatchAttendanceResult, 0, len(req.Items)),
+	}
+	for _, item := range req.Items {
+		result := s.syncBatchItem(ctx, req.DeviceID, item)
+		switch result.Status {
+		case batchStatusRecorded:
+			resp.Recorded++
+		case batchStatusDuplicate:
+			resp.Duplicates++
+		default:
+			resp.Failed++
+		}
+		resp.Results = append(resp.Results, result)
+	}
+
+	return resp, nil
+}
+
+func (s *AttendanceService) syncBatchItem(ctx context.Context, deviceID string, item dto.BatchAttendanceItem) dto.BatchAttendanceResult {
+	result := dto.BatchAttendanceResult{IdempotencyKey: item.IdempotencyKey}
+	reject := func(status string, err error) dto.BatchAttendanceResult {
+		result.Status = status
+		result.Message = err.Error()
+		return result
+	}
+
+	// A re-sent item returns the record it created the first time
+	existing, err := s.attendanceRepo.GetByIdempotencyKey(ctx, item.IdempotencyKey)
+	if err != nil {
+		return reject(batchStatusRejected, fmt.Errorf("failed to check idempotency key: %w", err))
+	}
+	if existing != nil {
+		result.Status = batchStatusDuplicate
+		result.Message = "check-in already synced"
+		result.Attendance = toAttendanceResponse(existing, s.attendeeUserID(ctx, existing))
+		return result
+	}
+
+	capturedAt, err := time.Parse(time.RFC3339, item.CapturedAt)
+	if err != nil {
+		return reject(batchStatusRejected, errors.New("captured_at must be in RFC3339 format"))
+	}
+	now := time.Now()
+	if capturedAt.After(now.Add(maxDeviceClockSkew)) {
+		return reject(batchStatusRejected, errors.New("captured_at is in the future"))
+	}
+	if capturedAt.Before(now.Add(-s.cfg.OfflineSyncMaxAge)) {
+		return reject(batchStatusRejected, errors.New("check-in is too old to sync"))
+	}
+
+	var user *models.User
+	var qrUse *qrTokenUse
+	method := models.CheckinMethodQR
+	switch {
+	case item.QRCodeToken != "":
+		user, qrUse, err = s.userFromQRToken(ctx, item.QRCodeToken, capturedAt)
+		if err != nil {
+			return reject(batchStatusInvalidToken, err)
+		}
+	case item.UserID != "":
+		method = models.CheckinMethodManual
+		user, err = s.userRepo.GetByUserID(ctx, item.UserID)
+		if err != nil {
+			return reject(batchStatusRejected, fmt.Errorf("failed to get user: %w", err))
+		}
+		if user == nil {
+			return reject(batchStatusInvalidToken, errors.New("user not found"))
+		}
+	default:
+		return reject(batchStatusRejected, errors.New("either qr_code_token or user_id is required"))
+	}
+
+	attendance, err := s.recordAttendance(ctx, user, checkin{
+		churchID:       item.ChurchID,
+		eventID:        item.EventID,
+		method:         method,
+		capturedAt:     capturedAt,
+		idempotencyKey: item.IdempotencyKey,
+		deviceID:       deviceID,
+		qrUse:          qrUse,
+	})
+	if err != nil {
+		if errors.Is(err, ErrAlreadyCheckedIn) {
+			return reject(batchStatusDuplicate, err)
+		}
+		if errors.Is(err, errQRTokenUsed) {
+			return reject(batchStatusInvalidToken, err)
+		}
+		return reject(batchStatusRejected, err)
+	}
+
+	result.Status = batchStatusRecorded
+	result.Attendance = toAttendanceResponse(attendance, user.UserID)
+	return result
+}
+
+// SelfCheckin records attendance for the logged-in user from a venue code scanned at the entrance
+func (s *AttendanceService) SelfCheckin(ctx context.Context, req *dto.SelfCheckinRequest, userID string) (*dto.AttendanceResponse, error) {
+	venueCode, err := s.venueCodeRepo.GetByCode(ctx, strings.TrimPrefix(req.VenueCode, utils.VenueQRPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get venue code: %w", err)
+	}
+	if venueCode == nil || time.Now().After(venueCode.ExpiresAt) {
+		return nil, errors.New("invalid or expired venue code")
+	}
+
+	event, err := s.serviceEventRepo.GetByID(ctx, venueCode.Event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service event: %w", err)
+	}
+	if event == nil {
+		return nil, errors.New("service event not found")
+	}
+	if time.Now().Before(event.StartTime.Add(-s.cfg.EventCheckinEarlyWindow)) {
+		return nil, fmt.Errorf("check-in for %s has not opened yet", event.Name)
+	}
+
+	// Get user
+	user, err := s.userRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	attendance, err := s.recordAttendance(ctx, user, checkin{eventID: event.ID.Hex(), method: models.CheckinMethodVenueQR, venue: venueCode.Venue})
+	if err != nil {
+		return nil, err
+	}
+
+	return toAttendanceResponse(attendance, user.UserID), nil
+}
+
+// GeofenceCheckin checks a member in from the mobile app when their reported GPS position is inside the church's
+// geofence and the event is open for check-in. The distance from the church is recorded on the attendance.
+func (s *AttendanceService) GeofenceCheckin(ctx context.Context, req *dto.GeofenceCheckinRequest, userID string) (*dto.AttendanceResponse, error) {
+	now := time.Now()
+
+	var event *models.ServiceEvent
+	if req.EventID == "" {
+		church, err := checkinChurch(ctx, s.localChurchRepo, req.ChurchID)
+		if err != nil {
+			return nil, err
+		}
+		open, err := openEventAt(ctx, s.localChurchRepo, s.serviceEventRepo, church, now, s.cfg.EventCheckinEarlyWindow)
+		if err != nil {
+			return nil, err
+		}
+		if open == nil {
+			return nil, fmt.Errorf("%w: no service event is currently open", ErrCheckinWindowClosed)
+		}
+		event = open
+	} else {
+		requested, err := s.resolveEvent(ctx, req.ChurchID, req.EventID, now)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.checkinOpenAt(requested, now); err != nil {
+			return nil, err
+		}
+		event = requested
+	}
+
+	church, err := s.churchForEvent(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	if church == nil || church.Geofence == nil {
+		return nil, ErrGeofenceNotConfigured
+	}
+
+	if req.Accuracy > s.cfg.GeofenceMaxAccuracy {
+		return nil, fmt.Errorf("%w, reported accuracy is %.0fm and at most %.0fm is accepted", ErrLocationInaccurate, req.Accuracy, s.cfg.GeofenceMaxAccuracy)
+	}
+
+	distance := utils.DistanceMeters(church.Geofence.Latitude, church.Geofence.Longitude, *req.Latitude, *req.Longitude)
+	distance = math.Round(distance*10) / 10
+	if distance > float64(church.Geofence.RadiusMeters) {
+		return nil, fmt.Errorf("%w, you are %.0fm away and must be within %dm", ErrOutsideGeofence, distance, church.Geofence.RadiusMeters)
+	}
+
+	user, err := s.userRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	accuracy := req.Accuracy
+	attendance, err := s.recordAttendance(ctx, user, checkin{
+		eventID:        event.ID.Hex(),
+		method:         models.CheckinMethodGeofence,
+		distanceMeters: &distance,
+		accuracy:       &accuracy,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toAttendanceResponse(attendance, user.UserID), nil
+}
+
+// RegisterVisitor checks in a guest who has no account. Their first visit creates a visitor profile together with
+// the attendance in one transaction; later visits with the same phone number reuse the unmerged profile.
+func (s *AttendanceService) RegisterVisitor(ctx context.Context, req *dto.VisitorCheckinRequest, performedBy string) (*dto.VisitorCheckinResponse, error) {
+	phone := utils.NormalizePhoneNumber(req.PhoneNumber)
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	if email != "" {
+		user, err := s.userRepo.GetByEmail(ctx, email)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check existing user: %w", err)
+		}
+		if user != nil {
+			return nil, errors.New("an account already exists with this email, please check in with the member's user ID")
+		}
+	}
+
+	visitor, err := s.visitorRepo.GetUnmergedByPhone(ctx, phone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get visitor profile: %w", err)
+	}
+
+	attendance, event, err := s.newAttendance(ctx, checkin{churchID: req.ChurchID, eventID: req.EventID, method: models.CheckinMethodManual})
+	if err != nil {
+		return nil, err
+	}
+
+	firstVisit := visitor == nil
+	if firstVisit {
+		visitor = &models.VisitorProfile{
+			FirstName:    strings.TrimSpace(req.FirstName),
+			LastName:     strings.TrimSpace(req.LastName),
+			PhoneNumber:  phone,
+			Email:        email,
+			InvitedBy:    strings.TrimSpace(req.InvitedBy),
+			RegisteredBy: performedBy,
+			FirstVisit:   attendance.DateTimeOfAttendance,
+		}
+	} else {
+		existing, err := s.attendanceRepo.GetByVisitorAndEvent(ctx, visitor.ID, event.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check existing attendance: %w", err)
+		}
+		if existing != nil {
+			return nil, fmt.Errorf("%w for %s", ErrAlreadyCheckedIn, event.Name)
+		}
+	}
+
+	attendance.FirstVisit = firstVisit
+	attendance.Visitor = true
+
+	err = s.db.WithTransaction(ctx, func(txCtx context.Context) error {
+		if firstVisit {
+			if err := s.visitorRepo.Create(txCtx, visitor); err != nil {
+				return fmt.Errorf("failed to create visitor profile: %w", err)
+			}
+		}
+
+		attendance.VisitorProfile = &visitor.ID
+		if err := s.attendanceRepo.Create(txCtx, attendance); err != nil {
+			return createAttendanceError(err, event)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.publishCheckin(ctx, attendance, event, "", visitor.FirstName, visitor.LastName)
+
+	return &dto.VisitorCheckinResponse{
+		Visitor:    toVisitorProfileResponse(visitor),
+		Attendance: toAttendanceResponse(attendance, ""),
+	}, nil
+}
+
+// MergeVisitor moves a visitor profile and its attendance onto a member's account. Staff use it for guests who
+// registered with a different email, or none, so their profile was not merged when they set their password.
+func (s *AttendanceService) MergeVisitor(ctx context.Context, visitorProfileID, userID string) (*dto.MergeVisitorResponse, error) {
+	objID, err := primitive.ObjectIDFromHex(visitorProfileID)
+	if err != nil {
+		return nil, errors.New("invalid visitor profile ID")
+	}
+
+	visitor, err := s.visitorRepo.GetByID(ctx, objID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get visitor profile: %w", err)
+	}
+	if visitor == nil {
+		return nil, ErrVisitorNotFound
+	}
+	if visitor.MergedInto != "" {
+		return nil, ErrVisitorAlreadyMerged
+	}
+
+	user, err := s.userRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	var merged int
+	err = s.db.WithTransaction(ctx, func(txCtx context.Context) error {
+		merged, err = mergeVisitorProfile(txCtx, s.visitorRepo, s.attendanceRepo, visitor, user)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.MergeVisitorResponse{
+		VisitorProfileID: visitor.ID.Hex(),
+		UserID:           user.UserID,
+		MergedAttendance: merged,
+	}, nil
+}
+
+// mergeVisitorProfile marks the visitor profile merged into the user and moves its attendance onto the account,
+// returning how many records moved. Callers run it inside a transaction.
+func mergeVisitorProfile(ctx context.Context, visitorRepo *repository.VisitorRepository, attendanceRepo *repository.AttendanceRepository, visitor *models.VisitorProfile, user *models.User) (int, error) {
+	merged, err := visitorRepo.MarkMerged(ctx, visitor.ID, user.UserID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to merge visitor profile: %w", err)
+	}
+	if !merged {
+		return 0, ErrVisitorAlreadyMerged
+	}
+
+	moved, err := attendanceRepo.AssignVisitorToUser(ctx, visitor.ID, user.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to move visitor attendance: %w", err)
+	}
+	return moved, nil
+}
+
+// CheckInHousehold checks in a family head and the household members they choose to the same event in one go.
+// Household members without an account are recorded against their family member record and take the family
+// head's member or visitor status. Anyone already checked in is reported as a duplicate rather than failing the
+// whole household; everyone else is recorded together in one transaction.
+func (s *AttendanceService) CheckInHousehold(ctx context.Context, req *dto.HouseholdCheckinRequest, performedBy string) (*dto.HouseholdCheckinResponse, error) {
+	headID := req.FamilyHeadID
+	if headID == "" {
+		headID = performedBy
+	}
+	head, err := s.userRepo.GetByUserID(ctx, headID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get family head: %w", err)
+	}
+	if head == nil {
+		return nil, errors.New("family head not found")
+	}
+
+	household, err := s.familyMemberRepo.GetAllByFamilyHead(ctx, head.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get household: %w", err)
+	}
+
+	dependents := household
+	if len(req.FamilyMemberIDs) > 0 {
+		byID := make(map[string]*models.FamilyMember, len(household))
+		for _, member := range household {
+			byID[member.ID.Hex()] = member
+		}
+		dependents = make([]*models.FamilyMember, 0, len(req.FamilyMemberIDs))
+		selected := map[string]bool{}
+		for _, id := range req.FamilyMemberIDs {
+			member, ok := byID[id]
+			if !ok {
+				return nil, fmt.Errorf("family member %s is not in %s's household", id, head.UserID)
+			}
+			if !selected[id] {
+				selected[id] = true
+				dependents = append(dependents, member)
+			}
+		}
+	}
+
+	includeHead := req.IncludeHead == nil || *req.IncludeHead
+	if !includeHead && len(dependents) == 0 {
+		return nil, errors.New("no one selected to check in")
+	}
+
+	template, event, err := s.newAttendance(ctx, checkin{churchID: req.ChurchID, eventID: req.EventID, method: models.CheckinMethodManual})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dto.HouseholdCheckinResponse{
+		FamilyHead: head.UserID,
+		EventID:    event.ID.Hex(),
+		EventName:  event.Name,
+		Results:    []dto.HouseholdCheckinResult{},
+	}
+
+	type pending struct {
+		result     int
+		attendance *models.Attendance
+	}
+	var toRecord []pending
+	add := func(result dto.HouseholdCheckinResult, existing *models.Attendance) *models.Attendance {
+		if existing != nil {
+			result.Status = batchStatusDuplicate
+			result.Message = fmt.Sprintf("%s for %s", ErrAlreadyCheckedIn, event.Name)
+			resp.Duplicates++
+			resp.Results = append(resp.Results, result)
+			return nil
+		}
+
+		attendance := *template
+		attendance.Household = head.UserID
+		attendance.Visitor = head.Visitor
+		attendance.Member = head.Member
+		result.Status = batchStatusRecorded
+		resp.Results = append(resp.Results, result)
+		toRecord = append(toRecord, pending{result: len(resp.Results) - 1, attendance: &attendance})
+		return &attendance
+	}
+
+	if includeHead {
+		existing, err := s.attendanceRepo.GetByUserAndEvent(ctx, head.ID, event.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check existing attendance: %w", err)
+		}
+		result := dto.HouseholdCheckinResult{UserID: head.UserID, Name: strings.TrimSpace(head.FirstName + " " + head.LastName)}
+		if attendance := add(result, existing); attendance != nil {
+			attendance.User = head.ID
+		}
+	}
+
+	for _, member := range dependents {
+		existing, err := s.attendanceRepo.GetByFamilyMemberAndEvent(ctx, member.ID, event.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check existing attendance: %w", err)
+		}
+		memberID := member.ID
+		result := dto.HouseholdCheckinResult{FamilyMemberID: memberID.Hex(), Name: member.FamilyMemberName}
+		if attendance := add(result, existing); attendance != nil {
+			attendance.FamilyMember = &memberID
+		}
+	}
+
+	if len(toRecord) > 0 {
+		err = s.db.WithTransaction(ctx, func(txCtx context.Context) error {
+			for _, p := range toRecord {
+				p.attendance.ID = primitive.NilObjectID
+				if err := s.attendanceRepo.Create(txCtx, p.attendance); err != nil {
+					return createAttendanceError(err, event)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, p := range toRecord {
+		result := &resp.Results[p.result]
+		result.Attendance = toAttendanceResponse(p.attendance, result.UserID)
+		if p.attendance.FamilyMember != nil {
+			s.publishCheckin(ctx, p.attendance, event, "", result.Name, "")
+		} else {
+			s.publishCheckin(ctx, p.attendance, event, head.UserID, head.FirstName, head.LastName)
+		}
+		resp.Recorded++
+	}
+
+	return resp, nil
+}
+
+// VoidAttendance cancels an attendance record entered by mistake. The record is kept, but no longer counts
+// anywhere, and the change is written to the audit trail.
+func (s *AttendanceService) VoidAttendance(ctx context.Context, attendanceID string, req *dto.VoidAttendanceRequest, performedBy string) (*dto.AttendanceResponse, error) {
+	attendance, err := s.getCorrectableAttendance(ctx, attendanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	before := *attendance
+	now := time.Now()
+	attendance.Voided = true
+	attendance.VoidedAt = &now
+	attendance.VoidedBy = performedBy
+	attendance.VoidReason = req.Reason
+
+	err = s.db.WithTransaction(ctx, func(txCtx context.Context) error {
+		voided, err := s.attendanceRepo.Void(txCtx, attendance)
+		if err != nil {
+			return fmt.Errorf("failed to void attendance: %w", err)
+		}
+		if !voided {
+			return errors.New("attendance record has already been voided")
+		}
+		return s.writeAudit(txCtx, models.AttendanceAuditVoid, req.Reason, performedBy, &before, attendance)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.publishCounters(ctx, attendance.Event)
+
+	return toAttendanceResponse(attendance, s.attendeeUserID(ctx, attendance)), nil
+}
+
+// AmendAttendance corrects the event, lateness or visitor/member flags of an attendance record. Moving a record
+// to another event works its lateness out again unless late is given too.
+func (s *AttendanceService) AmendAttendance(ctx context.Context, attendanceID string, req *dto.AmendAttendanceRequest, performedBy string) (*dto.AttendanceResponse, error) {
+	attendance, err := s.getCorrectableAttendance(ctx, attendanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	before := *attendance
+	changed := false
+
+	if req.EventID != "" && (attendance.Event == nil || attendance.Event.Hex() != req.EventID) {
+		event, err := s.resolveEvent(ctx, "", req.EventID, attendance.DateTimeOfAttendance)
+		if err != nil {
+			return nil, err
+		}
+
+		if !attendance.User.IsZero() {
+			existing, err := s.attendanceRepo.GetByUserAndEvent(ctx, attendance.User, event.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check existing attendance: %w", err)
+			}
+			if existing != nil {
+				return nil, fmt.Errorf("%w for %s", ErrAlreadyCheckedIn, event.Name)
+			}
+		}
+
+		church, err := s.churchForEvent(ctx, event)
+		if err != nil {
+			return nil, err
+		}
+		attendance.Event = &event.ID
+		attendance.MinutesLate, attendance.Late = s.lateness(event, church, attendance.DateTimeOfAttendance)
+		changed = true
+	}
+	if req.Late != nil && *req.Late != attendance.Late {
+		attendance.Late = *req.Late
+		changed = true
+	}
+	if req.Visitor != nil && *req.Visitor != attendance.Visitor {
+		attendance.Visitor = *req.Visitor
+		changed = true
+	}
+	if req.Member != nil && *req.Member != attendance.Member {
+		attendance.Member = *req.Member
+		changed = true
+	}
+	if !changed {
+		return nil, errors.New("no changes to apply")
+	}
+
+	err = s.db.WithTransaction(ctx, func(txCtx context.Context) error {
+		amended, err := s.attendanceRepo.Amend(txCtx, attendance)
+		if errors.Is(err, repository.ErrDuplicateAttendance) {
+			return fmt.Errorf("%w for the new event", ErrAlreadyCheckedIn)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to amend attendance: %w", err)
+		}
+		if !amended {
+			return errors.New("attendance record has been voided")
+		}
+		return s.writeAudit(txCtx, models.AttendanceAuditAmend, req.Reason, performedBy, &before, attendance)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if before.Event != nil && attendance.Event != nil && *before.Event == *attendance.Event {
+		s.publishCounters(ctx, attendance.Event)
+	} else {
+		s.publishCounters(ctx, before.Event, attendance.Event)
+	}
+
+	return toAttendanceResponse(attendance, s.attendeeUserID(ctx, attendance)), nil
+}
+
+// BackdateAttendance adds an attendance that ushers missed on the day. The check-in time defaults to the
+// event's start, and lateness is worked out from it as for a live check-in.
+func (s *AttendanceService) BackdateAttendance(ctx context.Context, req *dto.BackdateAttendanceRequest, performedBy string) (*dto.AttendanceResponse, error) {
+	user, err := s.userRepo.GetByUserID(ctx, req.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	event, err := s.resolveEvent(ctx, "", req.EventID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	attendedAt := event.StartTime
+	if req.DateTimeOfAttendance != "" {
+		attendedAt, err = time.Parse(time.RFC3339, req.DateTimeOfAttendance)
+		if err != nil {
+			return nil, errors.New("date_time_of_attendance must be in RFC3339 format")
+		}
+	}
+	if attendedAt.After(time.Now()) {
+		return nil, errors.New("a back-dated attendance cannot be in the future")
+	}
+
+	attendance, _, err := s.newAttendance(ctx, checkin{
+		eventID:     event.ID.Hex(),
+		method:      models.CheckinMethodManual,
+		capturedAt:  attendedAt,
+		backdatedBy: performedBy,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.attendanceRepo.GetByUserAndEvent(ctx, user.ID, event.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existing attendance: %w", err)
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("%w for %s", ErrAlreadyCheckedIn, event.Name)
+	}
+
+	attendance.User = user.ID
+	attendance.Visitor = user.Visitor
+	attendance.Member = user.Member
+
+	err = s.db.WithTransaction(ctx, func(txCtx context.Context) error {
+		if err := s.attendanceRepo.Create(txCtx, attendance); err != nil {
+			return createAttendanceError(err, event)
+		}
+		return s.writeAudit(txCtx, models.AttendanceAuditBackdate, req.Reason, performedBy, nil, attendance)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.publishCounters(ctx, attendance.Event)
+
+	return toAttendanceResponse(attendance, user.UserID), nil
+}
+
+// GetAttendanceAudit returns the corrections made to an attendance record, oldest first
+func (s *AttendanceService) GetAttendanceAudit(ctx context.Context, attendanceID string) ([]*dto.AttendanceAuditResponse, error) {
+	objID, err := primitive.ObjectIDFromHex(attendanceID)
+	if err != nil {
+		return nil, errors.New("invalid attendance ID")
+	}
+
+	attendance, err := s.attendanceRepo.GetByID(ctx, objID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attendance: %w", err)
+	}
+	if attendance == nil {
+		return nil, errors.New("attendance record not found")
+	}
+
+	audits, err := s.auditRepo.GetByAttendance(ctx, objID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attendance audit: %w", err)
+	}
+
+	userID := s.attendeeUserID(ctx, attendance)
+	responses := make([]*dto.AttendanceAuditResponse, 0, len(audits))
+	for _, audit := range audits {
+		resp := &dto.AttendanceAuditResponse{
+			ID:           audit.ID.Hex(),
+			AttendanceID: audit.Attendance.Hex(),
+			Action:       audit.Action,
+			Reason:       audit.Reason,
+			PerformedBy:  audit.PerformedBy,
+			PerformedAt:  audit.PerformedAt,
+		}
+		if audit.Before != nil {
+			resp.Before = toAttendanceResponse(audit.Before, userID)
+		}
+		if audit.After != nil {
+			resp.After = toAttendanceResponse(audit.After, userID)
+		}
+		responses = append(responses, resp)
+	}
+
+	return responses, nil
+}
+
+// getCorrectableAttendance loads an attendance record for an admin correction, rejecting voided records
+func (s *AttendanceService) getCorrectableAttendance(ctx context.Context, attendanceID string) (*models.Attendance, error) {
+	objID, err := primitive.ObjectIDFromHex(attendanceID)
+	if err != nil {
+		return nil, errors.New("invalid attendance ID")
+	}
+
+	attendance, err := s.attendanceRepo.GetByID(ctx, objID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attendance: %w", err)
+	}
+	if attendance == nil {
+		return nil, errors.New("attendance record not found")
+	}
+	if attendance.Voided {
+		return nil, errors.New("attendance record has been voided")
+	}
+	return attendance, nil
+}
+
+func (s *AttendanceService) writeAudit(ctx context.Context, action, reason, performedBy string, before, after *models.Attendance) error {
+	audit := &models.AttendanceAudit{
+		Attendance:  after.ID,
+		Action:      action,
+		Reason:      reason,
+		PerformedBy: performedBy,
+		Before:      before,
+		After:       after,
+	}
+	if err := s.auditRepo.Create(ctx, audit); err != nil {
+		return fmt.Errorf("failed to write attendance audit: %w", err)
+	}
+	return nil
+}
+
+// attendeeUserID returns the user ID of the member an attendance record belongs to, or "" for visitor records
+func (s *AttendanceService) attendeeUserID(ctx context.Context, attendance *models.Attendance) string {
+	if attendance.User.IsZero() {
+		return ""
+	}
+	user, err := s.userRepo.GetByID(ctx, attendance.User)
+	if err != nil || user == nil {
+		return ""
+	}
+	return user.UserID
+}
+
+// closeAttendance checks the user out of the given event, or of their latest open attendance when no event is given
+func (s *AttendanceService) closeAttendance(ctx context.Context, user *models.User, eventID, performedBy string, qrUse *qrTokenUse, qrBased bool) (*models.Attendance, error) {
+	var attendance *models.Attendance
+	if eventID == "" {
+		open, err := s.attendanceRepo.GetLatestOpenForUser(ctx, user.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get open attendance: %w", err)
+		}
+		if open == nil {
+			return nil, errors.New("no open attendance found to check out")
+		}
+		attendance = open
+	} else {
+		objID, err := primitive.ObjectIDFromHex(eventID)
+		if err != nil {
+			return nil, errors.New("invalid event ID")
+		}
+
+		existing, err := s.attendanceRepo.GetByUserAndEvent(ctx, user.ID, objID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get attendance: %w", err)
+		}
+		if existing == nil {
+			return nil, errors.New("user has not checked in to this event")
+		}
+		attendance = existing
+	}
+
+	if attendance.CheckOutTime != nil {
+		return nil, errors.New("attendance already checked out")
+	}
+
+	now := time.Now()
+	attendance.CheckOutTime = &now
+	attendance.CheckedOutBy = performedBy
+	attendance.QRCodeBasedCheckout = qrBased
+
+	err := s.withQRUse(ctx, qrUse, func(ctx context.Context) error {
+		closed, err := s.attendanceRepo.CheckOut(ctx, attendance)
+		if err != nil {
+			return fmt.Errorf("failed to check out attendance: %w", err)
+		}
+		if !closed {
+			return errors.New("attendance already checked out")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return attendance, nil
+}
+
+// userFromQRToken resolves the user a scanned QR payload belongs to. Rotating codes must carry a valid
+// signature, be within one rotation step of now and not have been scanned before; the returned use is only
+// recorded by withQRUse once the scan's check-in or checkout succeeds. Printed member cards and static tokens
+// have no use to record, and static tokens are only accepted while QR_ALLOW_STATIC_TOKENS is on. at is when
+// the code was scanned.
+func (s *AttendanceService) userFromQRToken(ctx context.Context, token string, at time.Time) (*models.User, *qrTokenUse, error) {
+	if utils.IsMemberCardToken(token) {
+		user, err := s.userFromMemberCard(ctx, token)
+		return user, nil, err
+	}
+
+	if !utils.IsRotatingQRToken(token) {
+		if !s.cfg.QRAllowStaticTokens {
+			return nil, nil, errors.New("static QR codes are no longer accepted, please use your rotating QR code")
+		}
+
+		user, err := s.userRepo.GetByQRToken(ctx, token)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get user by QR token: %w", err)
+		}
+		if user == nil {
+			return nil, nil, errInvalidQRToken
+		}
+		return user, nil, nil
+	}
+
+	userID, counter, signature, ok := utils.ParseRotatingQRToken(token)
+	if !ok {
+		return nil, nil, errInvalidQRToken
+	}
+
+	if !utils.QRCounterInWindow(counter, at, s.cfg.QRRotationInterval) {
+		return nil, nil, errors.New("QR code has expired, please refresh it")
+	}
+
+	user, err := s.userRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil || user.QRSecret == "" || !utils.VerifyRotatingQRToken(userID, user.QRSecret, counter, signature) {
+		return nil, nil, errInvalidQRToken
+	}
+
+	used, err := s.qrTokenUseRepo.IsUsed(ctx, token)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to check QR code use: %w", err)
+	}
+	if used {
+		return nil, nil, errQRTokenUsed
+	}
+
+	// Keep the use until the code could no longer pass the window check above
+	return user, &qrTokenUse{
+		token:     token,
+		user:      user.ID,
+		expiresAt: time.Unix((counter+2)*int64(s.cfg.QRRotationInterval.Seconds()), 0),
+	}, nil
+}
+
+// userFromMemberCard resolves the user a printed member card belongs to. Cards can be scanned any number of
+// times until the member's cards are revoked.
+func (s *AttendanceService) userFromMemberCard(ctx context.Context, token string) (*models.User, error) {
+	userID, version, signature, ok := utils.ParseMemberCardToken(token)
+	if !ok || !utils.VerifyMemberCardToken(userID, s.cfg.QRCardSecret, version, signature) {
+		return nil, errInvalidQRToken
+	}
+
+	user, err := s.userRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, errInvalidQRToken
+	}
+	if version != user.CardVersion {
+		return nil, errors.New("this member card has been revoked, please print a new one")
+	}
+	return user, nil
+}
+
+// withQRUse runs fn, recording the scanned QR code as used in the same transaction so the code is only spent
+// when fn succeeds. Without a code to record fn runs on its own.
+func (s *AttendanceService) withQRUse(ctx context.Context, use *qrTokenUse, fn func(ctx context.Context) error) error {
+	if use == nil {
+		return fn(ctx)
+	}
+
+	return s.db.WithTransaction(ctx, func(txCtx context.Context) error {
+		firstUse, err := s.qrTokenUseRepo.MarkUsed(txCtx, use.token, use.user, use.expiresAt)
+		if err != nil {
+			return fmt.Errorf("failed to record QR code use: %w", err)
+		}
+		if !firstUse {
+			return errQRTokenUsed
+		}
+		return fn(txCtx)
+	})
+}
+
+// resolveEvent returns the requested event, or the event currently open for check-in at the church when no ID
+// is given
+func (s *AttendanceService) resolveEvent(ctx context.Context, churchID, eventID string, at time.Time) (*models.ServiceEvent, error) {
+	if eventID == "" {
+		church, err := checkinChurch(ctx, s.localChurchRepo, churchID)
+		if err != nil {
+			return nil, err
+		}
+		event, err := openEventAt(ctx, s.localChurchRepo, s.serviceEventRepo, church, at, s.cfg.EventCheckinEarlyWindow)
+		if err != nil {
+			return nil, err
+		}
+		if event == nil {
+			return nil, errors.New("no service event is currently open for check-in, please specify an event")
+		}
+		return event, nil
+	}
+
+	objID, err := primitive.ObjectIDFromHex(eventID)
+	if err != nil {
+		return nil, errors.New("invalid event ID")
+	}
+
+	event, err := s.serviceEventRepo.GetByID(ctx, objID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service event: %w", err)
+	}
+	if event == nil {
+		return nil, errors.New("service event not found")
+	}
+	return event, nil
+}
+
+// recordAttendance checks the user in to the target event, rejecting duplicates for the same event
+func (s *AttendanceService) recordAttendance(ctx context.Context, user *models.User, c checkin) (*models.Attendance, error) {
+	attendance, event, err := s.newAttendance(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if user already has attendance for this event
+	existingAttendance, err := s.attendanceRepo.GetByUserAndEvent(ctx, user.ID, event.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existing attendance: %w", err)
+	}
+	if existingAttendance != nil {
+		return nil, fmt.Errorf("%w for %s", ErrAlreadyCheckedIn, event.Name)
+	}
+
+	attendance.User = user.ID
+	attendance.Visitor = user.Visitor
+	attendance.Member = user.Member
+
+	err = s.withQRUse(ctx, c.qrUse, func(ctx context.Context) error {
+		if err := s.attendanceRepo.Create(ctx, attendance); err != nil {
+			return createAttendanceError(err, event)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.publishCheckin(ctx, attendance, event, user.UserID, user.FirstName, user.LastName)
+
+	return attendance, nil
+}
+
+// checkinOpenAt returns ErrCheckinWindowClosed unless check-in for the event was open at the given time, from
+// EVENT_CHECKIN_EARLY_WINDOW before it starts until it ends. Attendance after that has to be back-dated.
+func (s *AttendanceService) checkinOpenAt(event *models.ServiceEvent, at time.Time) error {
+	if at.Before(event.StartTime.Add(-s.cfg.EventCheckinEarlyWindow)) {
+		return fmt.Errorf("%w: check-in for %s has not opened yet", ErrCheckinWindowClosed, event.Name)
+	}
+	if at.After(event.EndTime) {
+		return fmt.Errorf("%w: %s has ended, back-date the attendance instead", ErrCheckinWindowClosed, event.Name)
+	}
+	return nil
+}
+
+// createAttendanceError reports a record the unique indexes rejected, because a concurrent check-in for the same
+// person and event or the same offline capture got there first, as ErrAlreadyCheckedIn
+func createAttendanceError(err error, event *models.ServiceEvent) error {
+	if errors.Is(err, repository.ErrDuplicateAttendance) || errors.Is(err, repository.ErrDuplicateIdempotencyKey) {
+		return fmt.Errorf("%w for %s", ErrAlreadyCheckedIn, event.Name)
+	}
+	return fmt.Errorf("failed to create attendance: %w", err)
+}
+
+// newAttendance resolves the target event and builds an unsaved attendance record for a check-in.
+// Lateness and the open event are worked out from when the check-in was captured.
+func (s *AttendanceService) newAttendance(ctx context.Context, c checkin) (*models.Attendance, *models.ServiceEvent, error) {
+	now := time.Now()
+	capturedAt := c.capturedAt
+	if capturedAt.IsZero() {
+		capturedAt = now
+	}
+
+	event, err := s.resolveEvent(ctx, c.churchID, c.eventID, capturedAt)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Offline captures are held to the window as it stood when they were scanned; their delay is already bounded
+	// by OFFLINE_SYNC_MAX_AGE
+	if c.backdatedBy == "" {
+		if err := s.checkinOpenAt(event, capturedAt); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	church, err := s.churchForEvent(ctx, event)
+	if err != nil {
+		return nil, nil, err
+	}
+	minutesLate, isLate := s.lateness(event, church, capturedAt)
+
+	attendance := &models.Attendance{
+		Event:                &event.ID,
+		DateTimeOfAttendance: capturedAt,
+		QRCodeBasedCheckin:   c.method == models.CheckinMethodQR || c.method == models.CheckinMethodVenueQR,
+		Late:                 isLate,
+		MinutesLate:          minutesLate,
+		ManualCheckin:        c.method == models.CheckinMethodManual,
+		CheckinMethod:        c.method,
+		Venue:                c.venue,
+		IdempotencyKey:       c.idempotencyKey,
+		DeviceID:             c.deviceID,
+		Backdated:            c.backdatedBy != "",
+		RecordedBy:           c.backdatedBy,
+		DistanceMeters:       c.distanceMeters,
+		LocationAccuracy:     c.accuracy,
+	}
+	if !c.capturedAt.IsZero() && c.backdatedBy == "" {
+		attendance.SyncedAt = &now
+	}
+
+	return attendance, event, nil
+}
+
+// churchForEvent returns the church an event belongs to, falling back to the first configured church
+func (s *AttendanceService) churchForEvent(ctx context.Context, event *models.ServiceEvent) (*models.LocalChurch, error) {
+	if event.Church != nil {
+		church, err := s.localChurchRepo.GetByID(ctx, *event.Church)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get church: %w", err)
+		}
+		if church != nil {
+			return church, nil
+		}
+	}
+
+	church, err := s.localChurchRepo.GetFirst(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get church: %w", err)
+	}
+	return church, nil
+}
+
+// churchLocation returns the timezone a church's days are counted in, falling back to the configured default
+func (s *AttendanceService) churchLocation(church *models.LocalChurch) *time.Location {
+	if church != nil && church.Timezone != "" {
+		return utils.LoadLocation(church.Timezone)
+	}
+	return utils.LoadLocation(s.cfg.Timezone)
+}
+
+// reportScope is the church an attendance report covers: the timezone its days are split in and the events
+// it reads. events is nil when the report covers every church.
+type reportScope struct {
+	loc    *time.Location
+	events []primitive.ObjectID
+}
+
+// reportScopeFor resolves the church a report is for. With a church ID the report covers that church's events
+// in its timezone; with only an event ID it is split into days in the timezone of the church holding the event.
+// Otherwise it covers every church in the default church's timezone.
+func (s *AttendanceService) reportScopeFor(ctx context.Context, churchID, eventID string) (reportScope, error) {
+	if churchID == "" {
+		if eventID != "" {
+			return s.eventReportScope(ctx, eventID)
+		}
+		church, err := s.localChurchRepo.GetFirst(ctx)
+		if err != nil {
+			return reportScope{}, fmt.Errorf("failed to get church: %w", err)
+		}
+		return reportScope{loc: s.churchLocation(church)}, nil
+	}
+
+	objID, err := primitive.ObjectIDFromHex(churchID)
+	if err != nil {
+		return reportScope{}, fmt.Errorf("%w: invalid church ID", ErrInvalidHistoryFilter)
+	}
+	church, err := s.localChurchRepo.GetByID(ctx, objID)
+	if err != nil {
+		return reportScope{}, fmt.Errorf("failed to get church: %w", err)
+	}
+	if church == nil {
+		return reportScope{}, fmt.Errorf("%w: church not found", ErrInvalidHistoryFilter)
+	}
+
+	// Events without a church belong to the default church
+	defaultChurch, err := s.localChurchRepo.GetFirst(ctx)
+	if err != nil {
+		return reportScope{}, fmt.Errorf("failed to get church: %w", err)
+	}
+	events, err := s.serviceEventRepo.GetIDsForChurch(ctx, church.ID, defaultChurch != nil && defaultChurch.ID == church.ID)
+	if err != nil {
+		return reportScope{}, fmt.Errorf("failed to get service events: %w", err)
+	}
 
-func NewAttendanceService(cfg *config.Config, attendanceRepo *repository.AttendanceRepository, userRepo *repository.UserRepository) *AttendanceService {
-	return &AttendanceService{
-		cfg:            cfg,
-		attendanceRepo: attendanceRepo,
-		userRepo:       userRepo,
+	if eventID != "" {
+		eventObjID, err := primitive.ObjectIDFromHex(eventID)
+		if err != nil {
+			return reportScope{}, fmt.Errorf("%w: invalid event ID", ErrInvalidHistoryFilter)
+		}
+		if !containsObjectID(events, eventObjID) {
+			return reportScope{}, fmt.Errorf("%w: event does not belong to the church", ErrInvalidHistoryFilter)
+		}
+		// The event filter already keeps the report to this church
+		events = nil
 	}
+
+	return reportScope{loc: s.churchLocation(church), events: events}, nil
 }
 
-func (s *AttendanceService) CreateAttendance(ctx context.Context, req *dto.CreateAttendanceRequest) (*dto.AttendanceResponse, error) {
-	// Get user
-	user, err := s.userRepo.GetByUserID(ctx, req.UserID)
+// eventReportScope splits a single event's report into days in the timezone of the church holding it
+func (s *AttendanceService) eventReportScope(ctx context.Context, eventID string) (reportScope, error) {
+	objID, err := primitive.ObjectIDFromHex(eventID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user: %w", err)
+		return reportScope{}, fmt.Errorf("%w: invalid event ID", ErrInvalidHistoryFilter)
 	}
-	if user == nil {
-		return nil, errors.New("user not found")
+	event, err := s.serviceEventRepo.GetByID(ctx, objID)
+	if err != nil {
+		return reportScope{}, fmt.Errorf("failed to get service event: %w", err)
+	}
+	if event == nil {
+		return reportScope{}, fmt.Errorf("%w: service event not found", ErrInvalidHistoryFilter)
 	}
 
-	// Check if user already has attendance for today
-	today := time.Now()
-	existingAttendance, err := s.attendanceRepo.GetByUserAndDate(ctx, user.ID, today)
+	church, err := s.churchForEvent(ctx, event)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check existing attendance: %w", err)
+		return reportScope{}, err
 	}
-	if existingAttendance != nil {
-		return nil, errors.New("attendance already recorded for today, relax there is nothing to worry about")
+	return reportScope{loc: s.churchLocation(church)}, nil
+}
+
+func containsObjectID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
 	}
+	return false
+}
 
-	// Determine if late (assuming service starts at 9 AM)
-	isLate := today.Hour() > 9
+// lateness returns how many minutes after the scheduled start a check-in happened,
+// and whether that is beyond the church's grace period
+func (s *AttendanceService) lateness(event *models.ServiceEvent, church *models.LocalChurch, checkinTime time.Time) (int, bool) {
+	gracePeriod := s.cfg.DefaultLateGracePeriod
+	if church != nil && church.LateGracePeriod != nil {
+		gracePeriod = time.Duration(*church.LateGracePeriod) * time.Minute
+	}
 
-	// Create attendance record
-	attendance := &models.Attendance{
-		User:                 user.ID,
-		DateTimeOfAttendance: today,
-		QRCodeBasedCheckin:   false,
-		Late:                 isLate,
-		ManualCheckin:        true,
-		Visitor:              user.Visitor,
-		Member:               user.Member,
+	start := scheduledStart(event, church, s.churchLocation(church))
+	if !checkinTime.After(start) {
+		return 0, false
 	}
 
-	err = s.attendanceRepo.Create(ctx, attendance)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create attendance: %w", err)
+	return int(checkinTime.Sub(start).Minutes()), checkinTime.After(start.Add(gracePeriod))
+}
+
+// scheduledStart resolves when an event is meant to begin. Sunday and midweek services use the
+// church's configured meeting hour on the event's day; everything else uses the event's own start time.
+func scheduledStart(event *models.ServiceEvent, church *models.LocalChurch, loc *time.Location) time.Time {
+	if church == nil {
+		return event.StartTime
+	}
+
+	day := event.StartTime.In(loc)
+	switch {
+	case event.EventType == models.EventTypeSundayService && day.Weekday() == time.Sunday:
+		return time.Date(day.Year(), day.Month(), day.Day(), church.SundayMeetingTime, 0, 0, 0, loc)
+	case event.EventType == models.EventTypeMidweekService && day.Weekday().String() == church.MidweekMeetingDay:
+		return time.Date(day.Year(), day.Month(), day.Day(), church.MidweekMeetingTime, 0, 0, 0, loc)
+	default:
+		return event.StartTime
+	}
+}
+
+func toAttendanceResponse(attendance *models.Attendance, userID string) *dto.AttendanceResponse {
+	eventID := ""
+	if attendance.Event != nil {
+		eventID = attendance.Event.Hex()
+	}
+
+	visitorProfileID := ""
+	if attendance.VisitorProfile != nil {
+		visitorProfileID = attendance.VisitorProfile.Hex()
+	}
+
+	familyMemberID := ""
+	if attendance.FamilyMember != nil {
+		familyMemberID = attendance.FamilyMember.Hex()
+	}
+
+	var durationMinutes *int
+	if attendance.CheckOutTime != nil {
+		minutes := int(attendance.CheckOutTime.Sub(attendance.DateTimeOfAttendance).Minutes())
+		durationMinutes = &minutes
 	}
 
 	return &dto.AttendanceResponse{
 		ID:                   attendance.ID.Hex(),
-		UserID:               user.UserID,
+		UserID:               userID,
+		EventID:              eventID,
 		DateTimeOfAttendance: attendance.DateTimeOfAttendance,
 		QRCodeBasedCheckin:   attendance.QRCodeBasedCheckin,
 		Late:                 attendance.Late,
 		ManualCheckin:        attendance.ManualCheckin,
-	}, nil
+		MinutesLate:          attendance.MinutesLate,
+		CheckinMethod:        attendance.CheckinMethod,
+		Venue:                attendance.Venue,
+		DistanceMeters:       attendance.DistanceMeters,
+		CheckOutTime:         attendance.CheckOutTime,
+		CheckedOutBy:         attendance.CheckedOutBy,
+		DurationMinutes:      durationMinutes,
+		VisitorProfileID:     visitorProfileID,
+		FamilyMemberID:       familyMemberID,
+		Household:            attendance.Household,
+		FirstVisit:           attendance.FirstVisit,
+		Backdated:            attendance.Backdated,
+		RecordedBy:           attendance.RecordedBy,
+		Voided:               attendance.Voided,
+		VoidedAt:             attendance.VoidedAt,
+		VoidedBy:             attendance.VoidedBy,
+		VoidReason:           attendance.VoidReason,
+		Visitor:              attendance.Visitor,
+		Member:               attendance.Member,
+	}
 }
 
-func (s *AttendanceService) QRCheckin(ctx context.Context, req *dto.QRCheckinRequest) (*dto.AttendanceResponse, error) {
-	// Get user by QR token
-	user, err := s.userRepo.GetByQRToken(ctx, req.QRCodeToken)
+func toVisitorProfileResponse(visitor *models.VisitorProfile) *dto.VisitorProfileResponse {
+	return &dto.VisitorProfileResponse{
+		ID:          visitor.ID.Hex(),
+		FirstName:   visitor.FirstName,
+		LastName:    visitor.LastName,
+		PhoneNumber: visitor.PhoneNumber,
+		Email:       visitor.Email,
+		InvitedBy:   visitor.InvitedBy,
+		FirstVisit:  visitor.FirstVisit,
+	}
+}
+
+// GetAttendanceHistory summarises attendance per day or, with groupBy "event", per service event. Sorting and
+// pagination happen in the database.
+func (s *AttendanceService) GetAttendanceHistory(ctx context.Context, startDate, endDate *time.Time, groupBy string, filter dto.AttendanceHistoryFilter, page, limit int) (*dto.PaginatedResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	scope, err := s.reportScopeFor(ctx, filter.ChurchID, filter.EventID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user by QR token: %w", err)
+		return nil, err
 	}
-	if user == nil {
-		return nil, errors.New("invalid QR code token")
+	loc := scope.loc
+
+	historyFilter, err := historyFilterFor(startDate, endDate, filter, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	if groupBy == "event" {
+		return s.getAttendanceHistoryByEvent(ctx, historyFilter, filter, page, limit)
 	}
 
-	// Check if user already has attendance for today
-	today := time.Now()
-	existingAttendance, err := s.attendanceRepo.GetByUserAndDate(ctx, user.ID, today)
+	sortField, ascending, err := historySortFor(dailyHistorySorts, filter)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check existing attendance: %w", err)
+		return nil, err
 	}
-	if existingAttendance != nil {
-		return nil, errors.New("attendance already recorded for today")
+
+	summaries, total, err := s.attendanceRepo.GetAttendanceByDateRange(ctx, historyFilter, loc, sortField, ascending, page, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attendance history: %w", err)
 	}
 
-	// Determine if late (assuming service starts at 9 AM)
-	isLate := today.Hour() > 9
+	history := make([]dto.AttendanceHistoryItem, 0, len(summaries))
+	for _, summary := range summaries {
+		history = append(history, dto.AttendanceHistoryItem{
+			Date:               summary.Date,
+			TotalAttendance:    summary.TotalAttendance,
+			Members:            summary.Members,
+			Visitors:           summary.Visitors,
+			AverageStayMinutes: summary.AverageStayMinutes,
+			NotCheckedOut:      summary.NotCheckedOut,
+		})
+	}
 
-	// Create attendance record
-	attendance := &models.Attendance{
-		User:                 user.ID,
-		DateTimeOfAttendance: today,
-		QRCodeBasedCheckin:   true,
-		Late:                 isLate,
-		ManualCheckin:        false,
+	return &dto.PaginatedResponse{
+		Data:       history,
+		Pagination: utils.NewPagination(page, limit, total),
+	}, nil
+}
+
+// GetAttendanceRecords lists individual check-ins with the attendee's details, newest first unless sorted otherwise
+func (s *AttendanceService) GetAttendanceRecords(ctx context.Context, startDate, endDate *time.Time, filter dto.AttendanceHistoryFilter, page, limit int) (*dto.PaginatedResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
 	}
 
-	err = s.attendanceRepo.Create(ctx, attendance)
+	scope, err := s.reportScopeFor(ctx, filter.ChurchID, filter.EventID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create attendance: %w", err)
+		return nil, err
+	}
+	historyFilter, err := historyFilterFor(startDate, endDate, filter, scope)
+	if err != nil {
+		return nil, err
 	}
 
-	return &dto.AttendanceResponse{
-		ID:                   attendance.ID.Hex(),
-		UserID:               user.UserID,
-		DateTimeOfAttendance: attendance.DateTimeOfAttendance,
-		QRCodeBasedCheckin:   attendance.QRCodeBasedCheckin,
-		Late:                 attendance.Late,
-		ManualCheckin:        attendance.ManualCheckin,
-		Visitor:              attendance.Visitor,
-		Member:               attendance.Member,
+	sortField, ascending, err := historySortFor(recordHistorySorts, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	records, total, err := s.attendanceRepo.GetHistory(ctx, historyFilter, sortField, ascending, page, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attendance records: %w", err)
+	}
+
+	items := make([]dto.AttendanceRecordHistoryItem, 0, len(records))
+	for _, record := range records {
+		item := dto.AttendanceRecordHistoryItem{
+			AttendanceID:         record.ID.Hex(),
+			UserID:               record.UserID,
+			Household:            record.Household,
+			FirstName:            record.FirstName,
+			LastName:             record.LastName,
+			Email:                record.Email,
+			PhoneNumber:          record.PhoneNumber,
+			Campus:               record.Campus,
+			EventName:            record.EventName,
+			EventType:            record.EventType,
+			DateTimeOfAttendance: record.DateTimeOfAttendance,
+			CheckinMethod:        record.CheckinMethod,
+			Late:                 record.Late,
+			MinutesLate:          record.MinutesLate,
+			Member:               record.Member,
+			Visitor:              record.Visitor,
+			CheckOutTime:         record.CheckOutTime,
+		}
+		if record.VisitorProfile != nil {
+			item.VisitorProfileID = record.VisitorProfile.Hex()
+		}
+		if record.FamilyMember != nil {
+			item.FamilyMemberID = record.FamilyMember.Hex()
+		}
+		if record.Event != nil {
+			item.EventID = record.Event.Hex()
+		}
+		items = append(items, item)
+	}
+
+	return &dto.PaginatedResponse{
+		Data:       items,
+		Pagination: utils.NewPagination(page, limit, total),
 	}, nil
 }
 
-func (s *AttendanceService) GetAttendanceHistory(ctx context.Context, startDate, endDate *time.Time, page, limit int) (*dto.PaginatedResponse, error) {
-	// Set default date range if not provided
+// historyFilterFor builds the repository filter for a history request. The range defaults to the last month and
+// both ends are whole calendar days in the church's timezone.
+func historyFilterFor(startDate, endDate *time.Time, filter dto.AttendanceHistoryFilter, scope reportScope) (repository.AttendanceHistoryFilter, error) {
+	loc := scope.loc
+	now := time.Now().In(loc)
 	if startDate == nil {
-		start := time.Now().AddDate(0, -1, 0) // Last month
+		start := now.AddDate(0, -1, 0) // Last month
 		startDate = &start
 	}
 	if endDate == nil {
-		end := time.Now()
-		endDate = &end
+		endDate = &now
 	}
 
-	// Get attendance data grouped by date
-	attendanceData, err := s.attendanceRepo.GetAttendanceByDateRange(ctx, *startDate, *endDate)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get attendance history: %w", err)
-	}
+	rangeStart, _ := utils.DayBounds(*startDate, loc)
+	_, rangeEnd := utils.DayBounds(*endDate, loc)
 
-	// Transform data to response format
-	var history []dto.AttendanceHistoryItem
-	for _, data := range attendanceData {
-		dateMap := data["_id"].(map[string]interface{})
-		year := int(dateMap["year"].(int32))
-		month := int(dateMap["month"].(int32))
-		day := int(dateMap["day"].(int32))
+	historyFilter := repository.AttendanceHistoryFilter{
+		StartDate: rangeStart,
+		EndDate:   rangeEnd,
+		Campus:    filter.Campus,
+		Events:    scope.events,
+	}
 
-		date := fmt.Sprintf("%d-%02d-%02d", year, month, day)
+	if filter.EventID != "" {
+		eventID, err := primitive.ObjectIDFromHex(filter.EventID)
+		if err != nil {
+			return historyFilter, fmt.Errorf("%w: invalid event ID", ErrInvalidHistoryFilter)
+		}
+		historyFilter.EventID = &eventID
+	}
 
-		history = append(history, dto.AttendanceHistoryItem{
-			Date:            date,
-			TotalAttendance: int(data["total_attendance"].(int32)),
-			Members:         int(data["members"].(int32)),
-			Visitors:        int(data["visitors"].(int32)),
-		})
+	switch filter.CheckinMethod {
+	case "", models.CheckinMethodQR, models.CheckinMethodVenueQR, models.CheckinMethodGeofence, models.CheckinMethodManual:
+		historyFilter.CheckinMethod = filter.CheckinMethod
+	default:
+		return historyFilter, fmt.Errorf("%w: checkin_method must be one of %s, %s, %s or %s", ErrInvalidHistoryFilter,
+			models.CheckinMethodQR, models.CheckinMethodVenueQR, models.CheckinMethodGeofence, models.CheckinMethodManual)
 	}
 
-	// Calculate pagination
-	total := len(history)
-	start := (page - 1) * limit
-	end := start + limit
+	return historyFilter, nil
+}
 
-	if start >= total {
-		history = []dto.AttendanceHistoryItem{}
-	} else {
-		if end > total {
-			end = total
-		}
-		history = history[start:end]
+// historySortFor resolves the requested sort option and order against the options a view supports. Results are
+// sorted descending unless order is "asc".
+func historySortFor(sorts []historySort, filter dto.AttendanceHistoryFilter) (string, bool, error) {
+	var ascending bool
+	switch filter.Order {
+	case "", "desc":
+	case "asc":
+		ascending = true
+	default:
+		return "", false, fmt.Errorf("%w: order must be either 'asc' or 'desc'", ErrInvalidHistoryFilter)
 	}
 
-	pagination := dto.Pagination{
-		Page:       page,
-		Limit:      limit,
-		Total:      total,
-		TotalPages: (total + limit - 1) / limit,
+	if filter.Sort == "" {
+		return sorts[0].field, ascending, nil
 	}
 
-	return &dto.PaginatedResponse{
-		Data:       history,
-		Pagination: pagination,
-	}, nil
+	names := make([]string, 0, len(sorts))
+	for _, sort := range sorts {
+		if sort.name == filter.Sort {
+			return sort.field, ascending, nil
+		}
+		names = append(names, sort.name)
+	}
+	return "", false, fmt.Errorf("%w: sort must be one of %s", ErrInvalidHistoryFilter, strings.Join(names, ", "))
 }
 
-func (s *AttendanceService) GetAttendanceAnalytics(ctx context.Context, date time.Time) (*dto.AttendanceAnalytics, error) {
+func (s *AttendanceService) GetAttendanceAnalytics(ctx context.Context, churchID string, date time.Time) (*dto.AttendanceAnalytics, error) {
 	// Get total active users all time
 	totalUsers, err := s.userRepo.CountTotal(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to count total users: %w", err)
 	}
 
+	scope, err := s.reportScopeFor(ctx, churchID, "")
+	if err != nil {
+		return nil, err
+	}
+	loc := scope.loc
+
 	// Get total attendance for the specific date
-	totalAttendanceForDate, err := s.attendanceRepo.CountTotalForDate(ctx, date)
+	totalAttendanceForDate, err := s.attendanceRepo.CountTotalForDate(ctx, scope.events, date, loc)
 	if err != nil {
 		return nil, fmt.Errorf("failed to count attendance for date: %w", err)
 	}
 
-	// Get members count for the month
-	membersForMonth, err := s.attendanceRepo.CountMembersForDate(ctx, date)
+	// Get members count for the date
+	membersForDate, err := s.attendanceRepo.CountMembersForDate(ctx, scope.events, date, loc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count members for date: %w", err)
+	}
+
+	// Get distinct members who attended during the date's month
+	membersForMonth, err := s.attendanceRepo.CountMembersForMonth(ctx, scope.events, date, loc)
 	if err != nil {
 		return nil, fmt.Errorf("failed to count members for month: %w", err)
 	}
 
 	// Get visitors count
-	visitorsCount, err := s.attendanceRepo.CountVisitorsForDate(ctx, date)
+	visitorsCount, err := s.attendanceRepo.CountVisitorsForDate(ctx, scope.events, date, loc)
 	if err != nil {
 		return nil, fmt.Errorf("failed to count visitors: %w", err)
 	}
@@ -214,7 +1684,364 @@ func (s *AttendanceService) GetAttendanceAnalytics(ctx context.Context, date tim
 	return &dto.AttendanceAnalytics{
 		TotalActiveUsersAllTime: totalUsers,
 		TotalAttendanceForDate:  totalAttendanceForDate,
+		MembersForDate:          membersForDate,
 		MembersForMonth:         membersForMonth,
 		VisitorsCount:           visitorsCount,
 	}, nil
 }
+
+func (s *AttendanceService) getAttendanceHistoryByEvent(ctx context.Context, historyFilter repository.AttendanceHistoryFilter, filter dto.AttendanceHistoryFilter, page, limit int) (*dto.PaginatedResponse, error) {
+	sortField, ascending, err := historySortFor(eventHistorySorts, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries, total, err := s.attendanceRepo.GetAttendanceByEvent(ctx, historyFilter, sortField, ascending, page, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attendance history by event: %w", err)
+	}
+
+	history := make([]dto.AttendanceEventHistoryItem, 0, len(summaries))
+	for _, summary := range summaries {
+		history = append(history, dto.AttendanceEventHistoryItem{
+			EventID:            summary.EventID.Hex(),
+			EventName:          summary.EventName,
+			EventType:          summary.EventType,
+			StartTime:          summary.StartTime,
+			Members:            summary.Members,
+			Visitors:           summary.Visitors,
+			TotalAttendance:    summary.TotalAttendance,
+			AverageStayMinutes: summary.AverageStayMinutes,
+			NotCheckedOut:      summary.NotCheckedOut,
+		})
+	}
+
+	return &dto.PaginatedResponse{
+		Data:       history,
+		Pagination: utils.NewPagination(page, limit, total),
+	}, nil
+}
+
+func (s *AttendanceService) GetEventAttendanceAnalytics(ctx context.Context, eventID string) (*dto.EventAttendanceAnalytics, error) {
+	objID, err := primitive.ObjectIDFromHex(eventID)
+	if err != nil {
+		return nil, errors.New("invalid event ID")
+	}
+
+	event, err := s.serviceEventRepo.GetByID(ctx, objID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service event: %w", err)
+	}
+	if event == nil {
+		return nil, errors.New("service event not found")
+	}
+
+	total, members, visitors, late, err := s.attendanceRepo.CountForEvent(ctx, event.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count attendance for event: %w", err)
+	}
+
+	buckets, averageMinutesLate, err := s.attendanceRepo.GetPunctualityForEvent(ctx, event.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get punctuality for event: %w", err)
+	}
+
+	households, dependents, err := s.attendanceRepo.CountHouseholdsForEvent(ctx, event.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count households for event: %w", err)
+	}
+
+	checkedOut, averageStay, err := s.attendanceRepo.GetStayForEvent(ctx, event.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stay length for event: %w", err)
+	}
+
+	openRecords, err := s.attendanceRepo.GetOpenForEvent(ctx, event.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attendees not checked out: %w", err)
+	}
+
+	notCheckedOut := make([]dto.OpenAttendanceItem, 0, len(openRecords))
+	for _, record := range openRecords {
+		notCheckedOut = append(notCheckedOut, dto.OpenAttendanceItem{
+			AttendanceID:         record.ID.Hex(),
+			UserID:               record.UserID,
+			FirstName:            record.FirstName,
+			LastName:             record.LastName,
+			DateTimeOfAttendance: record.DateTimeOfAttendance,
+		})
+	}
+
+	punctuality := make([]dto.PunctualityBucket, 0, len(buckets))
+	for _, bucket := range buckets {
+		punctuality = append(punctuality, dto.PunctualityBucket{
+			Label: bucket.Label,
+			Count: bucket.Count,
+		})
+	}
+
+	return &dto.EventAttendanceAnalytics{
+		EventID:            event.ID.Hex(),
+		EventName:          event.Name,
+		EventType:          event.EventType,
+		TotalAttendance:    total,
+		Members:            members,
+		Visitors:           visitors,
+		Late:               late,
+		Households:         households,
+		Dependents:         dependents,
+		AverageMinutesLate: averageMinutesLate,
+		Punctuality:        punctuality,
+		CheckedOut:         checkedOut,
+		AverageStayMinutes: averageStay,
+		NotCheckedOut:      notCheckedOut,
+	}, nil
+}
+
+// GetMemberAttendance returns a member's attendance records by event together with their consecutive-Sunday
+// streaks, attendance rates over the given windows (in days) and a daily heatmap of the past year
+func (s *AttendanceService) GetMemberAttendance(ctx context.Context, userID string, windows []int, page, limit int) (*dto.MemberAttendanceResponse, error) {
+	if len(windows) == 0 {
+		windows = defaultRateWindows
+	}
+	if len(windows) > maxRateWindows {
+		return nil, fmt.Errorf("at most %d rate windows can be requested", maxRateWindows)
+	}
+	for _, days := range windows {
+		if days < 1 || days > maxRateWindowDays {
+			return nil, fmt.Errorf("rate windows must be between 1 and %d days", maxRateWindowDays)
+		}
+	}
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	user, err := s.userRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	scope, err := s.reportScopeFor(ctx, "", "")
+	if err != nil {
+		return nil, err
+	}
+	loc := scope.loc
+
+	times, err := s.attendanceRepo.GetTimesForUser(ctx, user.ID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attendance: %w", err)
+	}
+
+	records, total, err := s.attendanceRepo.GetForUser(ctx, user.ID, page, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attendance records: %w", err)
+	}
+
+	now := time.Now()
+	rates, err := s.attendanceRates(ctx, user.ID, times, windows, now, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]dto.MemberAttendanceRecord, 0, len(records))
+	for _, record := range records {
+		item := dto.MemberAttendanceRecord{
+			AttendanceID:         record.ID.Hex(),
+			EventName:            record.EventName,
+			EventType:            record.EventType,
+			EventStart:           record.EventStart,
+			DateTimeOfAttendance: record.DateTimeOfAttendance,
+			Late:                 record.Late,
+			MinutesLate:          record.MinutesLate,
+			CheckinMethod:        record.CheckinMethod,
+			CheckOutTime:         record.CheckOutTime,
+		}
+		if record.Event != nil {
+			item.EventID = record.Event.Hex()
+		}
+		if record.CheckOutTime != nil {
+			minutes := int(record.CheckOutTime.Sub(record.DateTimeOfAttendance).Minutes())
+			item.DurationMinutes = &minutes
+		}
+		items = append(items, item)
+	}
+
+	return &dto.MemberAttendanceResponse{
+		UserID:          user.UserID,
+		FirstName:       user.FirstName,
+		LastName:        user.LastName,
+		TotalAttendance: len(times),
+		Streaks:         sundayStreaks(times, now, loc),
+		Rates:           rates,
+		Heatmap:         attendanceHeatmap(times, now, loc),
+		Records:         items,
+		Pagination:      utils.NewPagination(page, limit, total),
+	}, nil
+}
+
+// attendanceRates compares how many of their church's regular services a member attended with how many were held
+// in each window. A window never reaches back before the member's first attendance, so newcomers aren't measured
+// against services they could not have attended.
+func (s *AttendanceService) attendanceRates(ctx context.Context, userID primitive.ObjectID, times []time.Time, windows []int, now time.Time, loc *time.Location) ([]dto.AttendanceRateWindow, error) {
+	var firstDay time.Time
+	if len(times) > 0 {
+		firstDay, _ = utils.DayBounds(times[0].In(loc), loc)
+	}
+
+	scope, err := s.memberServiceScope(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	longest := 0
+	for _, days := range windows {
+		if days > longest {
+			longest = days
+		}
+	}
+	services, err := s.serviceEventRepo.GetIDsStartedBetween(ctx, now.AddDate(0, 0, -longest), now, scope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service events: %w", err)
+	}
+	serviceTimes, err := s.attendanceRepo.GetTimesForUser(ctx, userID, services)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attendance: %w", err)
+	}
+
+	rates := make([]dto.AttendanceRateWindow, 0, len(windows))
+	for _, days := range windows {
+		start := now.AddDate(0, 0, -days)
+		if firstDay.After(start) {
+			start = firstDay
+		}
+
+		held, err := s.serviceEventRepo.CountStartedBetween(ctx, start, now, scope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count service events: %w", err)
+		}
+
+		attended := 0
+		for _, t := range serviceTimes {
+			if !t.Before(start) && !t.After(now) {
+				attended++
+			}
+		}
+
+		rate := 0.0
+		if held > 0 {
+			rate = math.Min(100, math.Round(float64(attended)/float64(held)*1000)/10)
+		}
+
+		rates = append(rates, dto.AttendanceRateWindow{
+			Days:           days,
+			EventsHeld:     held,
+			EventsAttended: attended,
+			Rate:           rate,
+		})
+	}
+	return rates, nil
+}
+
+// memberServiceScope returns the regular services of the member's church: the church holding the last regular
+// service they attended, or the default church when they have not attended one
+func (s *AttendanceService) memberServiceScope(ctx context.Context, userID primitive.ObjectID) (repository.EventScope, error) {
+	scope := repository.EventScope{Types: regularServiceTypes}
+
+	defaultChurch, err := s.localChurchRepo.GetFirst(ctx)
+	if err != nil {
+		return scope, fmt.Errorf("failed to get church: %w", err)
+	}
+	church := defaultChurch
+
+	eventID, err := s.attendanceRepo.GetLatestEventForUser(ctx, userID, regularServiceTypes)
+	if err != nil {
+		return scope, fmt.Errorf("failed to get attendance: %w", err)
+	}
+	if eventID != nil {
+		event, err := s.serviceEventRepo.GetByID(ctx, *eventID)
+		if err != nil {
+			return scope, fmt.Errorf("failed to get service event: %w", err)
+		}
+		if event != nil {
+			if church, err = s.churchForEvent(ctx, event); err != nil {
+				return scope, err
+			}
+		}
+	}
+
+	if church != nil {
+		scope.Church = &church.ID
+		scope.IncludeUnassigned = defaultChurch != nil && defaultChurch.ID == church.ID
+	}
+	return scope, nil
+}
+
+// sundayStreaks counts runs of consecutive Sundays with at least one attendance. The current streak is still
+// alive on a Sunday the member hasn't checked in to yet.
+func sundayStreaks(times []time.Time, now time.Time, loc *time.Location) dto.AttendanceStreaks {
+	var streaks dto.AttendanceStreaks
+
+	attended := make(map[string]bool)
+	var sundays []time.Time
+	for _, t := range times {
+		local := t.In(loc)
+		if local.Weekday() != time.Sunday {
+			continue
+		}
+		day, _ := utils.DayBounds(local, loc)
+		key := day.Format("2006-01-02")
+		if !attended[key] {
+			attended[key] = true
+			sundays = append(sundays, day)
+		}
+	}
+	if len(sundays) == 0 {
+		return streaks
+	}
+
+	run := 0
+	for i, day := range sundays {
+		if i > 0 && sundays[i-1].AddDate(0, 0, 7).Equal(day) {
+			run++
+		} else {
+			run = 1
+		}
+		if run > streaks.LongestSundayStreak {
+			streaks.LongestSundayStreak = run
+		}
+	}
+	streaks.LastSundayAttended = sundays[len(sundays)-1].Format("2006-01-02")
+
+	today := now.In(loc)
+	sunday, _ := utils.DayBounds(today.AddDate(0, 0, -int(today.Weekday())), loc)
+	if today.Weekday() == time.Sunday && !attended[sunday.Format("2006-01-02")] {
+		sunday = sunday.AddDate(0, 0, -7)
+	}
+	for attended[sunday.Format("2006-01-02")] {
+		streaks.CurrentSundayStreak++
+		sunday = sunday.AddDate(0, 0, -7)
+	}
+
+	return streaks
+}
+
+// attendanceHeatmap returns one entry per day for the past year, oldest first, with the number of check-ins that day
+func attendanceHeatmap(times []time.Time, now time.Time, loc *time.Location) []dto.AttendanceHeatmapDay {
+	counts := make(map[string]int)
+	for _, t := range times {
+		counts[t.In(loc).Format("2006-01-02")]++
+	}
+
+	today, _ := utils.DayBounds(now.In(loc), loc)
+	heatmap := make([]dto.AttendanceHeatmapDay, 0, heatmapDays)
+	for i := heatmapDays - 1; i >= 0; i-- {
+		date := today.AddDate(0, 0, -i).Format("2006-01-02")
+		heatmap = append(heatmap, dto.AttendanceHeatmapDay{Date: date, Count: counts[date]})
+	}
+	return heatmap
+}
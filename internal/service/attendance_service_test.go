@@ -0,0 +1,815 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"cci-api/internal/config"
+	"cci-api/internal/dto"
+	"cci-api/internal/models"
+	"cci-api/internal/repository"
+	"cci-api/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+var lagos = time.FixedZone("WAT", 60*60)
+
+func TestLateness(t *testing.T) {
+	fiveMinutes := 5
+	church := &models.LocalChurch{
+		SundayMeetingTime:  9,
+		MidweekMeetingDay:  "Wednesday",
+		MidweekMeetingTime: 18,
+		Timezone:           "Africa/Lagos",
+	}
+	strictChurch := *church
+	strictChurch.LateGracePeriod = &fiveMinutes
+
+	// 17 August 2025 is a Sunday and 20 August a Wednesday
+	sunday := &models.ServiceEvent{
+		EventType: models.EventTypeSundayService,
+		StartTime: time.Date(2025, 8, 17, 8, 30, 0, 0, lagos),
+	}
+	wednesday := &models.ServiceEvent{
+		EventType: models.EventTypeMidweekService,
+		StartTime: time.Date(2025, 8, 20, 17, 30, 0, 0, lagos),
+	}
+	thursday := &models.ServiceEvent{
+		EventType: models.EventTypeMidweekService,
+		StartTime: time.Date(2025, 8, 21, 17, 30, 0, 0, lagos),
+	}
+	vigil := &models.ServiceEvent{
+		EventType: models.EventTypeVigil,
+		StartTime: time.Date(2025, 8, 22, 22, 0, 0, 0, lagos),
+	}
+
+	tests := []struct {
+		name        string
+		event       *models.ServiceEvent
+		church      *models.LocalChurch
+		checkin     time.Time
+		wantMinutes int
+		wantLate    bool
+	}{
+		{"early", sunday, church, time.Date(2025, 8, 17, 8, 50, 0, 0, lagos), 0, false},
+		{"on time", sunday, church, time.Date(2025, 8, 17, 9, 0, 0, 0, lagos), 0, false},
+		{"within grace", sunday, church, time.Date(2025, 8, 17, 9, 10, 0, 0, lagos), 10, false},
+		{"end of grace", sunday, church, time.Date(2025, 8, 17, 9, 15, 0, 0, lagos), 15, false},
+		{"after grace", sunday, church, time.Date(2025, 8, 17, 9, 16, 0, 0, lagos), 16, true},
+		{"church grace period", sunday, &strictChurch, time.Date(2025, 8, 17, 9, 10, 0, 0, lagos), 10, true},
+		{"check-in in another zone", sunday, church, time.Date(2025, 8, 17, 8, 20, 0, 0, time.UTC), 20, true},
+		{"no church uses event start", sunday, nil, time.Date(2025, 8, 17, 9, 0, 0, 0, lagos), 30, true},
+		{"midweek meeting day", wednesday, church, time.Date(2025, 8, 20, 18, 5, 0, 0, lagos), 5, false},
+		{"midweek on another day", thursday, church, time.Date(2025, 8, 21, 18, 5, 0, 0, lagos), 35, true},
+		{"other event types use event start", vigil, church, time.Date(2025, 8, 22, 22, 20, 0, 0, lagos), 20, true},
+	}
+
+	s := &AttendanceService{cfg: &config.Config{
+		Timezone:               "Africa/Lagos",
+		DefaultLateGracePeriod: 15 * time.Minute,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			minutes, late := s.lateness(tt.event, tt.church, tt.checkin)
+			if minutes != tt.wantMinutes || late != tt.wantLate {
+				t.Errorf("lateness = (%d, %v), want (%d, %v)", minutes, late, tt.wantMinutes, tt.wantLate)
+			}
+		})
+	}
+}
+
+func TestSundayStreaks(t *testing.T) {
+	at := func(month time.Month, day, hour int) time.Time {
+		return time.Date(2025, month, day, hour, 0, 0, 0, lagos)
+	}
+	wednesday := at(time.August, 20, 12)
+
+	tests := []struct {
+		name  string
+		times []time.Time
+		now   time.Time
+		want  dto.AttendanceStreaks
+	}{
+		{
+			name: "no attendance",
+			now:  wednesday,
+			want: dto.AttendanceStreaks{},
+		},
+		{
+			name:  "unbroken run",
+			times: []time.Time{at(time.August, 3, 9), at(time.August, 10, 9), at(time.August, 17, 9)},
+			now:   wednesday,
+			want:  dto.AttendanceStreaks{CurrentSundayStreak: 3, LongestSundayStreak: 3, LastSundayAttended: "2025-08-17"},
+		},
+		{
+			name: "missed last sunday",
+			times: []time.Time{
+				at(time.July, 6, 9), at(time.July, 13, 9), at(time.July, 20, 9),
+				at(time.August, 3, 9), at(time.August, 10, 9),
+			},
+			now:  wednesday,
+			want: dto.AttendanceStreaks{CurrentSundayStreak: 0, LongestSundayStreak: 3, LastSundayAttended: "2025-08-10"},
+		},
+		{
+			name:  "sunday not checked in yet",
+			times: []time.Time{at(time.August, 10, 9), at(time.August, 17, 9)},
+			now:   at(time.August, 24, 7),
+			want:  dto.AttendanceStreaks{CurrentSundayStreak: 2, LongestSundayStreak: 2, LastSundayAttended: "2025-08-17"},
+		},
+		{
+			name:  "sunday checked in",
+			times: []time.Time{at(time.August, 10, 9), at(time.August, 17, 9)},
+			now:   at(time.August, 17, 12),
+			want:  dto.AttendanceStreaks{CurrentSundayStreak: 2, LongestSundayStreak: 2, LastSundayAttended: "2025-08-17"},
+		},
+		{
+			name:  "weekdays and repeat check-ins ignored",
+			times: []time.Time{at(time.August, 13, 18), at(time.August, 17, 9), at(time.August, 17, 11)},
+			now:   wednesday,
+			want:  dto.AttendanceStreaks{CurrentSundayStreak: 1, LongestSundayStreak: 1, LastSundayAttended: "2025-08-17"},
+		},
+		{
+			name:  "day read in the church timezone",
+			times: []time.Time{time.Date(2025, 8, 16, 23, 30, 0, 0, time.UTC)},
+			now:   wednesday,
+			want:  dto.AttendanceStreaks{CurrentSundayStreak: 1, LongestSundayStreak: 1, LastSundayAttended: "2025-08-17"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sundayStreaks(tt.times, tt.now, lagos); got != tt.want {
+				t.Errorf("sundayStreaks = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func newMockAttendanceService(mt *mtest.T) *AttendanceService {
+	db := mockDatabase(mt)
+	cfg := &config.Config{
+		Timezone:                "Africa/Lagos",
+		DefaultLateGracePeriod:  15 * time.Minute,
+		EventCheckinEarlyWindow: time.Hour,
+		QRRotationInterval:      30 * time.Second,
+		OfflineSyncMaxAge:       72 * time.Hour,
+	}
+	return NewAttendanceService(cfg, db,
+		repository.NewAttendanceRepository(db),
+		repository.NewUserRepository(db),
+		repository.NewServiceEventRepository(db),
+		repository.NewLocalChurchRepository(db),
+		repository.NewQRTokenUseRepository(db),
+		repository.NewVenueCodeRepository(db),
+		repository.NewVisitorRepository(db),
+		repository.NewAttendanceAuditRepository(db),
+		repository.NewFamilyMemberRepository(db),
+	)
+}
+
+// openService is a Sunday service that started ten minutes ago and runs for another two hours. Times are kept
+// to the millisecond, as Mongo stores them.
+func openService() *models.ServiceEvent {
+	now := time.Now().Truncate(time.Millisecond)
+	return &models.ServiceEvent{
+		ID:        primitive.NewObjectID(),
+		Name:      "Sunday Service",
+		EventType: models.EventTypeSundayService,
+		StartTime: now.Add(-10 * time.Minute),
+		EndTime:   now.Add(2 * time.Hour),
+	}
+}
+
+func TestCreateAttendanceDuplicates(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	user := &models.User{ID: primitive.NewObjectID(), UserID: "CCIMRB-10422", FirstName: "Ada", LastName: "Obi", Member: true}
+	event := openService()
+	req := &dto.CreateAttendanceRequest{UserID: user.UserID, EventID: event.ID.Hex()}
+
+	mt.Run("first check-in is recorded", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mockFound(mt, "users", user),
+			mockFound(mt, "service_events", event),
+			mockFound(mt, "local_churches"),
+			mockFound(mt, "attendance"),
+			mtest.CreateSuccessResponse(),
+		)
+
+		resp, err := newMockAttendanceService(mt).CreateAttendance(context.Background(), req)
+		if err != nil {
+			t.Fatalf("CreateAttendance: %v", err)
+		}
+		if resp.UserID != user.UserID || resp.Late {
+			t.Errorf("CreateAttendance = %+v, want an on-time record for %s", resp, user.UserID)
+		}
+	})
+
+	mt.Run("existing check-in is rejected before writing", func(mt *mtest.T) {
+		existing := &models.Attendance{ID: primitive.NewObjectID(), User: user.ID, Event: &event.ID}
+		mt.AddMockResponses(
+			mockFound(mt, "users", user),
+			mockFound(mt, "service_events", event),
+			mockFound(mt, "local_churches"),
+			mockFound(mt, "attendance", existing),
+		)
+
+		_, err := newMockAttendanceService(mt).CreateAttendance(context.Background(), req)
+		if !errors.Is(err, ErrAlreadyCheckedIn) {
+			t.Fatalf("CreateAttendance error = %v, want ErrAlreadyCheckedIn", err)
+		}
+		for _, command := range sentCommands(mt) {
+			if command == "insert" {
+				t.Error("a duplicate check-in was written")
+			}
+		}
+	})
+
+	mt.Run("check-in that loses a race to the unique index is a duplicate", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mockFound(mt, "users", user),
+			mockFound(mt, "service_events", event),
+			mockFound(mt, "local_churches"),
+			mockFound(mt, "attendance"),
+			mockDuplicateKey("user_1_event_1"),
+		)
+
+		_, err := newMockAttendanceService(mt).CreateAttendance(context.Background(), req)
+		if !errors.Is(err, ErrAlreadyCheckedIn) {
+			t.Fatalf("CreateAttendance error = %v, want ErrAlreadyCheckedIn", err)
+		}
+	})
+}
+
+func TestQRCheckinReplay(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	user := &models.User{ID: primitive.NewObjectID(), UserID: "CCIMRB-10422", QRSecret: "member-secret", Member: true}
+	event := openService()
+	current := utils.QRCounter(time.Now(), 30*time.Second)
+	token := utils.SignRotatingQRToken(user.UserID, user.QRSecret, current)
+	req := &dto.QRCheckinRequest{QRCodeToken: token, EventID: event.ID.Hex()}
+
+	mt.Run("scanned code is refused", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mockFound(mt, "users", user),
+			mockFound(mt, "qr_token_uses", bson.D{{Key: "n", Value: 1}}),
+		)
+
+		_, err := newMockAttendanceService(mt).QRCheckin(context.Background(), req)
+		if !errors.Is(err, errQRTokenUsed) {
+			t.Fatalf("QRCheckin error = %v, want errQRTokenUsed", err)
+		}
+	})
+
+	mt.Run("code spent by a concurrent scan is refused", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mockFound(mt, "users", user),
+			mockFound(mt, "qr_token_uses"),
+			mockFound(mt, "service_events", event),
+			mockFound(mt, "local_churches"),
+			mockFound(mt, "attendance"),
+			mockDuplicateKey("_id_"),
+			mtest.CreateSuccessResponse(),
+		)
+
+		_, err := newMockAttendanceService(mt).QRCheckin(context.Background(), req)
+		if !errors.Is(err, errQRTokenUsed) {
+			t.Fatalf("QRCheckin error = %v, want errQRTokenUsed", err)
+		}
+		for _, event := range mt.GetAllStartedEvents() {
+			if event.CommandName == "insert" && event.Command.Lookup("insert").StringValue() != "qr_token_uses" {
+				t.Errorf("check-in with a spent code wrote to %s", event.Command.Lookup("insert").StringValue())
+			}
+		}
+	})
+
+	mt.Run("expired code is refused without a lookup", func(mt *mtest.T) {
+		stale := &dto.QRCheckinRequest{QRCodeToken: utils.SignRotatingQRToken(user.UserID, user.QRSecret, current-4)}
+
+		_, err := newMockAttendanceService(mt).QRCheckin(context.Background(), stale)
+		if err == nil {
+			t.Fatal("QRCheckin accepted an expired code")
+		}
+		if commands := sentCommands(mt); len(commands) > 0 {
+			t.Errorf("expired code sent %v", commands)
+		}
+	})
+
+	mt.Run("static token is refused by default", func(mt *mtest.T) {
+		static := &dto.QRCheckinRequest{QRCodeToken: "3f2a9c1e7b"}
+
+		_, err := newMockAttendanceService(mt).QRCheckin(context.Background(), static)
+		if err == nil {
+			t.Fatal("QRCheckin accepted a static token")
+		}
+	})
+}
+
+func TestSyncBatchItems(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	now := time.Now()
+	user := &models.User{ID: primitive.NewObjectID(), UserID: "CCIMRB-10422", Member: true}
+	// The service ended an hour ago, so only captures made while it was open can still be synced
+	ended := &models.ServiceEvent{
+		ID:        primitive.NewObjectID(),
+		Name:      "Midweek Service",
+		EventType: models.EventTypeMidweekService,
+		StartTime: now.Add(-3 * time.Hour),
+		EndTime:   now.Add(-time.Hour),
+	}
+	item := func(capturedAt time.Time) dto.BatchAttendanceItem {
+		return dto.BatchAttendanceItem{
+			IdempotencyKey: "tablet-2:" + capturedAt.Format(time.RFC3339),
+			UserID:         user.UserID,
+			EventID:        ended.ID.Hex(),
+			CapturedAt:     capturedAt.Format(time.RFC3339),
+		}
+	}
+
+	tests := []struct {
+		name       string
+		item       dto.BatchAttendanceItem
+		replies    func(mt *mtest.T) []bson.D
+		wantStatus string
+		wantReason string
+	}{
+		{
+			name: "captured while open",
+			item: item(now.Add(-2 * time.Hour)),
+			replies: func(mt *mtest.T) []bson.D {
+				return []bson.D{
+					mockFound(mt, "attendance"),
+					mockFound(mt, "users", user),
+					mockFound(mt, "service_events", ended),
+					mockFound(mt, "local_churches"),
+					mockFound(mt, "attendance"),
+					mtest.CreateSuccessResponse(),
+				}
+			},
+			wantStatus: batchStatusRecorded,
+		},
+		{
+			name: "re-sent item",
+			item: item(now.Add(-2 * time.Hour)),
+			replies: func(mt *mtest.T) []bson.D {
+				synced := &models.Attendance{ID: primitive.NewObjectID(), Event: &ended.ID, IdempotencyKey: "tablet-2"}
+				return []bson.D{mockFound(mt, "attendance", synced), mockFound(mt, "users")}
+			},
+			wantStatus: batchStatusDuplicate,
+		},
+		{
+			name: "captured after the event ended",
+			item: item(now.Add(-30 * time.Minute)),
+			replies: func(mt *mtest.T) []bson.D {
+				return []bson.D{
+					mockFound(mt, "attendance"),
+					mockFound(mt, "users", user),
+					mockFound(mt, "service_events", ended),
+				}
+			},
+			wantStatus: batchStatusRejected,
+			wantReason: "has ended",
+		},
+		{
+			name: "older than the sync limit",
+			item: item(now.Add(-100 * time.Hour)),
+			replies: func(mt *mtest.T) []bson.D {
+				return []bson.D{mockFound(mt, "attendance")}
+			},
+			wantStatus: batchStatusRejected,
+		},
+		{
+			name: "captured in the future",
+			item: item(now.Add(time.Hour)),
+			replies: func(mt *mtest.T) []bson.D {
+				return []bson.D{mockFound(mt, "attendance")}
+			},
+			wantStatus: batchStatusRejected,
+		},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tt.replies(mt)...)
+
+			resp, err := newMockAttendanceService(mt).SyncBatch(context.Background(), &dto.BatchAttendanceRequest{
+				DeviceID: "tablet-2",
+				Items:    []dto.BatchAttendanceItem{tt.item},
+			})
+			if err != nil {
+				t.Fatalf("SyncBatch: %v", err)
+			}
+			got := resp.Results[0]
+			if got.Status != tt.wantStatus {
+				t.Errorf("status = %q (%s), want %q", got.Status, got.Message, tt.wantStatus)
+			}
+			if !strings.Contains(got.Message, tt.wantReason) {
+				t.Errorf("message = %q, want it to mention %q", got.Message, tt.wantReason)
+			}
+		})
+	}
+
+	mt.Run("oversized batch", func(mt *mtest.T) {
+		items := make([]dto.BatchAttendanceItem, maxBatchItems+1)
+		if _, err := newMockAttendanceService(mt).SyncBatch(context.Background(), &dto.BatchAttendanceRequest{Items: items}); err == nil {
+			t.Error("SyncBatch accepted an oversized batch")
+		}
+	})
+}
+
+func TestAttendanceCorrections(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	user := &models.User{ID: primitive.NewObjectID(), UserID: "CCIMRB-10422", Member: true}
+	event := openService()
+	record := &models.Attendance{ID: primitive.NewObjectID(), User: user.ID, Event: &event.ID, DateTimeOfAttendance: time.Now()}
+	void := &dto.VoidAttendanceRequest{Reason: "scanned the wrong card"}
+
+	mt.Run("void is audited in the same transaction", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mockFound(mt, "attendance", record),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateSuccessResponse(),
+			mockFound(mt, "users", user),
+		)
+
+		resp, err := newMockAttendanceService(mt).VoidAttendance(context.Background(), record.ID.Hex(), void, "CCIMRB-00001")
+		if err != nil {
+			t.Fatalf("VoidAttendance: %v", err)
+		}
+		if !resp.Voided {
+			t.Error("VoidAttendance returned a record that is not voided")
+		}
+		if got := writesTo(mt); len(got) != 2 || got[0] != "attendance" || got[1] != "attendance_audit" {
+			t.Errorf("writes = %v, want the attendance update then its audit entry", got)
+		}
+	})
+
+	mt.Run("void that loses a race is not audited", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mockFound(mt, "attendance", record),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+			mtest.CreateSuccessResponse(),
+		)
+
+		if _, err := newMockAttendanceService(mt).VoidAttendance(context.Background(), record.ID.Hex(), void, "CCIMRB-00001"); err == nil {
+			t.Fatal("VoidAttendance voided a record twice")
+		}
+		for _, collection := range writesTo(mt) {
+			if collection == "attendance_audit" {
+				t.Error("a void that changed nothing was audited")
+			}
+		}
+	})
+
+	mt.Run("voided record cannot be corrected again", func(mt *mtest.T) {
+		voided := *record
+		voided.Voided = true
+		mt.AddMockResponses(mockFound(mt, "attendance", &voided))
+
+		if _, err := newMockAttendanceService(mt).VoidAttendance(context.Background(), record.ID.Hex(), void, "CCIMRB-00001"); err == nil {
+			t.Fatal("VoidAttendance accepted a voided record")
+		}
+		if got := writesTo(mt); len(got) > 0 {
+			t.Errorf("writes = %v, want none", got)
+		}
+	})
+
+	mt.Run("back-dating is audited and flagged", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mockFound(mt, "users", user),
+			mockFound(mt, "service_events", event),
+			mockFound(mt, "service_events", event),
+			mockFound(mt, "local_churches"),
+			mockFound(mt, "attendance"),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateSuccessResponse(),
+		)
+
+		resp, err := newMockAttendanceService(mt).BackdateAttendance(context.Background(), &dto.BackdateAttendanceRequest{
+			UserID:  user.UserID,
+			EventID: event.ID.Hex(),
+			Reason:  "usher tablet was offline",
+		}, "CCIMRB-00001")
+		if err != nil {
+			t.Fatalf("BackdateAttendance: %v", err)
+		}
+		if !resp.Backdated || !resp.DateTimeOfAttendance.Equal(event.StartTime) {
+			t.Errorf("BackdateAttendance = %+v, want a back-dated record at the event start", resp)
+		}
+		if got := writesTo(mt); len(got) != 2 || got[1] != "attendance_audit" {
+			t.Errorf("writes = %v, want the attendance then its audit entry", got)
+		}
+	})
+
+	mt.Run("back-dating into the future is refused", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mockFound(mt, "users", user),
+			mockFound(mt, "service_events", event),
+		)
+
+		_, err := newMockAttendanceService(mt).BackdateAttendance(context.Background(), &dto.BackdateAttendanceRequest{
+			UserID:               user.UserID,
+			EventID:              event.ID.Hex(),
+			DateTimeOfAttendance: time.Now().Add(time.Hour).Format(time.RFC3339),
+			Reason:               "usher tablet was offline",
+		}, "CCIMRB-00001")
+		if err == nil {
+			t.Fatal("BackdateAttendance accepted a future check-in")
+		}
+	})
+}
+
+func TestHistorySortFor(t *testing.T) {
+	tests := []struct {
+		name          string
+		sorts         []historySort
+		sort, order   string
+		wantField     string
+		wantAscending bool
+		wantErr       bool
+	}{
+		{"daily default", dailyHistorySorts, "", "", "_id", false, false},
+		{"daily by visitors", dailyHistorySorts, "visitors", "asc", "visitors", true, false},
+		{"event default", eventHistorySorts, "", "asc", "start_time", true, false},
+		{"records default", recordHistorySorts, "", "desc", "date_time_of_attendance", false, false},
+		{"records by lateness", recordHistorySorts, "minutes_late", "", "minutes_late", false, false},
+		{"option from another view", recordHistorySorts, "visitors", "", "", false, true},
+		{"unknown order", dailyHistorySorts, "date", "up", "", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			field, ascending, err := historySortFor(tt.sorts, dto.AttendanceHistoryFilter{Sort: tt.sort, Order: tt.order})
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidHistoryFilter) {
+					t.Fatalf("err = %v, want ErrInvalidHistoryFilter", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if field != tt.wantField || ascending != tt.wantAscending {
+				t.Errorf("got (%q, %v), want (%q, %v)", field, ascending, tt.wantField, tt.wantAscending)
+			}
+		})
+	}
+}
+
+func TestGetAttendanceRecordsPages(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	church := models.LocalChurch{ID: primitive.NewObjectID(), ChurchName: "Ikeja", Timezone: "Africa/Lagos"}
+	checkedIn := time.Date(2025, 8, 17, 9, 20, 0, 0, time.UTC)
+	page := bson.M{
+		"data": bson.A{
+			repository.AttendanceHistoryRecord{ID: primitive.NewObjectID(), UserID: "u1", DateTimeOfAttendance: checkedIn, Late: true, MinutesLate: 20},
+			repository.AttendanceHistoryRecord{ID: primitive.NewObjectID(), UserID: "u2", DateTimeOfAttendance: checkedIn, Late: true, MinutesLate: 12},
+		},
+		"total": bson.A{bson.M{"n": 23}},
+	}
+
+	mt.Run("limits out of range fall back to the default page", func(mt *mtest.T) {
+		s := newMockAttendanceService(mt)
+		mt.AddMockResponses(mockFound(mt, "local_churches", church), mockFound(mt, "attendance", page))
+
+		filter := dto.AttendanceHistoryFilter{Sort: "minutes_late"}
+		resp, err := s.GetAttendanceRecords(context.Background(), nil, nil, filter, 0, 500)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		want := dto.Pagination{Page: 1, Limit: 10, Total: 23, TotalPages: 3}
+		if resp.Pagination != want {
+			t.Errorf("pagination = %+v, want %+v", resp.Pagination, want)
+		}
+		items := resp.Data.([]dto.AttendanceRecordHistoryItem)
+		if len(items) != 2 || items[0].UserID != "u1" || items[0].MinutesLate != 20 {
+			t.Errorf("items = %+v", items)
+		}
+
+		var sortStage bson.D
+		for _, event := range mt.GetAllStartedEvents() {
+			if event.CommandName != "aggregate" {
+				continue
+			}
+			stages, err := event.Command.Lookup("pipeline").Array().Values()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, stage := range stages {
+				if sortSpec, ok := stage.Document().Lookup("$sort").DocumentOK(); ok {
+					if err := bson.Unmarshal(sortSpec, &sortStage); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+		}
+		if len(sortStage) == 0 || sortStage[0].Key != "minutes_late" || sortStage[0].Value != int32(-1) {
+			t.Errorf("sort stage = %v, want minutes_late descending", sortStage)
+		}
+	})
+
+	mt.Run("unknown sort is refused before querying attendance", func(mt *mtest.T) {
+		s := newMockAttendanceService(mt)
+		mt.AddMockResponses(mockFound(mt, "local_churches", church))
+
+		filter := dto.AttendanceHistoryFilter{Sort: "total_attendance"}
+		_, err := s.GetAttendanceRecords(context.Background(), nil, nil, filter, 1, 10)
+		if !errors.Is(err, ErrInvalidHistoryFilter) {
+			t.Fatalf("err = %v, want ErrInvalidHistoryFilter", err)
+		}
+		if cmds := sentCommands(mt); len(cmds) != 1 || cmds[0] != "find" {
+			t.Errorf("commands = %v, want only the church lookup", cmds)
+		}
+	})
+}
+
+func TestCheckInHousehold(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	head := &models.User{ID: primitive.NewObjectID(), UserID: "CCIMRB-20310", FirstName: "Chidi", LastName: "Eze", Member: true}
+	spouse := &models.FamilyMember{ID: primitive.NewObjectID(), FamilyHead: head.UserID, FamilyMemberName: "Ngozi Eze"}
+	son := &models.FamilyMember{ID: primitive.NewObjectID(), FamilyHead: head.UserID, FamilyMemberName: "Obinna Eze"}
+	event := openService()
+
+	mt.Run("household is recorded together and duplicates are reported", func(mt *mtest.T) {
+		earlier := &models.Attendance{ID: primitive.NewObjectID(), FamilyMember: &son.ID, Event: &event.ID}
+		mt.AddMockResponses(
+			mockFound(mt, "users", head),
+			mockFound(mt, "family_members", spouse, son),
+			mockFound(mt, "service_events", event),
+			mockFound(mt, "local_churches"),
+			mockFound(mt, "attendance"),          // head
+			mockFound(mt, "attendance"),          // spouse
+			mockFound(mt, "attendance", earlier), // son, checked in at the children's desk
+			mtest.CreateSuccessResponse(),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateSuccessResponse(), // commitTransaction
+		)
+
+		req := &dto.HouseholdCheckinRequest{EventID: event.ID.Hex()}
+		resp, err := newMockAttendanceService(mt).CheckInHousehold(context.Background(), req, head.UserID)
+		if err != nil {
+			t.Fatalf("CheckInHousehold: %v", err)
+		}
+		if resp.Recorded != 2 || resp.Duplicates != 1 || len(resp.Results) != 3 {
+			t.Fatalf("recorded %d, duplicates %d, results %+v", resp.Recorded, resp.Duplicates, resp.Results)
+		}
+		if got := resp.Results[2]; got.FamilyMemberID != son.ID.Hex() || got.Status != batchStatusDuplicate || got.Attendance != nil {
+			t.Errorf("son's result = %+v, want a duplicate", got)
+		}
+
+		var inserted []bson.Raw
+		for _, started := range mt.GetAllStartedEvents() {
+			if started.CommandName == "insert" {
+				inserted = append(inserted, started.Command.Lookup("documents").Array().Index(0).Value().Document())
+			}
+		}
+		if len(inserted) != 2 {
+			t.Fatalf("inserted %d records, want 2", len(inserted))
+		}
+		spouseRecord := inserted[1]
+		if spouseRecord.Lookup("family_member").ObjectID() != spouse.ID ||
+			spouseRecord.Lookup("household").StringValue() != head.UserID ||
+			!spouseRecord.Lookup("member").Boolean() {
+			t.Errorf("spouse's record = %v, want it in %s's household with the head's member status", spouseRecord, head.UserID)
+		}
+	})
+
+	mt.Run("someone outside the household is refused before any check-in", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mockFound(mt, "users", head),
+			mockFound(mt, "family_members", spouse, son),
+		)
+
+		req := &dto.HouseholdCheckinRequest{EventID: event.ID.Hex(), FamilyMemberIDs: []string{spouse.ID.Hex(), primitive.NewObjectID().Hex()}}
+		_, err := newMockAttendanceService(mt).CheckInHousehold(context.Background(), req, head.UserID)
+		if err == nil || !strings.Contains(err.Error(), "household") {
+			t.Fatalf("err = %v, want the member refused as outside the household", err)
+		}
+		if cmds := sentCommands(mt); len(cmds) != 2 {
+			t.Errorf("commands = %v, want only the head and household lookups", cmds)
+		}
+	})
+
+	mt.Run("nobody selected", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mockFound(mt, "users", head),
+			mockFound(mt, "family_members"),
+		)
+
+		excludeHead := false
+		req := &dto.HouseholdCheckinRequest{EventID: event.ID.Hex(), IncludeHead: &excludeHead}
+		if _, err := newMockAttendanceService(mt).CheckInHousehold(context.Background(), req, head.UserID); err == nil {
+			t.Fatal("CheckInHousehold accepted an empty selection")
+		}
+	})
+}
+
+func TestGeofenceCheckin(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	church := &models.LocalChurch{
+		ID:         primitive.NewObjectID(),
+		ChurchName: "Ikeja",
+		Timezone:   "Africa/Lagos",
+		Geofence:   &models.Geofence{Latitude: 6.6018, Longitude: 3.3515, RadiusMeters: 150},
+	}
+	unfenced := *church
+	unfenced.Geofence = nil
+	user := &models.User{ID: primitive.NewObjectID(), UserID: "CCIMRB-10422", Member: true}
+
+	event := openService()
+	event.Church = &church.ID
+	ended := openService()
+	ended.Church = &church.ID
+	ended.StartTime = ended.StartTime.Add(-4 * time.Hour)
+	ended.EndTime = ended.StartTime.Add(2 * time.Hour)
+
+	// 0.0005 degrees of latitude is about 56m, 0.005 about 556m
+	at := func(dLat, accuracy float64) *dto.GeofenceCheckinRequest {
+		lat, lon := church.Geofence.Latitude+dLat, church.Geofence.Longitude
+		return &dto.GeofenceCheckinRequest{Latitude: &lat, Longitude: &lon, Accuracy: accuracy, EventID: event.ID.Hex()}
+	}
+	endedReq := at(0, 10)
+	endedReq.EventID = ended.ID.Hex()
+
+	inside := func(mt *mtest.T) []bson.D {
+		return []bson.D{
+			mockFound(mt, "service_events", event),
+			mockFound(mt, "local_churches", church),
+			mockFound(mt, "users", user),
+			mockFound(mt, "service_events", event),
+			mockFound(mt, "local_churches", church),
+			mockFound(mt, "attendance"),
+			mtest.CreateSuccessResponse(),
+		}
+	}
+	refused := func(found ...interface{}) func(mt *mtest.T) []bson.D {
+		return func(mt *mtest.T) []bson.D {
+			replies := []bson.D{mockFound(mt, "service_events", found[0])}
+			for _, c := range found[1:] {
+				replies = append(replies, mockFound(mt, "local_churches", c))
+			}
+			return replies
+		}
+	}
+
+	tests := []struct {
+		name         string
+		req          *dto.GeofenceCheckinRequest
+		replies      func(mt *mtest.T) []bson.D
+		wantErr      error
+		wantDistance float64
+	}{
+		{"inside the fence", at(0.0005, 20), inside, nil, 55.6},
+		{"outside the fence", at(0.005, 20), refused(event, church), ErrOutsideGeofence, 0},
+		{"fix too imprecise", at(0.0005, 500), refused(event, church), ErrLocationInaccurate, 0},
+		{"church without a fence", at(0, 10), refused(event, &unfenced), ErrGeofenceNotConfigured, 0},
+		{"event has ended", endedReq, refused(ended), ErrCheckinWindowClosed, 0},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			s := newMockAttendanceService(mt)
+			s.cfg.GeofenceMaxAccuracy = 100
+			mt.AddMockResponses(tt.replies(mt)...)
+
+			resp, err := s.GeofenceCheckin(context.Background(), tt.req, user.UserID)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				if writes := writesTo(mt); len(writes) != 0 {
+					t.Errorf("refused check-in wrote to %v", writes)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GeofenceCheckin: %v", err)
+			}
+			if resp.CheckinMethod != models.CheckinMethodGeofence || resp.DistanceMeters == nil || *resp.DistanceMeters != tt.wantDistance {
+				t.Errorf("GeofenceCheckin = %+v, want a geofence check-in %.1fm away", resp, tt.wantDistance)
+			}
+		})
+	}
+}
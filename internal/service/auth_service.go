@@ -4,28 +4,54 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"cci-api/internal/config"
+	"cci-api/internal/database"
 	"cci-api/internal/dto"
 	"cci-api/internal/models"
 	"cci-api/internal/repository"
 	"cci-api/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type AuthService struct {
 	cfg              *config.Config
+	db               *database.Database
 	userRepo         *repository.UserRepository
 	refreshTokenRepo *repository.RefreshTokenRepository
+	visitorRepo      *repository.VisitorRepository
+	attendanceRepo   *repository.AttendanceRepository
 	emailService     EmailService
+	loginProtection  *LoginProtectionService
+	twoFactor        *TwoFactorService
+	sessionService   *SessionService
 }
 
-func NewAuthService(cfg *config.Config, userRepo *repository.UserRepository, refreshTokenRepo *repository.RefreshTokenRepository, emailService EmailService) *AuthService {
+// ErrRefreshTokenReused is returned when a rotated refresh token is presented again; its session is revoked
+var ErrRefreshTokenReused = errors.New("refresh token was already used, the session has been revoked")
+
+// ClientInfo describes the device a login or refresh came from, recorded on its session
+type ClientInfo struct {
+	IPAddress  string
+	UserAgent  string
+	DeviceName string
+}
+
+func NewAuthService(cfg *config.Config, db *database.Database, userRepo *repository.UserRepository, refreshTokenRepo *repository.RefreshTokenRepository, visitorRepo *repository.VisitorRepository, attendanceRepo *repository.AttendanceRepository, emailService EmailService, loginProtection *LoginProtectionService, twoFactor *TwoFactorService, sessionService *SessionService) *AuthService {
 	return &AuthService{
 		cfg:              cfg,
+		db:               db,
 		userRepo:         userRepo,
 		refreshTokenRepo: refreshTokenRepo,
+		visitorRepo:      visitorRepo,
+		attendanceRepo:   attendanceRepo,
 		emailService:     emailService,
+		loginProtection:  loginProtection,
+		twoFactor:        twoFactor,
+		sessionService:   sessionService,
 	}
 }
 
@@ -71,6 +97,7 @@ func (s *AuthService) BasicRegister(ctx context.Context, req *dto.BasicRegisterR
 		DateJoined:  time.Now(),
 		DateUpdated: time.Now(),
 	}
+	user.MembershipStage = membershipStageOf(user)
 
 	err = s.userRepo.Create(ctx, user)
 	if err != nil {
@@ -162,6 +189,7 @@ func (s *AuthService) CompleteRegister(ctx context.Context, req *dto.CompleteReg
 		DateJoined:                   time.Now(),
 		DateUpdated:                  time.Now(),
 	}
+	user.MembershipStage = membershipStageOf(user)
 
 	err = s.userRepo.Create(ctx, user)
 	if err != nil {
@@ -274,68 +302,184 @@ func (s *AuthService) SetPassword(ctx context.Context, req *dto.SetPasswordReque
 		}
 	}()
 
+	// The emailed token proves the member owns the address, so a visitor profile the guest gave the same
+	// email at check-in can now be merged into the account
+	visitor, err := s.visitorRepo.GetUnmergedByEmail(ctx, strings.ToLower(user.Email))
+	if err != nil {
+		return fmt.Errorf("failed to get visitor profile: %w", err)
+	}
+
 	// Update user's password
 	user.Password = hashedPassword
 	user.PasswordResetToken = ""
 	user.PasswordResetExpires = time.Time{}
-	if err := s.userRepo.Update(ctx, user); err != nil {
-		return fmt.Errorf("failed to update password: %w", err)
+	if visitor == nil {
+		if err := s.userRepo.Update(ctx, user); err != nil {
+			return fmt.Errorf("failed to update password: %w", err)
+		}
+		return nil
 	}
 
-	return nil
+	return s.db.WithTransaction(ctx, func(txCtx context.Context) error {
+		if err := s.userRepo.Update(txCtx, user); err != nil {
+			return fmt.Errorf("failed to update password: %w", err)
+		}
+		_, err := mergeVisitorProfile(txCtx, s.visitorRepo, s.attendanceRepo, visitor, user)
+		return err
+	})
 }
 
-func (s *AuthService) Login(ctx context.Context, req *dto.LoginRequest) (*dto.LoginResponse, error) {
+// Login checks the member's credentials from client. Repeated failures are slowed down and then locked out,
+// returning a *LoginThrottledError. Members using two-factor authentication get a challenge token to finish
+// the login with VerifyTwoFactorLogin instead of their tokens.
+func (s *AuthService) Login(ctx context.Context, req *dto.LoginRequest, client ClientInfo) (*dto.LoginResponse, error) {
+	if err := s.loginProtection.Check(ctx, req.Email, client.IPAddress); err != nil {
+		return nil, err
+	}
+
 	// Get user by email
 	user, err := s.userRepo.GetByEmail(ctx, req.Email)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
-	if user == nil {
-		return nil, errors.New("invalid email or password")
-	}
 
 	// Check password
-	if !utils.CheckPasswordHash(req.Password, user.Password) {
+	if user == nil || !utils.CheckPasswordHash(req.Password, user.Password) {
+		if err := s.loginProtection.RecordFailure(ctx, req.Email, client.IPAddress, user); err != nil {
+			return nil, err
+		}
 		return nil, errors.New("invalid email or password")
 	}
 
-	// Generate JWT tokens
-	accessToken, err := utils.GenerateJWT(user.UserID, user.Email, user.Admin, s.cfg.JWTSecret, s.cfg.JWTAccessExpiry)
+	// The account's failed logins are only cleared once the second factor checks out as well
+	if s.twoFactor.Required(user) {
+		challengeToken, expiresAt, err := s.twoFactor.NewChallenge(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		return &dto.LoginResponse{
+			TwoFactorRequired:      user.TwoFactorEnabled,
+			TwoFactorSetupRequired: !user.TwoFactorEnabled,
+			ChallengeToken:         challengeToken,
+			ChallengeExpiresAt:     &expiresAt,
+			User:                   userSummary(user),
+		}, nil
+	}
+
+	if err := s.loginProtection.RecordSuccess(ctx, req.Email); err != nil {
+		return nil, err
+	}
+
+	client.DeviceName = req.DeviceName
+	return s.issueTokens(ctx, user, client)
+}
+
+// VerifyTwoFactorLogin finishes a login with the code for its challenge. Wrong codes count as failed logins.
+func (s *AuthService) VerifyTwoFactorLogin(ctx context.Context, req *dto.TwoFactorLoginRequest, client ClientInfo) (*dto.LoginResponse, error) {
+	user, err := s.twoFactor.ChallengeUser(ctx, req.ChallengeToken)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate access token: %w", err)
+		return nil, err
 	}
 
-	refreshToken, err := utils.GenerateRandomToken(32)
+	if err := s.loginProtection.Check(ctx, user.Email, client.IPAddress); err != nil {
+		return nil, err
+	}
+
+	recoveryCodes, err := s.twoFactor.CompleteChallenge(ctx, user, req)
+	if err != nil {
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			if err := s.loginProtection.RecordFailure(ctx, user.Email, client.IPAddress, user); err != nil {
+				return nil, err
+			}
+		}
+		return nil, err
+	}
+
+	if err := s.loginProtection.RecordSuccess(ctx, user.Email); err != nil {
+		return nil, err
+	}
+
+	client.DeviceName = req.DeviceName
+	resp, err := s.issueTokens(ctx, user, client)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+		return nil, err
+	}
+	resp.RecoveryCodes = recoveryCodes
+	return resp, nil
+}
+
+// SetupTwoFactorLogin starts two-factor setup for an admin whose login is waiting on it
+func (s *AuthService) SetupTwoFactorLogin(ctx context.Context, req *dto.TwoFactorChallengeRequest) (*dto.TwoFactorSetupResponse, error) {
+	return s.twoFactor.EnrollWithChallenge(ctx, req.ChallengeToken)
+}
+
+// issueTokens starts a new session on the client's device with its first access and refresh tokens
+func (s *AuthService) issueTokens(ctx context.Context, user *models.User, client ClientInfo) (*dto.LoginResponse, error) {
+	now := time.Now()
+	deviceName := client.DeviceName
+	if deviceName == "" {
+		deviceName = utils.DeviceName(client.UserAgent)
 	}
 
-	// Store refresh token
 	refreshTokenModel := &models.RefreshToken{
-		UserID:    user.ID,
-		Token:     refreshToken,
-		ExpiresAt: time.Now().Add(s.cfg.JWTRefreshExpiry),
+		UserID:           user.ID,
+		FamilyID:         primitive.NewObjectID(),
+		DeviceName:       deviceName,
+		UserAgent:        client.UserAgent,
+		IPAddress:        client.IPAddress,
+		SessionCreatedAt: now,
+		LastUsedAt:       now,
+		ExpiresAt:        now.Add(s.cfg.JWTRefreshExpiry),
 	}
 
-	err = s.refreshTokenRepo.Create(ctx, refreshTokenModel)
+	accessToken, refreshToken, err := s.storeTokens(ctx, user, refreshTokenModel)
 	if err != nil {
-		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+		return nil, err
 	}
 
 	return &dto.LoginResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
-		User: dto.UserSummary{
-			UserID:    user.UserID,
-			FirstName: user.FirstName,
-			LastName:  user.LastName,
-			Email:     user.Email,
-		},
+		User:         userSummary(user),
 	}, nil
 }
 
-func (s *AuthService) RefreshToken(ctx context.Context, req *dto.RefreshTokenRequest) (*dto.TokenResponse, error) {
+// storeTokens generates an access token and a refresh token for the session the refresh token model belongs to
+func (s *AuthService) storeTokens(ctx context.Context, user *models.User, refreshTokenModel *models.RefreshToken) (string, string, error) {
+	accessToken, err := utils.GenerateJWT(user.UserID, user.Email, user.Admin, refreshTokenModel.FamilyID.Hex(), s.cfg.JWTSecret, s.cfg.JWTAccessExpiry)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	refreshToken, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	refreshTokenModel.Token = refreshToken
+	if err := s.refreshTokenRepo.Create(ctx, refreshTokenModel); err != nil {
+		return "", "", fmt.Errorf("failed to store refresh token: %w", err)
+	}
+	return accessToken, refreshToken, nil
+}
+
+func userSummary(user *models.User) dto.UserSummary {
+	return dto.UserSummary{
+		UserID:    user.UserID,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Email:     user.Email,
+	}
+}
+
+// UnlockAccount lifts a login lockout on the member's account
+func (s *AuthService) UnlockAccount(ctx context.Context, userID string) error {
+	return s.loginProtection.Unlock(ctx, userID)
+}
+
+// RefreshToken rotates a session's refresh token. Presenting a token that was already rotated means it leaked
+// or was copied, so the whole session is revoked.
+func (s *AuthService) RefreshToken(ctx context.Context, req *dto.RefreshTokenRequest, client ClientInfo) (*dto.TokenResponse, error) {
 	// Get refresh token
 	refreshToken, err := s.refreshTokenRepo.GetByToken(ctx, req.RefreshToken)
 	if err != nil {
@@ -345,8 +489,13 @@ func (s *AuthService) RefreshToken(ctx context.Context, req *dto.RefreshTokenReq
 		return nil, errors.New("invalid refresh token")
 	}
 
+	if refreshToken.RotatedAt != nil {
+		return nil, s.revokeReusedFamily(ctx, refreshToken)
+	}
+
 	// Check if token is expired
-	if time.Now().After(refreshToken.ExpiresAt) {
+	now := time.Now()
+	if now.After(refreshToken.ExpiresAt) {
 		// Delete expired token
 		s.refreshTokenRepo.DeleteByToken(ctx, req.RefreshToken)
 		return nil, errors.New("refresh token has expired")
@@ -361,34 +510,37 @@ func (s *AuthService) RefreshToken(ctx context.Context, req *dto.RefreshTokenReq
 		return nil, errors.New("user not found")
 	}
 
-	// Generate new access token
-	accessToken, err := utils.GenerateJWT(user.UserID, user.Email, user.Admin, s.cfg.JWTSecret, s.cfg.JWTAccessExpiry)
+	// Retire the old token. Losing the race to another refresh with the same token is a replay as well.
+	rotated, err := s.refreshTokenRepo.MarkRotated(ctx, refreshToken.ID, now)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate access token: %w", err)
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
 	}
-
-	// Generate new refresh token
-	newRefreshToken, err := utils.GenerateRandomToken(32)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate new refresh token: %w", err)
+	if !rotated {
+		return nil, s.revokeReusedFamily(ctx, refreshToken)
 	}
 
-	// Delete old refresh token
-	err = s.refreshTokenRepo.DeleteByToken(ctx, req.RefreshToken)
-	if err != nil {
-		return nil, fmt.Errorf("failed to delete old refresh token: %w", err)
-	}
-
-	// Store new refresh token
+	// The session keeps its family, device name and expiry; the device details follow the latest refresh
 	newRefreshTokenModel := &models.RefreshToken{
-		UserID:    user.ID,
-		Token:     newRefreshToken,
-		ExpiresAt: time.Now().Add(s.cfg.JWTRefreshExpiry),
-	}
-
-	err = s.refreshTokenRepo.Create(ctx, newRefreshTokenModel)
+		UserID:           user.ID,
+		FamilyID:         refreshToken.FamilyID,
+		DeviceName:       refreshToken.DeviceName,
+		UserAgent:        client.UserAgent,
+		IPAddress:        client.IPAddress,
+		SessionCreatedAt: refreshToken.SessionCreatedAt,
+		LastUsedAt:       now,
+		ExpiresAt:        refreshToken.ExpiresAt,
+	}
+	if newRefreshTokenModel.FamilyID.IsZero() {
+		// Tokens issued before sessions were tracked start a session named after them, so the session keeps the
+		// ID it was listed under and replaying the old token revokes the tokens issued in its place
+		newRefreshTokenModel.FamilyID = refreshToken.ID
+		newRefreshTokenModel.DeviceName = utils.DeviceName(client.UserAgent)
+		newRefreshTokenModel.SessionCreatedAt = refreshToken.CreatedAt
+	}
+
+	accessToken, newRefreshToken, err := s.storeTokens(ctx, user, newRefreshTokenModel)
 	if err != nil {
-		return nil, fmt.Errorf("failed to store new refresh token: %w", err)
+		return nil, err
 	}
 
 	return &dto.TokenResponse{
@@ -397,23 +549,29 @@ func (s *AuthService) RefreshToken(ctx context.Context, req *dto.RefreshTokenReq
 	}, nil
 }
 
-func (s *AuthService) Logout(ctx context.Context, userID string) error {
-	// Get user
-	user, err := s.userRepo.GetByUserID(ctx, userID)
-	if err != nil {
-		return fmt.Errorf("failed to get user: %w", err)
+// revokeReusedFamily ends the session a replayed refresh token belongs to
+func (s *AuthService) revokeReusedFamily(ctx context.Context, refreshToken *models.RefreshToken) error {
+	sessionID := refreshToken.FamilyID
+	if sessionID.IsZero() {
+		sessionID = refreshToken.ID
 	}
-	if user == nil {
-		return errors.New("user not found")
+	if _, err := s.refreshTokenRepo.DeleteByFamily(ctx, refreshToken.UserID, sessionID); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
 	}
+	return ErrRefreshTokenReused
+}
 
-	// Delete all refresh tokens for user
-	err = s.refreshTokenRepo.DeleteByUserID(ctx, user.ID)
-	if err != nil {
-		return fmt.Errorf("failed to delete refresh tokens: %w", err)
+// Logout ends the session the access token belongs to. Tokens issued before sessions were tracked carry no
+// session, and then every session is ended.
+func (s *AuthService) Logout(ctx context.Context, userID, sessionID string) error {
+	if sessionID != "" {
+		err := s.sessionService.RevokeSession(ctx, userID, sessionID)
+		if err != nil && !errors.Is(err, ErrSessionNotFound) {
+			return err
+		}
+		return nil
 	}
-
-	return nil
+	return s.sessionService.RevokeAllSessions(ctx, userID)
 }
 
 func (s *AuthService) ForgotPassword(ctx context.Context, req *dto.ForgotPasswordRequest) (*dto.ForgotPasswordResponse, error) {
@@ -494,6 +652,11 @@ func (s *AuthService) ResetPassword(ctx context.Context, req *dto.ResetPasswordR
 		return nil, fmt.Errorf("failed to update password: %w", err)
 	}
 
+	// A new password lifts any lockout on the account
+	if err := s.loginProtection.RecordSuccess(ctx, user.Email); err != nil {
+		return nil, err
+	}
+
 	// Send password reset success email
 	go func() {
 		data := map[string]interface{}{
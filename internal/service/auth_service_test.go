@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cci-api/internal/config"
+	"cci-api/internal/dto"
+	"cci-api/internal/models"
+	"cci-api/internal/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func newMockAuthService(mt *mtest.T) *AuthService {
+	db := mockDatabase(mt)
+	cfg := &config.Config{JWTSecret: "test-secret", JWTAccessExpiry: 15 * time.Minute}
+	return NewAuthService(cfg, db,
+		repository.NewUserRepository(db),
+		repository.NewRefreshTokenRepository(db),
+		repository.NewVisitorRepository(db),
+		repository.NewAttendanceRepository(db),
+		nil, nil, nil, nil,
+	)
+}
+
+// revokedFamily returns the session the code revoked, the family_id of the first branch of the delete filter
+func revokedFamily(mt *mtest.T) (primitive.ObjectID, bool) {
+	for _, event := range mt.GetAllStartedEvents() {
+		if event.CommandName != "delete" {
+			continue
+		}
+		filter := event.Command.Lookup("deletes").Array().Index(0).Value().Document().Lookup("q").Document()
+		return filter.Lookup("$or").Array().Index(0).Value().Document().Lookup("family_id").ObjectID(), true
+	}
+	return primitive.NilObjectID, false
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	user := &models.User{ID: primitive.NewObjectID(), UserID: "CCIMRB-10422", Email: "ada@example.com"}
+	sessionStart := time.Now().Add(-48 * time.Hour).Truncate(time.Millisecond)
+	current := &models.RefreshToken{
+		ID:               primitive.NewObjectID(),
+		UserID:           user.ID,
+		Token:            "current-token",
+		FamilyID:         primitive.NewObjectID(),
+		DeviceName:       "Chrome on Android",
+		SessionCreatedAt: sessionStart,
+		ExpiresAt:        sessionStart.Add(30 * 24 * time.Hour),
+	}
+	rotatedAt := time.Now().Add(-time.Hour)
+	rotated := *current
+	rotated.Token = "rotated-token"
+	rotated.RotatedAt = &rotatedAt
+	legacy := rotated
+	legacy.FamilyID = primitive.NilObjectID
+
+	req := func(token string) *dto.RefreshTokenRequest { return &dto.RefreshTokenRequest{RefreshToken: token} }
+	client := ClientInfo{IPAddress: "203.0.113.7", UserAgent: "Mozilla/5.0 (Linux; Android 14) Chrome/126.0"}
+	revoked := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 3})
+
+	mt.Run("rotation keeps the session", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mockFound(mt, "refresh_tokens", current),
+			mockFound(mt, "users", user),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateSuccessResponse(),
+		)
+
+		resp, err := newMockAuthService(mt).RefreshToken(context.Background(), req(current.Token), client)
+		if err != nil {
+			t.Fatalf("RefreshToken: %v", err)
+		}
+		if resp.AccessToken == "" || resp.RefreshToken == "" || resp.RefreshToken == current.Token {
+			t.Fatalf("RefreshToken = %+v, want a new token pair", resp)
+		}
+
+		var issued models.RefreshToken
+		for _, event := range mt.GetAllStartedEvents() {
+			if event.CommandName == "insert" {
+				doc := event.Command.Lookup("documents").Array().Index(0).Value().Document()
+				if err := bson.Unmarshal(doc, &issued); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		if issued.FamilyID != current.FamilyID || issued.DeviceName != current.DeviceName {
+			t.Errorf("new token is in session %s (%s), want %s (%s)", issued.FamilyID.Hex(), issued.DeviceName, current.FamilyID.Hex(), current.DeviceName)
+		}
+		if !issued.ExpiresAt.Equal(current.ExpiresAt) || !issued.SessionCreatedAt.Equal(sessionStart) {
+			t.Errorf("rotation moved the session window to %v - %v", issued.SessionCreatedAt, issued.ExpiresAt)
+		}
+		if issued.IPAddress != client.IPAddress {
+			t.Errorf("ip address = %q, want the refreshing client's", issued.IPAddress)
+		}
+	})
+
+	mt.Run("replayed token revokes the session", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mockFound(mt, "refresh_tokens", rotated),
+			revoked,
+		)
+
+		_, err := newMockAuthService(mt).RefreshToken(context.Background(), req(rotated.Token), client)
+		if !errors.Is(err, ErrRefreshTokenReused) {
+			t.Fatalf("err = %v, want ErrRefreshTokenReused", err)
+		}
+		if family, ok := revokedFamily(mt); !ok || family != current.FamilyID {
+			t.Errorf("revoked session %s, want %s", family.Hex(), current.FamilyID.Hex())
+		}
+		if writes := writesTo(mt); len(writes) != 0 {
+			t.Errorf("replay issued new tokens: %v", writes)
+		}
+	})
+
+	mt.Run("losing a concurrent refresh is a replay", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mockFound(mt, "refresh_tokens", current),
+			mockFound(mt, "users", user),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+			revoked,
+		)
+
+		_, err := newMockAuthService(mt).RefreshToken(context.Background(), req(current.Token), client)
+		if !errors.Is(err, ErrRefreshTokenReused) {
+			t.Fatalf("err = %v, want ErrRefreshTokenReused", err)
+		}
+		if _, ok := revokedFamily(mt); !ok {
+			t.Error("session was not revoked")
+		}
+	})
+
+	mt.Run("replayed token from before sessions revokes its successors", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mockFound(mt, "refresh_tokens", legacy),
+			revoked,
+		)
+
+		_, err := newMockAuthService(mt).RefreshToken(context.Background(), req(legacy.Token), client)
+		if !errors.Is(err, ErrRefreshTokenReused) {
+			t.Fatalf("err = %v, want ErrRefreshTokenReused", err)
+		}
+		// Its successors were issued into a session named after the legacy token
+		if family, _ := revokedFamily(mt); family != legacy.ID {
+			t.Errorf("revoked session %s, want %s", family.Hex(), legacy.ID.Hex())
+		}
+	})
+
+	mt.Run("unknown token", func(mt *mtest.T) {
+		mt.AddMockResponses(mockFound(mt, "refresh_tokens"))
+
+		_, err := newMockAuthService(mt).RefreshToken(context.Background(), req("never-issued"), client)
+		if err == nil || errors.Is(err, ErrRefreshTokenReused) {
+			t.Fatalf("err = %v, want the token refused as invalid", err)
+		}
+	})
+}
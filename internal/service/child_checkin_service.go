@@ -0,0 +1,519 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"cci-api/internal/config"
+	"cci-api/internal/database"
+	"cci-api/internal/dto"
+	"cci-api/internal/models"
+	"cci-api/internal/repository"
+	"cci-api/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// pickupCodeLength is short enough to read off a label at a busy pickup desk. Codes only need to be unique
+	// among the children still checked in.
+	pickupCodeLength = 4
+	// pickupCodeAttempts bounds how many random codes are tried before giving up on finding an unused one
+	pickupCodeAttempts = 10
+)
+
+var (
+	// ErrChildCheckinNotFound is returned when a children's check-in does not exist
+	ErrChildCheckinNotFound = newNotFoundError("child check-in not found")
+	// ErrPickupNotAuthorized is returned when a pickup code or guardian does not match the child, or a label is
+	// requested by someone other than the child's guardian
+	ErrPickupNotAuthorized = newForbiddenError("not authorized to collect this child")
+	// ErrChildAlreadyCheckedOut is returned when a child has already been picked up
+	ErrChildAlreadyCheckedOut = repository.ErrChildAlreadyCheckedOut
+)
+
+type ChildCheckinService struct {
+	cfg              *config.Config
+	db               *database.Database
+	childCheckinRepo *repository.ChildCheckinRepository
+	familyMemberRepo *repository.FamilyMemberRepository
+	userRepo         *repository.UserRepository
+	serviceEventRepo *repository.ServiceEventRepository
+	localChurchRepo  *repository.LocalChurchRepository
+}
+
+func NewChildCheckinService(cfg *config.Config, db *database.Database, childCheckinRepo *repository.ChildCheckinRepository, familyMemberRepo *repository.FamilyMemberRepository, userRepo *repository.UserRepository, serviceEventRepo *repository.ServiceEventRepository, localChurchRepo *repository.LocalChurchRepository) *ChildCheckinService {
+	return &ChildCheckinService{
+		cfg:              cfg,
+		db:               db,
+		childCheckinRepo: childCheckinRepo,
+		familyMemberRepo: familyMemberRepo,
+		userRepo:         userRepo,
+		serviceEventRepo: serviceEventRepo,
+		localChurchRepo:  localChurchRepo,
+	}
+}
+
+// CheckIn checks children in from the guardian's family members. The guardian is the user checking in, unless
+// an admin checks children in on a guardian's behalf. All the children share one new pickup code, which is
+// only returned here and printed on the guardian's label.
+func (s *ChildCheckinService) CheckIn(ctx context.Context, req *dto.ChildCheckinRequest, userID string, isAdmin bool) (*dto.ChildCheckinResponse, error) {
+	guardianID := userID
+	if req.GuardianID != "" && req.GuardianID != userID {
+		if !isAdmin {
+			return nil, errors.New("only admins can check children in for another guardian")
+		}
+		guardianID = req.GuardianID
+	}
+
+	guardian, err := s.userRepo.GetByUserID(ctx, guardianID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guardian: %w", err)
+	}
+	if guardian == nil {
+		return nil, errors.New("guardian not found")
+	}
+
+	authorized := []string{guardian.UserID}
+	for _, id := range req.AuthorizedGuardians {
+		if containsString(authorized, id) {
+			continue
+		}
+		user, err := s.userRepo.GetByUserID(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get authorized guardian: %w", err)
+		}
+		if user == nil {
+			return nil, fmt.Errorf("authorized guardian %s not found", id)
+		}
+		authorized = append(authorized, user.UserID)
+	}
+
+	now := time.Now()
+	var event *models.ServiceEvent
+	if req.EventID != "" {
+		eventID, err := primitive.ObjectIDFromHex(req.EventID)
+		if err != nil {
+			return nil, errors.New("invalid event ID")
+		}
+		event, err = s.serviceEventRepo.GetByID(ctx, eventID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get service event: %w", err)
+		}
+		if event == nil {
+			return nil, errors.New("service event not found")
+		}
+	} else {
+		// Children can still be checked in when no event is open, e.g. for a midweek children's programme
+		church, err := checkinChurch(ctx, s.localChurchRepo, req.ChurchID)
+		if err != nil {
+			return nil, err
+		}
+		event, err = openEventAt(ctx, s.localChurchRepo, s.serviceEventRepo, church, now, s.cfg.EventCheckinEarlyWindow)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	classroom := strings.TrimSpace(req.Classroom)
+	seen := map[primitive.ObjectID]bool{}
+	var checkins []*models.ChildCheckin
+	for _, child := range req.Children {
+		memberID, err := primitive.ObjectIDFromHex(child.FamilyMemberID)
+		if err != nil {
+			return nil, errors.New("invalid family member ID")
+		}
+		if seen[memberID] {
+			continue
+		}
+		seen[memberID] = true
+
+		member, err := s.familyMemberRepo.GetByID(ctx, memberID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get family member: %w", err)
+		}
+		if member == nil || member.FamilyHead != guardian.UserID {
+			return nil, fmt.Errorf("family member %s not found in the guardian's family", child.FamilyMemberID)
+		}
+
+		open, err := s.childCheckinRepo.GetOpenByFamilyMember(ctx, memberID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check existing check-in: %w", err)
+		}
+		if open != nil {
+			return nil, fmt.Errorf("%s is already checked in to %s", member.FamilyMemberName, open.Classroom)
+		}
+
+		checkin := &models.ChildCheckin{
+			FamilyMember:        memberID,
+			ChildName:           member.FamilyMemberName,
+			Guardian:            guardian.UserID,
+			AuthorizedGuardians: authorized,
+			Classroom:           classroom,
+			Allergies:           strings.TrimSpace(child.Allergies),
+			Notes:               strings.TrimSpace(child.Notes),
+			CheckedInBy:         userID,
+			CheckedInAt:         now,
+		}
+		if event != nil {
+			checkin.Event = &event.ID
+		}
+		checkins = append(checkins, checkin)
+	}
+
+	code, err := s.newPickupCode(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.WithTransaction(ctx, func(txCtx context.Context) error {
+		for _, checkin := range checkins {
+			checkin.ID = primitive.NilObjectID
+			checkin.PickupCode = code
+			if err := s.childCheckinRepo.Create(txCtx, checkin); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check children in: %w", err)
+	}
+
+	resp := &dto.ChildCheckinResponse{PickupCode: code}
+	if event != nil {
+		resp.EventID = event.ID.Hex()
+	}
+	for _, checkin := range checkins {
+		resp.Children = append(resp.Children, toChildCheckinItem(checkin))
+	}
+	return resp, nil
+}
+
+// newPickupCode picks a random code that no child still waiting to be collected has
+func (s *ChildCheckinService) newPickupCode(ctx context.Context) (string, error) {
+	for i := 0; i < pickupCodeAttempts; i++ {
+		code, err := utils.GeneratePickupCode(pickupCodeLength)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate pickup code: %w", err)
+		}
+		inUse, err := s.childCheckinRepo.PickupCodeInUse(ctx, code)
+		if err != nil {
+			return "", fmt.Errorf("failed to check pickup code: %w", err)
+		}
+		if !inUse {
+			return code, nil
+		}
+	}
+	return "", errors.New("failed to generate an unused pickup code")
+}
+
+// CheckOut releases a child to whoever presents the matching pickup code or, without the code, to one of the
+// child's authorized guardians once staff have confirmed who they are
+func (s *ChildCheckinService) CheckOut(ctx context.Context, id string, req *dto.ChildCheckoutRequest, staffID string) (*dto.ChildCheckinItem, error) {
+	checkin, err := s.getCheckin(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if checkin.CheckedOutAt != nil {
+		return nil, ErrChildAlreadyCheckedOut
+	}
+
+	code := strings.ToUpper(strings.TrimSpace(req.PickupCode))
+	switch {
+	case code != "":
+		if subtle.ConstantTimeCompare([]byte(code), []byte(checkin.PickupCode)) != 1 {
+			return nil, fmt.Errorf("%w: pickup code does not match", ErrPickupNotAuthorized)
+		}
+		checkin.PickupMethod = models.PickupMethodCode
+		checkin.PickedUpBy = req.GuardianID
+	case req.GuardianID != "":
+		if !containsString(checkin.AuthorizedGuardians, req.GuardianID) {
+			return nil, fmt.Errorf("%w: %s is not an authorized guardian", ErrPickupNotAuthorized, req.GuardianID)
+		}
+		checkin.PickupMethod = models.PickupMethodGuardian
+		checkin.PickedUpBy = req.GuardianID
+	default:
+		return nil, errors.New("pickup_code or guardian_id is required")
+	}
+
+	now := time.Now()
+	checkin.CheckedOutAt = &now
+	checkin.CheckedOutBy = staffID
+	if err := s.childCheckinRepo.CheckOut(ctx, checkin); err != nil {
+		if errors.Is(err, repository.ErrChildAlreadyCheckedOut) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to check child out: %w", err)
+	}
+
+	return toChildCheckinItem(checkin), nil
+}
+
+// GetRoster lists the children still checked in, grouped by classroom
+func (s *ChildCheckinService) GetRoster(ctx context.Context, classroom, eventID string) (*dto.ChildRosterResponse, error) {
+	var eventObjID *primitive.ObjectID
+	if eventID != "" {
+		objID, err := primitive.ObjectIDFromHex(eventID)
+		if err != nil {
+			return nil, errors.New("invalid event ID")
+		}
+		eventObjID = &objID
+	}
+
+	checkins, err := s.childCheckinRepo.GetOpen(ctx, strings.TrimSpace(classroom), eventObjID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get roster: %w", err)
+	}
+
+	// Check-ins come sorted by classroom, so each classroom's children are together
+	resp := &dto.ChildRosterResponse{Total: len(checkins), Classrooms: []*dto.ClassroomRoster{}}
+	var current *dto.ClassroomRoster
+	for _, checkin := range checkins {
+		if current == nil || current.Classroom != checkin.Classroom {
+			current = &dto.ClassroomRoster{Classroom: checkin.Classroom}
+			resp.Classrooms = append(resp.Classrooms, current)
+		}
+		current.Count++
+		if checkin.Allergies != "" {
+			current.WithAllergies++
+		}
+		current.Children = append(current.Children, toChildCheckinItem(checkin))
+	}
+	return resp, nil
+}
+
+// GetPickupAlerts lists the children still waiting to be collected after their service ended, with their
+// guardian's contact details
+func (s *ChildCheckinService) GetPickupAlerts(ctx context.Context) ([]*dto.ChildPickupAlert, error) {
+	now := time.Now()
+	children, err := s.childCheckinRepo.GetAwaitingPickup(ctx, now, s.cfg.ChildPickupGracePeriod, s.cfg.ChildPickupAlertAfter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get children awaiting pickup: %w", err)
+	}
+
+	guardians := map[string]*models.User{}
+	alerts := make([]*dto.ChildPickupAlert, 0, len(children))
+	for i := range children {
+		child := &children[i]
+		guardian, ok := guardians[child.Guardian]
+		if !ok {
+			guardian, err = s.userRepo.GetByUserID(ctx, child.Guardian)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get guardian: %w", err)
+			}
+			guardians[child.Guardian] = guardian
+		}
+
+		waitingSince := child.CheckedInAt
+		if child.EventEnd != nil {
+			waitingSince = *child.EventEnd
+		}
+		alert := &dto.ChildPickupAlert{
+			Child:          toChildCheckinItem(&child.ChildCheckin),
+			EventName:      child.EventName,
+			EventEnd:       child.EventEnd,
+			WaitingMinutes: int(now.Sub(waitingSince).Minutes()),
+		}
+		if guardian != nil {
+			alert.GuardianName = strings.TrimSpace(guardian.FirstName + " " + guardian.LastName)
+			alert.GuardianPhone = guardian.PhoneNumber
+			alert.GuardianEmail = guardian.Email
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, nil
+}
+
+// GetChildLabel renders the label for one checked-in child
+func (s *ChildCheckinService) GetChildLabel(ctx context.Context, id, userID string, isAdmin bool) ([]byte, error) {
+	checkin, err := s.getCheckin(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !isAdmin && checkin.Guardian != userID {
+		return nil, ErrPickupNotAuthorized
+	}
+
+	church, loc, err := s.labelChurch(ctx, checkin)
+	if err != nil {
+		return nil, err
+	}
+	return utils.RenderChildLabelPNG(childLabel(checkin, church, loc))
+}
+
+// GetLabelSheet renders a printable PDF with a label for each of the check-ins, followed by one guardian label
+// per pickup code listing the children it collects
+func (s *ChildCheckinService) GetLabelSheet(ctx context.Context, req *dto.ChildLabelsRequest, userID string, isAdmin bool) ([]byte, error) {
+	ids := make([]primitive.ObjectID, 0, len(req.CheckinIDs))
+	for _, id := range req.CheckinIDs {
+		objID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, errors.New("invalid check-in ID")
+		}
+		ids = append(ids, objID)
+	}
+
+	checkins, err := s.childCheckinRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get check-ins: %w", err)
+	}
+	if len(checkins) == 0 {
+		return nil, ErrChildCheckinNotFound
+	}
+	for _, checkin := range checkins {
+		if !isAdmin && checkin.Guardian != userID {
+			return nil, ErrPickupNotAuthorized
+		}
+	}
+
+	var labels [][]byte
+	var codes []string
+	childrenByCode := map[string][]*models.ChildCheckin{}
+	churchByCode := map[string]*models.LocalChurch{}
+	for _, checkin := range checkins {
+		church, loc, err := s.labelChurch(ctx, checkin)
+		if err != nil {
+			return nil, err
+		}
+		label, err := utils.RenderChildLabelPNG(childLabel(checkin, church, loc))
+		if err != nil {
+			return nil, err
+		}
+		labels = append(labels, label)
+
+		if _, ok := childrenByCode[checkin.PickupCode]; !ok {
+			codes = append(codes, checkin.PickupCode)
+			churchByCode[checkin.PickupCode] = church
+		}
+		childrenByCode[checkin.PickupCode] = append(childrenByCode[checkin.PickupCode], checkin)
+	}
+
+	for _, code := range codes {
+		label, err := utils.RenderChildLabelPNG(guardianLabel(childrenByCode[code], churchByCode[code]))
+		if err != nil {
+			return nil, err
+		}
+		labels = append(labels, label)
+	}
+
+	return utils.RenderLabelSheetPDF(labels)
+}
+
+func (s *ChildCheckinService) getCheckin(ctx context.Context, id string) (*models.ChildCheckin, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid check-in ID")
+	}
+	checkin, err := s.childCheckinRepo.GetByID(ctx, objID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get check-in: %w", err)
+	}
+	if checkin == nil {
+		return nil, ErrChildCheckinNotFound
+	}
+	return checkin, nil
+}
+
+// labelChurch returns the church printed on a check-in's label and the timezone its times are shown in: the
+// church holding the check-in's event, or the default church when the event has none
+func (s *ChildCheckinService) labelChurch(ctx context.Context, checkin *models.ChildCheckin) (*models.LocalChurch, *time.Location, error) {
+	var church *models.LocalChurch
+	if checkin.Event != nil {
+		event, err := s.serviceEventRepo.GetByID(ctx, *checkin.Event)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get service event: %w", err)
+		}
+		if event != nil && event.Church != nil {
+			church, err = s.localChurchRepo.GetByID(ctx, *event.Church)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to get church: %w", err)
+			}
+		}
+	}
+	if church == nil {
+		var err error
+		church, err = s.localChurchRepo.GetFirst(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get church: %w", err)
+		}
+	}
+	if church != nil && church.Timezone != "" {
+		return church, utils.LoadLocation(church.Timezone), nil
+	}
+	return church, utils.LoadLocation(s.cfg.Timezone), nil
+}
+
+func childLabel(checkin *models.ChildCheckin, church *models.LocalChurch, loc *time.Location) utils.ChildLabel {
+	label := utils.ChildLabel{
+		Title: checkin.ChildName,
+		Lines: []string{
+			checkin.Classroom,
+			checkin.CheckedInAt.In(loc).Format("Mon 2 Jan 2006, 15:04"),
+		},
+		PickupCode: checkin.PickupCode,
+	}
+	if church != nil {
+		label.ChurchName = church.ChurchName
+	}
+	if checkin.Notes != "" {
+		label.Lines = append(label.Lines, checkin.Notes)
+	}
+	if checkin.Allergies != "" {
+		label.Alert = "ALLERGIES: " + checkin.Allergies
+	}
+	return label
+}
+
+// guardianLabel is kept by the guardian and shown at pickup. It has no allergy band, as it is not worn by a child.
+func guardianLabel(children []*models.ChildCheckin, church *models.LocalChurch) utils.ChildLabel {
+	label := utils.ChildLabel{
+		Title:      "Guardian pickup",
+		PickupCode: children[0].PickupCode,
+	}
+	if church != nil {
+		label.ChurchName = church.ChurchName
+	}
+	names := make([]string, 0, len(children))
+	for _, child := range children {
+		names = append(names, child.ChildName)
+	}
+	label.Lines = []string{strings.Join(names, ", "), "Show this label to collect"}
+	return label
+}
+
+func toChildCheckinItem(checkin *models.ChildCheckin) *dto.ChildCheckinItem {
+	item := &dto.ChildCheckinItem{
+		ID:                  checkin.ID.Hex(),
+		FamilyMemberID:      checkin.FamilyMember.Hex(),
+		ChildName:           checkin.ChildName,
+		Guardian:            checkin.Guardian,
+		AuthorizedGuardians: checkin.AuthorizedGuardians,
+		Classroom:           checkin.Classroom,
+		Allergies:           checkin.Allergies,
+		Notes:               checkin.Notes,
+		CheckedInBy:         checkin.CheckedInBy,
+		CheckedInAt:         checkin.CheckedInAt,
+		CheckedOutAt:        checkin.CheckedOutAt,
+		CheckedOutBy:        checkin.CheckedOutBy,
+		PickedUpBy:          checkin.PickedUpBy,
+		PickupMethod:        checkin.PickupMethod,
+	}
+	if checkin.Event != nil {
+		item.EventID = checkin.Event.Hex()
+	}
+	return item
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cci-api/internal/config"
+	"cci-api/internal/dto"
+	"cci-api/internal/models"
+	"cci-api/internal/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestChildCheckOut(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	collected := time.Now().Add(-5 * time.Minute).Truncate(time.Millisecond)
+	waiting := models.ChildCheckin{
+		ID:                  primitive.NewObjectID(),
+		FamilyMember:        primitive.NewObjectID(),
+		ChildName:           "Tobi",
+		Guardian:            "parent-1",
+		AuthorizedGuardians: []string{"parent-1", "aunt-1"},
+		Classroom:           "toddlers",
+		PickupCode:          "K7QP",
+		CheckedInAt:         collected.Add(-2 * time.Hour),
+	}
+	pickedUp := waiting
+	pickedUp.CheckedOutAt = &collected
+
+	updated := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1})
+	raced := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0})
+
+	tests := []struct {
+		name       string
+		checkin    models.ChildCheckin
+		req        dto.ChildCheckoutRequest
+		update     bson.D // reply to the check-out write, nil when none should be sent
+		wantErr    error
+		wantMethod string
+		wantBy     string
+	}{
+		{
+			name:       "matching code in any case",
+			checkin:    waiting,
+			req:        dto.ChildCheckoutRequest{PickupCode: " k7qp "},
+			update:     updated,
+			wantMethod: models.PickupMethodCode,
+		},
+		{
+			name:    "wrong code",
+			checkin: waiting,
+			req:     dto.ChildCheckoutRequest{PickupCode: "K7QX", GuardianID: "parent-1"},
+			wantErr: ErrPickupNotAuthorized,
+		},
+		{
+			name:       "authorized guardian without the code",
+			checkin:    waiting,
+			req:        dto.ChildCheckoutRequest{GuardianID: "aunt-1"},
+			update:     updated,
+			wantMethod: models.PickupMethodGuardian,
+			wantBy:     "aunt-1",
+		},
+		{
+			name:    "stranger without the code",
+			checkin: waiting,
+			req:     dto.ChildCheckoutRequest{GuardianID: "neighbour-1"},
+			wantErr: ErrPickupNotAuthorized,
+		},
+		{
+			name:    "already picked up",
+			checkin: pickedUp,
+			req:     dto.ChildCheckoutRequest{PickupCode: "K7QP"},
+			wantErr: ErrChildAlreadyCheckedOut,
+		},
+		{
+			name:    "picked up at another desk meanwhile",
+			checkin: waiting,
+			req:     dto.ChildCheckoutRequest{PickupCode: "K7QP"},
+			update:  raced,
+			wantErr: ErrChildAlreadyCheckedOut,
+		},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			db := mockDatabase(mt)
+			s := NewChildCheckinService(&config.Config{Timezone: "Africa/Lagos"}, db,
+				repository.NewChildCheckinRepository(db),
+				repository.NewFamilyMemberRepository(db),
+				repository.NewUserRepository(db),
+				repository.NewServiceEventRepository(db),
+				repository.NewLocalChurchRepository(db),
+			)
+			mt.AddMockResponses(mockFound(mt, "child_checkins", tt.checkin))
+			if tt.update != nil {
+				mt.AddMockResponses(tt.update)
+			}
+
+			item, err := s.CheckOut(context.Background(), tt.checkin.ID.Hex(), &tt.req, "staff-1")
+
+			if tt.update == nil && len(writesTo(mt)) != 0 {
+				t.Errorf("check-out was written: %v", writesTo(mt))
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if item.PickupMethod != tt.wantMethod || item.PickedUpBy != tt.wantBy || item.CheckedOutBy != "staff-1" || item.CheckedOutAt == nil {
+				t.Errorf("checked out as %+v", item)
+			}
+		})
+	}
+}
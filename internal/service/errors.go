@@ -0,0 +1,39 @@
+package service
+
+import "errors"
+
+// Access errors shared by every service, so handlers answer a missing resource with 404 and someone else's
+// resource with 403 the same way everywhere. Service-specific sentinels built with newNotFoundError and
+// newForbiddenError match them with errors.Is while keeping their own message.
+var (
+	ErrNotFound  = errors.New("not found")
+	ErrForbidden = errors.New("forbidden")
+)
+
+// ErrInvalidInput is matched by errors built with newValidationError, for requests the caller has to correct,
+// such as a malformed ID, which handlers answer with 400
+var ErrInvalidInput = errors.New("invalid input")
+
+type accessError struct {
+	message string
+	kind    error
+}
+
+func (e *accessError) Error() string { return e.message }
+
+func (e *accessError) Unwrap() error { return e.kind }
+
+// ErrUserNotFound is returned when an admin acts on a member's account that does not exist
+var ErrUserNotFound = newNotFoundError("user not found")
+
+func newNotFoundError(message string) error {
+	return &accessError{message: message, kind: ErrNotFound}
+}
+
+func newForbiddenError(message string) error {
+	return &accessError{message: message, kind: ErrForbidden}
+}
+
+func newValidationError(message string) error {
+	return &accessError{message: message, kind: ErrInvalidInput}
+}
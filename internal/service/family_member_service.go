@@ -12,11 +12,16 @@ import (
 	"cci-api/internal/repository"
 	"cci-api/internal/utils"
 
-	"github.com/labstack/echo/v4"
-
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+var (
+	// ErrFamilyMemberNotFound is returned when a family member does not exist
+	ErrFamilyMemberNotFound = newNotFoundError("family member not found")
+	// ErrNotFamilyHead is returned when someone other than an admin works on another household's family member
+	ErrNotFamilyHead = newForbiddenError("this family member belongs to another household")
+)
+
 type FamilyMemberService struct {
 	config           *config.Config
 	familyMemberRepo repository.FamilyMemberRepository
@@ -29,14 +34,17 @@ func NewFamilyMemberService(cfg *config.Config, familyMemberRepo *repository.Fam
 	}
 }
 
-func (s *FamilyMemberService) CreateFamilyMember(ctx context.Context, c echo.Context, req *dto.CreateFamilyMemberRequest) (*dto.FamilyMemberResponse, error) {
+// CreateFamilyMember adds a family member to the household headed by userID
+func (s *FamilyMemberService) CreateFamilyMember(ctx context.Context, req *dto.CreateFamilyMemberRequest, userID string) (*dto.FamilyMemberResponse, error) {
 	// Validate request
 	if req.FamilyMemberName == "" {
 		return nil, errors.New("family member name is required")
 	}
-	userID := c.Get("user_id").(string)
 
-	dateOfBirth, _ := time.Parse("2022-04-03", req.FamilyMemberDateOfBirth)
+	dateOfBirth, err := parseDateOfBirth(req.FamilyMemberDateOfBirth)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create family member
 	familyMember := &models.FamilyMember{
@@ -51,25 +59,17 @@ func (s *FamilyMemberService) CreateFamilyMember(ctx context.Context, c echo.Con
 		DateAdded:                time.Now(),
 	}
 
-	err := s.familyMemberRepo.Create(ctx, familyMember)
+	err = s.familyMemberRepo.Create(ctx, familyMember)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create family member: %w", err)
 	}
 
-	return &dto.FamilyMemberResponse{
-		ID:                       familyMember.ID.Hex(),
-		FamilyMemberName:         familyMember.FamilyMemberName,
-		FamilyMemberPhone:        familyMember.FamilyMemberPhone,
-		FamilyMemberEmail:        familyMember.FamilyMemberEmail,
-		FamilyMemberRelationship: familyMember.FamilyMemberRelationship,
-		FamilyMemberDateOfBirth:  familyMember.FamilyMemberDateOfBirth,
-		FamilyMemberGender:       familyMember.FamilyMemberGender,
-		FamilyMemberOccupation:   familyMember.FamilyMemberOccupation,
-		DateAdded:                familyMember.DateAdded,
-	}, nil
+	return toFamilyMemberResponse(familyMember), nil
 }
 
-func (s *FamilyMemberService) GetFamilyMembers(ctx context.Context, page, limit int) (*dto.PaginatedFamilyMembersResponse, error) {
+// GetFamilyMembers lists the caller's own household. Admins see every family member, or one household's
+// when familyHead is given.
+func (s *FamilyMemberService) GetFamilyMembers(ctx context.Context, userID string, isAdmin bool, familyHead string, page, limit int) (*dto.PaginatedFamilyMembersResponse, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -77,24 +77,26 @@ func (s *FamilyMemberService) GetFamilyMembers(ctx context.Context, page, limit
 		limit = 10
 	}
 
-	familyMembers, total, err := s.familyMemberRepo.GetAll(ctx, page, limit)
+	var (
+		familyMembers []*models.FamilyMember
+		total         int
+		err           error
+	)
+	switch {
+	case !isAdmin:
+		familyMembers, total, err = s.familyMemberRepo.GetByFamilyHead(ctx, userID, page, limit)
+	case familyHead != "":
+		familyMembers, total, err = s.familyMemberRepo.GetByFamilyHead(ctx, familyHead, page, limit)
+	default:
+		familyMembers, total, err = s.familyMemberRepo.GetAll(ctx, page, limit)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get family members: %w", err)
 	}
 
 	familyMemberResponses := make([]*dto.FamilyMemberResponse, len(familyMembers))
 	for i, familyMember := range familyMembers {
-		familyMemberResponses[i] = &dto.FamilyMemberResponse{
-			ID:                       familyMember.ID.Hex(),
-			FamilyMemberName:         familyMember.FamilyMemberName,
-			FamilyMemberPhone:        familyMember.FamilyMemberPhone,
-			FamilyMemberEmail:        familyMember.FamilyMemberEmail,
-			FamilyMemberRelationship: familyMember.FamilyMemberRelationship,
-			FamilyMemberDateOfBirth:  familyMember.FamilyMemberDateOfBirth,
-			FamilyMemberGender:       familyMember.FamilyMemberGender,
-			FamilyMemberOccupation:   familyMember.FamilyMemberOccupation,
-			DateAdded:                familyMember.DateAdded,
-		}
+		familyMemberResponses[i] = toFamilyMemberResponse(familyMember)
 	}
 
 	return &dto.PaginatedFamilyMembersResponse{
@@ -103,39 +105,19 @@ func (s *FamilyMemberService) GetFamilyMembers(ctx context.Context, page, limit
 	}, nil
 }
 
-func (s *FamilyMemberService) GetFamilyMemberByID(ctx context.Context, id string) (*dto.FamilyMemberResponse, error) {
-	objID, err := primitive.ObjectIDFromHex(id)
+func (s *FamilyMemberService) GetFamilyMemberByID(ctx context.Context, id, userID string, isAdmin bool) (*dto.FamilyMemberResponse, error) {
+	familyMember, err := s.getFamilyMember(ctx, id, userID, isAdmin)
 	if err != nil {
-		return nil, errors.New("invalid family member ID")
+		return nil, err
 	}
 
-	familyMember, err := s.familyMemberRepo.GetByID(ctx, objID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get family member: %w", err)
-	}
-
-	return &dto.FamilyMemberResponse{
-		ID:                       familyMember.ID.Hex(),
-		FamilyMemberName:         familyMember.FamilyMemberName,
-		FamilyMemberPhone:        familyMember.FamilyMemberPhone,
-		FamilyMemberEmail:        familyMember.FamilyMemberEmail,
-		FamilyMemberRelationship: familyMember.FamilyMemberRelationship,
-		FamilyMemberDateOfBirth:  familyMember.FamilyMemberDateOfBirth,
-		FamilyMemberGender:       familyMember.FamilyMemberGender,
-		FamilyMemberOccupation:   familyMember.FamilyMemberOccupation,
-		DateAdded:                familyMember.DateAdded,
-	}, nil
+	return toFamilyMemberResponse(familyMember), nil
 }
 
-func (s *FamilyMemberService) UpdateFamilyMember(ctx context.Context, id string, req *dto.UpdateFamilyMemberRequest) (*dto.FamilyMemberResponse, error) {
-	objID, err := primitive.ObjectIDFromHex(id)
+func (s *FamilyMemberService) UpdateFamilyMember(ctx context.Context, id string, req *dto.UpdateFamilyMemberRequest, userID string, isAdmin bool) (*dto.FamilyMemberResponse, error) {
+	familyMember, err := s.getFamilyMember(ctx, id, userID, isAdmin)
 	if err != nil {
-		return nil, errors.New("invalid family member ID")
-	}
-
-	familyMember, err := s.familyMemberRepo.GetByID(ctx, objID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get family member: %w", err)
+		return nil, err
 	}
 
 	// Update fields
@@ -152,7 +134,10 @@ func (s *FamilyMemberService) UpdateFamilyMember(ctx context.Context, id string,
 		familyMember.FamilyMemberRelationship = req.FamilyMemberRelationship
 	}
 	if req.FamilyMemberDateOfBirth != "" {
-		familyMemberDateOfBirth, _ := time.Parse("2024-04-04", req.FamilyMemberDateOfBirth)
+		familyMemberDateOfBirth, err := parseDateOfBirth(req.FamilyMemberDateOfBirth)
+		if err != nil {
+			return nil, err
+		}
 		familyMember.FamilyMemberDateOfBirth = familyMemberDateOfBirth
 	}
 	if req.FamilyMemberGender != "" {
@@ -167,6 +152,54 @@ func (s *FamilyMemberService) UpdateFamilyMember(ctx context.Context, id string,
 		return nil, fmt.Errorf("failed to update family member: %w", err)
 	}
 
+	return toFamilyMemberResponse(familyMember), nil
+}
+
+func (s *FamilyMemberService) DeleteFamilyMember(ctx context.Context, id, userID string, isAdmin bool) error {
+	familyMember, err := s.getFamilyMember(ctx, id, userID, isAdmin)
+	if err != nil {
+		return err
+	}
+
+	if err := s.familyMemberRepo.Delete(ctx, familyMember.ID); err != nil {
+		return fmt.Errorf("failed to delete family member: %w", err)
+	}
+	return nil
+}
+
+// getFamilyMember loads a family member, allowing only admins and the head of the member's household
+func (s *FamilyMemberService) getFamilyMember(ctx context.Context, id, userID string, isAdmin bool) (*models.FamilyMember, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid family member ID")
+	}
+
+	familyMember, err := s.familyMemberRepo.GetByID(ctx, objID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get family member: %w", err)
+	}
+	if familyMember == nil {
+		return nil, ErrFamilyMemberNotFound
+	}
+	if !isAdmin && familyMember.FamilyHead != userID {
+		return nil, ErrNotFamilyHead
+	}
+	return familyMember, nil
+}
+
+// parseDateOfBirth reads an optional YYYY-MM-DD date of birth
+func parseDateOfBirth(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	dateOfBirth, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, errors.New("date_of_birth must be in YYYY-MM-DD format")
+	}
+	return dateOfBirth, nil
+}
+
+func toFamilyMemberResponse(familyMember *models.FamilyMember) *dto.FamilyMemberResponse {
 	return &dto.FamilyMemberResponse{
 		ID:                       familyMember.ID.Hex(),
 		FamilyMemberName:         familyMember.FamilyMemberName,
@@ -176,18 +209,7 @@ func (s *FamilyMemberService) UpdateFamilyMember(ctx context.Context, id string,
 		FamilyMemberDateOfBirth:  familyMember.FamilyMemberDateOfBirth,
 		FamilyMemberGender:       familyMember.FamilyMemberGender,
 		FamilyMemberOccupation:   familyMember.FamilyMemberOccupation,
+		FamilyMemberHead:         familyMember.FamilyHead,
 		DateAdded:                familyMember.DateAdded,
-	}, nil
-}
-
-func (s *FamilyMemberService) DeleteFamilyMember(ctx context.Context, id string) error {
-	objID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return errors.New("invalid family member ID")
 	}
-
-	if err := s.familyMemberRepo.Delete(ctx, objID); err != nil {
-		return fmt.Errorf("failed to delete family member: %w", err)
-	}
-	return nil
 }
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"cci-api/internal/config"
+	"cci-api/internal/models"
+	"cci-api/internal/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestFamilyMemberOwnership(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	daughter := models.FamilyMember{ID: primitive.NewObjectID(), FamilyHead: "CCIMRB-20310", FamilyMemberName: "Adaeze Eze"}
+
+	tests := []struct {
+		name    string
+		userID  string
+		isAdmin bool
+		found   bool
+		wantErr error
+	}{
+		{"family head", "CCIMRB-20310", false, true, nil},
+		{"another member", "CCIMRB-10422", false, true, ErrNotFamilyHead},
+		{"admin", "CCIMRB-00001", true, true, nil},
+		{"missing record", "CCIMRB-20310", false, false, ErrFamilyMemberNotFound},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			s := NewFamilyMemberService(&config.Config{}, repository.NewFamilyMemberRepository(mockDatabase(mt)))
+			if tt.found {
+				mt.AddMockResponses(mockFound(mt, "family_members", daughter))
+			} else {
+				mt.AddMockResponses(mockFound(mt, "family_members"))
+			}
+
+			resp, err := s.GetFamilyMemberByID(context.Background(), daughter.ID.Hex(), tt.userID, tt.isAdmin)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetFamilyMemberByID: %v", err)
+			}
+			if resp.ID != daughter.ID.Hex() {
+				t.Errorf("got family member %s, want %s", resp.ID, daughter.ID.Hex())
+			}
+		})
+	}
+
+	// Someone else's family member is neither changed nor deleted
+	mt.Run("delete by another member", func(mt *mtest.T) {
+		s := NewFamilyMemberService(&config.Config{}, repository.NewFamilyMemberRepository(mockDatabase(mt)))
+		mt.AddMockResponses(mockFound(mt, "family_members", daughter))
+
+		if err := s.DeleteFamilyMember(context.Background(), daughter.ID.Hex(), "CCIMRB-10422", false); !errors.Is(err, ErrForbidden) {
+			t.Fatalf("err = %v, want ErrForbidden", err)
+		}
+		for _, cmd := range sentCommands(mt) {
+			if cmd == "delete" {
+				t.Error("another household's family member was deleted")
+			}
+		}
+	})
+}
@@ -0,0 +1,365 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"cci-api/internal/config"
+	"cci-api/internal/dto"
+	"cci-api/internal/models"
+	"cci-api/internal/repository"
+	"cci-api/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrNotAssignedFollowUp is returned when someone other than follow-ups:manage or the assigned shepherd works on a follow-up
+var ErrNotAssignedFollowUp = newForbiddenError("you are not assigned to this follow-up")
+
+type FollowUpService struct {
+	cfg              *config.Config
+	followUpRepo     *repository.FollowUpRepository
+	attendanceRepo   *repository.AttendanceRepository
+	userRepo         *repository.UserRepository
+	serviceEventRepo *repository.ServiceEventRepository
+	localChurchRepo  *repository.LocalChurchRepository
+}
+
+func NewFollowUpService(cfg *config.Config, followUpRepo *repository.FollowUpRepository, attendanceRepo *repository.AttendanceRepository, userRepo *repository.UserRepository, serviceEventRepo *repository.ServiceEventRepository, localChurchRepo *repository.LocalChurchRepository) *FollowUpService {
+	return &FollowUpService{
+		cfg:              cfg,
+		followUpRepo:     followUpRepo,
+		attendanceRepo:   attendanceRepo,
+		userRepo:         userRepo,
+		serviceEventRepo: serviceEventRepo,
+		localChurchRepo:  localChurchRepo,
+	}
+}
+
+// StartScheduler scans for absentees on startup and then every FOLLOW_UP_SCAN_INTERVAL until ctx is cancelled.
+// An interval of zero turns the job off.
+func (s *FollowUpService) StartScheduler(ctx context.Context) {
+	if s.cfg.FollowUpScanInterval <= 0 {
+		log.Println("Absentee follow-up scan is disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.cfg.FollowUpScanInterval)
+		defer ticker.Stop()
+
+		for {
+			created, err := s.DetectAbsentees(ctx)
+			if err != nil {
+				log.Printf("Absentee follow-up scan failed: %v", err)
+			} else if created > 0 {
+				log.Printf("Absentee follow-up scan created %d follow-ups", created)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// churchAttendance is how a church's members attended its regular services over the scan windows
+type churchAttendance struct {
+	// missedServices is how many services in a row count as missed, or zero when the church has held too few
+	missedServices int
+	recentHeld     int
+	baselineHeld   int
+	summary        repository.MemberAttendanceSummary
+}
+
+// DetectAbsentees raises a follow-up for every member who either missed the last FOLLOW_UP_MISSED_SERVICES
+// services in a row, or whose attendance rate over the recent window fell below FOLLOW_UP_RATE_THRESHOLD times
+// their own rate over the baseline window before it. Each member is measured against the regular services of
+// their church, the one whose services they attended most recently. Only members who attended at some point in
+// those windows are considered, and members who already have an unresolved follow-up are skipped. It returns how
+// many follow-ups were created.
+func (s *FollowUpService) DetectAbsentees(ctx context.Context) (int, error) {
+	now := time.Now()
+	recentStart := now.Add(-s.cfg.FollowUpRecentWindow)
+	baselineStart := recentStart.Add(-s.cfg.FollowUpBaselineWindow)
+
+	scopes, err := s.churchServiceScopes(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	members := map[primitive.ObjectID]churchAttendance{}
+	for _, scope := range scopes {
+		services, err := s.serviceEventRepo.GetLastEnded(ctx, now, scope, s.cfg.FollowUpMissedServices)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get recent services: %w", err)
+		}
+		// Too few services have been held to tell whether anyone missed them all
+		var serviceIDs []primitive.ObjectID
+		if len(services) == s.cfg.FollowUpMissedServices {
+			for _, event := range services {
+				serviceIDs = append(serviceIDs, event.ID)
+			}
+		}
+
+		recentHeld, err := s.serviceEventRepo.CountStartedBetween(ctx, recentStart, now, scope)
+		if err != nil {
+			return 0, fmt.Errorf("failed to count service events: %w", err)
+		}
+		baselineHeld, err := s.serviceEventRepo.CountStartedBetween(ctx, baselineStart, recentStart, scope)
+		if err != nil {
+			return 0, fmt.Errorf("failed to count service events: %w", err)
+		}
+		rateEvents, err := s.serviceEventRepo.GetIDsStartedBetween(ctx, baselineStart, now, scope)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get service events: %w", err)
+		}
+
+		summaries, err := s.attendanceRepo.GetMemberSummaries(ctx, baselineStart, recentStart, rateEvents, serviceIDs)
+		if err != nil {
+			return 0, fmt.Errorf("failed to summarise member attendance: %w", err)
+		}
+		for _, summary := range summaries {
+			if current, ok := members[summary.User]; ok && !summary.LastAttended.After(current.summary.LastAttended) {
+				continue
+			}
+			members[summary.User] = churchAttendance{
+				missedServices: len(serviceIDs),
+				recentHeld:     recentHeld,
+				baselineHeld:   baselineHeld,
+				summary:        summary,
+			}
+		}
+	}
+
+	created := 0
+	for _, member := range members {
+		summary := member.summary
+		var reason, details string
+		switch {
+		case member.missedServices > 0 && summary.AttendedServices == 0:
+			reason = models.FollowUpReasonMissedServices
+			details = fmt.Sprintf("Missed the last %d services, last attended on %s",
+				member.missedServices, summary.LastAttended.Format("2006-01-02"))
+		case member.recentHeld > 0 && member.baselineHeld > 0 && summary.BaselineCount > 0:
+			baselineRate := float64(summary.BaselineCount) / float64(member.baselineHeld)
+			recentRate := float64(summary.RecentCount) / float64(member.recentHeld)
+			if recentRate >= baselineRate*s.cfg.FollowUpRateThreshold {
+				continue
+			}
+			reason = models.FollowUpReasonRateDrop
+			details = fmt.Sprintf("Attended %.0f%% of events over the last %d days, down from %.0f%% before",
+				recentRate*100, int(s.cfg.FollowUpRecentWindow.Hours()/24), baselineRate*100)
+		default:
+			continue
+		}
+
+		open, err := s.followUpRepo.HasOpenForUser(ctx, summary.User)
+		if err != nil {
+			return created, fmt.Errorf("failed to check existing follow-ups: %w", err)
+		}
+		if open {
+			continue
+		}
+
+		followUp := &models.FollowUp{
+			User:       summary.User,
+			UserID:     summary.UserID,
+			Reason:     reason,
+			Details:    details,
+			AssignedTo: summary.Shepherd,
+			Status:     models.FollowUpStatusOpen,
+			DueDate:    now.Add(s.cfg.FollowUpDueAfter),
+		}
+		if err := s.followUpRepo.Create(ctx, followUp); err != nil {
+			// Another replica's scan got there first
+			if errors.Is(err, repository.ErrOpenFollowUpExists) {
+				continue
+			}
+			return created, fmt.Errorf("failed to create follow-up: %w", err)
+		}
+		created++
+	}
+
+	return created, nil
+}
+
+// churchServiceScopes returns the regular services of each church. Events without a church belong to the default
+// church, and with no churches set up every event counts as one church's.
+func (s *FollowUpService) churchServiceScopes(ctx context.Context) ([]repository.EventScope, error) {
+	churches, err := s.localChurchRepo.ListAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get churches: %w", err)
+	}
+	if len(churches) == 0 {
+		return []repository.EventScope{{Types: regularServiceTypes}}, nil
+	}
+
+	defaultChurch, err := s.localChurchRepo.GetFirst(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get church: %w", err)
+	}
+
+	scopes := make([]repository.EventScope, 0, len(churches))
+	for _, church := range churches {
+		churchID := church.ID
+		scopes = append(scopes, repository.EventScope{
+			Church:            &churchID,
+			IncludeUnassigned: defaultChurch != nil && defaultChurch.ID == churchID,
+			Types:             regularServiceTypes,
+		})
+	}
+	return scopes, nil
+}
+
+// GetFollowUps lists follow-ups. Admins see every follow-up, everyone else only those assigned to them.
+func (s *FollowUpService) GetFollowUps(ctx context.Context, status, assignedTo, requesterID string, isAdmin bool, page, limit int) (*dto.PaginatedResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+	if !isAdmin {
+		assignedTo = requesterID
+	}
+
+	followUps, total, err := s.followUpRepo.GetAll(ctx, status, assignedTo, page, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get follow-ups: %w", err)
+	}
+
+	responses := make([]*dto.FollowUpResponse, 0, len(followUps))
+	for _, followUp := range followUps {
+		responses = append(responses, toFollowUpResponse(followUp))
+	}
+
+	return &dto.PaginatedResponse{
+		Data:       responses,
+		Pagination: utils.NewPagination(page, limit, total),
+	}, nil
+}
+
+func (s *FollowUpService) GetFollowUpByID(ctx context.Context, id, requesterID string, isAdmin bool) (*dto.FollowUpResponse, error) {
+	followUp, err := s.getFollowUp(ctx, id, requesterID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+	return toFollowUpResponse(followUp), nil
+}
+
+// UpdateFollowUp records progress on a follow-up: a new status, a note, a new due date or, for admins, a new assignee
+func (s *FollowUpService) UpdateFollowUp(ctx context.Context, id string, req *dto.UpdateFollowUpRequest, requesterID string, isAdmin bool) (*dto.FollowUpResponse, error) {
+	followUp, err := s.getFollowUp(ctx, id, requesterID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := false
+	if req.Status != "" && req.Status != followUp.Status {
+		followUp.Status = req.Status
+		if req.Status == models.FollowUpStatusResolved {
+			now := time.Now()
+			followUp.ResolvedAt = &now
+		} else {
+			followUp.ResolvedAt = nil
+		}
+		changed = true
+	}
+	if req.DueDate != "" {
+		dueDate, err := time.Parse("2006-01-02", req.DueDate)
+		if err != nil {
+			return nil, errors.New("due_date must be in YYYY-MM-DD format")
+		}
+		followUp.DueDate = dueDate
+		changed = true
+	}
+	if req.AssignedTo != "" && req.AssignedTo != followUp.AssignedTo {
+		if !isAdmin {
+			return nil, fmt.Errorf("%w: only admins can reassign follow-ups", ErrNotAssignedFollowUp)
+		}
+		assignee, err := s.userRepo.GetByUserID(ctx, req.AssignedTo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get assignee: %w", err)
+		}
+		if assignee == nil {
+			return nil, errors.New("assignee not found")
+		}
+		followUp.AssignedTo = req.AssignedTo
+		changed = true
+	}
+
+	var note *models.FollowUpNote
+	if text := strings.TrimSpace(req.Note); text != "" {
+		note = &models.FollowUpNote{
+			Text:    text,
+			AddedBy: requesterID,
+			AddedAt: time.Now(),
+		}
+		followUp.Notes = append(followUp.Notes, *note)
+		changed = true
+	}
+
+	if !changed {
+		return nil, errors.New("no changes to apply")
+	}
+
+	if err := s.followUpRepo.Update(ctx, followUp, note); err != nil {
+		if errors.Is(err, repository.ErrOpenFollowUpExists) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to update follow-up: %w", err)
+	}
+
+	return toFollowUpResponse(followUp), nil
+}
+
+func (s *FollowUpService) getFollowUp(ctx context.Context, id, requesterID string, isAdmin bool) (*models.FollowUp, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid follow-up ID")
+	}
+
+	followUp, err := s.followUpRepo.GetByID(ctx, objID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get follow-up: %w", err)
+	}
+	if followUp == nil {
+		return nil, newNotFoundError("follow-up not found")
+	}
+	if !isAdmin && followUp.AssignedTo != requesterID {
+		return nil, ErrNotAssignedFollowUp
+	}
+	return followUp, nil
+}
+
+func toFollowUpResponse(followUp *models.FollowUp) *dto.FollowUpResponse {
+	notes := make([]dto.FollowUpNote, 0, len(followUp.Notes))
+	for _, note := range followUp.Notes {
+		notes = append(notes, dto.FollowUpNote{
+			Text:    note.Text,
+			AddedBy: note.AddedBy,
+			AddedAt: note.AddedAt,
+		})
+	}
+
+	return &dto.FollowUpResponse{
+		ID:          followUp.ID.Hex(),
+		UserID:      followUp.UserID,
+		Reason:      followUp.Reason,
+		Details:     followUp.Details,
+		AssignedTo:  followUp.AssignedTo,
+		Status:      followUp.Status,
+		DueDate:     followUp.DueDate,
+		Notes:       notes,
+		ResolvedAt:  followUp.ResolvedAt,
+		DateAdded:   followUp.DateAdded,
+		DateUpdated: followUp.DateUpdated,
+	}
+}
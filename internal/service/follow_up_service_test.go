@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"cci-api/internal/config"
+	"cci-api/internal/models"
+	"cci-api/internal/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestDetectAbsenteesPerChurch(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("members are measured against their own church", func(mt *mtest.T) {
+		db := mockDatabase(mt)
+		s := NewFollowUpService(&config.Config{
+			FollowUpMissedServices: 3,
+			FollowUpRecentWindow:   28 * 24 * time.Hour,
+			FollowUpBaselineWindow: 84 * 24 * time.Hour,
+			FollowUpRateThreshold:  0.5,
+			FollowUpDueAfter:       7 * 24 * time.Hour,
+		}, repository.NewFollowUpRepository(db), repository.NewAttendanceRepository(db), repository.NewUserRepository(db),
+			repository.NewServiceEventRepository(db), repository.NewLocalChurchRepository(db))
+
+		now := time.Now()
+		ikeja := &models.LocalChurch{ID: primitive.NewObjectID(), ChurchName: "Ikeja"}
+		lekki := &models.LocalChurch{ID: primitive.NewObjectID(), ChurchName: "Lekki"}
+		services := func(church *models.LocalChurch) []interface{} {
+			var events []interface{}
+			for week := 1; week <= 3; week++ {
+				start := now.AddDate(0, 0, -7*week)
+				events = append(events, &models.ServiceEvent{
+					ID:        primitive.NewObjectID(),
+					Name:      church.ChurchName + " Sunday Service",
+					EventType: models.EventTypeSundayService,
+					Church:    &church.ID,
+					StartTime: start,
+					EndTime:   start.Add(2 * time.Hour),
+				})
+			}
+			return events
+		}
+		summary := func(userID string, lastAttended time.Time, baseline, recent, attended int) repository.MemberAttendanceSummary {
+			return repository.MemberAttendanceSummary{
+				User:             primitive.NewObjectID(),
+				UserID:           userID,
+				Shepherd:         "CCIMRB-00007",
+				LastAttended:     lastAttended,
+				BaselineCount:    baseline,
+				RecentCount:      recent,
+				AttendedServices: attended,
+			}
+		}
+
+		// Moved from Ikeja to Lekki, so missing Ikeja's services is not an absence
+		moved := summary("CCIMRB-10001", now.AddDate(0, 0, -60), 6, 0, 0)
+		movedAtLekki := moved
+		movedAtLekki.LastAttended = now.AddDate(0, 0, -7)
+		movedAtLekki.BaselineCount, movedAtLekki.RecentCount, movedAtLekki.AttendedServices = 2, 2, 1
+		absent := summary("CCIMRB-10002", now.AddDate(0, 0, -40), 8, 0, 0)
+		slipping := summary("CCIMRB-10003", now.AddDate(0, 0, -14), 8, 1, 1)
+
+		mt.AddMockResponses(
+			mockFound(mt, "local_churches", ikeja, lekki),
+			mockFound(mt, "local_churches", ikeja),
+			mockFound(mt, "service_events", services(ikeja)...),
+			mockFound(mt, "service_events", bson.D{{Key: "n", Value: 4}}),
+			mockFound(mt, "service_events", bson.D{{Key: "n", Value: 8}}),
+			mockFound(mt, "service_events"),
+			mockFound(mt, "attendance", moved, absent),
+			mockFound(mt, "service_events", services(lekki)...),
+			mockFound(mt, "service_events", bson.D{{Key: "n", Value: 4}}),
+			mockFound(mt, "service_events", bson.D{{Key: "n", Value: 8}}),
+			mockFound(mt, "service_events"),
+			mockFound(mt, "attendance", movedAtLekki, slipping),
+			// Two follow-ups are due, each checked for an open one and then created
+			mockFound(mt, "follow_ups"),
+			mtest.CreateSuccessResponse(),
+			mockFound(mt, "follow_ups"),
+			mtest.CreateSuccessResponse(),
+		)
+
+		created, err := s.DetectAbsentees(context.Background())
+		if err != nil {
+			t.Fatalf("DetectAbsentees: %v", err)
+		}
+		if created != 2 {
+			t.Errorf("DetectAbsentees created %d follow-ups, want 2", created)
+		}
+
+		reasons := map[string]string{}
+		var scannedChurches []string
+		for _, event := range mt.GetAllStartedEvents() {
+			switch event.CommandName {
+			case "insert":
+				doc := event.Command.Lookup("documents").Array().Index(0).Value().Document()
+				reasons[doc.Lookup("user_id").StringValue()] = doc.Lookup("reason").StringValue()
+			case "find":
+				if event.Command.Lookup("find").StringValue() == "service_events" {
+					scannedChurches = append(scannedChurches, event.Command.Lookup("filter").String())
+				}
+			}
+		}
+		want := map[string]string{
+			absent.UserID:   models.FollowUpReasonMissedServices,
+			slipping.UserID: models.FollowUpReasonRateDrop,
+		}
+		for userID, reason := range want {
+			if reasons[userID] != reason {
+				t.Errorf("follow-up for %s = %q, want %q", userID, reasons[userID], reason)
+			}
+		}
+		if _, ok := reasons[moved.UserID]; ok {
+			t.Errorf("opened a follow-up for %s, who attends another church now", moved.UserID)
+		}
+		if len(scannedChurches) < 1 || !strings.Contains(scannedChurches[0], ikeja.ID.Hex()) {
+			t.Errorf("first service lookup %v is not limited to the church", scannedChurches)
+		}
+	})
+}
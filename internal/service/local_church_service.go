@@ -32,6 +32,11 @@ func (s *LocalChurchService) CreateChurch(ctx context.Context, req *dto.CreateLo
 	if req.ChurchName == "" {
 		return nil, errors.New("church name is required")
 	}
+	if req.Timezone != "" {
+		if _, err := time.LoadLocation(req.Timezone); err != nil {
+			return nil, errors.New("invalid timezone")
+		}
+	}
 
 	// Create church
 	church := &models.LocalChurch{
@@ -44,6 +49,9 @@ func (s *LocalChurchService) CreateChurch(ctx context.Context, req *dto.CreateLo
 		SundayMeetingTime:  req.SundayMeetingTime,
 		MidweekMeetingDay:  req.MidweekMeetingDay,
 		MidweekMeetingTime: req.MidweekMeetingTime,
+		Timezone:           req.Timezone,
+		LateGracePeriod:    req.LateGracePeriod,
+		Geofence:           toGeofenceModel(req.Geofence),
 		Website:            req.Website,
 		SocialMedia:        req.SocialMedia,
 		PastorName:         req.PastorName,
@@ -71,6 +79,9 @@ func (s *LocalChurchService) CreateChurch(ctx context.Context, req *dto.CreateLo
 		SundayMeetingTime:  church.SundayMeetingTime,
 		MidweekMeetingDay:  church.MidweekMeetingDay,
 		MidweekMeetingTime: church.MidweekMeetingTime,
+		Timezone:           church.Timezone,
+		LateGracePeriod:    church.LateGracePeriod,
+		Geofence:           toGeofenceResponse(church.Geofence),
 		Website:            church.Website,
 		SocialMedia:        church.SocialMedia,
 		PastorName:         church.ChurchName,
@@ -109,6 +120,9 @@ func (s *LocalChurchService) GetChurches(ctx context.Context, page, limit int) (
 			SundayMeetingTime:  church.SundayMeetingTime,
 			MidweekMeetingDay:  church.MidweekMeetingDay,
 			MidweekMeetingTime: church.MidweekMeetingTime,
+			Timezone:           church.Timezone,
+			LateGracePeriod:    church.LateGracePeriod,
+			Geofence:           toGeofenceResponse(church.Geofence),
 			Website:            church.Website,
 			SocialMedia:        church.SocialMedia,
 			PastorName:         church.ChurchName,
@@ -149,6 +163,9 @@ func (s *LocalChurchService) GetChurchByID(ctx context.Context, id string) (*dto
 		SundayMeetingTime:  church.SundayMeetingTime,
 		MidweekMeetingDay:  church.MidweekMeetingDay,
 		MidweekMeetingTime: church.MidweekMeetingTime,
+		Timezone:           church.Timezone,
+		LateGracePeriod:    church.LateGracePeriod,
+		Geofence:           toGeofenceResponse(church.Geofence),
 		Website:            church.Website,
 		SocialMedia:        church.SocialMedia,
 		PastorName:         church.ChurchName,
@@ -200,6 +217,20 @@ func (s *LocalChurchService) UpdateChurch(ctx context.Context, id string, req *d
 	if req.MidweekMeetingTime != 0 {
 		church.MidweekMeetingTime = req.MidweekMeetingTime
 	}
+	if req.Timezone != "" {
+		if _, err := time.LoadLocation(req.Timezone); err != nil {
+			return nil, errors.New("invalid timezone")
+		}
+		church.Timezone = req.Timezone
+	}
+	if req.LateGracePeriod != nil {
+		church.LateGracePeriod = req.LateGracePeriod
+	}
+	if req.RemoveGeofence {
+		church.Geofence = nil
+	} else if req.Geofence != nil {
+		church.Geofence = toGeofenceModel(req.Geofence)
+	}
 
 	dateUpdated := time.Now()
 
@@ -219,6 +250,9 @@ func (s *LocalChurchService) UpdateChurch(ctx context.Context, id string, req *d
 		SundayMeetingTime:  church.SundayMeetingTime,
 		MidweekMeetingDay:  church.MidweekMeetingDay,
 		MidweekMeetingTime: church.MidweekMeetingTime,
+		Timezone:           church.Timezone,
+		LateGracePeriod:    church.LateGracePeriod,
+		Geofence:           toGeofenceResponse(church.Geofence),
 		Website:            church.Website,
 		SocialMedia:        church.SocialMedia,
 		PastorName:         church.ChurchName,
@@ -242,3 +276,25 @@ func (s *LocalChurchService) DeleteChurch(ctx context.Context, id string) error
 	}
 	return nil
 }
+
+func toGeofenceModel(geofence *dto.Geofence) *models.Geofence {
+	if geofence == nil {
+		return nil
+	}
+	return &models.Geofence{
+		Latitude:     geofence.Latitude,
+		Longitude:    geofence.Longitude,
+		RadiusMeters: geofence.RadiusMeters,
+	}
+}
+
+func toGeofenceResponse(geofence *models.Geofence) *dto.Geofence {
+	if geofence == nil {
+		return nil
+	}
+	return &dto.Geofence{
+		Latitude:     geofence.Latitude,
+		Longitude:    geofence.Longitude,
+		RadiusMeters: geofence.RadiusMeters,
+	}
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"cci-api/internal/config"
+	"cci-api/internal/models"
+	"cci-api/internal/repository"
+)
+
+// maxLoginDelay caps the delay that doubles after every failed login
+const maxLoginDelay = time.Minute
+
+// LoginThrottledError is returned while an account or IP address has to wait before it may log in again
+type LoginThrottledError struct {
+	Locked     bool
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	if e.Locked {
+		return fmt.Sprintf("too many failed login attempts, try again in %s", e.RetryAfter.Round(time.Second))
+	}
+	return fmt.Sprintf("please wait %s before trying to log in again", e.RetryAfter.Round(time.Second))
+}
+
+// LoginProtectionService counts failed logins per account and per IP address. Each failure doubles the wait
+// before the next attempt, and too many failures within LoginAttemptWindow lock the account or IP address
+// for LoginLockoutDuration. The counters live in Mongo so they hold across restarts and replicas.
+type LoginProtectionService struct {
+	cfg              *config.Config
+	loginAttemptRepo *repository.LoginAttemptRepository
+	userRepo         *repository.UserRepository
+	emailService     EmailService
+}
+
+func NewLoginProtectionService(cfg *config.Config, loginAttemptRepo *repository.LoginAttemptRepository, userRepo *repository.UserRepository, emailService EmailService) *LoginProtectionService {
+	return &LoginProtectionService{
+		cfg:              cfg,
+		loginAttemptRepo: loginAttemptRepo,
+		userRepo:         userRepo,
+		emailService:     emailService,
+	}
+}
+
+// Check refuses a login while the account or IP address is locked or still waiting out its delay
+func (s *LoginProtectionService) Check(ctx context.Context, email, ip string) error {
+	now := time.Now()
+	for _, key := range loginAttemptKeys(email, ip) {
+		attempt, err := s.loginAttemptRepo.Get(ctx, key)
+		if err != nil {
+			return fmt.Errorf("failed to get login attempts: %w", err)
+		}
+		if attempt == nil || !now.Before(attempt.ExpiresAt) {
+			continue
+		}
+		if attempt.LockedUntil != nil && now.Before(*attempt.LockedUntil) {
+			return &LoginThrottledError{Locked: true, RetryAfter: attempt.LockedUntil.Sub(now)}
+		}
+		if now.Before(attempt.NextAttemptAt) {
+			return &LoginThrottledError{RetryAfter: attempt.NextAttemptAt.Sub(now)}
+		}
+	}
+	return nil
+}
+
+// RecordFailure counts a failed login against the account and the IP address. user is nil when no account
+// uses the email; the attempt still counts so unknown and known emails behave the same.
+func (s *LoginProtectionService) RecordFailure(ctx context.Context, email, ip string, user *models.User) error {
+	attempt, locked, err := s.recordFailure(ctx, accountLoginKey(email), s.cfg.LoginMaxAttempts)
+	if err != nil {
+		return err
+	}
+	if locked && user != nil {
+		s.sendLockedEmail(user, attempt)
+	}
+
+	if ip != "" {
+		if _, _, err := s.recordFailure(ctx, ipLoginKey(ip), s.cfg.LoginIPMaxAttempts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordSuccess clears the account's failed logins. The IP address keeps its count until the window passes,
+// so one good password does not reset an address trying many accounts.
+func (s *LoginProtectionService) RecordSuccess(ctx context.Context, email string) error {
+	if err := s.loginAttemptRepo.Delete(ctx, accountLoginKey(email)); err != nil {
+		return fmt.Errorf("failed to clear login attempts: %w", err)
+	}
+	return nil
+}
+
+// Unlock lifts a lockout on the member's account
+func (s *LoginProtectionService) Unlock(ctx context.Context, userID string) error {
+	user, err := s.userRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	return s.RecordSuccess(ctx, user.Email)
+}
+
+// recordFailure counts one failure for key, reporting whether this failure locked it
+func (s *LoginProtectionService) recordFailure(ctx context.Context, key string, maxAttempts int) (*models.LoginAttempt, bool, error) {
+	now := time.Now()
+
+	// Mongo removes expired records about once a minute, so one may linger past its window
+	existing, err := s.loginAttemptRepo.Get(ctx, key)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get login attempts: %w", err)
+	}
+	if existing != nil && !now.Before(existing.ExpiresAt) {
+		if err := s.loginAttemptRepo.Delete(ctx, key); err != nil {
+			return nil, false, fmt.Errorf("failed to clear login attempts: %w", err)
+		}
+	}
+
+	attempt, err := s.loginAttemptRepo.RecordFailure(ctx, key, now, now.Add(s.cfg.LoginAttemptWindow))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to record login attempt: %w", err)
+	}
+
+	if attempt.Failures >= maxAttempts {
+		lockedUntil := now.Add(s.cfg.LoginLockoutDuration)
+		if err := s.loginAttemptRepo.Throttle(ctx, key, lockedUntil, &lockedUntil, lockedUntil); err != nil {
+			return nil, false, fmt.Errorf("failed to lock login: %w", err)
+		}
+		attempt.LockedUntil = &lockedUntil
+		return attempt, true, nil
+	}
+
+	nextAttemptAt := now.Add(s.loginDelay(attempt.Failures))
+	if err := s.loginAttemptRepo.Throttle(ctx, key, nextAttemptAt, nil, attempt.ExpiresAt); err != nil {
+		return nil, false, fmt.Errorf("failed to delay login: %w", err)
+	}
+	return attempt, false, nil
+}
+
+// loginDelay is LoginDelayBase doubled for every failure after the first, capped at maxLoginDelay
+func (s *LoginProtectionService) loginDelay(failures int) time.Duration {
+	delay := s.cfg.LoginDelayBase
+	for i := 1; i < failures && delay < maxLoginDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxLoginDelay {
+		return maxLoginDelay
+	}
+	return delay
+}
+
+func (s *LoginProtectionService) sendLockedEmail(user *models.User, attempt *models.LoginAttempt) {
+	go func() {
+		data := map[string]interface{}{
+			"FirstName":   user.FirstName,
+			"Failures":    attempt.Failures,
+			"LockedUntil": attempt.LockedUntil.Format("Jan 2, 2006 3:04 PM MST"),
+			"Link":        fmt.Sprintf("%s/forgot-password", s.cfg.FrontendURL),
+		}
+		if err := s.emailService.SendEmail(user.Email, "Your Account Has Been Locked", "account_locked_email.html", data); err != nil {
+			fmt.Printf("failed to send account locked email: %v\n", err)
+		}
+	}()
+}
+
+func accountLoginKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipLoginKey(ip string) string {
+	return "ip:" + ip
+}
+
+func loginAttemptKeys(email, ip string) []string {
+	keys := []string{accountLoginKey(email)}
+	if ip != "" {
+		keys = append(keys, ipLoginKey(ip))
+	}
+	return keys
+}
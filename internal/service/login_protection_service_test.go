@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"cci-api/internal/config"
+	"cci-api/internal/models"
+	"cci-api/internal/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestLoginDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     time.Duration
+		failures int
+		want     time.Duration
+	}{
+		{"first failure", time.Second, 1, time.Second},
+		{"second failure", time.Second, 2, 2 * time.Second},
+		{"fifth failure", time.Second, 5, 16 * time.Second},
+		{"sixth failure", time.Second, 6, 32 * time.Second},
+		{"capped", time.Second, 7, maxLoginDelay},
+		{"stays capped", time.Second, 1000, maxLoginDelay},
+		{"no failures", time.Second, 0, time.Second},
+		{"base above cap", 2 * time.Minute, 1, maxLoginDelay},
+		{"uneven base", 3 * time.Second, 5, 48 * time.Second},
+		{"uneven base capped", 3 * time.Second, 6, maxLoginDelay},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &LoginProtectionService{cfg: &config.Config{LoginDelayBase: tt.base}}
+			if got := s.loginDelay(tt.failures); got != tt.want {
+				t.Errorf("loginDelay(%d) = %v, want %v", tt.failures, got, tt.want)
+			}
+		})
+	}
+}
+
+// sentEmails records the emails a service sends, which it does from a goroutine
+type sentEmails chan string
+
+func (e sentEmails) SendEmail(to, subject, templateName string, data interface{}) error {
+	e <- to + " " + templateName
+	return nil
+}
+
+// throttleSet returns the $set of the nth update the code sent
+func throttleSet(mt *mtest.T, n int) bson.Raw {
+	var seen int
+	for _, event := range mt.GetAllStartedEvents() {
+		if event.CommandName != "update" {
+			continue
+		}
+		if seen == n {
+			return event.Command.Lookup("updates").Array().Index(0).Value().Document().Lookup("u", "$set").Document()
+		}
+		seen++
+	}
+	mt.Fatalf("no update %d in %v", n, sentCommands(mt))
+	return nil
+}
+
+func TestRecordLoginFailure(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	cfg := &config.Config{
+		LoginMaxAttempts:     5,
+		LoginIPMaxAttempts:   20,
+		LoginAttemptWindow:   15 * time.Minute,
+		LoginLockoutDuration: 30 * time.Minute,
+		LoginDelayBase:       time.Second,
+	}
+	user := &models.User{ID: primitive.NewObjectID(), UserID: "CCIMRB-10422", Email: "ada@example.com", FirstName: "Ada"}
+	counted := func(key string, failures int) bson.D {
+		return mtest.CreateSuccessResponse(bson.E{Key: "value", Value: models.LoginAttempt{
+			Key:       key,
+			Failures:  failures,
+			ExpiresAt: time.Now().Add(cfg.LoginAttemptWindow),
+		}})
+	}
+	ok := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1})
+
+	newService := func(mt *mtest.T, emails sentEmails) *LoginProtectionService {
+		db := mockDatabase(mt)
+		return NewLoginProtectionService(cfg, repository.NewLoginAttemptRepository(db), repository.NewUserRepository(db), emails)
+	}
+
+	mt.Run("failure under the limit delays the next attempt", func(mt *mtest.T) {
+		mt.AddMockResponses(mockFound(mt, "login_attempts"), counted("account:ada@example.com", 3), ok)
+
+		before := time.Now()
+		attempt, locked, err := newService(mt, nil).recordFailure(context.Background(), "account:ada@example.com", cfg.LoginMaxAttempts)
+		if err != nil {
+			t.Fatalf("recordFailure: %v", err)
+		}
+		if locked || attempt.LockedUntil != nil {
+			t.Fatalf("locked after %d failures", attempt.Failures)
+		}
+
+		set := throttleSet(mt, 0)
+		if _, isSet := set.Lookup("locked_until").TimeOK(); isSet {
+			t.Error("locked_until was set below the limit")
+		}
+		wait := set.Lookup("next_attempt_at").Time().Sub(before)
+		if wait < 4*time.Second-time.Millisecond || wait > 5*time.Second {
+			t.Errorf("next attempt in %v, want about 4s after the third failure", wait)
+		}
+	})
+
+	mt.Run("failure at the limit locks the account and emails the member", func(mt *mtest.T) {
+		emails := make(sentEmails, 1)
+		mt.AddMockResponses(
+			mockFound(mt, "login_attempts"), counted("account:ada@example.com", 5), ok,
+			mockFound(mt, "login_attempts"), counted("ip:203.0.113.7", 5), ok,
+		)
+
+		if err := newService(mt, emails).RecordFailure(context.Background(), " Ada@Example.com", "203.0.113.7", user); err != nil {
+			t.Fatalf("RecordFailure: %v", err)
+		}
+
+		lockedUntil, isSet := throttleSet(mt, 0).Lookup("locked_until").TimeOK()
+		if !isSet || time.Until(lockedUntil) < 29*time.Minute {
+			t.Errorf("account locked until %v, want about 30 minutes from now", lockedUntil)
+		}
+		if _, isSet := throttleSet(mt, 1).Lookup("locked_until").TimeOK(); isSet {
+			t.Error("IP address was locked after 5 of its 20 allowed failures")
+		}
+
+		select {
+		case sent := <-emails:
+			if sent != "ada@example.com account_locked_email.html" {
+				t.Errorf("sent %q", sent)
+			}
+		case <-time.After(time.Second):
+			t.Error("no lockout email was sent")
+		}
+	})
+
+	mt.Run("expired record starts counting again", func(mt *mtest.T) {
+		stale := models.LoginAttempt{Key: "account:ada@example.com", Failures: 9, ExpiresAt: time.Now().Add(-time.Minute)}
+		mt.AddMockResponses(
+			mockFound(mt, "login_attempts", stale),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			counted("account:ada@example.com", 1),
+			ok,
+		)
+
+		_, locked, err := newService(mt, nil).recordFailure(context.Background(), "account:ada@example.com", cfg.LoginMaxAttempts)
+		if err != nil {
+			t.Fatalf("recordFailure: %v", err)
+		}
+		if locked {
+			t.Error("locked on the first failure of a new window")
+		}
+		if cmds := strings.Join(sentCommands(mt), " "); cmds != "find delete findAndModify update" {
+			t.Errorf("commands = %s", cmds)
+		}
+	})
+
+	mt.Run("locked account is refused", func(mt *mtest.T) {
+		lockedUntil := time.Now().Add(10 * time.Minute)
+		locked := models.LoginAttempt{Key: "account:ada@example.com", Failures: 5, LockedUntil: &lockedUntil, ExpiresAt: lockedUntil}
+		mt.AddMockResponses(mockFound(mt, "login_attempts", locked))
+
+		err := newService(mt, nil).Check(context.Background(), "ada@example.com", "203.0.113.7")
+		var throttled *LoginThrottledError
+		if !errors.As(err, &throttled) || !throttled.Locked || throttled.RetryAfter > 10*time.Minute {
+			t.Fatalf("err = %v, want a lockout of at most 10 minutes", err)
+		}
+	})
+}
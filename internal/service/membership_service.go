@@ -0,0 +1,282 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"cci-api/internal/config"
+	"cci-api/internal/database"
+	"cci-api/internal/dto"
+	"cci-api/internal/models"
+	"cci-api/internal/repository"
+	"cci-api/internal/utils"
+)
+
+type MembershipService struct {
+	cfg            *config.Config
+	db             *database.Database
+	userRepo       *repository.UserRepository
+	attendanceRepo *repository.AttendanceRepository
+	transitionRepo *repository.MembershipTransitionRepository
+}
+
+func NewMembershipService(cfg *config.Config, db *database.Database, userRepo *repository.UserRepository, attendanceRepo *repository.AttendanceRepository, transitionRepo *repository.MembershipTransitionRepository) *MembershipService {
+	return &MembershipService{
+		cfg:            cfg,
+		db:             db,
+		userRepo:       userRepo,
+		attendanceRepo: attendanceRepo,
+		transitionRepo: transitionRepo,
+	}
+}
+
+// membershipStageOf returns the user's membership stage. Users from before the pipeline existed have no stage
+// saved, so it is inferred from their member flag and whether they serve in a department.
+// Keep in step with membershipStageExpr in the repository package.
+func membershipStageOf(user *models.User) string {
+	switch {
+	case user.MembershipStage != "":
+		return user.MembershipStage
+	case user.Member && (user.Usher || user.UserWorkDepartment != ""):
+		return models.MembershipStageWorker
+	case user.Member:
+		return models.MembershipStageMember
+	default:
+		return models.MembershipStageFirstTimeGuest
+	}
+}
+
+// nextMembershipStage returns the stage after stage, or "" for the last stage
+func nextMembershipStage(stage string) string {
+	for i, s := range models.MembershipStages {
+		if s == stage && i+1 < len(models.MembershipStages) {
+			return models.MembershipStages[i+1]
+		}
+	}
+	return ""
+}
+
+// stageThresholds maps each stage to the attendance needed in it before the next stage is suggested. Stages
+// with a threshold of zero or less never get suggestions.
+func (s *MembershipService) stageThresholds() map[string]int {
+	thresholds := map[string]int{}
+	for stage, visits := range map[string]int{
+		models.MembershipStageFirstTimeGuest:   s.cfg.MembershipReturningVisits,
+		models.MembershipStageReturningVisitor: s.cfg.MembershipClassVisits,
+		models.MembershipStageMembershipClass:  s.cfg.MembershipMemberVisits,
+		models.MembershipStageMember:           s.cfg.MembershipWorkerVisits,
+	} {
+		if visits > 0 {
+			thresholds[stage] = visits
+		}
+	}
+	return thresholds
+}
+
+// GetMembership returns a user's membership stage, their stage history and, if they have attended enough since
+// entering their stage, the stage they could move on to
+func (s *MembershipService) GetMembership(ctx context.Context, userID string) (*dto.MembershipResponse, error) {
+	user, err := s.userRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	return s.membershipResponse(ctx, user)
+}
+
+// TransitionStage moves a user to another membership stage and records the move. The member and visitor flags
+// are kept in line with the new stage.
+func (s *MembershipService) TransitionStage(ctx context.Context, userID string, req *dto.MembershipStageRequest, changedBy string) (*dto.MembershipResponse, error) {
+	user, err := s.userRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	from := membershipStageOf(user)
+	if req.Stage == from {
+		return nil, fmt.Errorf("user is already at the %s stage", from)
+	}
+
+	fromSince := user.DateJoined
+	if user.MembershipStageSince != nil {
+		fromSince = *user.MembershipStageSince
+	}
+
+	now := time.Now()
+	user.MembershipStage = req.Stage
+	user.MembershipStageSince = &now
+	switch req.Stage {
+	case models.MembershipStageMember, models.MembershipStageWorker:
+		user.Member = true
+		user.Visitor = false
+		if user.DateJoinedChurch.IsZero() {
+			user.DateJoinedChurch = now
+		}
+	default:
+		user.Member = false
+		user.Visitor = true
+	}
+
+	transition := &models.MembershipTransition{
+		User:      user.ID,
+		UserID:    user.UserID,
+		From:      from,
+		To:        req.Stage,
+		FromSince: fromSince,
+		Note:      req.Note,
+		ChangedBy: changedBy,
+		ChangedAt: now,
+	}
+
+	err = s.db.WithTransaction(ctx, func(txCtx context.Context) error {
+		if err := s.userRepo.UpdateMembershipStage(txCtx, user); err != nil {
+			return fmt.Errorf("failed to update membership stage: %w", err)
+		}
+		if err := s.transitionRepo.Create(txCtx, transition); err != nil {
+			return fmt.Errorf("failed to record stage transition: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.membershipResponse(ctx, user)
+}
+
+// GetStageSuggestions lists users who have attended enough since entering their stage to be moved on
+func (s *MembershipService) GetStageSuggestions(ctx context.Context, page, limit int) (*dto.PaginatedResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	suggestions, total, err := s.userRepo.GetStageSuggestions(ctx, s.stageThresholds(), page, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stage suggestions: %w", err)
+	}
+
+	responses := make([]*dto.StageSuggestionResponse, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		responses = append(responses, &dto.StageSuggestionResponse{
+			UserID:         suggestion.UserID,
+			FirstName:      suggestion.FirstName,
+			LastName:       suggestion.LastName,
+			Stage:          suggestion.Stage,
+			StageSince:     suggestion.Since,
+			Attended:       suggestion.Attended,
+			SuggestedStage: nextMembershipStage(suggestion.Stage),
+		})
+	}
+
+	return &dto.PaginatedResponse{
+		Data:       responses,
+		Pagination: utils.NewPagination(page, limit, total),
+	}, nil
+}
+
+// GetMembershipFunnel reports how many people are in each stage, how many have reached it, what share of them
+// went on to the next stage (as a percentage) and how long people spend in it on average. Someone has reached
+// a stage when their current stage is that stage or a later one.
+func (s *MembershipService) GetMembershipFunnel(ctx context.Context) (*dto.MembershipFunnelResponse, error) {
+	counts, err := s.userRepo.CountByMembershipStage(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count membership stages: %w", err)
+	}
+
+	durations, err := s.transitionRepo.GetAverageStageDurations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stage durations: %w", err)
+	}
+	durationByStage := make(map[string]repository.StageDuration, len(durations))
+	for _, duration := range durations {
+		durationByStage[duration.Stage] = duration
+	}
+
+	stages := make([]dto.MembershipFunnelStage, len(models.MembershipStages))
+	reached, members := 0, 0
+	for i := len(models.MembershipStages) - 1; i >= 0; i-- {
+		stage := models.MembershipStages[i]
+		reached += counts[stage]
+		if stage == models.MembershipStageMember {
+			members = reached
+		}
+
+		funnelStage := dto.MembershipFunnelStage{
+			Stage:   stage,
+			Current: counts[stage],
+			Reached: reached,
+		}
+		if i+1 < len(stages) && reached > 0 {
+			rate := math.Round(float64(stages[i+1].Reached)/float64(reached)*1000) / 10
+			funnelStage.ConversionRate = &rate
+		}
+		if duration, ok := durationByStage[stage]; ok {
+			days := math.Round(duration.AverageMillis/float64(24*time.Hour/time.Millisecond)*10) / 10
+			funnelStage.AverageDaysInStage = &days
+			funnelStage.MovedOn = duration.Count
+		}
+		stages[i] = funnelStage
+	}
+
+	response := &dto.MembershipFunnelResponse{Stages: stages}
+	if guests := stages[0].Reached; guests > 0 {
+		response.GuestToMemberRate = math.Round(float64(members)/float64(guests)*1000) / 10
+	}
+
+	return response, nil
+}
+
+func (s *MembershipService) membershipResponse(ctx context.Context, user *models.User) (*dto.MembershipResponse, error) {
+	stage := membershipStageOf(user)
+
+	var since time.Time
+	if user.MembershipStageSince != nil {
+		since = *user.MembershipStageSince
+	}
+	attended, err := s.attendanceRepo.CountForUserSince(ctx, user.ID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count attendance: %w", err)
+	}
+
+	transitions, err := s.transitionRepo.GetByUser(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stage history: %w", err)
+	}
+
+	history := make([]*dto.MembershipTransitionResponse, 0, len(transitions))
+	for _, transition := range transitions {
+		history = append(history, &dto.MembershipTransitionResponse{
+			From:      transition.From,
+			To:        transition.To,
+			FromSince: transition.FromSince,
+			Note:      transition.Note,
+			ChangedBy: transition.ChangedBy,
+			ChangedAt: transition.ChangedAt,
+		})
+	}
+
+	response := &dto.MembershipResponse{
+		UserID:          user.UserID,
+		Stage:           stage,
+		StageSince:      user.MembershipStageSince,
+		AttendedInStage: attended,
+		History:         history,
+	}
+	if threshold, ok := s.stageThresholds()[stage]; ok && attended >= threshold {
+		response.SuggestedStage = nextMembershipStage(stage)
+	}
+
+	return response, nil
+}
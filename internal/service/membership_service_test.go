@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cci-api/internal/config"
+	"cci-api/internal/dto"
+	"cci-api/internal/models"
+	"cci-api/internal/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestMembershipStageOf(t *testing.T) {
+	tests := []struct {
+		name string
+		user models.User
+		want string
+	}{
+		{"saved stage wins", models.User{MembershipStage: models.MembershipStageMembershipClass, Member: true}, models.MembershipStageMembershipClass},
+		{"legacy usher", models.User{Member: true, Usher: true}, models.MembershipStageWorker},
+		{"legacy department worker", models.User{Member: true, UserWorkDepartment: "Choir"}, models.MembershipStageWorker},
+		{"legacy member", models.User{Member: true}, models.MembershipStageMember},
+		{"legacy visitor", models.User{Visitor: true}, models.MembershipStageFirstTimeGuest},
+		{"usher who is not a member", models.User{Usher: true}, models.MembershipStageFirstTimeGuest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := membershipStageOf(&tt.user); got != tt.want {
+				t.Errorf("membershipStageOf = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := nextMembershipStage(models.MembershipStageMember); got != models.MembershipStageWorker {
+		t.Errorf("nextMembershipStage(member) = %q, want worker", got)
+	}
+	if got := nextMembershipStage(models.MembershipStageWorker); got != "" {
+		t.Errorf("nextMembershipStage(worker) = %q, want none", got)
+	}
+}
+
+func newMockMembershipService(mt *mtest.T) *MembershipService {
+	db := mockDatabase(mt)
+	return NewMembershipService(&config.Config{
+		MembershipReturningVisits: 2,
+		MembershipClassVisits:     4,
+		MembershipMemberVisits:    6,
+		MembershipWorkerVisits:    0,
+	}, db, repository.NewUserRepository(db), repository.NewAttendanceRepository(db), repository.NewMembershipTransitionRepository(db))
+}
+
+func TestTransitionStage(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	joined := time.Date(2025, 3, 2, 9, 0, 0, 0, time.UTC)
+	visitor := &models.User{
+		ID:              primitive.NewObjectID(),
+		UserID:          "CCIMRB-20417",
+		Visitor:         true,
+		MembershipStage: models.MembershipStageMembershipClass,
+		DateJoined:      joined,
+	}
+
+	mt.Run("becoming a member updates the flags and records the move", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mockFound(mt, "users", visitor),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateSuccessResponse(),
+			mockFound(mt, "attendance"),
+			mockFound(mt, "membership_transitions"),
+		)
+
+		resp, err := newMockMembershipService(mt).TransitionStage(context.Background(), visitor.UserID,
+			&dto.MembershipStageRequest{Stage: models.MembershipStageMember, Note: "Completed the class"}, "CCIMRB-00002")
+		if err != nil {
+			t.Fatalf("TransitionStage: %v", err)
+		}
+		if resp.Stage != models.MembershipStageMember {
+			t.Errorf("stage = %q, want member", resp.Stage)
+		}
+
+		var set, transition bson.Raw
+		for _, event := range mt.GetAllStartedEvents() {
+			switch event.CommandName {
+			case "update":
+				set = event.Command.Lookup("updates").Array().Index(0).Value().Document().Lookup("u", "$set").Document()
+			case "insert":
+				transition = event.Command.Lookup("documents").Array().Index(0).Value().Document()
+			}
+		}
+		if set == nil || !set.Lookup("member").Boolean() || set.Lookup("visitor").Boolean() {
+			t.Errorf("user update = %v, want member set and visitor cleared", set)
+		}
+		if transition == nil {
+			t.Fatal("no stage transition was recorded")
+		}
+		if from := transition.Lookup("from").StringValue(); from != models.MembershipStageMembershipClass {
+			t.Errorf("transition from = %q, want membership_class", from)
+		}
+		if since := transition.Lookup("from_since").Time(); !since.Equal(joined) {
+			t.Errorf("transition from_since = %v, want the join date %v", since, joined)
+		}
+	})
+
+	mt.Run("moving to the current stage is refused", func(mt *mtest.T) {
+		mt.AddMockResponses(mockFound(mt, "users", visitor))
+
+		_, err := newMockMembershipService(mt).TransitionStage(context.Background(), visitor.UserID,
+			&dto.MembershipStageRequest{Stage: models.MembershipStageMembershipClass}, "CCIMRB-00002")
+		if err == nil {
+			t.Fatal("TransitionStage accepted a move to the current stage")
+		}
+		if writes := writesTo(mt); len(writes) > 0 {
+			t.Errorf("writes = %v, want none", writes)
+		}
+	})
+
+	mt.Run("enough attendance suggests the next stage", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mockFound(mt, "users", visitor),
+			mockFound(mt, "attendance", bson.D{{Key: "n", Value: 6}}),
+			mockFound(mt, "membership_transitions"),
+		)
+
+		resp, err := newMockMembershipService(mt).GetMembership(context.Background(), visitor.UserID)
+		if err != nil {
+			t.Fatalf("GetMembership: %v", err)
+		}
+		if resp.AttendedInStage != 6 || resp.SuggestedStage != models.MembershipStageMember {
+			t.Errorf("GetMembership = %d attended, suggested %q; want 6 and member", resp.AttendedInStage, resp.SuggestedStage)
+		}
+	})
+}
+
+func TestMembershipFunnel(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("rates follow people through the stages", func(mt *mtest.T) {
+		stage := func(name string, count int) bson.D {
+			return bson.D{{Key: "_id", Value: name}, {Key: "count", Value: count}}
+		}
+		twoWeeks := float64(14 * 24 * time.Hour / time.Millisecond)
+		mt.AddMockResponses(
+			mockFound(mt, "users",
+				stage(models.MembershipStageFirstTimeGuest, 10),
+				stage(models.MembershipStageReturningVisitor, 5),
+				stage(models.MembershipStageMembershipClass, 3),
+				stage(models.MembershipStageMember, 4),
+				stage(models.MembershipStageWorker, 2),
+			),
+			mockFound(mt, "membership_transitions",
+				bson.D{{Key: "_id", Value: models.MembershipStageReturningVisitor}, {Key: "average_ms", Value: twoWeeks}, {Key: "count", Value: 9}},
+			),
+		)
+
+		resp, err := newMockMembershipService(mt).GetMembershipFunnel(context.Background())
+		if err != nil {
+			t.Fatalf("GetMembershipFunnel: %v", err)
+		}
+
+		wantReached := []int{24, 14, 9, 6, 2}
+		wantRates := []float64{58.3, 64.3, 66.7, 33.3}
+		for i, stage := range resp.Stages {
+			if stage.Reached != wantReached[i] {
+				t.Errorf("%s reached = %d, want %d", stage.Stage, stage.Reached, wantReached[i])
+			}
+			if i < len(wantRates) && (stage.ConversionRate == nil || *stage.ConversionRate != wantRates[i]) {
+				t.Errorf("%s conversion = %v, want %v", stage.Stage, stage.ConversionRate, wantRates[i])
+			}
+		}
+		if last := resp.Stages[len(resp.Stages)-1]; last.ConversionRate != nil {
+			t.Errorf("worker conversion = %v, want none", *last.ConversionRate)
+		}
+		if days := resp.Stages[1].AverageDaysInStage; days == nil || *days != 14 {
+			t.Errorf("returning visitor average days = %v, want 14", days)
+		}
+		if resp.GuestToMemberRate != 25 {
+			t.Errorf("guest to member rate = %v, want 25", resp.GuestToMemberRate)
+		}
+	})
+}
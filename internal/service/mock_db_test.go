@@ -0,0 +1,59 @@
+package service
+
+import (
+	"cci-api/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// Service tests run against mtest's mock deployment. Every command the code sends is answered by the next
+// queued reply, so a test queues one reply per query along the path it exercises, in order.
+
+func mockDatabase(mt *mtest.T) *database.Database {
+	return &database.Database{Client: mt.Client, DB: mt.DB}
+}
+
+// mockFound answers a find or aggregate on collection with docs, which may be models or bson documents
+func mockFound(mt *mtest.T, collection string, docs ...interface{}) bson.D {
+	batch := make([]bson.D, len(docs))
+	for i, doc := range docs {
+		raw, err := bson.Marshal(doc)
+		if err != nil {
+			mt.Fatalf("marshal mock document: %v", err)
+		}
+		if err := bson.Unmarshal(raw, &batch[i]); err != nil {
+			mt.Fatalf("unmarshal mock document: %v", err)
+		}
+	}
+	return mtest.CreateCursorResponse(0, mt.DB.Name()+"."+collection, mtest.FirstBatch, batch...)
+}
+
+// mockDuplicateKey answers a write with the error a unique index raises
+func mockDuplicateKey(index string) bson.D {
+	return mtest.CreateWriteErrorsResponse(mtest.WriteError{
+		Code:    11000,
+		Message: "E11000 duplicate key error index: " + index,
+	})
+}
+
+// sentCommands lists the commands the code sent, in order
+func sentCommands(mt *mtest.T) []string {
+	var names []string
+	for _, event := range mt.GetAllStartedEvents() {
+		names = append(names, event.CommandName)
+	}
+	return names
+}
+
+// writesTo lists the collections the code sent inserts or updates to, in order
+func writesTo(mt *mtest.T) []string {
+	var collections []string
+	for _, event := range mt.GetAllStartedEvents() {
+		switch event.CommandName {
+		case "insert", "update":
+			collections = append(collections, event.Command.Lookup(event.CommandName).StringValue())
+		}
+	}
+	return collections
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"cci-api/internal/config"
+	"cci-api/internal/repository"
+)
+
+// PermissionService resolves the permissions a member's role grants. Results are cached for
+// PermissionCacheTTL. The cache is held in memory by each process, so role changes clear it straight away only
+// on the server that made them; other servers pick the change up once their entries expire. Keep the TTL short
+// when running more than one server.
+type PermissionService struct {
+	cfg      *config.Config
+	userRepo *repository.UserRepository
+	roleRepo *repository.RoleRepository
+
+	mu    sync.RWMutex
+	cache map[string]cachedPermissions
+}
+
+type cachedPermissions struct {
+	permissions []string
+	expiresAt   time.Time
+}
+
+func NewPermissionService(cfg *config.Config, userRepo *repository.UserRepository, roleRepo *repository.RoleRepository) *PermissionService {
+	return &PermissionService{
+		cfg:      cfg,
+		userRepo: userRepo,
+		roleRepo: roleRepo,
+		cache:    make(map[string]cachedPermissions),
+	}
+}
+
+// UserPermissions returns the permissions granted by the user's role, none when they have no role
+func (s *PermissionService) UserPermissions(ctx context.Context, userID string) ([]string, error) {
+	now := time.Now()
+
+	s.mu.RLock()
+	cached, ok := s.cache[userID]
+	s.mu.RUnlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.permissions, nil
+	}
+
+	user, err := s.userRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	var permissions []string
+	if user != nil && user.Role != nil {
+		role, err := s.roleRepo.GetByID(ctx, *user.Role)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get role: %w", err)
+		}
+		if role != nil {
+			permissions = role.Permissions
+		}
+	}
+
+	s.mu.Lock()
+	s.cache[userID] = cachedPermissions{permissions: permissions, expiresAt: now.Add(s.cfg.PermissionCacheTTL)}
+	s.mu.Unlock()
+
+	return permissions, nil
+}
+
+// Invalidate drops the cached permissions of one user, after their role changes
+func (s *PermissionService) Invalidate(userID string) {
+	s.mu.Lock()
+	delete(s.cache, userID)
+	s.mu.Unlock()
+}
+
+// InvalidateAll drops every cached permission, after a role's permissions change
+func (s *PermissionService) InvalidateAll() {
+	s.mu.Lock()
+	s.cache = make(map[string]cachedPermissions)
+	s.mu.Unlock()
+}
@@ -4,22 +4,31 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"cci-api/internal/config"
 	"cci-api/internal/dto"
+	"cci-api/internal/models"
 	"cci-api/internal/repository"
 	"cci-api/internal/utils"
 )
 
+// maxCardsPerSheet caps how many member cards a single print request renders in memory; it matches the
+// user_ids limit on MemberCardSheetRequest
+const maxCardsPerSheet = 200
+
 type QRService struct {
-	cfg      *config.Config
-	userRepo *repository.UserRepository
+	cfg             *config.Config
+	userRepo        *repository.UserRepository
+	localChurchRepo *repository.LocalChurchRepository
 }
 
-func NewQRService(cfg *config.Config, userRepo *repository.UserRepository) *QRService {
+func NewQRService(cfg *config.Config, userRepo *repository.UserRepository, localChurchRepo *repository.LocalChurchRepository) *QRService {
 	return &QRService{
-		cfg:      cfg,
-		userRepo: userRepo,
+		cfg:             cfg,
+		userRepo:        userRepo,
+		localChurchRepo: localChurchRepo,
 	}
 }
 
@@ -33,32 +42,180 @@ func (s *QRService) GenerateQRCode(ctx context.Context, req *dto.GenerateQRReque
 		return nil, errors.New("user not found")
 	}
 
+	token, qrImage, err := s.issueStaticToken(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.QRCodeResponse{
+		QRCodeToken: token,
+		QRCodeImage: qrImage,
+	}, nil
+}
+
+// GetRotatingQRCode returns the user's current signed QR code. The code changes every
+// QR rotation interval, so clients should fetch a new one by expires_at.
+func (s *QRService) GetRotatingQRCode(ctx context.Context, userID string) (*dto.RotatingQRCodeResponse, error) {
+	// Get user
+	user, err := s.userRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	// Users get a QR secret the first time they request a rotating code
+	if user.QRSecret == "" {
+		secret, err := utils.GenerateRandomToken(32)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate QR secret: %w", err)
+		}
+		if err := s.userRepo.UpdateQRSecret(ctx, user.UserID, secret); err != nil {
+			return nil, fmt.Errorf("failed to update user QR secret: %w", err)
+		}
+		user.QRSecret = secret
+	}
+
+	interval := s.cfg.QRRotationInterval
+	counter := utils.QRCounter(time.Now(), interval)
+	token := utils.SignRotatingQRToken(user.UserID, user.QRSecret, counter)
+
+	// Generate QR code image
+	qrImage, err := utils.GenerateQRCode(token, s.cfg.QRCodeSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate QR code image: %w", err)
+	}
+
+	return &dto.RotatingQRCodeResponse{
+		QRCodeToken:     token,
+		QRCodeImage:     qrImage,
+		ExpiresAt:       time.Unix((counter+1)*int64(interval.Seconds()), 0),
+		RefreshInterval: int(interval.Seconds()),
+	}, nil
+}
+
+// GetMemberCard renders the user's printable member card as a PNG
+func (s *QRService) GetMemberCard(ctx context.Context, userID string) ([]byte, error) {
+	// Get user
+	user, err := s.userRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	church, err := s.localChurchRepo.GetFirst(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get church: %w", err)
+	}
+
+	return s.renderMemberCard(user, church)
+}
+
+// RevokeMemberCards stops every card printed for the user from scanning. Cards printed afterwards work again.
+func (s *QRService) RevokeMemberCards(ctx context.Context, userID string) error {
+	revoked, err := s.userRepo.IncrementCardVersion(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke member cards: %w", err)
+	}
+	if !revoked {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// GetMemberCardSheet renders member cards for the selected users onto printable A4 PDF pages
+func (s *QRService) GetMemberCardSheet(ctx context.Context, req *dto.MemberCardSheetRequest) ([]byte, int, error) {
+	var joinedAfter *time.Time
+	if req.JoinedAfter != "" {
+		parsed, err := time.Parse("2006-01-02", req.JoinedAfter)
+		if err != nil {
+			return nil, 0, errors.New("joined_after must be in YYYY-MM-DD format")
+		}
+		joinedAfter = &parsed
+	}
+
+	// Fetch one extra to tell a selection that is too large from one that fills the sheet exactly
+	users, err := s.userRepo.FindForCards(ctx, req.UserIDs, req.Field, req.Value, joinedAfter, maxCardsPerSheet+1)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get users: %w", err)
+	}
+	if len(users) == 0 {
+		return nil, 0, errors.New("no users match the selection")
+	}
+	if len(users) > maxCardsPerSheet {
+		return nil, 0, fmt.Errorf("more than %d users match the selection, please narrow it down", maxCardsPerSheet)
+	}
+
+	church, err := s.localChurchRepo.GetFirst(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get church: %w", err)
+	}
+
+	cards := make([][]byte, 0, len(users))
+	for _, user := range users {
+		card, err := s.renderMemberCard(user, church)
+		if err != nil {
+			return nil, 0, err
+		}
+		cards = append(cards, card)
+	}
+
+	sheet, err := utils.RenderCardSheetPDF(cards)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return sheet, len(cards), nil
+}
+
+// renderMemberCard draws a user's card. Printed cards carry a signed card token rather than the legacy static
+// token, so they keep scanning when static tokens are turned off and can be revoked with RevokeMemberCards.
+func (s *QRService) renderMemberCard(user *models.User, church *models.LocalChurch) ([]byte, error) {
+	card := utils.MemberCard{
+		FullName: strings.TrimSpace(user.FirstName + " " + user.LastName),
+		UserID:   user.UserID,
+		Campus:   user.UserCampus,
+		QRData:   utils.SignMemberCardToken(user.UserID, s.cfg.QRCardSecret, user.CardVersion),
+	}
+	if church != nil {
+		card.ChurchName = church.ChurchName
+	}
+
+	png, err := utils.RenderMemberCardPNG(card)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render member card: %w", err)
+	}
+	return png, nil
+}
+
+// issueStaticToken gives the user a new static QR token and stores its QR image
+func (s *QRService) issueStaticToken(ctx context.Context, user *models.User) (string, string, error) {
 	// Generate QR code token
 	token, err := utils.GenerateRandomToken(32)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate QR token: %w", err)
+		return "", "", fmt.Errorf("failed to generate QR token: %w", err)
 	}
 
 	// Update user with QR token
 	err = s.userRepo.UpdateQRToken(ctx, user.UserID, token)
 	if err != nil {
-		return nil, fmt.Errorf("failed to update user QR token: %w", err)
+		return "", "", fmt.Errorf("failed to update user QR token: %w", err)
 	}
 
 	// Generate QR code image
 	qrImage, err := utils.GenerateQRCode(token, s.cfg.QRCodeSize)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate QR code image: %w", err)
+		return "", "", fmt.Errorf("failed to generate QR code image: %w", err)
 	}
 
 	// Update user with QR code image string
 	err = s.userRepo.UpdateQRCodeImage(ctx, user.UserID, qrImage)
 	if err != nil {
-		return nil, fmt.Errorf("failed to update user QR code image: %w", err)
+		return "", "", fmt.Errorf("failed to update user QR code image: %w", err)
 	}
 
-	return &dto.QRCodeResponse{
-		QRCodeToken: token,
-		QRCodeImage: qrImage,
-	}, nil
+	return token, qrImage, nil
 }
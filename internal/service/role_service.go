@@ -15,19 +15,30 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// ErrUnknownPermission is returned when a role is given a permission that is not in the catalogue
+var ErrUnknownPermission = errors.New("unknown permission")
+
+// ErrPermissionNotHeld is returned when someone other than an admin creates, edits or hands out a role granting a
+// permission they do not have themselves
+var ErrPermissionNotHeld = newForbiddenError("you cannot grant a permission you do not have")
+
 type RoleService struct {
-	config   *config.Config
-	roleRepo repository.RoleRepository
+	config            *config.Config
+	roleRepo          repository.RoleRepository
+	userRepo          *repository.UserRepository
+	permissionService *PermissionService
 }
 
-func NewRoleService(cfg *config.Config, roleRepo *repository.RoleRepository) *RoleService {
+func NewRoleService(cfg *config.Config, roleRepo *repository.RoleRepository, userRepo *repository.UserRepository, permissionService *PermissionService) *RoleService {
 	return &RoleService{
-		config:   cfg,
-		roleRepo: *roleRepo,
+		config:            cfg,
+		roleRepo:          *roleRepo,
+		userRepo:          userRepo,
+		permissionService: permissionService,
 	}
 }
 
-func (s *RoleService) CreateRole(ctx context.Context, req *dto.CreateRoleRequest) (*dto.RoleResponse, error) {
+func (s *RoleService) CreateRole(ctx context.Context, req *dto.CreateRoleRequest, callerID string, isAdmin bool) (*dto.RoleResponse, error) {
 	// Validate request
 	if req.RoleName == "" {
 		return nil, errors.New("role name is required")
@@ -38,16 +49,24 @@ func (s *RoleService) CreateRole(ctx context.Context, req *dto.CreateRoleRequest
 		return nil, errors.New("role with this name already exists")
 	}
 
+	permissions, err := validPermissions(req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkGrantable(ctx, callerID, isAdmin, permissions); err != nil {
+		return nil, err
+	}
+
 	// Create role
 	role := &models.Role{
 		RoleName:        req.RoleName,
 		RoleDescription: req.RoleDescription,
-		Permissions:     req.Permissions,
+		Permissions:     permissions,
 		DateAdded:       time.Now(),
 		DateUpdated:     time.Now(),
 	}
 
-	err := s.roleRepo.Create(ctx, role)
+	err = s.roleRepo.Create(ctx, role)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create role: %w", err)
 	}
@@ -105,6 +124,9 @@ func (s *RoleService) GetRoleByID(ctx context.Context, id string) (*dto.RoleResp
 	if err != nil {
 		return nil, fmt.Errorf("failed to get role: %w", err)
 	}
+	if role == nil {
+		return nil, errors.New("role not found")
+	}
 
 	return &dto.RoleResponse{
 		ID:              role.ID.Hex(),
@@ -117,7 +139,7 @@ func (s *RoleService) GetRoleByID(ctx context.Context, id string) (*dto.RoleResp
 	}, nil
 }
 
-func (s *RoleService) UpdateRole(ctx context.Context, id string, req *dto.UpdateRoleRequest) (*dto.RoleResponse, error) {
+func (s *RoleService) UpdateRole(ctx context.Context, id string, req *dto.UpdateRoleRequest, callerID string, isAdmin bool) (*dto.RoleResponse, error) {
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, errors.New("invalid role ID")
@@ -127,6 +149,13 @@ func (s *RoleService) UpdateRole(ctx context.Context, id string, req *dto.Update
 	if err != nil {
 		return nil, fmt.Errorf("failed to get role: %w", err)
 	}
+	if role == nil {
+		return nil, errors.New("role not found")
+	}
+	// A role that already grants more than the caller holds is out of their reach altogether
+	if err := s.checkGrantable(ctx, callerID, isAdmin, role.Permissions); err != nil {
+		return nil, err
+	}
 
 	// Update fields
 	if req.RoleName != "" {
@@ -141,9 +170,16 @@ func (s *RoleService) UpdateRole(ctx context.Context, id string, req *dto.Update
 		role.RoleDescription = req.RoleDescription
 	}
 
-	// if req.Permissions != "" {
-	// 	role.Permissions = req.Permissions
-	// }
+	if req.Permissions != nil {
+		permissions, err := validPermissions(req.Permissions)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.checkGrantable(ctx, callerID, isAdmin, permissions); err != nil {
+			return nil, err
+		}
+		role.Permissions = permissions
+	}
 
 	role.DateUpdated = time.Now()
 
@@ -151,6 +187,7 @@ func (s *RoleService) UpdateRole(ctx context.Context, id string, req *dto.Update
 	if err != nil {
 		return nil, fmt.Errorf("failed to update role: %w", err)
 	}
+	s.permissionService.InvalidateAll()
 
 	return &dto.RoleResponse{
 		ID:              role.ID.Hex(),
@@ -169,8 +206,143 @@ func (s *RoleService) DeleteRole(ctx context.Context, id string) error {
 		return errors.New("invalid role ID")
 	}
 
+	members, err := s.userRepo.CountByRole(ctx, objID)
+	if err != nil {
+		return fmt.Errorf("failed to count role members: %w", err)
+	}
+	if members > 0 {
+		return fmt.Errorf("role is assigned to %d member(s), reassign them before deleting it", members)
+	}
+
 	if err := s.roleRepo.Delete(ctx, objID); err != nil {
 		return fmt.Errorf("failed to delete role: %w", err)
 	}
+	s.permissionService.InvalidateAll()
+	return nil
+}
+
+// GetRolesAndPermissions returns every role together with the catalogue of permissions a role can grant
+func (s *RoleService) GetRolesAndPermissions(ctx context.Context) (*dto.RolesAndPermissionsResponse, error) {
+	roles, err := s.roleRepo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get roles: %w", err)
+	}
+
+	resp := &dto.RolesAndPermissionsResponse{
+		Roles:                make([]dto.RoleResponse, len(roles)),
+		AvailablePermissions: make([]dto.PermissionResponse, len(models.PermissionCatalogue)),
+	}
+	for i, role := range roles {
+		resp.Roles[i] = dto.RoleResponse{
+			ID:              role.ID.Hex(),
+			RoleName:        role.RoleName,
+			RoleDescription: role.RoleDescription,
+			Permissions:     role.Permissions,
+			TotalMembers:    role.TotalMembers,
+			DateAdded:       role.DateAdded,
+			DateUpdated:     role.DateUpdated,
+		}
+	}
+	for i, permission := range models.PermissionCatalogue {
+		resp.AvailablePermissions[i] = dto.PermissionResponse{
+			Name:        permission.Name,
+			Description: permission.Description,
+		}
+	}
+
+	return resp, nil
+}
+
+// AssignRole gives a member a role, or removes their role when roleID is empty. The member counts of the
+// old and new roles are refreshed and the member's cached permissions dropped. Unless the caller is an admin,
+// neither the old nor the new role may grant a permission the caller lacks.
+func (s *RoleService) AssignRole(ctx context.Context, userID, roleID, callerID string, isAdmin bool) error {
+	user, err := s.userRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return errors.New("user not found")
+	}
+
+	var newRole *primitive.ObjectID
+	if roleID != "" {
+		objID, err := primitive.ObjectIDFromHex(roleID)
+		if err != nil {
+			return errors.New("invalid role ID")
+		}
+		role, err := s.roleRepo.GetByID(ctx, objID)
+		if err != nil {
+			return fmt.Errorf("failed to get role: %w", err)
+		}
+		if role == nil {
+			return errors.New("role not found")
+		}
+		if err := s.checkGrantable(ctx, callerID, isAdmin, role.Permissions); err != nil {
+			return err
+		}
+		newRole = &role.ID
+	}
+
+	if user.Role != nil {
+		current, err := s.roleRepo.GetByID(ctx, *user.Role)
+		if err != nil {
+			return fmt.Errorf("failed to get role: %w", err)
+		}
+		if current != nil {
+			if err := s.checkGrantable(ctx, callerID, isAdmin, current.Permissions); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := s.userRepo.UpdateRole(ctx, userID, newRole); err != nil {
+		return fmt.Errorf("failed to assign role: %w", err)
+	}
+	s.permissionService.Invalidate(userID)
+
+	for _, affected := range []*primitive.ObjectID{user.Role, newRole} {
+		if affected == nil {
+			continue
+		}
+		count, err := s.userRepo.CountByRole(ctx, *affected)
+		if err != nil {
+			return fmt.Errorf("failed to count role members: %w", err)
+		}
+		if err := s.roleRepo.UpdateMemberCount(ctx, *affected, count); err != nil {
+			return fmt.Errorf("failed to update role member count: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkGrantable returns ErrPermissionNotHeld unless the caller is an admin or holds every one of the permissions
+func (s *RoleService) checkGrantable(ctx context.Context, callerID string, isAdmin bool, permissions []string) error {
+	if isAdmin {
+		return nil
+	}
+	held, err := s.permissionService.UserPermissions(ctx, callerID)
+	if err != nil {
+		return err
+	}
+	for _, permission := range permissions {
+		if !containsString(held, permission) {
+			return fmt.Errorf("%w: %s", ErrPermissionNotHeld, permission)
+		}
+	}
 	return nil
 }
+
+// validPermissions checks every permission is in the catalogue and drops duplicates
+func validPermissions(permissions []string) ([]string, error) {
+	valid := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		if !models.IsValidPermission(permission) {
+			return nil, fmt.Errorf("%w %q", ErrUnknownPermission, permission)
+		}
+		if !containsString(valid, permission) {
+			valid = append(valid, permission)
+		}
+	}
+	return valid, nil
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cci-api/internal/config"
+	"cci-api/internal/dto"
+	"cci-api/internal/models"
+	"cci-api/internal/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func newMockRoleService(mt *mtest.T) *RoleService {
+	db := mockDatabase(mt)
+	cfg := &config.Config{PermissionCacheTTL: time.Minute}
+	roleRepo := repository.NewRoleRepository(db)
+	userRepo := repository.NewUserRepository(db)
+	return NewRoleService(cfg, roleRepo, userRepo, NewPermissionService(cfg, userRepo, roleRepo))
+}
+
+func TestRoleGrants(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	coordinator := &models.Role{
+		ID:          primitive.NewObjectID(),
+		RoleName:    "Attendance coordinator",
+		Permissions: []string{models.PermissionRolesManage, models.PermissionAttendanceRead, models.PermissionAttendanceWrite},
+	}
+	pastor := &models.Role{
+		ID:          primitive.NewObjectID(),
+		RoleName:    "Pastor",
+		Permissions: []string{models.PermissionUsersManage, models.PermissionAttendanceRead},
+	}
+	caller := &models.User{ID: primitive.NewObjectID(), UserID: "CCIMRB-30001", Role: &coordinator.ID}
+
+	mt.Run("coordinator creates a role within their own permissions", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mockFound(mt, "roles"),
+			mockFound(mt, "users", caller),
+			mockFound(mt, "roles", coordinator),
+			mtest.CreateSuccessResponse(),
+		)
+
+		req := &dto.CreateRoleRequest{RoleName: "Usher", Permissions: []string{models.PermissionAttendanceWrite}}
+		if _, err := newMockRoleService(mt).CreateRole(context.Background(), req, caller.UserID, false); err != nil {
+			t.Fatalf("CreateRole: %v", err)
+		}
+	})
+
+	mt.Run("coordinator cannot create a role with a permission they lack", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mockFound(mt, "roles"),
+			mockFound(mt, "users", caller),
+			mockFound(mt, "roles", coordinator),
+		)
+
+		req := &dto.CreateRoleRequest{RoleName: "Usher", Permissions: []string{models.PermissionAttendanceWrite, models.PermissionUsersManage}}
+		_, err := newMockRoleService(mt).CreateRole(context.Background(), req, caller.UserID, false)
+		if !errors.Is(err, ErrPermissionNotHeld) {
+			t.Fatalf("err = %v, want ErrPermissionNotHeld", err)
+		}
+		if writes := writesTo(mt); len(writes) != 0 {
+			t.Errorf("refused role was written to %v", writes)
+		}
+	})
+
+	mt.Run("admin creates any role without a permission lookup", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mockFound(mt, "roles"),
+			mtest.CreateSuccessResponse(),
+		)
+
+		req := &dto.CreateRoleRequest{RoleName: "Pastor", Permissions: []string{models.PermissionUsersManage}}
+		if _, err := newMockRoleService(mt).CreateRole(context.Background(), req, "CCIMRB-00001", true); err != nil {
+			t.Fatalf("CreateRole: %v", err)
+		}
+		if cmds := sentCommands(mt); len(cmds) != 2 {
+			t.Errorf("commands = %v, want the name check and the insert", cmds)
+		}
+	})
+
+	mt.Run("coordinator cannot take a more powerful role away", func(mt *mtest.T) {
+		member := &models.User{ID: primitive.NewObjectID(), UserID: "CCIMRB-30002", Role: &pastor.ID}
+		mt.AddMockResponses(
+			mockFound(mt, "users", member),
+			mockFound(mt, "roles", pastor),
+			mockFound(mt, "users", caller),
+			mockFound(mt, "roles", coordinator),
+		)
+
+		err := newMockRoleService(mt).AssignRole(context.Background(), member.UserID, "", caller.UserID, false)
+		if !errors.Is(err, ErrPermissionNotHeld) {
+			t.Fatalf("err = %v, want ErrPermissionNotHeld", err)
+		}
+		if writes := writesTo(mt); len(writes) != 0 {
+			t.Errorf("role change was written to %v", writes)
+		}
+	})
+
+	mt.Run("coordinator cannot widen a role they manage", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mockFound(mt, "roles", coordinator),
+			mockFound(mt, "users", caller),
+			mockFound(mt, "roles", coordinator),
+		)
+
+		req := &dto.UpdateRoleRequest{Permissions: []string{models.PermissionAttendanceRead, models.PermissionAttendanceCorrect}}
+		_, err := newMockRoleService(mt).UpdateRole(context.Background(), coordinator.ID.Hex(), req, caller.UserID, false)
+		if !errors.Is(err, ErrPermissionNotHeld) {
+			t.Fatalf("err = %v, want ErrPermissionNotHeld", err)
+		}
+	})
+}
+
+func TestPermissionCache(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	role := &models.Role{ID: primitive.NewObjectID(), Permissions: []string{models.PermissionAttendanceRead}}
+	user := &models.User{ID: primitive.NewObjectID(), UserID: "CCIMRB-30003", Role: &role.ID}
+
+	mt.Run("permissions are read once until invalidated", func(mt *mtest.T) {
+		s := newMockRoleService(mt).permissionService
+		mt.AddMockResponses(
+			mockFound(mt, "users", user),
+			mockFound(mt, "roles", role),
+			mockFound(mt, "users", user),
+			mockFound(mt, "roles", role),
+		)
+
+		for i := 0; i < 3; i++ {
+			permissions, err := s.UserPermissions(context.Background(), user.UserID)
+			if err != nil {
+				t.Fatalf("UserPermissions: %v", err)
+			}
+			if len(permissions) != 1 || permissions[0] != models.PermissionAttendanceRead {
+				t.Fatalf("permissions = %v", permissions)
+			}
+		}
+		if cmds := sentCommands(mt); len(cmds) != 2 {
+			t.Errorf("sent %d commands for three reads, want 2", len(cmds))
+		}
+
+		s.Invalidate(user.UserID)
+		if _, err := s.UserPermissions(context.Background(), user.UserID); err != nil {
+			t.Fatalf("UserPermissions: %v", err)
+		}
+		if cmds := sentCommands(mt); len(cmds) != 4 {
+			t.Errorf("sent %d commands after invalidating, want 4", len(cmds))
+		}
+	})
+}
@@ -15,19 +15,28 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+var (
+	// ErrSermonNotFound is returned when a sermon does not exist
+	ErrSermonNotFound = newNotFoundError("sermon not found")
+	// ErrNotSermonAuthor is returned when someone other than an admin or the sermon's author changes it
+	ErrNotSermonAuthor = newForbiddenError("only the author can change this sermon")
+)
+
 type SermonService struct {
 	config     *config.Config
 	sermonRepo repository.SermonRepository
+	userRepo   *repository.UserRepository
 }
 
-func NewSermonService(cfg *config.Config, sermonRepo *repository.SermonRepository) *SermonService {
+func NewSermonService(cfg *config.Config, sermonRepo *repository.SermonRepository, userRepo *repository.UserRepository) *SermonService {
 	return &SermonService{
 		config:     cfg,
 		sermonRepo: *sermonRepo,
+		userRepo:   userRepo,
 	}
 }
 
-func (s *SermonService) CreateSermon(ctx context.Context, req *dto.CreateSermonRequest, userID primitive.ObjectID) (*dto.SermonResponse, error) {
+func (s *SermonService) CreateSermon(ctx context.Context, req *dto.CreateSermonRequest, userID string) (*dto.SermonResponse, error) {
 	// Validate request
 	if req.Title == "" {
 		return nil, errors.New("sermon title is required")
@@ -40,6 +49,11 @@ func (s *SermonService) CreateSermon(ctx context.Context, req *dto.CreateSermonR
 		return nil, errors.New("sermon note is required")
 	}
 
+	author, err := resolveAuthor(ctx, s.userRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+
 	// date, err := time.Parse("2006-01-02", req.Date)
 	// if err != nil {
 	// 	return nil, fmt.Errorf("invalid date format: *w", err)
@@ -50,7 +64,7 @@ func (s *SermonService) CreateSermon(ctx context.Context, req *dto.CreateSermonR
 		DateOfMeeting: req.Date,
 		SermonTopic:   req.Title,
 		SermonNote:    req.Notes,
-		EntryMadeBy:   userID,
+		EntryMadeBy:   author,
 		VideoUrl:      req.VideoURL,
 		AudioUrl:      req.AudioURL,
 		Scripture:     req.Scripture,
@@ -58,7 +72,7 @@ func (s *SermonService) CreateSermon(ctx context.Context, req *dto.CreateSermonR
 		Tags:          req.Tags,
 	}
 
-	err := s.sermonRepo.Create(ctx, sermon)
+	err = s.sermonRepo.Create(ctx, sermon)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create sermon: %w", err)
 	}
@@ -122,7 +136,10 @@ func (s *SermonService) GetSermonByID(ctx context.Context, id string) (*dto.Serm
 
 	sermon, err := s.sermonRepo.GetByID(ctx, objID)
 	if err != nil {
-		return nil, fmt.Errorf("sermon with This ID does not exist: %w", err)
+		return nil, fmt.Errorf("failed to get sermon: %w", err)
+	}
+	if sermon == nil {
+		return nil, ErrSermonNotFound
 	}
 
 	return &dto.SermonResponse{
@@ -140,15 +157,11 @@ func (s *SermonService) GetSermonByID(ctx context.Context, id string) (*dto.Serm
 	}, nil
 }
 
-func (s *SermonService) UpdateSermon(ctx context.Context, id string, req *dto.UpdateSermonRequest) (*dto.SermonResponse, error) {
-	objID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return nil, errors.New("invalid sermon ID")
-	}
-
-	sermon, err := s.sermonRepo.GetByID(ctx, objID)
+// UpdateSermon changes a sermon; only admins and the sermon's author may
+func (s *SermonService) UpdateSermon(ctx context.Context, id string, req *dto.UpdateSermonRequest, userID string, isAdmin bool) (*dto.SermonResponse, error) {
+	sermon, err := s.getAuthoredSermon(ctx, id, userID, isAdmin)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get sermon: %w", err)
+		return nil, err
 	}
 
 	// Update fields
@@ -185,14 +198,55 @@ func (s *SermonService) UpdateSermon(ctx context.Context, id string, req *dto.Up
 	}, nil
 }
 
-func (s *SermonService) DeleteSermon(ctx context.Context, id string) error {
-	objID, err := primitive.ObjectIDFromHex(id)
+// DeleteSermon removes a sermon; only admins and the sermon's author may
+func (s *SermonService) DeleteSermon(ctx context.Context, id, userID string, isAdmin bool) error {
+	sermon, err := s.getAuthoredSermon(ctx, id, userID, isAdmin)
 	if err != nil {
-		return errors.New("invalid sermon ID")
+		return err
 	}
 
-	if err := s.sermonRepo.Delete(ctx, objID); err != nil {
+	if err := s.sermonRepo.Delete(ctx, sermon.ID); err != nil {
 		return fmt.Errorf("failed to delete sermon: %w", err)
 	}
 	return nil
 }
+
+// getAuthoredSermon loads a sermon, allowing only admins and its author
+func (s *SermonService) getAuthoredSermon(ctx context.Context, id, userID string, isAdmin bool) (*models.Sermon, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, newValidationError("invalid sermon ID")
+	}
+
+	sermon, err := s.sermonRepo.GetByID(ctx, objID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sermon: %w", err)
+	}
+	if sermon == nil {
+		return nil, ErrSermonNotFound
+	}
+	if isAdmin {
+		return sermon, nil
+	}
+
+	author, err := resolveAuthor(ctx, s.userRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+	if sermon.EntryMadeBy != author {
+		return nil, ErrNotSermonAuthor
+	}
+	return sermon, nil
+}
+
+// resolveAuthor returns the database ID of the user recorded as the author of a sermon or announcement
+func resolveAuthor(ctx context.Context, userRepo *repository.UserRepository, userID string) (primitive.ObjectID, error) {
+	user, err := userRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return primitive.NilObjectID, errors.New("user not found")
+	}
+	return user.ID, nil
+}
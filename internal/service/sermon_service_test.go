@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"cci-api/internal/config"
+	"cci-api/internal/dto"
+	"cci-api/internal/models"
+	"cci-api/internal/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestSermonAuthorChecks(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	author := &models.User{ID: primitive.NewObjectID(), UserID: "CCIMRB-40001"}
+	other := &models.User{ID: primitive.NewObjectID(), UserID: "CCIMRB-40002"}
+	sermon := &models.Sermon{ID: primitive.NewObjectID(), SermonTopic: "Walking in faith", Preacher: "Pastor Tunde", EntryMadeBy: author.ID}
+	update := &dto.UpdateSermonRequest{Title: "Walking by faith"}
+
+	newService := func(mt *mtest.T) *SermonService {
+		db := mockDatabase(mt)
+		return NewSermonService(&config.Config{}, repository.NewSermonRepository(db), repository.NewUserRepository(db))
+	}
+
+	mt.Run("malformed ID is invalid input", func(mt *mtest.T) {
+		_, err := newService(mt).UpdateSermon(context.Background(), "not-an-id", update, author.UserID, false)
+		if !errors.Is(err, ErrInvalidInput) {
+			t.Fatalf("err = %v, want ErrInvalidInput", err)
+		}
+		if cmds := sentCommands(mt); len(cmds) != 0 {
+			t.Errorf("commands = %v, want none", cmds)
+		}
+	})
+
+	mt.Run("missing sermon", func(mt *mtest.T) {
+		mt.AddMockResponses(mockFound(mt, "sermons"))
+
+		err := newService(mt).DeleteSermon(context.Background(), primitive.NewObjectID().Hex(), author.UserID, false)
+		if !errors.Is(err, ErrSermonNotFound) || !errors.Is(err, ErrNotFound) {
+			t.Fatalf("err = %v, want ErrSermonNotFound", err)
+		}
+	})
+
+	mt.Run("another member cannot edit it", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mockFound(mt, "sermons", sermon),
+			mockFound(mt, "users", other),
+		)
+
+		_, err := newService(mt).UpdateSermon(context.Background(), sermon.ID.Hex(), update, other.UserID, false)
+		if !errors.Is(err, ErrNotSermonAuthor) {
+			t.Fatalf("err = %v, want ErrNotSermonAuthor", err)
+		}
+		if writes := writesTo(mt); len(writes) != 0 {
+			t.Errorf("sermon was written by someone else: %v", writes)
+		}
+	})
+
+	mt.Run("author edits it", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mockFound(mt, "sermons", sermon),
+			mockFound(mt, "users", author),
+			mtest.CreateSuccessResponse(),
+		)
+
+		resp, err := newService(mt).UpdateSermon(context.Background(), sermon.ID.Hex(), update, author.UserID, false)
+		if err != nil {
+			t.Fatalf("UpdateSermon: %v", err)
+		}
+		if resp.Title != "Walking by faith" {
+			t.Errorf("title = %q, want the update applied", resp.Title)
+		}
+	})
+
+	mt.Run("admin edits it without an author lookup", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mockFound(mt, "sermons", sermon),
+			mtest.CreateSuccessResponse(),
+		)
+
+		if _, err := newService(mt).UpdateSermon(context.Background(), sermon.ID.Hex(), update, "CCIMRB-00001", true); err != nil {
+			t.Fatalf("UpdateSermon: %v", err)
+		}
+	})
+}
@@ -0,0 +1,459 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cci-api/internal/config"
+	"cci-api/internal/dto"
+	"cci-api/internal/models"
+	"cci-api/internal/repository"
+	"cci-api/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrServiceEventNotFound = newNotFoundError("service event not found")
+	ErrChurchNotFound       = newNotFoundError("church not found")
+	// ErrServiceEventInUse is returned when deleting an event that attendance is recorded against
+	ErrServiceEventInUse = errors.New("service event has attendance recorded against it and cannot be deleted")
+)
+
+type ServiceEventService struct {
+	cfg              *config.Config
+	serviceEventRepo *repository.ServiceEventRepository
+	localChurchRepo  *repository.LocalChurchRepository
+	venueCodeRepo    *repository.VenueCodeRepository
+	attendanceRepo   *repository.AttendanceRepository
+	childCheckinRepo *repository.ChildCheckinRepository
+}
+
+func NewServiceEventService(cfg *config.Config, serviceEventRepo *repository.ServiceEventRepository, localChurchRepo *repository.LocalChurchRepository, venueCodeRepo *repository.VenueCodeRepository, attendanceRepo *repository.AttendanceRepository, childCheckinRepo *repository.ChildCheckinRepository) *ServiceEventService {
+	return &ServiceEventService{
+		cfg:              cfg,
+		serviceEventRepo: serviceEventRepo,
+		localChurchRepo:  localChurchRepo,
+		venueCodeRepo:    venueCodeRepo,
+		attendanceRepo:   attendanceRepo,
+		childCheckinRepo: childCheckinRepo,
+	}
+}
+
+func (s *ServiceEventService) CreateEvent(ctx context.Context, req *dto.CreateServiceEventRequest, createdBy string) (*dto.ServiceEventResponse, error) {
+	startTime, err := time.Parse(time.RFC3339, req.StartTime)
+	if err != nil {
+		return nil, errors.New("start_time must be in RFC3339 format")
+	}
+	endTime, err := time.Parse(time.RFC3339, req.EndTime)
+	if err != nil {
+		return nil, errors.New("end_time must be in RFC3339 format")
+	}
+	if !endTime.After(startTime) {
+		return nil, errors.New("end_time must be after start_time")
+	}
+
+	church, err := s.resolveChurch(ctx, req.ChurchID)
+	if err != nil {
+		return nil, err
+	}
+
+	event := &models.ServiceEvent{
+		Name:        req.Name,
+		EventType:   req.EventType,
+		Church:      church,
+		StartTime:   startTime,
+		EndTime:     endTime,
+		Description: req.Description,
+		CreatedBy:   createdBy,
+	}
+
+	if err := s.serviceEventRepo.Create(ctx, event); err != nil {
+		return nil, fmt.Errorf("failed to create service event: %w", err)
+	}
+
+	return s.toResponse(event), nil
+}
+
+func (s *ServiceEventService) GetEvents(ctx context.Context, page, limit int, eventType string, startDate, endDate *time.Time) (*dto.PaginatedServiceEventsResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	var ranges []repository.EventDateRange
+	if startDate != nil || endDate != nil {
+		var err error
+		ranges, err = s.dateRanges(ctx, startDate, endDate)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	events, total, err := s.serviceEventRepo.GetAll(ctx, page, limit, eventType, ranges)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service events: %w", err)
+	}
+
+	eventResponses := make([]*dto.ServiceEventResponse, len(events))
+	for i, event := range events {
+		eventResponses[i] = s.toResponse(event)
+	}
+
+	return &dto.PaginatedServiceEventsResponse{
+		Data:       eventResponses,
+		Pagination: utils.NewPagination(page, limit, total),
+	}, nil
+}
+
+// dateRanges turns the date filters into whole calendar days in each church's own timezone. Either date may be
+// nil to leave that side open. Events without a church are read in the first church's timezone.
+func (s *ServiceEventService) dateRanges(ctx context.Context, startDate, endDate *time.Time) ([]repository.EventDateRange, error) {
+	churches, err := s.localChurchRepo.ListAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get churches: %w", err)
+	}
+
+	bounds := func(scope repository.EventScope, timezone string) repository.EventDateRange {
+		loc := utils.LoadLocation(timezone)
+		dateRange := repository.EventDateRange{Scope: scope}
+		if startDate != nil {
+			rangeStart, _ := utils.DayBounds(*startDate, loc)
+			dateRange.Start = &rangeStart
+		}
+		if endDate != nil {
+			_, rangeEnd := utils.DayBounds(*endDate, loc)
+			dateRange.End = &rangeEnd
+		}
+		return dateRange
+	}
+
+	if len(churches) == 0 {
+		return []repository.EventDateRange{bounds(repository.EventScope{}, s.cfg.Timezone)}, nil
+	}
+
+	defaultChurch, err := s.localChurchRepo.GetFirst(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get church: %w", err)
+	}
+
+	ranges := make([]repository.EventDateRange, 0, len(churches))
+	for _, church := range churches {
+		churchID := church.ID
+		timezone := s.cfg.Timezone
+		if church.Timezone != "" {
+			timezone = church.Timezone
+		}
+		ranges = append(ranges, bounds(repository.EventScope{
+			Church:            &churchID,
+			IncludeUnassigned: defaultChurch != nil && defaultChurch.ID == churchID,
+		}, timezone))
+	}
+	return ranges, nil
+}
+
+func (s *ServiceEventService) GetEventByID(ctx context.Context, id string) (*dto.ServiceEventResponse, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid service event ID")
+	}
+
+	event, err := s.serviceEventRepo.GetByID(ctx, objID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service event: %w", err)
+	}
+	if event == nil {
+		return nil, ErrServiceEventNotFound
+	}
+
+	return s.toResponse(event), nil
+}
+
+// GetCurrentEvent returns the event that check-ins at a church would currently be recorded against, at the
+// first church when churchID is empty
+func (s *ServiceEventService) GetCurrentEvent(ctx context.Context, churchID string) (*dto.ServiceEventResponse, error) {
+	church, err := checkinChurch(ctx, s.localChurchRepo, churchID)
+	if err != nil {
+		return nil, err
+	}
+	event, err := openEventAt(ctx, s.localChurchRepo, s.serviceEventRepo, church, time.Now(), s.cfg.EventCheckinEarlyWindow)
+	if err != nil {
+		return nil, err
+	}
+	if event == nil {
+		return nil, errors.New("no service event is currently open for check-in")
+	}
+
+	return s.toResponse(event), nil
+}
+
+func (s *ServiceEventService) UpdateEvent(ctx context.Context, id string, req *dto.UpdateServiceEventRequest) (*dto.ServiceEventResponse, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid service event ID")
+	}
+
+	event, err := s.serviceEventRepo.GetByID(ctx, objID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service event: %w", err)
+	}
+	if event == nil {
+		return nil, ErrServiceEventNotFound
+	}
+
+	// Update fields
+	if req.Name != "" {
+		event.Name = req.Name
+	}
+	if req.EventType != "" {
+		event.EventType = req.EventType
+	}
+	if req.ChurchID != "" {
+		church, err := s.resolveChurch(ctx, req.ChurchID)
+		if err != nil {
+			return nil, err
+		}
+		event.Church = church
+	}
+	if req.StartTime != "" {
+		startTime, err := time.Parse(time.RFC3339, req.StartTime)
+		if err != nil {
+			return nil, errors.New("start_time must be in RFC3339 format")
+		}
+		event.StartTime = startTime
+	}
+	if req.EndTime != "" {
+		endTime, err := time.Parse(time.RFC3339, req.EndTime)
+		if err != nil {
+			return nil, errors.New("end_time must be in RFC3339 format")
+		}
+		event.EndTime = endTime
+	}
+	if req.Description != "" {
+		event.Description = req.Description
+	}
+	if !event.EndTime.After(event.StartTime) {
+		return nil, errors.New("end_time must be after start_time")
+	}
+
+	if err := s.serviceEventRepo.Update(ctx, event); err != nil {
+		return nil, fmt.Errorf("failed to update service event: %w", err)
+	}
+
+	// Venue codes close with the event
+	if err := s.venueCodeRepo.UpdateExpiryForEvent(ctx, event.ID, event.EndTime); err != nil {
+		return nil, fmt.Errorf("failed to update venue codes: %w", err)
+	}
+
+	return s.toResponse(event), nil
+}
+
+// DeleteEvent removes an event nobody has checked in to yet. Events with attendance or children's check-ins
+// are kept so history and analytics can still name them.
+func (s *ServiceEventService) DeleteEvent(ctx context.Context, id string) error {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.New("invalid service event ID")
+	}
+
+	hasAttendance, err := s.attendanceRepo.HasForEvent(ctx, objID)
+	if err != nil {
+		return fmt.Errorf("failed to check attendance: %w", err)
+	}
+	hasChildCheckins, err := s.childCheckinRepo.HasForEvent(ctx, objID)
+	if err != nil {
+		return fmt.Errorf("failed to check children's check-ins: %w", err)
+	}
+	if hasAttendance || hasChildCheckins {
+		return ErrServiceEventInUse
+	}
+
+	deleted, err := s.serviceEventRepo.Delete(ctx, objID)
+	if err != nil {
+		return fmt.Errorf("failed to delete service event: %w", err)
+	}
+	if !deleted {
+		return ErrServiceEventNotFound
+	}
+	if err := s.venueCodeRepo.DeleteByEvent(ctx, objID); err != nil {
+		return fmt.Errorf("failed to delete venue codes: %w", err)
+	}
+	return nil
+}
+
+// CreateVenueCode generates a fresh self check-in code for a venue, replacing that venue's previous code
+// for the event. The code expires when the event ends.
+func (s *ServiceEventService) CreateVenueCode(ctx context.Context, eventID string, req *dto.CreateVenueCodeRequest, createdBy string) (*dto.VenueCodeResponse, error) {
+	objID, err := primitive.ObjectIDFromHex(eventID)
+	if err != nil {
+		return nil, errors.New("invalid service event ID")
+	}
+
+	event, err := s.serviceEventRepo.GetByID(ctx, objID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service event: %w", err)
+	}
+	if event == nil {
+		return nil, ErrServiceEventNotFound
+	}
+	if time.Now().After(event.EndTime) {
+		return nil, errors.New("service event has already ended")
+	}
+
+	code, err := utils.GenerateRandomToken(24)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate venue code: %w", err)
+	}
+
+	venueCode := &models.VenueCode{
+		Event:     event.ID,
+		Venue:     req.Venue,
+		Code:      code,
+		ExpiresAt: event.EndTime,
+		CreatedBy: createdBy,
+	}
+
+	if err := s.venueCodeRepo.Replace(ctx, venueCode); err != nil {
+		return nil, fmt.Errorf("failed to create venue code: %w", err)
+	}
+
+	return s.toVenueCodeResponse(venueCode)
+}
+
+func (s *ServiceEventService) GetVenueCodes(ctx context.Context, eventID string) ([]*dto.VenueCodeResponse, error) {
+	objID, err := primitive.ObjectIDFromHex(eventID)
+	if err != nil {
+		return nil, errors.New("invalid service event ID")
+	}
+
+	venueCodes, err := s.venueCodeRepo.GetByEvent(ctx, objID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get venue codes: %w", err)
+	}
+
+	responses := make([]*dto.VenueCodeResponse, 0, len(venueCodes))
+	for _, venueCode := range venueCodes {
+		response, err := s.toVenueCodeResponse(venueCode)
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, response)
+	}
+
+	return responses, nil
+}
+
+func (s *ServiceEventService) toVenueCodeResponse(venueCode *models.VenueCode) (*dto.VenueCodeResponse, error) {
+	payload := utils.VenueQRPrefix + venueCode.Code
+
+	qrImage, err := utils.GenerateQRCode(payload, s.cfg.QRCodeSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate QR code image: %w", err)
+	}
+
+	return &dto.VenueCodeResponse{
+		ID:          venueCode.ID.Hex(),
+		EventID:     venueCode.Event.Hex(),
+		Venue:       venueCode.Venue,
+		Code:        payload,
+		QRCodeImage: qrImage,
+		ExpiresAt:   venueCode.ExpiresAt,
+		CreatedBy:   venueCode.CreatedBy,
+		DateAdded:   venueCode.DateAdded,
+	}, nil
+}
+
+// resolveChurch validates an optional church ID and returns its ObjectID
+func (s *ServiceEventService) resolveChurch(ctx context.Context, churchID string) (*primitive.ObjectID, error) {
+	if churchID == "" {
+		return nil, nil
+	}
+
+	objID, err := primitive.ObjectIDFromHex(churchID)
+	if err != nil {
+		return nil, errors.New("invalid church ID")
+	}
+
+	church, err := s.localChurchRepo.GetByID(ctx, objID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get church: %w", err)
+	}
+	if church == nil {
+		return nil, ErrChurchNotFound
+	}
+
+	return &church.ID, nil
+}
+
+// checkinChurch returns the church a check-in without an event is for: the church with the given ID, or the
+// first church when churchID is empty. It is nil only when no church has been set up.
+func checkinChurch(ctx context.Context, localChurchRepo *repository.LocalChurchRepository, churchID string) (*models.LocalChurch, error) {
+	if churchID == "" {
+		church, err := localChurchRepo.GetFirst(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get church: %w", err)
+		}
+		return church, nil
+	}
+
+	objID, err := primitive.ObjectIDFromHex(churchID)
+	if err != nil {
+		return nil, errors.New("invalid church ID")
+	}
+	church, err := localChurchRepo.GetByID(ctx, objID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get church: %w", err)
+	}
+	if church == nil {
+		return nil, ErrChurchNotFound
+	}
+	return church, nil
+}
+
+// openEventAt returns the church's event open for check-in at the given time, or nil when none is. Events
+// created without a church belong to the first church.
+func openEventAt(ctx context.Context, localChurchRepo *repository.LocalChurchRepository, serviceEventRepo *repository.ServiceEventRepository, church *models.LocalChurch, at time.Time, earlyWindow time.Duration) (*models.ServiceEvent, error) {
+	defaultChurch, err := localChurchRepo.GetFirst(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get church: %w", err)
+	}
+
+	var churchID primitive.ObjectID
+	includeUnassigned := true
+	if church != nil {
+		churchID = church.ID
+		includeUnassigned = defaultChurch != nil && defaultChurch.ID == church.ID
+	}
+
+	event, err := serviceEventRepo.GetOpen(ctx, churchID, includeUnassigned, at, earlyWindow)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open service event: %w", err)
+	}
+	return event, nil
+}
+
+func (s *ServiceEventService) toResponse(event *models.ServiceEvent) *dto.ServiceEventResponse {
+	now := time.Now()
+	churchID := ""
+	if event.Church != nil {
+		churchID = event.Church.Hex()
+	}
+
+	return &dto.ServiceEventResponse{
+		ID:          event.ID.Hex(),
+		Name:        event.Name,
+		EventType:   event.EventType,
+		ChurchID:    churchID,
+		StartTime:   event.StartTime,
+		EndTime:     event.EndTime,
+		Description: event.Description,
+		IsOpen:      !now.Before(event.StartTime.Add(-s.cfg.EventCheckinEarlyWindow)) && !now.After(event.EndTime),
+		CreatedBy:   event.CreatedBy,
+		DateAdded:   event.DateAdded,
+		DateUpdated: event.DateUpdated,
+	}
+}
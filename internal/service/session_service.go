@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cci-api/internal/config"
+	"cci-api/internal/dto"
+	"cci-api/internal/models"
+	"cci-api/internal/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrSessionNotFound is returned when a member revokes a session that is not theirs or no longer exists
+var ErrSessionNotFound = newNotFoundError("session not found")
+
+// SessionService lists and revokes the devices a member is logged in on. Each session is a family of
+// rotated refresh tokens; revoking it stops the device refreshing, and its access token lapses within
+// JWTAccessExpiry.
+type SessionService struct {
+	cfg              *config.Config
+	userRepo         *repository.UserRepository
+	refreshTokenRepo *repository.RefreshTokenRepository
+}
+
+func NewSessionService(cfg *config.Config, userRepo *repository.UserRepository, refreshTokenRepo *repository.RefreshTokenRepository) *SessionService {
+	return &SessionService{
+		cfg:              cfg,
+		userRepo:         userRepo,
+		refreshTokenRepo: refreshTokenRepo,
+	}
+}
+
+// ListSessions returns the member's active sessions, flagging the one currentSessionID names
+func (s *SessionService) ListSessions(ctx context.Context, userID, currentSessionID string) ([]*dto.SessionResponse, error) {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.refreshTokenRepo.ListActiveByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+
+	sessions := make([]*dto.SessionResponse, 0, len(tokens))
+	for _, token := range tokens {
+		sessionID := token.FamilyID.Hex()
+		if token.FamilyID.IsZero() {
+			// Tokens issued before sessions were tracked are listed under their own ID
+			sessionID = token.ID.Hex()
+		}
+		sessions = append(sessions, &dto.SessionResponse{
+			ID:         sessionID,
+			DeviceName: token.DeviceName,
+			UserAgent:  token.UserAgent,
+			IPAddress:  token.IPAddress,
+			CreatedAt:  sessionCreatedAt(token),
+			LastUsedAt: token.LastUsedAt,
+			ExpiresAt:  token.ExpiresAt,
+			Current:    sessionID == currentSessionID,
+		})
+	}
+	return sessions, nil
+}
+
+// RevokeSession logs the member out on one device
+func (s *SessionService) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	familyID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return errors.New("invalid session ID")
+	}
+
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := s.refreshTokenRepo.DeleteByFamily(ctx, user.ID, familyID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if deleted == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeAllSessions logs the member out everywhere
+func (s *SessionService) RevokeAllSessions(ctx context.Context, userID string) error {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.refreshTokenRepo.DeleteByUserID(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to delete refresh tokens: %w", err)
+	}
+	return nil
+}
+
+func (s *SessionService) getUser(ctx context.Context, userID string) (*models.User, error) {
+	user, err := s.userRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+	return user, nil
+}
+
+func sessionCreatedAt(token *models.RefreshToken) time.Time {
+	if token.SessionCreatedAt.IsZero() {
+		return token.CreatedAt
+	}
+	return token.SessionCreatedAt
+}
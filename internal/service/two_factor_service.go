@@ -0,0 +1,333 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cci-api/internal/config"
+	"cci-api/internal/dto"
+	"cci-api/internal/models"
+	"cci-api/internal/repository"
+	"cci-api/internal/utils"
+)
+
+// recoveryCodeCount is how many one-time recovery codes a member gets at a time
+const recoveryCodeCount = 10
+
+var (
+	ErrTwoFactorNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnrolled    = errors.New("start two-factor enrollment before enabling it")
+	ErrInvalidTwoFactorCode    = errors.New("invalid two-factor code")
+	ErrInvalidLoginChallenge   = errors.New("invalid or expired login challenge")
+	// ErrTwoFactorRequired is returned when an admin tries to turn off two-factor authentication that policy requires
+	ErrTwoFactorRequired   = newForbiddenError("two-factor authentication is required for admin accounts")
+	ErrAdminTwoFactorReset = newForbiddenError("only an admin can reset an admin's two-factor authentication")
+)
+
+// TwoFactorService manages TOTP two-factor authentication: enrollment with an authenticator app, one-time
+// recovery codes, and the challenge a login passes through between password and code.
+type TwoFactorService struct {
+	cfg      *config.Config
+	userRepo *repository.UserRepository
+}
+
+func NewTwoFactorService(cfg *config.Config, userRepo *repository.UserRepository) *TwoFactorService {
+	return &TwoFactorService{
+		cfg:      cfg,
+		userRepo: userRepo,
+	}
+}
+
+// Required reports whether the user has to pass two-factor authentication to log in
+func (s *TwoFactorService) Required(user *models.User) bool {
+	return user.TwoFactorEnabled || (user.Admin && s.cfg.TwoFactorRequiredAdmins)
+}
+
+func (s *TwoFactorService) Status(ctx context.Context, userID string) (*dto.TwoFactorStatusResponse, error) {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.TwoFactorStatusResponse{
+		Enabled:           user.TwoFactorEnabled,
+		Required:          user.Admin && s.cfg.TwoFactorRequiredAdmins,
+		RecoveryCodesLeft: len(user.TwoFactorRecoveryCodes),
+	}, nil
+}
+
+// Enroll starts setting up an authenticator app. Two-factor authentication is only turned on once Enable
+// receives a code from the app.
+func (s *TwoFactorService) Enroll(ctx context.Context, userID string) (*dto.TwoFactorSetupResponse, error) {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.enroll(ctx, user)
+}
+
+// Enable turns on two-factor authentication with a code from the enrolled app and returns the recovery codes
+func (s *TwoFactorService) Enable(ctx context.Context, userID, code string) (*dto.TwoFactorRecoveryCodesResponse, error) {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	recoveryCodes, err := s.enable(ctx, user, code)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.TwoFactorRecoveryCodesResponse{RecoveryCodes: recoveryCodes}, nil
+}
+
+// Disable turns off two-factor authentication after checking the password and a code
+func (s *TwoFactorService) Disable(ctx context.Context, userID string, req *dto.DisableTwoFactorRequest) error {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.TwoFactorEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+	if user.Admin && s.cfg.TwoFactorRequiredAdmins {
+		return ErrTwoFactorRequired
+	}
+	if !utils.CheckPasswordHash(req.Password, user.Password) {
+		return errors.New("incorrect password")
+	}
+	if err := s.verify(ctx, user, req.Code, req.RecoveryCode); err != nil {
+		return err
+	}
+
+	if err := s.userRepo.ClearTwoFactor(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to disable two-factor authentication: %w", err)
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the member's recovery codes after checking a code from their app
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID, code string) (*dto.TwoFactorRecoveryCodesResponse, error) {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TwoFactorEnabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if err := s.verify(ctx, user, code, ""); err != nil {
+		return nil, err
+	}
+
+	recoveryCodes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.ReplaceRecoveryCodes(ctx, user.ID, hashes); err != nil {
+		return nil, fmt.Errorf("failed to save recovery codes: %w", err)
+	}
+	return &dto.TwoFactorRecoveryCodesResponse{RecoveryCodes: recoveryCodes}, nil
+}
+
+// Reset turns off two-factor authentication for a member who lost their authenticator and recovery codes.
+// Admins who must use it are asked to set it up again at their next login. Only an admin can reset an admin's.
+func (s *TwoFactorService) Reset(ctx context.Context, userID string, isAdmin bool) error {
+	user, err := s.userRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	if user.Admin && !isAdmin {
+		return ErrAdminTwoFactorReset
+	}
+
+	if err := s.userRepo.ClearTwoFactor(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to reset two-factor authentication: %w", err)
+	}
+	return nil
+}
+
+// NewChallenge issues the short-lived token a login exchanges for the real tokens once the code checks out
+func (s *TwoFactorService) NewChallenge(ctx context.Context, user *models.User) (string, time.Time, error) {
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate login challenge: %w", err)
+	}
+
+	expires := time.Now().Add(s.cfg.TwoFactorChallengeTTL)
+	if err := s.userRepo.SetTwoFactorChallenge(ctx, user.ID, utils.HashToken(token), expires); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to save login challenge: %w", err)
+	}
+	return token, expires, nil
+}
+
+// ChallengeUser returns the user a login challenge was issued to
+func (s *TwoFactorService) ChallengeUser(ctx context.Context, challengeToken string) (*models.User, error) {
+	user, err := s.userRepo.GetByTwoFactorChallenge(ctx, utils.HashToken(challengeToken))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get login challenge: %w", err)
+	}
+	if user == nil || time.Now().After(user.TwoFactorChallengeExpires) {
+		return nil, ErrInvalidLoginChallenge
+	}
+	return user, nil
+}
+
+// EnrollWithChallenge starts setup for an admin who must use two-factor authentication before logging in
+func (s *TwoFactorService) EnrollWithChallenge(ctx context.Context, challengeToken string) (*dto.TwoFactorSetupResponse, error) {
+	user, err := s.ChallengeUser(ctx, challengeToken)
+	if err != nil {
+		return nil, err
+	}
+	return s.enroll(ctx, user)
+}
+
+// CompleteChallenge checks the code for a login challenge and ends the challenge. When the login was waiting
+// on setup the code turns two-factor authentication on, and the new recovery codes are returned.
+func (s *TwoFactorService) CompleteChallenge(ctx context.Context, user *models.User, req *dto.TwoFactorLoginRequest) ([]string, error) {
+	var recoveryCodes []string
+	var err error
+	if user.TwoFactorEnabled {
+		err = s.verify(ctx, user, req.Code, req.RecoveryCode)
+	} else {
+		recoveryCodes, err = s.enable(ctx, user, req.Code)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cleared, err := s.userRepo.ClearTwoFactorChallenge(ctx, user.ID, utils.HashToken(req.ChallengeToken))
+	if err != nil {
+		return nil, fmt.Errorf("failed to end login challenge: %w", err)
+	}
+	if !cleared {
+		return nil, ErrInvalidLoginChallenge
+	}
+	return recoveryCodes, nil
+}
+
+func (s *TwoFactorService) enroll(ctx context.Context, user *models.User) (*dto.TwoFactorSetupResponse, error) {
+	if user.TwoFactorEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate two-factor secret: %w", err)
+	}
+
+	otpauthURL := utils.TOTPProvisioningURI(s.cfg.TwoFactorIssuer, user.Email, secret)
+	qrImage, err := utils.GenerateQRCode(otpauthURL, s.cfg.QRCodeSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate QR code: %w", err)
+	}
+
+	if err := s.userRepo.SetTwoFactorPendingSecret(ctx, user.ID, secret); err != nil {
+		return nil, fmt.Errorf("failed to save two-factor secret: %w", err)
+	}
+
+	return &dto.TwoFactorSetupResponse{
+		Secret:     secret,
+		OTPAuthURL: otpauthURL,
+		QRCode:     qrImage,
+	}, nil
+}
+
+func (s *TwoFactorService) enable(ctx context.Context, user *models.User, code string) ([]string, error) {
+	if user.TwoFactorEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if user.TwoFactorPendingSecret == "" {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+
+	step, ok := utils.VerifyTOTP(user.TwoFactorPendingSecret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	recoveryCodes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	enabled, err := s.userRepo.EnableTwoFactor(ctx, user.ID, user.TwoFactorPendingSecret, hashes, step)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable two-factor authentication: %w", err)
+	}
+	if !enabled {
+		// Another request enabled it or started a new enrollment in the meantime
+		return nil, ErrInvalidTwoFactorCode
+	}
+	return recoveryCodes, nil
+}
+
+// verify accepts an authenticator code, each time step only once, or an unused recovery code, which is spent
+func (s *TwoFactorService) verify(ctx context.Context, user *models.User, code, recoveryCode string) error {
+	if code != "" {
+		step, ok := utils.VerifyTOTP(user.TwoFactorSecret, code, time.Now())
+		if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+		fresh, err := s.userRepo.UseTwoFactorStep(ctx, user.ID, step)
+		if err != nil {
+			return fmt.Errorf("failed to record two-factor code: %w", err)
+		}
+		if !fresh {
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+
+	if recoveryCode != "" {
+		normalized := utils.NormalizeRecoveryCode(recoveryCode)
+		for _, hash := range user.TwoFactorRecoveryCodes {
+			if !utils.CheckPasswordHash(normalized, hash) {
+				continue
+			}
+			unused, err := s.userRepo.UseRecoveryCode(ctx, user.ID, hash)
+			if err != nil {
+				return fmt.Errorf("failed to use recovery code: %w", err)
+			}
+			if unused {
+				return nil
+			}
+			break
+		}
+	}
+	return ErrInvalidTwoFactorCode
+}
+
+func (s *TwoFactorService) getUser(ctx context.Context, userID string) (*models.User, error) {
+	user, err := s.userRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+	return user, nil
+}
+
+// newRecoveryCodes returns fresh recovery codes to show once, and the bcrypt hashes to store
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := utils.GenerateRecoveryCode()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		hash, err := utils.HashPassword(utils.NormalizeRecoveryCode(code))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to hash recovery code: %w", err)
+		}
+		codes[i] = code
+		hashes[i] = hash
+	}
+	return codes, hashes, nil
+}
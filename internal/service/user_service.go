@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"cci-api/internal/config"
@@ -87,6 +88,8 @@ func (s *UserService) GetAllUsers(ctx context.Context, page, limit int) (*dto.Pa
 			DateJoined:                   user.DateJoined,
 			DateUpdated:                  user.DateUpdated,
 			Role:                         user.Role,
+			Shepherd:                     user.Shepherd,
+			MembershipStage:              membershipStageOf(user),
 			EmergencyContactName:         user.EmergencyContactName,
 			EmergencyContactPhone:        user.EmergencyContactPhone,
 			EmergencyContactEmail:        user.EmergencyContactEmail,
@@ -107,6 +110,36 @@ func (s *UserService) GetAllUsers(ctx context.Context, page, limit int) (*dto.Pa
 	}, nil
 }
 
+// AssignShepherd sets the shepherd or cell leader new follow-ups for a member are assigned to. An empty
+// shepherd clears the assignment.
+func (s *UserService) AssignShepherd(ctx context.Context, userID, shepherd string) error {
+	user, err := s.userRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return errors.New("user not found")
+	}
+
+	if shepherd != "" {
+		if shepherd == userID {
+			return errors.New("a member cannot be their own shepherd")
+		}
+		leader, err := s.userRepo.GetByUserID(ctx, shepherd)
+		if err != nil {
+			return fmt.Errorf("failed to get shepherd: %w", err)
+		}
+		if leader == nil {
+			return errors.New("shepherd not found")
+		}
+	}
+
+	if err := s.userRepo.UpdateShepherd(ctx, userID, shepherd); err != nil {
+		return fmt.Errorf("failed to assign shepherd: %w", err)
+	}
+	return nil
+}
+
 func (s *UserService) FilterUsers(ctx context.Context, field, value string, page, limit int) (*dto.PaginatedResponse, error) {
 	users, total, err := s.userRepo.Filter(ctx, field, value, page, limit)
 	if err != nil {
@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/skip2/go-qrcode"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Member cards are ID-1 (credit card) size, 85.6mm x 54mm, rendered at 300 DPI
+const (
+	cardWidthPx  = 1011
+	cardHeightPx = 638
+	cardWidthMM  = 85.6
+	cardHeightMM = 54.0
+)
+
+var (
+	cardBrandColor = color.RGBA{R: 0x1f, G: 0x3a, B: 0x68, A: 0xff}
+	cardTextColor  = color.RGBA{R: 0x22, G: 0x22, B: 0x22, A: 0xff}
+	cardMutedColor = color.RGBA{R: 0x66, G: 0x66, B: 0x66, A: 0xff}
+)
+
+// MemberCard holds what is printed on a member's badge
+type MemberCard struct {
+	ChurchName string
+	FullName   string
+	UserID     string
+	Campus     string
+	QRData     string
+}
+
+// RenderMemberCardPNG draws a member card with the member's details on the left and their QR code on the right
+func RenderMemberCardPNG(card MemberCard) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, cardWidthPx, cardHeightPx))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	// Header band
+	draw.Draw(img, image.Rect(0, 0, cardWidthPx, 120), image.NewUniform(cardBrandColor), image.Point{}, draw.Src)
+
+	headerFace, err := loadCardFace(gobold.TTF, 44)
+	if err != nil {
+		return nil, err
+	}
+	nameFace, err := loadCardFace(gobold.TTF, 46)
+	if err != nil {
+		return nil, err
+	}
+	detailFace, err := loadCardFace(goregular.TTF, 34)
+	if err != nil {
+		return nil, err
+	}
+
+	churchName := card.ChurchName
+	if churchName == "" {
+		churchName = "Member Card"
+	}
+	drawCardText(img, headerFace, color.White, 40, 78, churchName, cardWidthPx-80)
+
+	// QR code
+	qr, err := qrcode.New(card.QRData, qrcode.Medium)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create QR code: %w", err)
+	}
+	qrSize := 460
+	qrRect := image.Rect(cardWidthPx-qrSize-10, 150, cardWidthPx-10, 150+qrSize)
+	qrImage := qr.Image(qrSize)
+	draw.NearestNeighbor.Scale(img, qrRect, qrImage, qrImage.Bounds(), draw.Over, nil)
+
+	// Member details
+	textWidth := qrRect.Min.X - 40
+	drawCardText(img, nameFace, cardTextColor, 40, 250, card.FullName, textWidth)
+	drawCardText(img, detailFace, cardMutedColor, 40, 330, card.UserID, textWidth)
+	if card.Campus != "" {
+		drawCardText(img, detailFace, cardMutedColor, 40, 385, card.Campus, textWidth)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode member card: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// RenderCardSheetPDF lays rendered member cards out on A4 pages, two columns by five rows, ready to print and cut
+func RenderCardSheetPDF(cards [][]byte) ([]byte, error) {
+	const (
+		columns = 2
+		rows    = 5
+	)
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pageWidth, pageHeight := pdf.GetPageSize()
+	gapX := (pageWidth - columns*cardWidthMM) / (columns + 1)
+	gapY := (pageHeight - rows*cardHeightMM) / (rows + 1)
+
+	for i, card := range cards {
+		if i%(columns*rows) == 0 {
+			pdf.AddPage()
+		}
+
+		position := i % (columns * rows)
+		x := gapX + float64(position%columns)*(cardWidthMM+gapX)
+		y := gapY + float64(position/columns)*(cardHeightMM+gapY)
+
+		name := fmt.Sprintf("card-%d", i)
+		pdf.RegisterImageOptionsReader(name, fpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(card))
+		pdf.ImageOptions(name, x, y, cardWidthMM, cardHeightMM, false, fpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+
+		// Thin cut guide around each card
+		pdf.SetDrawColor(200, 200, 200)
+		pdf.SetLineWidth(0.1)
+		pdf.Rect(x, y, cardWidthMM, cardHeightMM, "D")
+	}
+
+	if len(cards) == 0 {
+		pdf.AddPage()
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to render card sheet: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func loadCardFace(ttf []byte, size float64) (font.Face, error) {
+	parsed, err := opentype.Parse(ttf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse card font: %w", err)
+	}
+
+	face, err := opentype.NewFace(parsed, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load card font: %w", err)
+	}
+	return face, nil
+}
+
+// drawCardText writes a line of text at the given baseline, shortening it with an ellipsis if it would overflow maxWidth
+func drawCardText(img *image.RGBA, face font.Face, textColor color.Color, x, y int, text string, maxWidth int) {
+	drawer := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(textColor),
+		Face: face,
+		Dot:  fixed.P(x, y),
+	}
+
+	limit := fixed.I(maxWidth)
+	if drawer.MeasureString(text) > limit {
+		runes := []rune(strings.TrimSpace(text))
+		for len(runes) > 0 && drawer.MeasureString(string(runes)+"…") > limit {
+			runes = runes[:len(runes)-1]
+		}
+		text = string(runes) + "…"
+	}
+
+	drawer.DrawString(text)
+}
@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+
+	"github.com/go-pdf/fpdf"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// Children's church labels are 4in x 2in, the common size for thermal label printers, rendered at 300 DPI
+const (
+	labelWidthPx  = 1200
+	labelHeightPx = 600
+	labelWidthMM  = 101.6
+	labelHeightMM = 50.8
+)
+
+var labelAlertColor = color.RGBA{R: 0xc6, G: 0x28, B: 0x28, A: 0xff}
+
+// ChildLabel holds what is printed on a children's church label. A child's label names the child; the guardian's
+// matching label lists the children it collects. Both carry the same pickup code.
+type ChildLabel struct {
+	ChurchName string
+	Title      string
+	Lines      []string
+	Alert      string
+	PickupCode string
+}
+
+// RenderChildLabelPNG draws a label with its details on the left, the pickup code in a box on the right and any
+// alert, such as allergies, in a band along the bottom
+func RenderChildLabelPNG(label ChildLabel) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, labelWidthPx, labelHeightPx))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	// Header band
+	draw.Draw(img, image.Rect(0, 0, labelWidthPx, 100), image.NewUniform(cardBrandColor), image.Point{}, draw.Src)
+
+	headerFace, err := loadCardFace(gobold.TTF, 44)
+	if err != nil {
+		return nil, err
+	}
+	titleFace, err := loadCardFace(gobold.TTF, 64)
+	if err != nil {
+		return nil, err
+	}
+	detailFace, err := loadCardFace(goregular.TTF, 38)
+	if err != nil {
+		return nil, err
+	}
+	codeFace, err := loadCardFace(gobold.TTF, 88)
+	if err != nil {
+		return nil, err
+	}
+
+	churchName := label.ChurchName
+	if churchName == "" {
+		churchName = "Children's Church"
+	}
+	drawCardText(img, headerFace, color.White, 40, 68, churchName, labelWidthPx-80)
+
+	// Pickup code box
+	codeRect := image.Rect(labelWidthPx-480, 130, labelWidthPx-30, 410)
+	draw.Draw(img, codeRect, image.NewUniform(cardTextColor), image.Point{}, draw.Src)
+	draw.Draw(img, codeRect.Inset(6), image.White, image.Point{}, draw.Src)
+	drawCardText(img, detailFace, cardMutedColor, codeRect.Min.X+30, codeRect.Min.Y+60, "PICKUP CODE", codeRect.Dx()-60)
+	drawCardText(img, codeFace, cardTextColor, codeRect.Min.X+30, codeRect.Min.Y+200, label.PickupCode, codeRect.Dx()-60)
+
+	// Details
+	textWidth := codeRect.Min.X - 70
+	drawCardText(img, titleFace, cardTextColor, 40, 200, label.Title, textWidth)
+	y := 270
+	for _, line := range label.Lines {
+		if y > 420 {
+			break
+		}
+		drawCardText(img, detailFace, cardMutedColor, 40, y, line, textWidth)
+		y += 52
+	}
+
+	if label.Alert != "" {
+		draw.Draw(img, image.Rect(0, 460, labelWidthPx, labelHeightPx), image.NewUniform(labelAlertColor), image.Point{}, draw.Src)
+		drawCardText(img, titleFace, color.White, 40, 555, label.Alert, labelWidthPx-80)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode label: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// RenderLabelSheetPDF puts each rendered label on its own label-sized page, ready for a label printer
+func RenderLabelSheetPDF(labels [][]byte) ([]byte, error) {
+	// fpdf takes the page size in portrait and turns it for landscape
+	pdf := fpdf.NewCustom(&fpdf.InitType{
+		OrientationStr: "L",
+		UnitStr:        "mm",
+		Size:           fpdf.SizeType{Wd: labelHeightMM, Ht: labelWidthMM},
+	})
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+
+	for i, label := range labels {
+		pdf.AddPage()
+		name := fmt.Sprintf("label-%d", i)
+		pdf.RegisterImageOptionsReader(name, fpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(label))
+		pdf.ImageOptions(name, 0, 0, labelWidthMM, labelHeightMM, false, fpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+	}
+
+	if len(labels) == 0 {
+		pdf.AddPage()
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to render label sheet: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRotatingQRToken(t *testing.T) {
+	token := SignRotatingQRToken("CCIMRB-10422", "secret", 42)
+
+	if !IsRotatingQRToken(token) {
+		t.Fatalf("IsRotatingQRToken(%q) = false", token)
+	}
+	if IsMemberCardToken(token) {
+		t.Errorf("IsMemberCardToken(%q) = true", token)
+	}
+
+	userID, counter, signature, ok := ParseRotatingQRToken(token)
+	if !ok || userID != "CCIMRB-10422" || counter != 42 {
+		t.Fatalf("ParseRotatingQRToken(%q) = (%q, %d, %q, %v)", token, userID, counter, signature, ok)
+	}
+
+	tests := []struct {
+		name    string
+		userID  string
+		secret  string
+		counter int64
+		want    bool
+	}{
+		{"valid", "CCIMRB-10422", "secret", 42, true},
+		{"wrong secret", "CCIMRB-10422", "other", 42, false},
+		{"wrong user", "CCIMRB-10423", "secret", 42, false},
+		{"wrong counter", "CCIMRB-10422", "secret", 43, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyRotatingQRToken(tt.userID, tt.secret, tt.counter, signature); got != tt.want {
+				t.Errorf("VerifyRotatingQRToken = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRotatingQRTokenRejects(t *testing.T) {
+	tests := []string{
+		"",
+		"CCIMRB-10422",
+		"v2.CCIMRB-10422.42",
+		"v2.CCIMRB-10422.abc.sig",
+		"v1.CCIMRB-10422.42.sig",
+		"v2.CCIMRB.10422.42.sig",
+		"card.CCIMRB-10422.1.sig",
+	}
+
+	for _, token := range tests {
+		if _, _, _, ok := ParseRotatingQRToken(token); ok {
+			t.Errorf("ParseRotatingQRToken(%q) accepted the token", token)
+		}
+	}
+}
+
+func TestQRCounterInWindow(t *testing.T) {
+	interval := 30 * time.Second
+	at := time.Unix(3000, 0)
+	current := QRCounter(at, interval)
+	if current != 100 {
+		t.Fatalf("QRCounter = %d, want 100", current)
+	}
+
+	tests := []struct {
+		counter int64
+		want    bool
+	}{
+		{current - 2, false},
+		{current - 1, true},
+		{current, true},
+		{current + 1, true},
+		{current + 2, false},
+	}
+
+	for _, tt := range tests {
+		if got := QRCounterInWindow(tt.counter, at, interval); got != tt.want {
+			t.Errorf("QRCounterInWindow(%d) at step %d = %v, want %v", tt.counter, current, got, tt.want)
+		}
+	}
+}
+
+func TestMemberCardToken(t *testing.T) {
+	token := SignMemberCardToken("CCIMRB-10422", "secret", 3)
+
+	if !IsMemberCardToken(token) {
+		t.Fatalf("IsMemberCardToken(%q) = false", token)
+	}
+	if IsRotatingQRToken(token) {
+		t.Errorf("IsRotatingQRToken(%q) = true", token)
+	}
+
+	userID, version, signature, ok := ParseMemberCardToken(token)
+	if !ok || userID != "CCIMRB-10422" || version != 3 {
+		t.Fatalf("ParseMemberCardToken(%q) = (%q, %d, %q, %v)", token, userID, version, signature, ok)
+	}
+
+	tests := []struct {
+		name    string
+		userID  string
+		secret  string
+		version int
+		want    bool
+	}{
+		{"valid", "CCIMRB-10422", "secret", 3, true},
+		{"wrong secret", "CCIMRB-10422", "other", 3, false},
+		{"wrong user", "CCIMRB-10423", "secret", 3, false},
+		{"revoked version", "CCIMRB-10422", "secret", 4, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyMemberCardToken(tt.userID, tt.secret, tt.version, signature); got != tt.want {
+				t.Errorf("VerifyMemberCardToken = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// A card signature must not pass as a rotating token for the same user and counter
+	if VerifyRotatingQRToken("CCIMRB-10422", "secret", 3, signature) {
+		t.Error("member card signature verified as a rotating QR token")
+	}
+}
+
+func TestParseMemberCardTokenRejects(t *testing.T) {
+	tests := []string{
+		"",
+		"card.CCIMRB-10422.1",
+		"card.CCIMRB-10422.x.sig",
+		"v2.CCIMRB-10422.1.sig",
+	}
+
+	for _, token := range tests {
+		if _, _, _, ok := ParseMemberCardToken(token); ok {
+			t.Errorf("ParseMemberCardToken(%q) accepted the token", token)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 test key from RFC 6238 appendix B, "12345678901234567890", in base32
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 appendix B vectors, cut from eight digits to the six we issue
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := TOTPCode(rfc6238Secret, tt.unix/30)
+		if err != nil {
+			t.Fatalf("TOTPCode at %d: %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("TOTPCode at %d = %q, want %q", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestTOTPCodeInvalidSecret(t *testing.T) {
+	if _, err := TOTPCode("not base32!", 1); err == nil {
+		t.Error("TOTPCode with an invalid secret returned no error")
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	at := time.Unix(1111111111, 0)
+	step := at.Unix() / 30
+	code := func(counter int64) string {
+		c, err := TOTPCode(rfc6238Secret, counter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", rfc6238Secret, code(step), step, true},
+		{"previous step", rfc6238Secret, code(step - 1), step - 1, true},
+		{"next step", rfc6238Secret, code(step + 1), step + 1, true},
+		{"two steps behind", rfc6238Secret, code(step - 2), 0, false},
+		{"two steps ahead", rfc6238Secret, code(step + 2), 0, false},
+		{"lowercase secret", "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", code(step), step, true},
+		{"spaces in code", rfc6238Secret, "050 471", step, true},
+		{"too short", rfc6238Secret, "05047", 0, false},
+		{"too long", rfc6238Secret, "0504710", 0, false},
+		{"wrong code", rfc6238Secret, "000000", 0, false},
+		{"invalid secret", "not base32!", code(step), 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, ok := VerifyTOTP(tt.secret, tt.code, at)
+			if ok != tt.wantOK || gotStep != tt.wantStep {
+				t.Errorf("VerifyTOTP = (%d, %v), want (%d, %v)", gotStep, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"7KQ4M-XH2PD", "7KQ4MXH2PD"},
+		{"7kq4m-xh2pd", "7KQ4MXH2PD"},
+		{" 7kq4mxh2pd ", "7KQ4MXH2PD"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeRecoveryCode(tt.in); got != tt.want {
+			t.Errorf("NormalizeRecoveryCode(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
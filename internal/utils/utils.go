@@ -4,6 +4,8 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"fmt"
+	"log"
+	"math"
 	"math/big"
 	"strconv"
 	"strings"
@@ -50,20 +52,40 @@ func GenerateRandomToken(length int) (string, error) {
 	return base64.URLEncoding.EncodeToString(bytes), nil
 }
 
-// JWTClaims represents JWT claims
+// pickupCodeAlphabet leaves out characters that are easily misread on a printed label (0/O, 1/I/L)
+const pickupCodeAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+
+// GeneratePickupCode generates a random code of the given length for collecting children from children's church
+func GeneratePickupCode(length int) (string, error) {
+	code := make([]byte, length)
+	max := big.NewInt(int64(len(pickupCodeAlphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = pickupCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// JWTClaims represents JWT claims. SessionID names the login session (refresh token family) the token
+// belongs to.
 type JWTClaims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
-	Admin  bool   `json:"admin"`
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	Admin     bool   `json:"admin"`
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
 // GenerateJWT generates a JWT token
-func GenerateJWT(userID, email string, admin bool, secret string, expiry time.Duration) (string, error) {
+func GenerateJWT(userID, email string, admin bool, sessionID, secret string, expiry time.Duration) (string, error) {
 	claims := JWTClaims{
-		UserID: userID,
-		Email:  email,
-		Admin:  admin,
+		UserID:    userID,
+		Email:     email,
+		Admin:     admin,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiry)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -142,6 +164,52 @@ func GetLagosTime() time.Time {
 	return time.Now().In(location)
 }
 
+// LoadLocation loads the named timezone, falling back to UTC and logging the name when it is unknown
+func LoadLocation(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		log.Printf("Unknown timezone %q, using UTC: %v", name, err)
+		return time.UTC
+	}
+	return location
+}
+
+// DayBounds returns the start of the calendar day of t in loc and the start of the following day.
+// The calendar date is read from t as given, so callers holding an instant should convert it with In(loc) first.
+func DayBounds(t time.Time, loc *time.Location) (time.Time, time.Time) {
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// earthRadiusMeters is the mean radius of the earth used for distances between coordinates
+const earthRadiusMeters = 6371000
+
+// DistanceMeters returns the great-circle distance between two latitude/longitude points using the haversine formula
+func DistanceMeters(lat1, lon1, lat2, lon2 float64) float64 {
+	toRadians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+
+	dLat := toRadians(lat2 - lat1)
+	dLon := toRadians(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// NormalizePhoneNumber strips the spaces, dashes, dots and brackets people type into phone numbers so the same
+// number always compares equal
+func NormalizePhoneNumber(phone string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(phone))
+}
+
 // IsValidPassword checks if password meets requirements
 func IsValidPassword(password string) bool {
 	if len(password) < 8 {
@@ -170,3 +238,53 @@ func IsValidPassword(password string) bool {
 
 	return hasUpper && hasLower && hasNumber && hasSpecial
 }
+
+// DeviceName describes a device from its User-Agent header, such as "Chrome on Windows"
+func DeviceName(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+
+	browser := ""
+	switch {
+	case strings.Contains(userAgent, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(userAgent, "OPR/"):
+		browser = "Opera"
+	case strings.Contains(userAgent, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(userAgent, "Chrome/"):
+		browser = "Chrome"
+	case strings.Contains(userAgent, "Safari/"):
+		browser = "Safari"
+	case strings.Contains(userAgent, "Dart/"), strings.Contains(userAgent, "okhttp/"):
+		browser = "Mobile app"
+	}
+
+	os := ""
+	switch {
+	case strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPad"):
+		os = "iOS"
+	case strings.Contains(userAgent, "Android"):
+		os = "Android"
+	case strings.Contains(userAgent, "Windows"):
+		os = "Windows"
+	case strings.Contains(userAgent, "Mac OS X"), strings.Contains(userAgent, "Macintosh"):
+		os = "macOS"
+	case strings.Contains(userAgent, "Linux"):
+		os = "Linux"
+	}
+
+	switch {
+	case browser != "" && os != "":
+		return browser + " on " + os
+	case browser != "":
+		return browser
+	case os != "":
+		return os + " device"
+	}
+	if len(userAgent) > 50 {
+		return userAgent[:50]
+	}
+	return userAgent
+}
@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDayBounds(t *testing.T) {
+	lagos := time.FixedZone("WAT", 60*60)
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		t         time.Time
+		loc       *time.Location
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{
+			name:      "midday",
+			t:         time.Date(2025, 8, 17, 12, 30, 0, 0, lagos),
+			loc:       lagos,
+			wantStart: time.Date(2025, 8, 17, 0, 0, 0, 0, lagos),
+			wantEnd:   time.Date(2025, 8, 18, 0, 0, 0, 0, lagos),
+		},
+		{
+			name:      "midnight",
+			t:         time.Date(2025, 8, 17, 0, 0, 0, 0, lagos),
+			loc:       lagos,
+			wantStart: time.Date(2025, 8, 17, 0, 0, 0, 0, lagos),
+			wantEnd:   time.Date(2025, 8, 18, 0, 0, 0, 0, lagos),
+		},
+		{
+			name:      "end of month",
+			t:         time.Date(2025, 8, 31, 23, 59, 0, 0, lagos),
+			loc:       lagos,
+			wantStart: time.Date(2025, 8, 31, 0, 0, 0, 0, lagos),
+			wantEnd:   time.Date(2025, 9, 1, 0, 0, 0, 0, lagos),
+		},
+		{
+			name:      "calendar date read as given",
+			t:         time.Date(2025, 8, 17, 23, 30, 0, 0, time.UTC),
+			loc:       lagos,
+			wantStart: time.Date(2025, 8, 17, 0, 0, 0, 0, lagos),
+			wantEnd:   time.Date(2025, 8, 18, 0, 0, 0, 0, lagos),
+		},
+		{
+			name:      "daylight saving starts",
+			t:         time.Date(2025, 3, 9, 12, 0, 0, 0, newYork),
+			loc:       newYork,
+			wantStart: time.Date(2025, 3, 9, 0, 0, 0, 0, newYork),
+			wantEnd:   time.Date(2025, 3, 10, 0, 0, 0, 0, newYork),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := DayBounds(tt.t, tt.loc)
+			if !start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd) {
+				t.Errorf("DayBounds = (%v, %v), want (%v, %v)", start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+
+	// The day daylight saving starts is an hour short
+	start, end := DayBounds(time.Date(2025, 3, 9, 12, 0, 0, 0, newYork), newYork)
+	if got := end.Sub(start); got != 23*time.Hour {
+		t.Errorf("DayBounds on the daylight saving change spans %v, want 23h", got)
+	}
+}
+
+func TestDistanceMeters(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+		want                   float64
+	}{
+		{"same point", 6.6018, 3.3515, 6.6018, 3.3515, 0},
+		{"small step north", 6.6018, 3.3515, 6.6023, 3.3515, 55.6},
+		{"Ikeja to Lekki", 6.6018, 3.3515, 6.4474, 3.4730, 21793},
+		{"across the antimeridian", 0, 179.9995, 0, -179.9995, 111.2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DistanceMeters(tt.lat1, tt.lon1, tt.lat2, tt.lon2)
+			if math.Abs(got-tt.want) > tt.want*0.001+0.1 {
+				t.Errorf("DistanceMeters = %.1f, want about %.1f", got, tt.want)
+			}
+		})
+	}
+}
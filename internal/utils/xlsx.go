@@ -0,0 +1,161 @@
+package utils
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// XLSXSheet is one worksheet of a workbook. Cells may be strings, ints, float64s or bools; anything else is
+// written as text.
+type XLSXSheet struct {
+	Name string
+	Rows [][]interface{}
+}
+
+const (
+	xlsxContentTypesHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+`
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+	xlsxSheetHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetFooter = `</sheetData></worksheet>`
+)
+
+// WriteXLSX writes the sheets to w as an Excel workbook. Sheet names are shortened to Excel's 31 character
+// limit and stripped of the characters it does not allow. A workbook needs at least one sheet, so an empty
+// one is added when sheets is empty.
+func WriteXLSX(w io.Writer, sheets []XLSXSheet) error {
+	if len(sheets) == 0 {
+		sheets = []XLSXSheet{{Name: "Sheet1"}}
+	}
+
+	zw := zip.NewWriter(w)
+
+	var contentTypes, workbook, workbookRels strings.Builder
+	contentTypes.WriteString(xlsxContentTypesHeader)
+	workbook.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	workbookRels.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+
+	used := map[string]bool{}
+	for i, sheet := range sheets {
+		n := i + 1
+		fmt.Fprintf(&contentTypes, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`+"\n", n)
+		fmt.Fprintf(&workbook, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(xlsxSheetName(sheet.Name, n, used)), n, n)
+		fmt.Fprintf(&workbookRels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, n, n)
+
+		if err := writeXLSXSheet(zw, fmt.Sprintf("xl/worksheets/sheet%d.xml", n), sheet.Rows); err != nil {
+			return err
+		}
+	}
+
+	contentTypes.WriteString(`</Types>`)
+	workbook.WriteString(`</sheets></workbook>`)
+	workbookRels.WriteString(`</Relationships>`)
+
+	for _, part := range [][2]string{
+		{"[Content_Types].xml", contentTypes.String()},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", workbook.String()},
+		{"xl/_rels/workbook.xml.rels", workbookRels.String()},
+	} {
+		f, err := zw.Create(part[0])
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, part[1]); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+func writeXLSXSheet(zw *zip.Writer, name string, rows [][]interface{}) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(f)
+	bw.WriteString(xlsxSheetHeader)
+	for r, row := range rows {
+		fmt.Fprintf(bw, `<row r="%d">`, r+1)
+		for c, value := range row {
+			ref := xlsxColumn(c) + strconv.Itoa(r+1)
+			switch v := value.(type) {
+			case nil:
+			case int:
+				fmt.Fprintf(bw, `<c r="%s"><v>%d</v></c>`, ref, v)
+			case float64:
+				fmt.Fprintf(bw, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(v, 'f', -1, 64))
+			case bool:
+				b := 0
+				if v {
+					b = 1
+				}
+				fmt.Fprintf(bw, `<c r="%s" t="b"><v>%d</v></c>`, ref, b)
+			default:
+				fmt.Fprintf(bw, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, xmlEscape(EscapeSpreadsheetCell(fmt.Sprint(v))))
+			}
+		}
+		bw.WriteString(`</row>`)
+	}
+	bw.WriteString(xlsxSheetFooter)
+	return bw.Flush()
+}
+
+// xlsxColumn returns the column letters for a zero-based column index: A, B, ..., Z, AA, AB, ...
+func xlsxColumn(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// xlsxSheetName makes name a valid, unique sheet name, falling back to "Sheet<n>"
+func xlsxSheetName(name string, n int, used map[string]bool) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '-'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if runes := []rune(name); len(runes) > 31 {
+		name = string(runes[:31])
+	}
+	if name == "" || used[strings.ToLower(name)] {
+		name = fmt.Sprintf("Sheet%d", n)
+	}
+	used[strings.ToLower(name)] = true
+	return name
+}
+
+// EscapeSpreadsheetCell stops a text cell from being read as a formula when the export is opened in a
+// spreadsheet, by prefixing values that start with a formula character with a quote
+func EscapeSpreadsheetCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
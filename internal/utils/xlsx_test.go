@@ -0,0 +1,187 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestEscapeSpreadsheetCell(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", ""},
+		{"John Doe", "John Doe"},
+		{"=SUM(A1:A2)", "'=SUM(A1:A2)"},
+		{"+2348012345678", "'+2348012345678"},
+		{"-1", "'-1"},
+		{"@cmd", "'@cmd"},
+		{"\tindented", "'\tindented"},
+		{"\rreturn", "'\rreturn"},
+		{"a=b", "a=b"},
+	}
+
+	for _, tt := range tests {
+		if got := EscapeSpreadsheetCell(tt.in); got != tt.want {
+			t.Errorf("EscapeSpreadsheetCell(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestXLSXColumn(t *testing.T) {
+	tests := []struct {
+		i    int
+		want string
+	}{
+		{0, "A"},
+		{25, "Z"},
+		{26, "AA"},
+		{27, "AB"},
+		{51, "AZ"},
+		{52, "BA"},
+		{701, "ZZ"},
+		{702, "AAA"},
+	}
+
+	for _, tt := range tests {
+		if got := xlsxColumn(tt.i); got != tt.want {
+			t.Errorf("xlsxColumn(%d) = %q, want %q", tt.i, got, tt.want)
+		}
+	}
+}
+
+func TestXLSXSheetName(t *testing.T) {
+	tests := []struct {
+		name string
+		used []string
+		n    int
+		want string
+	}{
+		{"August 2025", nil, 1, "August 2025"},
+		{"  Padded  ", nil, 1, "Padded"},
+		{"Q3: Jul/Aug", nil, 1, "Q3- Jul-Aug"},
+		{"[a]*?\\", nil, 1, "-a----"},
+		{"A sheet name that is far too long for Excel", nil, 1, "A sheet name that is far too lo"},
+		{"", nil, 2, "Sheet2"},
+		{"August 2025", []string{"august 2025"}, 3, "Sheet3"},
+	}
+
+	for _, tt := range tests {
+		used := map[string]bool{}
+		for _, name := range tt.used {
+			used[name] = true
+		}
+		got := xlsxSheetName(tt.name, tt.n, used)
+		if got != tt.want {
+			t.Errorf("xlsxSheetName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+		if !used[strings.ToLower(got)] {
+			t.Errorf("xlsxSheetName(%q) did not record %q as used", tt.name, got)
+		}
+	}
+}
+
+func TestWriteXLSX(t *testing.T) {
+	tests := []struct {
+		name       string
+		sheets     []XLSXSheet
+		wantFiles  []string
+		wantInXML  map[string][]string
+		notInSheet []string
+	}{
+		{
+			name:      "empty workbook",
+			sheets:    nil,
+			wantFiles: []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"},
+			wantInXML: map[string][]string{
+				"xl/workbook.xml": {`<sheet name="Sheet1" sheetId="1" r:id="rId1"/>`},
+			},
+		},
+		{
+			name: "cells",
+			sheets: []XLSXSheet{{
+				Name: "Attendance",
+				Rows: [][]interface{}{
+					{"Name", "Attended", "Rate", "Member"},
+					{"Ade & Bola <3", 4, 0.5, true},
+					{"=HYPERLINK(\"x\")", nil, 1.25, false},
+				},
+			}},
+			wantInXML: map[string][]string{
+				"xl/worksheets/sheet1.xml": {
+					`<c r="A1" t="inlineStr"><is><t xml:space="preserve">Name</t></is></c>`,
+					`<c r="A2" t="inlineStr"><is><t xml:space="preserve">Ade &amp; Bola &lt;3</t></is></c>`,
+					`<c r="B2"><v>4</v></c>`,
+					`<c r="C2"><v>0.5</v></c>`,
+					`<c r="D2" t="b"><v>1</v></c>`,
+					`<c r="A3" t="inlineStr"><is><t xml:space="preserve">&#39;=HYPERLINK(&#34;x&#34;)</t></is></c>`,
+					`<c r="C3"><v>1.25</v></c>`,
+					`<c r="D3" t="b"><v>0</v></c>`,
+				},
+			},
+			notInSheet: []string{`r="B3"`},
+		},
+		{
+			name: "several sheets",
+			sheets: []XLSXSheet{
+				{Name: "July 2025"},
+				{Name: "july 2025"},
+			},
+			wantFiles: []string{"xl/worksheets/sheet1.xml", "xl/worksheets/sheet2.xml"},
+			wantInXML: map[string][]string{
+				"xl/workbook.xml": {
+					`<sheet name="July 2025" sheetId="1" r:id="rId1"/>`,
+					`<sheet name="Sheet2" sheetId="2" r:id="rId2"/>`,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteXLSX(&buf, tt.sheets); err != nil {
+				t.Fatalf("WriteXLSX: %v", err)
+			}
+
+			zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			if err != nil {
+				t.Fatalf("workbook is not a zip: %v", err)
+			}
+			files := map[string]string{}
+			for _, f := range zr.File {
+				rc, err := f.Open()
+				if err != nil {
+					t.Fatal(err)
+				}
+				content, err := io.ReadAll(rc)
+				rc.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+				files[f.Name] = string(content)
+			}
+
+			for _, name := range tt.wantFiles {
+				if _, ok := files[name]; !ok {
+					t.Errorf("workbook is missing %s", name)
+				}
+			}
+			for name, fragments := range tt.wantInXML {
+				for _, fragment := range fragments {
+					if !strings.Contains(files[name], fragment) {
+						t.Errorf("%s does not contain %s", name, fragment)
+					}
+				}
+			}
+			for _, fragment := range tt.notInSheet {
+				if strings.Contains(files["xl/worksheets/sheet1.xml"], fragment) {
+					t.Errorf("sheet1.xml contains %s", fragment)
+				}
+			}
+		})
+	}
+}
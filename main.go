@@ -6,12 +6,16 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"time"
+	// Embedded zone data, so church timezones resolve on hosts without a zoneinfo database
+	_ "time/tzdata"
 
 	"cci-api/internal/config"
 	"cci-api/internal/database"
 	"cci-api/internal/handler"
 	"cci-api/internal/middleware"
+	"cci-api/internal/models"
 	"cci-api/internal/repository"
 	"cci-api/internal/service"
 
@@ -55,21 +59,40 @@ func main() {
 	roleRepo := repository.NewRoleRepository(db)
 	familyMemberRepo := repository.NewFamilyMemberRepository(db)
 	localChurchRepo := repository.NewLocalChurchRepository(db)
+	serviceEventRepo := repository.NewServiceEventRepository(db)
+	qrTokenUseRepo := repository.NewQRTokenUseRepository(db)
+	venueCodeRepo := repository.NewVenueCodeRepository(db)
+	visitorRepo := repository.NewVisitorRepository(db)
+	attendanceAuditRepo := repository.NewAttendanceAuditRepository(db)
+	followUpRepo := repository.NewFollowUpRepository(db)
+	membershipTransitionRepo := repository.NewMembershipTransitionRepository(db)
+	childCheckinRepo := repository.NewChildCheckinRepository(db)
+	loginAttemptRepo := repository.NewLoginAttemptRepository(db)
 
 	// Initialize services
 	emailService := service.NewEmailService(cfg)
-	authService := service.NewAuthService(cfg, userRepo, refreshTokenRepo, emailService)
+	loginProtectionService := service.NewLoginProtectionService(cfg, loginAttemptRepo, userRepo, emailService)
+	twoFactorService := service.NewTwoFactorService(cfg, userRepo)
+	sessionService := service.NewSessionService(cfg, userRepo, refreshTokenRepo)
+	authService := service.NewAuthService(cfg, db, userRepo, refreshTokenRepo, visitorRepo, attendanceRepo, emailService, loginProtectionService, twoFactorService, sessionService)
 	userService := service.NewUserService(cfg, userRepo)
-	attendanceService := service.NewAttendanceService(cfg, attendanceRepo, userRepo)
-	qrService := service.NewQRService(cfg, userRepo)
-	sermonService := service.NewSermonService(cfg, sermonRepo)
-	announcementService := service.NewAnnouncementService(cfg, announcementRepo)
-	roleService := service.NewRoleService(cfg, roleRepo)
+	attendanceService := service.NewAttendanceService(cfg, db, attendanceRepo, userRepo, serviceEventRepo, localChurchRepo, qrTokenUseRepo, venueCodeRepo, visitorRepo, attendanceAuditRepo, familyMemberRepo)
+	qrService := service.NewQRService(cfg, userRepo, localChurchRepo)
+	sermonService := service.NewSermonService(cfg, sermonRepo, userRepo)
+	announcementService := service.NewAnnouncementService(cfg, announcementRepo, userRepo)
+	permissionService := service.NewPermissionService(cfg, userRepo, roleRepo)
+	roleService := service.NewRoleService(cfg, roleRepo, userRepo, permissionService)
 	familyMemberService := service.NewFamilyMemberService(cfg, familyMemberRepo)
 	localChurchService := service.NewLocalChurchService(cfg, localChurchRepo)
+	serviceEventService := service.NewServiceEventService(cfg, serviceEventRepo, localChurchRepo, venueCodeRepo, attendanceRepo, childCheckinRepo)
+	followUpService := service.NewFollowUpService(cfg, followUpRepo, attendanceRepo, userRepo, serviceEventRepo, localChurchRepo)
+	membershipService := service.NewMembershipService(cfg, db, userRepo, attendanceRepo, membershipTransitionRepo)
+	childCheckinService := service.NewChildCheckinService(cfg, db, childCheckinRepo, familyMemberRepo, userRepo, serviceEventRepo, localChurchRepo)
 
 	// Initialize handlers
 	authHandler := handler.NewAuthHandler(authService)
+	twoFactorHandler := handler.NewTwoFactorHandler(twoFactorService)
+	sessionHandler := handler.NewSessionHandler(sessionService)
 	userHandler := handler.NewUserHandler(userService)
 	attendanceHandler := handler.NewAttendanceHandler(attendanceService)
 	qrHandler := handler.NewQRHandler(qrService)
@@ -78,6 +101,15 @@ func main() {
 	announcementHandler := handler.NewAnnouncementHandler(announcementService)
 	familyMemberHandler := handler.NewFamilyMemberHandler(familyMemberService)
 	localChurchHandler := handler.NewLocalChurchHandler(localChurchService)
+	serviceEventHandler := handler.NewServiceEventHandler(serviceEventService)
+	followUpHandler := handler.NewFollowUpHandler(followUpService)
+	membershipHandler := handler.NewMembershipHandler(membershipService)
+	childCheckinHandler := handler.NewChildCheckinHandler(childCheckinService)
+
+	// Background jobs run until the server shuts down
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	followUpService.StartScheduler(jobsCtx)
 
 	// Initialize Echo
 	e := echo.New()
@@ -85,8 +117,14 @@ func main() {
 	// Set custom validator
 	e.Validator = &CustomValidator{validator: validator.New()}
 
+	// Client IPs, used to throttle failed logins, only come from X-Forwarded-For when set by a private proxy
+	e.IPExtractor = echo.ExtractIPFromXFFHeader()
+
 	// Middleware
-	e.Use(echomiddleware.Logger())
+	// Requests are logged by path rather than URI, so access_token query parameters never reach the logs
+	loggerConfig := echomiddleware.DefaultLoggerConfig
+	loggerConfig.Format = strings.Replace(loggerConfig.Format, `"uri":"${uri}"`, `"path":"${path}"`, 1)
+	e.Use(echomiddleware.LoggerWithConfig(loggerConfig))
 	e.Use(echomiddleware.Recover())
 	e.Use(middleware.CORSMiddleware(cfg.CORSOrigins))
 	e.Use(middleware.SecurityHeadersMiddleware())
@@ -94,9 +132,12 @@ func main() {
 	// Rate limiting
 	e.Use(echomiddleware.RateLimiter(echomiddleware.NewRateLimiterMemoryStore(100)))
 
-	// Request timeout
+	// Request timeout. Exports and the live feed are streamed, which the timeout's buffered response does not allow.
 	e.Use(echomiddleware.TimeoutWithConfig(echomiddleware.TimeoutConfig{
 		Timeout: 30 * time.Second,
+		Skipper: func(c echo.Context) bool {
+			return strings.HasPrefix(c.Path(), "/api/v1/attendance/export/") || c.Path() == "/api/v1/attendance/live"
+		},
 	}))
 
 	// Health check endpoint
@@ -115,60 +156,126 @@ func main() {
 	auth.POST("/register", authHandler.BasicRegister)
 	auth.POST("/register/complete", authHandler.CompleteRegister)
 	auth.POST("/login", authHandler.Login)
+	auth.POST("/login/2fa", authHandler.VerifyTwoFactorLogin)
+	auth.POST("/login/2fa/setup", authHandler.SetupTwoFactorLogin)
 	auth.POST("/refresh", authHandler.RefreshToken)
 	auth.POST("/set-password", authHandler.SetPassword)
 	auth.POST("/forgot-password", authHandler.ForgotPassword)
 	auth.POST("/reset-password", authHandler.ResetPassword)
 
-	// Protected routes
+	// Live attendance feed. EventSource cannot send headers, so the token may also come in the query string.
+	api.GET("/attendance/live", attendanceHandler.StreamLiveAttendance,
+		middleware.QueryTokenMiddleware(),
+		middleware.JWTMiddleware(cfg),
+		middleware.LoadPermissions(permissionService),
+		middleware.RequirePermission(models.PermissionAnalyticsRead))
+
+	// Protected routes. Admins may use every route; everyone else needs the permission a route asks for,
+	// granted by their role.
 	protected := api.Group("")
 	protected.Use(middleware.JWTMiddleware(cfg))
+	protected.Use(middleware.LoadPermissions(permissionService))
 
 	// Auth protected routes
 	protected.POST("/logout", authHandler.Logout)
+	protected.POST("/logout/all", sessionHandler.RevokeAllSessions)
+
+	// Devices the logged-in member is logged in on
+	sessions := protected.Group("/sessions")
+	sessions.GET("", sessionHandler.ListSessions)
+	sessions.DELETE("/:id", sessionHandler.RevokeSession)
+
+	// Two-factor authentication for the logged-in member
+	twoFactor := protected.Group("/auth/2fa")
+	twoFactor.GET("", twoFactorHandler.GetStatus)
+	twoFactor.POST("/enroll", twoFactorHandler.Enroll)
+	twoFactor.POST("/enable", twoFactorHandler.Enable)
+	twoFactor.POST("/disable", twoFactorHandler.Disable)
+	twoFactor.POST("/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes)
 
 	// User routes
 	users := protected.Group("/users")
-	users.GET("/search", userHandler.SearchUsers)
-	users.GET("", userHandler.GetAllUsers)
-	users.GET("/filter", userHandler.FilterUsers)
-
-	// Attendance routes
+	users.GET("/search", userHandler.SearchUsers, middleware.RequirePermission(models.PermissionUsersRead))
+	users.GET("", userHandler.GetAllUsers, middleware.RequirePermission(models.PermissionUsersRead))
+	users.GET("/filter", userHandler.FilterUsers, middleware.RequirePermission(models.PermissionUsersRead))
+	users.PUT("/:user_id/shepherd", userHandler.AssignShepherd, middleware.RequirePermission(models.PermissionUsersManage))
+	users.PUT("/:user_id/role", roleHandler.AssignRole, middleware.RequirePermission(models.PermissionRolesManage))
+	users.POST("/:user_id/unlock", authHandler.UnlockAccount, middleware.RequirePermission(models.PermissionUsersManage))
+	users.DELETE("/:user_id/2fa", twoFactorHandler.Reset, middleware.RequirePermission(models.PermissionUsersManage))
+
+	// Attendance routes; members check themselves in, ushers with attendance:write check others in
 	attendance := protected.Group("/attendance")
-	attendance.POST("", attendanceHandler.CreateAttendance)
-	attendance.POST("/qr-checkin", attendanceHandler.QRCheckin)
-	attendance.GET("/history", attendanceHandler.GetAttendanceHistory)
-	attendance.GET("/analytics", attendanceHandler.GetAttendanceAnalytics)
+	attendance.POST("", attendanceHandler.CreateAttendance, middleware.RequirePermission(models.PermissionAttendanceWrite))
+	attendance.POST("/qr-checkin", attendanceHandler.QRCheckin, middleware.RequirePermission(models.PermissionAttendanceWrite))
+	attendance.POST("/self-checkin", attendanceHandler.SelfCheckin)
+	attendance.POST("/geofence-checkin", attendanceHandler.GeofenceCheckin)
+	attendance.POST("/visitors", attendanceHandler.RegisterVisitor, middleware.RequirePermission(models.PermissionAttendanceWrite))
+	attendance.POST("/visitors/:id/merge", attendanceHandler.MergeVisitor, middleware.RequirePermission(models.PermissionUsersManage))
+	attendance.POST("/household", attendanceHandler.CheckInHousehold, middleware.RequirePermission(models.PermissionAttendanceWrite))
+	attendance.POST("/batch", attendanceHandler.SyncBatch, middleware.RequirePermission(models.PermissionAttendanceWrite))
+	attendance.POST("/checkout", attendanceHandler.CheckOut, middleware.RequirePermission(models.PermissionAttendanceWrite))
+	attendance.POST("/qr-checkout", attendanceHandler.QRCheckout, middleware.RequirePermission(models.PermissionAttendanceWrite))
+	attendance.GET("/history", attendanceHandler.GetAttendanceHistory, middleware.RequirePermission(models.PermissionAttendanceRead))
+	attendance.GET("/history/records", attendanceHandler.GetAttendanceRecords, middleware.RequirePermission(models.PermissionAttendanceRead))
+	attendance.GET("/export/:report", attendanceHandler.ExportAttendance, middleware.RequirePermission(models.PermissionAnalyticsRead))
+	attendance.GET("/analytics", attendanceHandler.GetAttendanceAnalytics, middleware.RequirePermission(models.PermissionAnalyticsRead))
+	attendance.GET("/analytics/trends", attendanceHandler.GetAttendanceTrend, middleware.RequirePermission(models.PermissionAnalyticsRead))
+	attendance.GET("/analytics/comparisons", attendanceHandler.GetAttendanceComparisons, middleware.RequirePermission(models.PermissionAnalyticsRead))
+	attendance.GET("/analytics/breakdown", attendanceHandler.GetAttendanceBreakdown, middleware.RequirePermission(models.PermissionAnalyticsRead))
+	attendance.GET("/analytics/retention", attendanceHandler.GetFirstTimerRetention, middleware.RequirePermission(models.PermissionAnalyticsRead))
+	attendance.GET("/analytics/households", attendanceHandler.GetHouseholdAttendance, middleware.RequirePermission(models.PermissionAnalyticsRead))
+	attendance.GET("/me", attendanceHandler.GetMyAttendance)
+	attendance.GET("/users/:user_id", attendanceHandler.GetUserAttendance, middleware.RequirePermission(models.PermissionAttendanceRead))
+	attendance.POST("/backdate", attendanceHandler.BackdateAttendance, middleware.RequirePermission(models.PermissionAttendanceCorrect))
+	attendance.PUT("/:id", attendanceHandler.AmendAttendance, middleware.RequirePermission(models.PermissionAttendanceCorrect))
+	attendance.POST("/:id/void", attendanceHandler.VoidAttendance, middleware.RequirePermission(models.PermissionAttendanceCorrect))
+	attendance.GET("/:id/audit", attendanceHandler.GetAttendanceAudit, middleware.RequirePermission(models.PermissionAttendanceCorrect))
+
+	// Service event routes
+	events := protected.Group("/events")
+	events.GET("", serviceEventHandler.GetEvents)
+	events.GET("/current", serviceEventHandler.GetCurrentEvent)
+	events.GET("/:id", serviceEventHandler.GetEventByID)
+	events.POST("", serviceEventHandler.CreateEvent, middleware.RequirePermission(models.PermissionEventsManage))
+	events.PUT("/:id", serviceEventHandler.UpdateEvent, middleware.RequirePermission(models.PermissionEventsManage))
+	events.DELETE("/:id", serviceEventHandler.DeleteEvent, middleware.RequirePermission(models.PermissionEventsManage))
+	events.GET("/:id/venue-codes", serviceEventHandler.GetVenueCodes, middleware.RequirePermission(models.PermissionEventsManage))
+	events.POST("/:id/venue-codes", serviceEventHandler.CreateVenueCode, middleware.RequirePermission(models.PermissionEventsManage))
 
 	// QR Code routes
 	qr := protected.Group("/qr")
 	qr.POST("/generate", qrHandler.GenerateQRCode)
+	qr.GET("/rotating", qrHandler.GetRotatingQRCode)
+	qr.GET("/cards/:user_id", qrHandler.GetMemberCard)
+	qr.POST("/cards/:user_id/revoke", qrHandler.RevokeMemberCards)
+	qr.POST("/cards/sheet", qrHandler.GetMemberCardSheet, middleware.RequirePermission(models.PermissionUsersManage))
 
-	// Role routes (Admin only)
+	// Role routes
 	roles := protected.Group("/roles")
-	roles.Use(middleware.AdminMiddleware())
+	roles.Use(middleware.RequirePermission(models.PermissionRolesManage))
 	roles.POST("", roleHandler.CreateRole)
 	roles.GET("", roleHandler.GetRoles)
+	roles.GET("/permissions", roleHandler.GetRolesAndPermissions)
 	roles.GET("/:id", roleHandler.GetRoleByID)
 	roles.PUT("/:id", roleHandler.UpdateRole)
 	roles.DELETE("/:id", roleHandler.DeleteRole)
 
 	// Sermon routes
 	sermons := protected.Group("/sermons")
-	sermons.POST("", sermonHandler.CreateSermon)
+	sermons.POST("", sermonHandler.CreateSermon, middleware.RequirePermission(models.PermissionSermonsManage))
 	sermons.GET("", sermonHandler.GetSermons)
 	sermons.GET("/:id", sermonHandler.GetSermonByID)
-	sermons.PUT("/:id", sermonHandler.UpdateSermon)
-	sermons.DELETE("/:id", sermonHandler.DeleteSermon)
+	sermons.PUT("/:id", sermonHandler.UpdateSermon, middleware.RequirePermission(models.PermissionSermonsManage))
+	sermons.DELETE("/:id", sermonHandler.DeleteSermon, middleware.RequirePermission(models.PermissionSermonsManage))
 
 	// Announcement routes
 	announcements := protected.Group("/announcements")
-	announcements.POST("", announcementHandler.CreateAnnouncement)
+	announcements.POST("", announcementHandler.CreateAnnouncement, middleware.RequirePermission(models.PermissionAnnouncementsManage))
 	announcements.GET("", announcementHandler.GetAnnouncements)
 	announcements.GET("/active", announcementHandler.GetActiveAnnouncements)
 	announcements.GET("/:id", announcementHandler.GetAnnouncementByID)
-	announcements.PUT("/:id", announcementHandler.UpdateAnnouncement)
-	announcements.DELETE("/:id", announcementHandler.DeleteAnnouncement)
+	announcements.PUT("/:id", announcementHandler.UpdateAnnouncement, middleware.RequirePermission(models.PermissionAnnouncementsManage))
+	announcements.DELETE("/:id", announcementHandler.DeleteAnnouncement, middleware.RequirePermission(models.PermissionAnnouncementsManage))
 
 	// Family member routes
 	familyMembers := protected.Group("/family-members")
@@ -178,15 +285,40 @@ func main() {
 	familyMembers.PUT("/:id", familyMemberHandler.UpdateFamilyMember)
 	familyMembers.DELETE("/:id", familyMemberHandler.DeleteFamilyMember)
 
-	// Local church routes (Admin only)
+	// Local church routes
 	churches := protected.Group("/churches")
-	churches.Use(middleware.AdminMiddleware())
+	churches.Use(middleware.RequirePermission(models.PermissionChurchesManage))
 	churches.POST("", localChurchHandler.CreateChurch)
 	churches.GET("", localChurchHandler.GetChurches)
 	churches.GET("/:id", localChurchHandler.GetChurchByID)
 	churches.PUT("/:id", localChurchHandler.UpdateChurch)
 	churches.DELETE("/:id", localChurchHandler.DeleteChurch)
 
+	// Pastoral follow-up routes; shepherds see the follow-ups assigned to them, follow-ups:manage sees all
+	followUps := protected.Group("/follow-ups")
+	followUps.GET("", followUpHandler.GetFollowUps)
+	followUps.POST("/scan", followUpHandler.ScanFollowUps, middleware.RequirePermission(models.PermissionFollowUpsManage))
+	followUps.GET("/:id", followUpHandler.GetFollowUpByID)
+	followUps.PUT("/:id", followUpHandler.UpdateFollowUp)
+
+	// Membership pipeline routes
+	membership := protected.Group("/membership")
+	membership.Use(middleware.RequirePermission(models.PermissionMembershipManage))
+	membership.GET("/funnel", membershipHandler.GetMembershipFunnel)
+	membership.GET("/suggestions", membershipHandler.GetStageSuggestions)
+	membership.GET("/users/:user_id", membershipHandler.GetMembership)
+	membership.PUT("/users/:user_id/stage", membershipHandler.TransitionStage)
+
+	// Children's check-in routes; guardians check their children in and print labels, staff with the
+	// children:manage permission release children and watch the rosters
+	children := protected.Group("/children")
+	children.POST("/checkins", childCheckinHandler.CheckIn)
+	children.GET("/checkins/:id/label", childCheckinHandler.GetChildLabel)
+	children.POST("/labels", childCheckinHandler.GetLabelSheet)
+	children.POST("/checkins/:id/checkout", childCheckinHandler.CheckOut, middleware.RequirePermission(models.PermissionChildrenManage))
+	children.GET("/roster", childCheckinHandler.GetRoster, middleware.RequirePermission(models.PermissionChildrenManage))
+	children.GET("/alerts", childCheckinHandler.GetPickupAlerts, middleware.RequirePermission(models.PermissionChildrenManage))
+
 	// Start server in a goroutine
 	go func() {
 		if err := e.Start(":" + cfg.Port); err != nil && err != http.ErrServerClosed {
@@ -200,6 +332,7 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server...")
+	stopJobs()
 
 	// Create a context with timeout for shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)